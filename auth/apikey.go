@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+)
+
+// APIKeyPrefix starts every API key, so keys and JWTs can share the bearer header.
+// A key looks like "lsk_<8 hex id>_<secret>"; only the id part is stored in clear.
+const APIKeyPrefix = "lsk_"
+
+// KeyVerifier resolves an API key to the principal it authenticates.
+type KeyVerifier interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*Principal, error)
+}
+
+// GenerateAPIKey returns a new key, its lookup prefix and the hash to store.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(id)
+	key = APIKeyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey hashes a key for storage. Keys carry 256 random bits, so a plain SHA-256 is enough.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// ParseAPIKeyPrefix returns the lookup prefix of a well-formed key.
+func ParseAPIKeyPrefix(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, APIKeyPrefix)
+	if !ok {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != 8 || secret == "" {
+		return "", false
+	}
+	if _, err := hex.DecodeString(prefix); err != nil {
+		return "", false
+	}
+	return prefix, true
+}
+
+// KeyPrincipal builds the principal for a verified API key.
+// Key principals have no user id or role; what they may call is decided by their scopes.
+func KeyPrincipal(key *pb.ApiKey, token string) *Principal {
+	return &Principal{
+		APIKeyID: key.Id,
+		Scopes:   key.Scopes,
+		Token:    token,
+	}
+}
+
+type cachedKey struct {
+	principal *Principal
+	expires   time.Time
+}
+
+type keyCache struct {
+	verifier KeyVerifier
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedKey
+}
+
+// CacheKeys remembers successful verifications for ttl, so that services don't ask
+// the users service on every call. Revocation takes effect within ttl.
+func CacheKeys(verifier KeyVerifier, ttl time.Duration) KeyVerifier {
+	return &keyCache{
+		verifier: verifier,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[string]cachedKey),
+	}
+}
+
+func (c *keyCache) AuthenticateAPIKey(ctx context.Context, key string) (*Principal, error) {
+	hash := HashAPIKey(key)
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[hash]
+	if ok && now.After(entry.expires) {
+		delete(c.entries, hash)
+		ok = false
+	}
+	c.mu.Unlock()
+	if ok {
+		return entry.principal, nil
+	}
+
+	p, err := c.verifier.AuthenticateAPIKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[hash] = cachedKey{principal: p, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return p, nil
+}
@@ -0,0 +1,365 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func newTestTokenManager(t *testing.T) *TokenManager {
+	tokens, err := NewTokenManager(testSecret, time.Minute, time.Hour)
+	require.NoError(t, err)
+	return tokens
+}
+
+func TestNewTokenManager_ShortSecret(t *testing.T) {
+	_, err := NewTokenManager("short", time.Minute, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestTokenManager_IssueAndVerify(t *testing.T) {
+	tokens := newTestTokenManager(t)
+
+	pair, err := tokens.IssuePair("42", "ivan@example.com", RoleLibrarian, 3)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, pair.ExpiresIn)
+
+	claims, err := tokens.Verify(pair.AccessToken, AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "42", claims.Subject)
+	assert.Equal(t, "ivan@example.com", claims.Email)
+	assert.Equal(t, RoleLibrarian, claims.Role)
+
+	claims, err = tokens.Verify(pair.RefreshToken, RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, "42", claims.Subject)
+	assert.Equal(t, 3, claims.Version)
+
+	_, err = tokens.Verify(pair.RefreshToken, AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = tokens.Verify(pair.AccessToken, RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestTokenManager_RejectsExpiredAndForeignTokens(t *testing.T) {
+	tokens := newTestTokenManager(t)
+	pair, err := tokens.IssuePair("42", "ivan@example.com", RolePatron, 0)
+	require.NoError(t, err)
+
+	tokens.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, err = tokens.Verify(pair.AccessToken, AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	other, err := NewTokenManager("fedcba9876543210fedcba9876543210", time.Minute, time.Hour)
+	require.NoError(t, err)
+	_, err = other.Verify(pair.RefreshToken, RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = tokens.Verify("not-a-jwt", AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	tokens := newTestTokenManager(t)
+	pair, err := tokens.IssuePair("7", "anna@example.com", "", 0)
+	require.NoError(t, err)
+
+	interceptor := UnaryServerInterceptor(tokens, nil, "/library.UserService/Login")
+	var got *Principal
+	handler := func(ctx context.Context, req any) (any, error) {
+		got, _ = FromContext(ctx)
+		return "ok", nil
+	}
+	call := func(ctx context.Context, method string) error {
+		got = nil
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+	withAuth := func(value string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", value))
+	}
+
+	err = call(withAuth("Bearer "+pair.AccessToken), "/library.BookService/GetBook")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "7", got.UserID)
+	assert.Equal(t, RolePatron, got.Role)
+	assert.Equal(t, pair.AccessToken, got.Token)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{name: "no metadata", ctx: context.Background()},
+		{name: "no header", ctx: metadata.NewIncomingContext(context.Background(), metadata.MD{})},
+		{name: "wrong scheme", ctx: withAuth("Basic " + pair.AccessToken)},
+		{name: "refresh token", ctx: withAuth("Bearer " + pair.RefreshToken)},
+		{name: "garbage", ctx: withAuth("Bearer garbage")},
+		{name: "api key without verifier", ctx: withAuth("Bearer lsk_0123abcd_secret")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := call(tt.ctx, "/library.BookService/GetBook")
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+			assert.Nil(t, got)
+		})
+	}
+
+	require.NoError(t, call(context.Background(), "/library.UserService/Login"))
+}
+
+// fakeServerStream is a server stream that only carries a context.
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptors(t *testing.T) {
+	tokens := newTestTokenManager(t)
+	patron, err := tokens.IssuePair("7", "anna@example.com", "", 0)
+	require.NoError(t, err)
+	librarian, err := tokens.IssuePair("8", "olga@example.com", RoleLibrarian, 0)
+	require.NoError(t, err)
+
+	policy := Policy{
+		"/library.BookService/ImportBooks": {RoleLibrarian, ScopeBooksWrite},
+		"/grpc.health.v1.Health/Watch":     {RolePublic, ""},
+	}
+	authenticate := StreamServerInterceptor(tokens, nil, policy.PublicMethods()...)
+	authorize := StreamAuthorizationInterceptor(policy)
+	var got *Principal
+	handler := func(srv any, ss grpc.ServerStream) error {
+		got, _ = FromContext(ss.Context())
+		return nil
+	}
+	call := func(token, method string) error {
+		got = nil
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		}
+		info := &grpc.StreamServerInfo{FullMethod: method}
+		return authenticate(nil, &fakeServerStream{ctx: ctx}, info, func(srv any, ss grpc.ServerStream) error {
+			return authorize(srv, ss, info, handler)
+		})
+	}
+
+	require.NoError(t, call(librarian.AccessToken, "/library.BookService/ImportBooks"))
+	require.NotNil(t, got)
+	assert.Equal(t, "8", got.UserID)
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(call(patron.AccessToken, "/library.BookService/ImportBooks")))
+	assert.Equal(t, codes.Unauthenticated, status.Code(call("", "/library.BookService/ImportBooks")))
+	assert.Equal(t, codes.Unauthenticated, status.Code(call("garbage", "/library.BookService/ImportBooks")))
+	assert.Nil(t, got)
+
+	require.NoError(t, call("", "/grpc.health.v1.Health/Watch"))
+}
+
+func TestUnaryClientInterceptor_ForwardsToken(t *testing.T) {
+	interceptor := UnaryClientInterceptor()
+	var sent metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+
+	require.NoError(t, interceptor(WithToken(context.Background(), "abc"), "/m", nil, nil, nil, invoker))
+	assert.Equal(t, []string{"Bearer abc"}, sent.Get("authorization"))
+
+	require.NoError(t, interceptor(Detach(WithToken(context.Background(), "abc")), "/m", nil, nil, nil, invoker))
+	assert.Equal(t, []string{"Bearer abc"}, sent.Get("authorization"))
+
+	require.NoError(t, interceptor(context.Background(), "/m", nil, nil, nil, invoker))
+	assert.Empty(t, sent.Get("authorization"))
+}
+
+func TestRole_Allows(t *testing.T) {
+	assert.True(t, RoleAdmin.Allows(RoleLibrarian))
+	assert.True(t, RoleLibrarian.Allows(RolePatron))
+	assert.True(t, RolePatron.Allows(RolePatron))
+	assert.False(t, RolePatron.Allows(RoleLibrarian))
+	assert.False(t, RoleLibrarian.Allows(RoleAdmin))
+	assert.False(t, Role("root").Allows(RolePatron))
+
+	_, ok := ParseRole("public")
+	assert.False(t, ok)
+	r, ok := ParseRole("admin")
+	assert.True(t, ok)
+	assert.Equal(t, RoleAdmin, r)
+}
+
+func TestUnaryAuthorizationInterceptor(t *testing.T) {
+	policy := Policy{
+		"/library.BookService/GetBook":    {RolePatron, ScopeBooksRead},
+		"/library.BookService/CreateBook": {RoleLibrarian, ScopeBooksWrite},
+		"/library.UserService/Login":      {RolePublic, ""},
+		"/library.UserService/DeleteUser": {RoleAdmin, ""},
+	}
+	interceptor := UnaryAuthorizationInterceptor(policy)
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+	call := func(ctx context.Context, method string) codes.Code {
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return status.Code(err)
+	}
+	as := func(role Role) context.Context {
+		return WithPrincipal(context.Background(), &Principal{UserID: "1", Role: role})
+	}
+
+	assert.Equal(t, codes.OK, call(as(RolePatron), "/library.BookService/GetBook"))
+	assert.Equal(t, codes.PermissionDenied, call(as(RolePatron), "/library.BookService/CreateBook"))
+	assert.Equal(t, codes.OK, call(as(RoleLibrarian), "/library.BookService/CreateBook"))
+	assert.Equal(t, codes.OK, call(as(RoleAdmin), "/library.BookService/CreateBook"))
+	assert.Equal(t, codes.OK, call(context.Background(), "/library.UserService/Login"))
+	assert.Equal(t, codes.Unauthenticated, call(context.Background(), "/library.BookService/GetBook"))
+	assert.Equal(t, codes.PermissionDenied, call(as(RoleAdmin), "/library.BookService/DeleteEverything"))
+
+	kiosk := WithPrincipal(context.Background(), &Principal{APIKeyID: "3", Scopes: []string{ScopeBooksRead}})
+	assert.Equal(t, codes.OK, call(kiosk, "/library.BookService/GetBook"))
+	assert.Equal(t, codes.PermissionDenied, call(kiosk, "/library.BookService/CreateBook"))
+	assert.Equal(t, codes.PermissionDenied, call(kiosk, "/library.UserService/DeleteUser"))
+}
+
+func TestPermissions_CoverAllMethods(t *testing.T) {
+	for _, sd := range []grpc.ServiceDesc{pb.UserService_ServiceDesc, pb.BookService_ServiceDesc, pb.LoanService_ServiceDesc, pb.NotificationService_ServiceDesc, healthpb.Health_ServiceDesc} {
+		names := make([]string, 0, len(sd.Methods)+len(sd.Streams))
+		for _, m := range sd.Methods {
+			names = append(names, m.MethodName)
+		}
+		for _, s := range sd.Streams {
+			names = append(names, s.StreamName)
+		}
+		for _, name := range names {
+			method := "/" + sd.ServiceName + "/" + name
+			perm, ok := Permissions[method]
+			assert.True(t, ok, "no permission entry for %s", method)
+			if perm.Scope != "" {
+				assert.True(t, ValidScope(perm.Scope), "unknown scope %q for %s", perm.Scope, method)
+			}
+		}
+	}
+}
+
+func TestRequireSelfOrStaff(t *testing.T) {
+	patron := WithPrincipal(context.Background(), &Principal{UserID: "1", Role: RolePatron})
+	librarian := WithPrincipal(context.Background(), &Principal{UserID: "2", Role: RoleLibrarian})
+
+	assert.NoError(t, RequireSelfOrStaff(patron, "1"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(RequireSelfOrStaff(patron, "3")))
+	assert.NoError(t, RequireSelfOrStaff(librarian, "3"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(RequireSelfOrStaff(context.Background(), "1")))
+
+	kiosk := WithPrincipal(context.Background(), &Principal{APIKeyID: "3", Scopes: []string{ScopeLoansWrite}})
+	assert.NoError(t, RequireSelfOrStaff(kiosk, "1"))
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	require.NoError(t, err)
+	assert.True(t, IsAPIKey(key))
+	assert.Equal(t, HashAPIKey(key), hash)
+	assert.NotContains(t, hash, prefix)
+
+	parsed, ok := ParseAPIKeyPrefix(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, parsed)
+
+	other, _, _, err := GenerateAPIKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+
+	for _, bad := range []string{"", "lsk_", "lsk_0123abcd", "lsk_0123abcd_", "lsk_xyz_secret", "lsk_0123abcg_secret", "jwt.token.here"} {
+		_, ok := ParseAPIKeyPrefix(bad)
+		assert.False(t, ok, bad)
+	}
+}
+
+type fakeKeyVerifier struct {
+	calls int
+	keys  map[string]*pb.ApiKey
+}
+
+func (f *fakeKeyVerifier) AuthenticateAPIKey(ctx context.Context, key string) (*Principal, error) {
+	f.calls++
+	k, ok := f.keys[key]
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid api key")
+	}
+	return KeyPrincipal(k, key), nil
+}
+
+func TestUnaryServerInterceptor_APIKey(t *testing.T) {
+	tokens := newTestTokenManager(t)
+	key, _, _, err := GenerateAPIKey()
+	require.NoError(t, err)
+	verifier := &fakeKeyVerifier{keys: map[string]*pb.ApiKey{
+		key: {Id: "5", Name: "kiosk", Scopes: []string{ScopeLoansWrite}},
+	}}
+
+	interceptor := UnaryServerInterceptor(tokens, verifier)
+	var got *Principal
+	handler := func(ctx context.Context, req any) (any, error) {
+		got, _ = FromContext(ctx)
+		return "ok", nil
+	}
+	call := func(token string) error {
+		got = nil
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/library.LoanService/BorrowBook"}, handler)
+		return err
+	}
+
+	require.NoError(t, call(key))
+	require.NotNil(t, got)
+	assert.True(t, got.IsAPIKey())
+	assert.Equal(t, "5", got.APIKeyID)
+	assert.True(t, got.HasScope(ScopeLoansWrite))
+	assert.False(t, got.HasScope(ScopeBooksWrite))
+	assert.Empty(t, got.UserID)
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(call(APIKeyPrefix+"deadbeef_nope")))
+	assert.Nil(t, got)
+}
+
+func TestCacheKeys(t *testing.T) {
+	verifier := &fakeKeyVerifier{keys: map[string]*pb.ApiKey{
+		"lsk_0123abcd_secret": {Id: "1"},
+	}}
+	cache := CacheKeys(verifier, time.Minute).(*keyCache)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		p, err := cache.AuthenticateAPIKey(ctx, "lsk_0123abcd_secret")
+		require.NoError(t, err)
+		assert.Equal(t, "1", p.APIKeyID)
+	}
+	assert.Equal(t, 1, verifier.calls)
+
+	_, err := cache.AuthenticateAPIKey(ctx, "lsk_0123abcd_wrong")
+	assert.Error(t, err)
+	_, err = cache.AuthenticateAPIKey(ctx, "lsk_0123abcd_wrong")
+	assert.Error(t, err)
+	assert.Equal(t, 3, verifier.calls, "failures are not cached")
+
+	delete(verifier.keys, "lsk_0123abcd_secret")
+	now = now.Add(2 * time.Minute)
+	_, err = cache.AuthenticateAPIKey(ctx, "lsk_0123abcd_secret")
+	assert.Error(t, err, "revocation applies once the entry expires")
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Principal is the authenticated caller of an RPC.
+type Principal struct {
+	UserID string
+	Email  string
+	Role   Role
+	// APIKeyID and Scopes are set when the caller authenticated with an API key instead of a JWT.
+	APIKeyID string
+	Scopes   []string
+	// Token is the raw bearer token, forwarded on calls to downstream services.
+	Token string
+}
+
+func (p *Principal) IsAPIKey() bool {
+	return p.APIKeyID != ""
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return scope != "" && slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// WithToken attaches a bearer token to ctx so that clients built with
+// UnaryClientInterceptor or StreamClientInterceptor send it with every call.
+func WithToken(ctx context.Context, token string) context.Context {
+	return WithPrincipal(ctx, &Principal{Token: token})
+}
+
+// Detach returns a background context that keeps the caller's principal,
+// for work that outlives the RPC but still calls other services.
+func Detach(ctx context.Context) context.Context {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return context.Background()
+	}
+	return WithPrincipal(context.Background(), p)
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const authorizationHeader = "authorization"
+
+// UnaryServerInterceptor rejects calls without a valid access token or API key and puts the principal into the context.
+// Methods listed in publicMethods (full names, e.g. "/library.UserService/Login") skip authentication.
+// API keys are rejected when keys is nil.
+func UnaryServerInterceptor(tokens *TokenManager, keys KeyVerifier, publicMethods ...string) grpc.UnaryServerInterceptor {
+	public := make(map[string]bool, len(publicMethods))
+	for _, m := range publicMethods {
+		public[m] = true
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, tokens, keys)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming RPCs.
+func StreamServerInterceptor(tokens *TokenManager, keys KeyVerifier, publicMethods ...string) grpc.StreamServerInterceptor {
+	public := make(map[string]bool, len(publicMethods))
+	for _, m := range publicMethods {
+		public[m] = true
+	}
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if public[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), tokens, keys)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream carries the principal in the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate verifies the bearer token or API key of a call and returns ctx with its principal.
+func authenticate(ctx context.Context, tokens *TokenManager, keys KeyVerifier) (context.Context, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if IsAPIKey(token) {
+		if keys == nil {
+			return nil, status.Error(codes.Unauthenticated, "api keys are not accepted")
+		}
+		p, err := keys.AuthenticateAPIKey(ctx, token)
+		if err != nil {
+			if status.Code(err) == codes.Unavailable {
+				return nil, status.Error(codes.Unavailable, "cannot verify api key")
+			}
+			return nil, status.Error(codes.Unauthenticated, "invalid, expired or revoked api key")
+		}
+		return WithPrincipal(ctx, p), nil
+	}
+	claims, err := tokens.Verify(token, AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+
+	role, ok := ParseRole(string(claims.Role))
+	if !ok {
+		role = RolePatron
+	}
+	return WithPrincipal(ctx, &Principal{
+		UserID: claims.Subject,
+		Email:  claims.Email,
+		Role:   role,
+		Token:  token,
+	}), nil
+}
+
+// UnaryClientInterceptor forwards the principal's token to downstream services
+// unless the outgoing context already carries credentials.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(forwardToken(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor is UnaryClientInterceptor for streaming RPCs.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(forwardToken(ctx), desc, cc, method, opts...)
+	}
+}
+
+func forwardToken(ctx context.Context) context.Context {
+	if p, ok := FromContext(ctx); ok && p.Token != "" {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if len(md.Get(authorizationHeader)) == 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, authorizationHeader, "Bearer "+p.Token)
+		}
+	}
+	return ctx
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	scheme, token, found := strings.Cut(values[0], " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", status.Error(codes.Unauthenticated, "authorization header must be a bearer token")
+	}
+	return token, nil
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type Role string
+
+const (
+	RolePublic    Role = "public"
+	RolePatron    Role = "patron"
+	RoleLibrarian Role = "librarian"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RolePublic:    0,
+	RolePatron:    1,
+	RoleLibrarian: 2,
+	RoleAdmin:     3,
+}
+
+func ParseRole(s string) (Role, bool) {
+	r := Role(s)
+	if r == RolePublic {
+		return "", false
+	}
+	_, ok := roleRank[r]
+	return r, ok
+}
+
+// Allows reports whether a caller with role r may call a method that requires role required.
+func (r Role) Allows(required Role) bool {
+	rank, ok := roleRank[r]
+	return ok && rank >= roleRank[required]
+}
+
+// IsStaff reports whether the role may act on behalf of other users.
+func (r Role) IsStaff() bool {
+	return r.Allows(RoleLibrarian)
+}
+
+// API key scopes.
+const (
+	ScopeUsersRead         = "users:read"
+	ScopeUsersWrite        = "users:write"
+	ScopeBooksRead         = "books:read"
+	ScopeBooksWrite        = "books:write"
+	ScopeLoansRead         = "loans:read"
+	ScopeLoansWrite        = "loans:write"
+	ScopeNotificationsSend = "notifications:send"
+)
+
+var scopes = []string{
+	ScopeUsersRead, ScopeUsersWrite,
+	ScopeBooksRead, ScopeBooksWrite,
+	ScopeLoansRead, ScopeLoansWrite,
+	ScopeNotificationsSend,
+}
+
+func ValidScope(s string) bool {
+	return slices.Contains(scopes, s)
+}
+
+// Permission is the minimum user role for a method and the scope an API key needs for it.
+// An empty Scope means API keys cannot call the method.
+type Permission struct {
+	Role  Role
+	Scope string
+}
+
+// Policy maps full gRPC method names to their permissions.
+type Policy map[string]Permission
+
+// Permissions is the permission table shared by all services.
+// Methods missing from the table are denied.
+var Permissions = Policy{
+	pb.UserService_CreateUser_FullMethodName:     {RolePublic, ""},
+	pb.UserService_Login_FullMethodName:          {RolePublic, ""},
+	pb.UserService_RefreshToken_FullMethodName:   {RolePublic, ""},
+	pb.UserService_VerifyApiKey_FullMethodName:   {RolePublic, ""},
+	pb.UserService_GetUser_FullMethodName:        {RolePatron, ScopeUsersRead},
+	pb.UserService_UpdateUser_FullMethodName:     {RolePatron, ScopeUsersWrite},
+	pb.UserService_ListUsers_FullMethodName:      {RoleLibrarian, ScopeUsersRead},
+	pb.UserService_SearchUsers_FullMethodName:    {RoleLibrarian, ScopeUsersRead},
+	pb.UserService_DeactivateUser_FullMethodName: {RoleLibrarian, ScopeUsersWrite},
+	pb.UserService_DeleteUser_FullMethodName:     {RoleAdmin, ""},
+	pb.UserService_SetUserRole_FullMethodName:    {RoleAdmin, ""},
+	pb.UserService_CreateApiKey_FullMethodName:   {RoleAdmin, ""},
+	pb.UserService_ListApiKeys_FullMethodName:    {RoleAdmin, ""},
+	pb.UserService_RevokeApiKey_FullMethodName:   {RoleAdmin, ""},
+
+	pb.BookService_GetBook_FullMethodName:            {RolePatron, ScopeBooksRead},
+	pb.BookService_GetBooks_FullMethodName:           {RolePatron, ScopeBooksRead},
+	pb.BookService_UpdateBook_FullMethodName:         {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_WithdrawBook_FullMethodName:       {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_GetBookHistory_FullMethodName:     {RoleLibrarian, ScopeBooksRead},
+	pb.BookService_CreateBook_FullMethodName:         {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_ImportBooks_FullMethodName:        {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_ImportMarc_FullMethodName:         {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_ExportMarc_FullMethodName:         {RolePatron, ScopeBooksRead},
+	pb.BookService_Suggest_FullMethodName:            {RolePatron, ScopeBooksRead},
+	pb.BookService_SearchCatalog_FullMethodName:      {RolePatron, ScopeBooksRead},
+	pb.BookService_GetBookByISBN_FullMethodName:      {RolePatron, ScopeBooksRead},
+	pb.BookService_LookupBooksByISBN_FullMethodName:  {RolePatron, ScopeBooksRead},
+	pb.BookService_CreateAuthor_FullMethodName:       {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_GetAuthor_FullMethodName:          {RolePatron, ScopeBooksRead},
+	pb.BookService_SearchAuthors_FullMethodName:      {RolePatron, ScopeBooksRead},
+	pb.BookService_MergeAuthors_FullMethodName:       {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_CreateSubject_FullMethodName:      {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_ListSubjects_FullMethodName:       {RolePatron, ScopeBooksRead},
+	pb.BookService_ListBooksBySubject_FullMethodName: {RolePatron, ScopeBooksRead},
+	pb.BookService_SetBookSubjects_FullMethodName:    {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_SetBookTags_FullMethodName:        {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_ListTags_FullMethodName:           {RolePatron, ScopeBooksRead},
+	pb.BookService_AddItem_FullMethodName:            {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_GetItem_FullMethodName:            {RolePatron, ScopeBooksRead},
+	pb.BookService_ListItems_FullMethodName:          {RolePatron, ScopeBooksRead},
+	pb.BookService_UpdateItem_FullMethodName:         {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_CheckoutItem_FullMethodName:       {RoleLibrarian, ScopeBooksWrite},
+	pb.BookService_CheckinItem_FullMethodName:        {RoleLibrarian, ScopeBooksWrite},
+
+	pb.LoanService_BorrowBook_FullMethodName:         {RolePatron, ScopeLoansWrite},
+	pb.LoanService_ReturnBook_FullMethodName:         {RolePatron, ScopeLoansWrite},
+	pb.LoanService_GetUserLoanSummary_FullMethodName: {RolePatron, ScopeLoansRead},
+	pb.LoanService_ListUserLoans_FullMethodName:      {RolePatron, ScopeLoansRead},
+
+	pb.NotificationService_SendNotification_FullMethodName: {RoleLibrarian, ScopeNotificationsSend},
+
+	// Health checks come from orchestrators and load balancers without credentials.
+	healthpb.Health_Check_FullMethodName: {RolePublic, ""},
+	healthpb.Health_List_FullMethodName:  {RolePublic, ""},
+	healthpb.Health_Watch_FullMethodName: {RolePublic, ""},
+}
+
+// PublicMethods lists the methods that can be called without credentials.
+func (p Policy) PublicMethods() []string {
+	var methods []string
+	for m, perm := range p {
+		if perm.Role == RolePublic {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// UnaryAuthorizationInterceptor enforces the policy; it must run after UnaryServerInterceptor.
+func UnaryAuthorizationInterceptor(policy Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := policy.authorize(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthorizationInterceptor is UnaryAuthorizationInterceptor for streaming RPCs.
+func StreamAuthorizationInterceptor(policy Policy) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := policy.authorize(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (p Policy) authorize(ctx context.Context, method string) error {
+	perm, ok := p[method]
+	if !ok {
+		return status.Error(codes.PermissionDenied, "method is not allowed")
+	}
+	if perm.Role == RolePublic {
+		return nil
+	}
+	principal, ok := FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	if principal.IsAPIKey() {
+		if !principal.HasScope(perm.Scope) {
+			return status.Error(codes.PermissionDenied, "api key lacks the required scope")
+		}
+		return nil
+	}
+	if !principal.Role.Allows(perm.Role) {
+		return status.Errorf(codes.PermissionDenied, "%s role required", perm.Role)
+	}
+	return nil
+}
+
+// ServerInterceptors returns the authentication and authorization interceptors for a gRPC server.
+// keys may be nil, in which case only JWTs are accepted.
+func ServerInterceptors(tokens *TokenManager, keys KeyVerifier, policy Policy) grpc.ServerOption {
+	return grpc.ChainUnaryInterceptor(
+		UnaryServerInterceptor(tokens, keys, policy.PublicMethods()...),
+		UnaryAuthorizationInterceptor(policy),
+	)
+}
+
+// StreamServerInterceptors is ServerInterceptors for servers with streaming RPCs.
+func StreamServerInterceptors(tokens *TokenManager, keys KeyVerifier, policy Policy) grpc.ServerOption {
+	return grpc.ChainStreamInterceptor(
+		StreamServerInterceptor(tokens, keys, policy.PublicMethods()...),
+		StreamAuthorizationInterceptor(policy),
+	)
+}
+
+// RequireSelfOrStaff allows the call if the caller is userID or a librarian/admin.
+// API keys act for any user; their scopes were already checked by the authorization interceptor.
+func RequireSelfOrStaff(ctx context.Context, userID string) error {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	if p.IsAPIKey() || p.UserID == userID || p.Role.IsStaff() {
+		return nil
+	}
+	return status.Error(codes.PermissionDenied, "cannot act on behalf of another user")
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+
+	issuer            = "library-system"
+	minSecretLength   = 32
+	defaultAccessTTL  = 15 * time.Minute
+	defaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type Claims struct {
+	Email string `json:"email,omitempty"`
+	Role  Role   `json:"role,omitempty"`
+	Type  string `json:"typ"`
+	// Version is the user's token version at issue time; refresh tokens with an older
+	// version are refused, which is how a password change revokes them.
+	Version int `json:"ver,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// TokenManager issues and verifies HS256-signed JWTs with a key shared by all services.
+type TokenManager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+func NewTokenManager(secret string, accessTTL, refreshTTL time.Duration) (*TokenManager, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("jwt secret must be at least %d bytes", minSecretLength)
+	}
+	if accessTTL <= 0 {
+		accessTTL = defaultAccessTTL
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = defaultRefreshTTL
+	}
+	return &TokenManager{
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		now:        time.Now,
+	}, nil
+}
+
+// IssuePair issues an access token carrying the user's role and a refresh token carrying
+// the user's token version. The role is not put into the refresh token so that role
+// changes apply on refresh.
+func (m *TokenManager) IssuePair(userID, email string, role Role, version int) (*TokenPair, error) {
+	access, err := m.issue(userID, email, role, 0, AccessToken, m.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := m.issue(userID, "", "", version, RefreshToken, m.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    m.accessTTL,
+	}, nil
+}
+
+// Verify checks the signature, expiry and type of a token and returns its claims.
+func (m *TokenManager) Verify(token, tokenType string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(m.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Type != tokenType || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (m *TokenManager) issue(userID, email string, role Role, version int, tokenType string, ttl time.Duration) (string, error) {
+	id, err := randomID()
+	if err != nil {
+		return "", err
+	}
+	now := m.now()
+	claims := &Claims{
+		Email:   email,
+		Role:    role,
+		Type:    tokenType,
+		Version: version,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Issuer:    issuer,
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -2,26 +2,45 @@ package bookclient
 
 import (
 	"context"
+	"errors"
+	"io"
 	"strconv"
 	"time"
 
+	"github.com/ViktorOHJ/library-system/grpcclient"
 	pb "github.com/ViktorOHJ/library-system/protos/pb"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
 )
 
+// service sets the deadlines and retries of every call made by this client.
+var service = grpcclient.Service{
+	Name:       pb.BookService_ServiceDesc.ServiceName,
+	Idempotent: []string{"GetBook", "GetBooks", "GetBookByISBN", "LookupBooksByISBN", "ExportMarc", "SearchCatalog", "Suggest", "GetBookHistory", "GetAuthor", "SearchAuthors", "ListSubjects", "ListBooksBySubject", "ListTags", "GetItem", "ListItems"},
+	Timeout:    5 * time.Second,
+	Timeouts: map[string]time.Duration{
+		"GetBook":       2 * time.Second,
+		"GetBooks":      3 * time.Second,
+		"GetItem":       2 * time.Second,
+		"GetBookByISBN": 2 * time.Second,
+		"SearchCatalog": 3 * time.Second,
+		"Suggest":       300 * time.Millisecond,
+		"ImportBooks":   30 * time.Minute,
+		"ImportMarc":    time.Minute,
+	},
+}
+
 type BookClient struct {
 	client  pb.BookServiceClient
 	timeout time.Duration
-	conn    *grpc.ClientConn
+	conn    *grpcclient.Conn
 	logger  *logrus.Logger
 }
 
-func NewBookClient(addr string, timeout time.Duration, logger *logrus.Logger) (*BookClient, error) {
-	conn, err := grpc.Dial("localhost:"+addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewBookClient(addr string, timeout time.Duration, logger *logrus.Logger, opts ...grpc.DialOption) (*BookClient, error) {
+	conn, err := grpcclient.Dial(addr, service, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +53,8 @@ func NewBookClient(addr string, timeout time.Duration, logger *logrus.Logger) (*
 	}, nil
 }
 
-func (c *BookClient) Create(ctx context.Context, title, author string, year int32) (*pb.BookResponse, error) {
+// Create adds a book with one copy; isbn is optional and may be either form.
+func (c *BookClient) Create(ctx context.Context, title, author string, year int32, isbn string) (*pb.BookResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
@@ -42,12 +62,14 @@ func (c *BookClient) Create(ctx context.Context, title, author string, year int3
 		"title":  title,
 		"author": author,
 		"year":   year,
+		"isbn":   isbn,
 	}).Info("Creating book")
 
 	resp, err := c.client.CreateBook(ctx, &pb.CreateBookRequest{
 		Title:  title,
 		Author: author,
 		Year:   year,
+		Isbn:   isbn,
 	})
 
 	if err != nil {
@@ -85,31 +107,421 @@ func (c *BookClient) Get(ctx context.Context, id string) (*pb.BookResponse, erro
 	return resp, nil
 }
 
-func (c *BookClient) Update(ctx context.Context, id string) (*pb.BookResponse, error) {
-	if err := validateBookID(id); err != nil {
+// GetBatch returns the books with the given ids in request order; unknown ids are skipped.
+func (c *BookClient) GetBatch(ctx context.Context, ids []string) ([]*pb.BookResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.logger.WithField("count", len(ids)).Info("Getting books")
+
+	resp, err := c.client.GetBooks(ctx, &pb.GetBooksRequest{
+		BookIds: ids,
+	})
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to get books")
+		return nil, err
+	}
+	return resp.Books, nil
+}
+
+// Update changes the fields named in req.UpdateMask, or all non-empty fields without a mask.
+func (c *BookClient) Update(ctx context.Context, req *pb.UpdateBookRequest) (*pb.BookResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.UpdateBook(ctx, req)
+	if err != nil {
+		c.logger.WithError(err).WithField("book_id", req.GetBookId()).Error("Failed to update book")
 		return nil, err
 	}
+	return resp, nil
+}
 
-	c.logger.WithField("book_id", id).Info("Updating book status")
+// Withdraw removes a book and its copies from the catalog; it fails while a copy is on loan.
+func (c *BookClient) Withdraw(ctx context.Context, id, reason string) (*pb.BookResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
 
+	resp, err := c.client.WithdrawBook(ctx, &pb.WithdrawBookRequest{BookId: id, Reason: reason})
+	if err != nil {
+		c.logger.WithError(err).WithField("book_id", id).Error("Failed to withdraw book")
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *BookClient) History(ctx context.Context, id string) ([]*pb.BookRevision, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
-	resp, err := c.client.UpdateBookStatus(ctx, &pb.UpdateBookRequest{
-		BookId: id,
+	resp, err := c.client.GetBookHistory(ctx, &pb.GetBookHistoryRequest{BookId: id})
+	if err != nil {
+		c.logger.WithError(err).WithField("book_id", id).Error("Failed to get book history")
+		return nil, err
+	}
+	return resp.Revisions, nil
+}
+
+// Search runs a catalog search; filters and paging are set on req.
+func (c *BookClient) Search(ctx context.Context, req *pb.SearchCatalogRequest) (*pb.SearchCatalogResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.SearchCatalog(ctx, req)
+	if err != nil {
+		c.logger.WithError(err).WithField("query", req.GetQuery()).Error("Failed to search catalog")
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Suggest completes a search box prefix; limit 0 uses the service default.
+func (c *BookClient) Suggest(ctx context.Context, prefix string, limit int32) ([]*pb.Suggestion, error) {
+	resp, err := c.client.Suggest(ctx, &pb.SuggestRequest{Prefix: prefix, Limit: limit})
+	if err != nil {
+		c.logger.WithError(err).WithField("prefix", prefix).Error("Failed to get suggestions")
+		return nil, err
+	}
+	return resp.Suggestions, nil
+}
+
+// GetByISBN finds a book by its ISBN-10 or ISBN-13.
+func (c *BookClient) GetByISBN(ctx context.Context, isbn string) (*pb.BookResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.GetBookByISBN(ctx, &pb.GetBookByISBNRequest{Isbn: isbn})
+	if err != nil {
+		c.logger.WithError(err).WithField("isbn", isbn).Error("Failed to get book by isbn")
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LookupISBNs resolves scanned ISBNs, returning one result per ISBN in request order.
+func (c *BookClient) LookupISBNs(ctx context.Context, isbns []string) ([]*pb.ISBNLookupResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.logger.WithField("count", len(isbns)).Info("Looking up isbns")
+
+	resp, err := c.client.LookupBooksByISBN(ctx, &pb.LookupBooksByISBNRequest{Isbns: isbns})
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to look up isbns")
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Import streams the rows returned by next to ImportBooks in chunks of chunkSize, until next
+// returns io.EOF, and returns the report. The call is bounded by the ImportBooks timeout of
+// the service rather than the client timeout, since a catalog may take minutes.
+func (c *BookClient) Import(ctx context.Context, next func() (*pb.ImportRow, error), chunkSize int, dryRun bool) (*pb.ImportBooksResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c.logger.WithField("dry_run", dryRun).Info("Importing books")
+
+	stream, err := c.client.ImportBooks(ctx)
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to start import")
+		return nil, err
+	}
+	chunk := &pb.ImportBooksRequest{DryRun: dryRun}
+	sent := false
+	for {
+		row, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		chunk.Rows = append(chunk.Rows, row)
+		if len(chunk.Rows) < chunkSize {
+			continue
+		}
+		// io.EOF means the server has ended the call; CloseAndRecv returns its status.
+		if err := stream.Send(chunk); err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			chunk.Rows, sent = nil, true
+			break
+		}
+		chunk, sent = &pb.ImportBooksRequest{}, true
+	}
+	if len(chunk.Rows) > 0 || !sent {
+		if err := stream.Send(chunk); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to import books")
+		return nil, err
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"created":   resp.Created,
+		"updated":   resp.Updated,
+		"unchanged": resp.Unchanged,
+		"rejected":  resp.Rejected,
+	}).Info("Books imported")
+	return resp, nil
+}
+
+// ImportMarc upserts the MARC 21 records in data by ISBN and returns the report, one result
+// per record. An empty format is detected from data. Like Import, the call is bounded by the
+// ImportMarc timeout of the service rather than the client timeout.
+func (c *BookClient) ImportMarc(ctx context.Context, data []byte, format string, dryRun bool) (*pb.ImportBooksResponse, error) {
+	c.logger.WithFields(logrus.Fields{
+		"size":    len(data),
+		"dry_run": dryRun,
+	}).Info("Importing MARC records")
+
+	resp, err := c.client.ImportMarc(ctx, &pb.ImportMarcRequest{Data: data, Format: format, DryRun: dryRun})
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to import MARC records")
+		return nil, err
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"created":   resp.Created,
+		"updated":   resp.Updated,
+		"unchanged": resp.Unchanged,
+		"rejected":  resp.Rejected,
+	}).Info("MARC records imported")
+	return resp, nil
+}
+
+// ExportMarc returns the books with the given ids as MARC 21 records in request order.
+func (c *BookClient) ExportMarc(ctx context.Context, ids []string, format string) (*pb.ExportMarcResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.logger.WithField("count", len(ids)).Info("Exporting MARC records")
+
+	resp, err := c.client.ExportMarc(ctx, &pb.ExportMarcRequest{BookIds: ids, Format: format})
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to export MARC records")
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateAuthor adds an author heading with other spellings of the name.
+func (c *BookClient) CreateAuthor(ctx context.Context, name string, variants []string) (*pb.Author, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.logger.WithField("name", name).Info("Creating author")
+
+	resp, err := c.client.CreateAuthor(ctx, &pb.CreateAuthorRequest{Name: name, Variants: variants})
+	if err != nil {
+		c.logger.WithError(err).WithField("name", name).Error("Failed to create author")
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *BookClient) GetAuthor(ctx context.Context, id string) (*pb.Author, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.GetAuthor(ctx, &pb.GetAuthorRequest{AuthorId: id})
+	if err != nil {
+		c.logger.WithError(err).WithField("author_id", id).Error("Failed to get author")
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SearchAuthors finds authors by any of their names, tolerating typos.
+func (c *BookClient) SearchAuthors(ctx context.Context, query string, pageSize int32) ([]*pb.Author, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.SearchAuthors(ctx, &pb.SearchAuthorsRequest{Query: query, PageSize: pageSize})
+	if err != nil {
+		c.logger.WithError(err).WithField("query", query).Error("Failed to search authors")
+		return nil, err
+	}
+	return resp.Authors, nil
+}
+
+// MergeAuthors folds duplicate authors into id; their names become its variants.
+func (c *BookClient) MergeAuthors(ctx context.Context, id string, sourceIDs []string) (*pb.Author, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.logger.WithFields(logrus.Fields{"author_id": id, "source_ids": sourceIDs}).Info("Merging authors")
+
+	resp, err := c.client.MergeAuthors(ctx, &pb.MergeAuthorsRequest{AuthorId: id, SourceIds: sourceIDs})
+	if err != nil {
+		c.logger.WithError(err).WithField("author_id", id).Error("Failed to merge authors")
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateSubject adds a subject under parentID, or at the top level when parentID is empty.
+func (c *BookClient) CreateSubject(ctx context.Context, name, parentID, classNumber string) (*pb.Subject, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.logger.WithFields(logrus.Fields{"name": name, "parent_id": parentID}).Info("Creating subject")
+
+	resp, err := c.client.CreateSubject(ctx, &pb.CreateSubjectRequest{Name: name, ParentId: parentID, ClassNumber: classNumber})
+	if err != nil {
+		c.logger.WithError(err).WithField("name", name).Error("Failed to create subject")
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListSubjects returns the subjects right under parentID with their book counts.
+func (c *BookClient) ListSubjects(ctx context.Context, parentID string) (*pb.ListSubjectsResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.ListSubjects(ctx, &pb.ListSubjectsRequest{ParentId: parentID})
+	if err != nil {
+		c.logger.WithError(err).WithField("parent_id", parentID).Error("Failed to list subjects")
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *BookClient) ListBySubject(ctx context.Context, req *pb.ListBooksBySubjectRequest) (*pb.ListBooksBySubjectResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.ListBooksBySubject(ctx, req)
+	if err != nil {
+		c.logger.WithError(err).WithField("subject_id", req.SubjectId).Error("Failed to list books by subject")
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SetSubjects replaces the subjects of a book.
+func (c *BookClient) SetSubjects(ctx context.Context, bookID string, subjectIDs []string) (*pb.BookResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.SetBookSubjects(ctx, &pb.SetBookSubjectsRequest{BookId: bookID, SubjectIds: subjectIDs})
+	if err != nil {
+		c.logger.WithError(err).WithField("book_id", bookID).Error("Failed to set subjects")
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SetTags replaces the tags of a book.
+func (c *BookClient) SetTags(ctx context.Context, bookID string, tags []string) (*pb.BookResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.SetBookTags(ctx, &pb.SetBookTagsRequest{BookId: bookID, Tags: tags})
+	if err != nil {
+		c.logger.WithError(err).WithField("book_id", bookID).Error("Failed to set tags")
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListTags returns the most used tags starting with prefix.
+func (c *BookClient) ListTags(ctx context.Context, prefix string, limit int32) ([]*pb.FacetCount, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.ListTags(ctx, &pb.ListTagsRequest{Prefix: prefix, Limit: limit})
+	if err != nil {
+		c.logger.WithError(err).WithField("prefix", prefix).Error("Failed to list tags")
+		return nil, err
+	}
+	return resp.Tags, nil
+}
+
+// AddItem adds a copy of a book; an empty barcode is generated by the service.
+func (c *BookClient) AddItem(ctx context.Context, bookID, barcode, condition, location string) (*pb.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.AddItem(ctx, &pb.AddItemRequest{
+		BookId:    bookID,
+		Barcode:   barcode,
+		Condition: condition,
+		Location:  location,
 	})
+	if err != nil {
+		c.logger.WithError(err).WithField("book_id", bookID).Error("Failed to add item")
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *BookClient) GetItem(ctx context.Context, id string) (*pb.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
 
+	resp, err := c.client.GetItem(ctx, &pb.GetItemRequest{ItemId: id})
 	if err != nil {
-		c.logger.WithFields(logrus.Fields{
-			"book_id": id,
-			"error":   err,
-		}).Error("Failed to update book")
+		c.logger.WithError(err).WithField("item_id", id).Error("Failed to get item")
 		return nil, err
 	}
+	return resp, nil
+}
+
+func (c *BookClient) ListItems(ctx context.Context, bookID string) ([]*pb.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
 
+	resp, err := c.client.ListItems(ctx, &pb.ListItemsRequest{BookId: bookID})
+	if err != nil {
+		c.logger.WithError(err).WithField("book_id", bookID).Error("Failed to list items")
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+// Checkout marks a copy as on loan: itemID, or any available copy of bookID when itemID is empty.
+func (c *BookClient) Checkout(ctx context.Context, bookID, itemID string) (*pb.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.logger.WithFields(logrus.Fields{"book_id": bookID, "item_id": itemID}).Info("Checking out item")
+
+	resp, err := c.client.CheckoutItem(ctx, &pb.CheckoutItemRequest{BookId: bookID, ItemId: itemID})
+	if err != nil {
+		c.logger.WithError(err).WithField("book_id", bookID).Error("Failed to check out item")
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Checkin returns a copy to the shelf: itemID, or any copy of bookID on loan when itemID is empty.
+func (c *BookClient) Checkin(ctx context.Context, itemID, bookID string) (*pb.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.logger.WithFields(logrus.Fields{"book_id": bookID, "item_id": itemID}).Info("Checking in item")
+
+	resp, err := c.client.CheckinItem(ctx, &pb.CheckinItemRequest{ItemId: itemID, BookId: bookID})
+	if err != nil {
+		c.logger.WithError(err).WithField("item_id", itemID).Error("Failed to check in item")
+		return nil, err
+	}
 	return resp, nil
 }
 
+// Ping fails while the circuit breaker is open and otherwise asks the service's health
+// endpoint whether it is serving.
+func (c *BookClient) Ping(ctx context.Context) error {
+	return c.conn.Ping(ctx)
+}
+
 func (c *BookClient) Close() error {
 	c.logger.Info("Closing book client connection")
 	return c.conn.Close()
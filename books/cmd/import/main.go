@@ -0,0 +1,91 @@
+// Command import loads a catalog file into the books service and prints a report with one
+// line per row. It exits with status 2 when some rows were rejected.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/auth"
+	bookclient "github.com/ViktorOHJ/library-system/books/client"
+	"github.com/ViktorOHJ/library-system/books/importfile"
+	"github.com/ViktorOHJ/library-system/config"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/ViktorOHJ/library-system/tlsconfig"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(logrus.WarnLevel)
+
+	cfg, err := config.LoadBookImport(os.Args[1:])
+	if err != nil {
+		logger.Fatal(err)
+	}
+	format := importfile.Format(cfg.Format)
+	if format == "" {
+		if format, err = importfile.DetectFormat(cfg.File); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	var input io.Reader = os.Stdin
+	if cfg.File != "-" {
+		f, err := os.Open(cfg.File)
+		if err != nil {
+			logger.Fatalf("Failed to open catalog file: %v", err)
+		}
+		defer f.Close()
+		input = f
+	}
+	reader, err := importfile.NewReader(input, format)
+	if err != nil {
+		logger.Fatalf("Failed to read catalog file: %v", err)
+	}
+
+	tlsCreds, err := tlsconfig.Load(cfg.TLS, "import", logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure TLS: %v", err)
+	}
+	booksClient, err := bookclient.NewBookClient(cfg.Services.Books, 10*time.Second, logger, tlsCreds.DialOption())
+	if err != nil {
+		logger.Fatalf("Failed to create books client: %v", err)
+	}
+	defer booksClient.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	res, err := booksClient.Import(auth.WithToken(ctx, cfg.Token), reader.Read, cfg.ChunkSize, cfg.DryRun)
+	if err != nil {
+		logger.Fatalf("Import failed: %v", err)
+	}
+
+	printReport(os.Stdout, res)
+	if res.Rejected > 0 {
+		os.Exit(2)
+	}
+}
+
+func printReport(out io.Writer, res *pb.ImportBooksResponse) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tSTATUS\tISBN\tBOOK\tREASON")
+	for _, r := range res.Results {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", r.Line, r.Status, r.Isbn13, r.BookId, r.Reason)
+	}
+	w.Flush()
+
+	summary := fmt.Sprintf("%d created, %d updated, %d unchanged, %d rejected",
+		res.Created, res.Updated, res.Unchanged, res.Rejected)
+	if res.DryRun {
+		summary += " (dry run, nothing saved)"
+	}
+	fmt.Fprintln(out, summary)
+}
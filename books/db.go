@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/ViktorOHJ/library-system/observability"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -11,7 +12,13 @@ func InitDB(parentContext context.Context, dbUrl string) (*pgxpool.Pool, error)
 	ctx, cancel := context.WithTimeout(parentContext, 10*time.Second)
 	defer cancel()
 
-	pool, err := pgxpool.New(ctx, dbUrl)
+	config, err := pgxpool.ParseConfig(dbUrl)
+	if err != nil {
+		return nil, err
+	}
+	config.ConnConfig.Tracer = observability.PgxTracer()
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, err
 	}
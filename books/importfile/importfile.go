@@ -0,0 +1,182 @@
+// Package importfile reads catalog files for ImportBooks: CSV with a header row, or JSON
+// Lines with one book per line. A row that cannot be read comes back with its error set,
+// so that it is rejected in the import report instead of stopping the import.
+package importfile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+)
+
+type Format string
+
+const (
+	CSV       Format = "csv"
+	JSONLines Format = "jsonl"
+)
+
+// maxLineSize bounds one JSON line; catalog records are far smaller.
+const maxLineSize = 1 << 20
+
+// DetectFormat picks the format from a file extension: .csv, or .jsonl and .ndjson.
+func DetectFormat(name string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return CSV, nil
+	case ".jsonl", ".ndjson":
+		return JSONLines, nil
+	default:
+		return "", fmt.Errorf("cannot tell the format of %q: use .csv, .jsonl or set the format", name)
+	}
+}
+
+// Reader yields the rows of a catalog file one at a time.
+type Reader struct {
+	next func() (*pb.ImportRow, error)
+}
+
+// NewReader reads the CSV header right away, so that a file without the required
+// columns fails before anything is sent.
+func NewReader(r io.Reader, format Format) (*Reader, error) {
+	switch format {
+	case CSV:
+		return newCSVReader(r)
+	case JSONLines:
+		return newJSONReader(r), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// Read returns the next row, or io.EOF after the last one. Other errors mean the file
+// itself cannot be read any further.
+func (r *Reader) Read() (*pb.ImportRow, error) {
+	return r.next()
+}
+
+var (
+	requiredColumns = []string{"title", "author", "isbn"}
+	optionalColumns = []string{"year", "copies"}
+)
+
+func newCSVReader(r io.Reader) (*Reader, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("csv file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // spreadsheet exports start with a BOM
+		}
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv header has no %q column", name)
+		}
+	}
+	cr.FieldsPerRecord = len(header)
+
+	next := func() (*pb.ImportRow, error) {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			reason := parseErr.Err.Error()
+			if errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				reason = fmt.Sprintf("expected %d fields, got %d", len(header), len(record))
+			}
+			return &pb.ImportRow{Line: int32(parseErr.StartLine), Error: reason}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		row := &pb.ImportRow{
+			Line:   int32(line),
+			Title:  strings.TrimSpace(record[columns["title"]]),
+			Author: strings.TrimSpace(record[columns["author"]]),
+			Isbn:   strings.TrimSpace(record[columns["isbn"]]),
+		}
+		for _, name := range optionalColumns {
+			i, ok := columns[name]
+			if !ok {
+				continue
+			}
+			value := strings.TrimSpace(record[i])
+			if value == "" {
+				continue
+			}
+			n, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				row.Error = fmt.Sprintf("%s is not a number: %q", name, value)
+				break
+			}
+			if name == "year" {
+				row.Year = int32(n)
+			} else {
+				row.Copies = int32(n)
+			}
+		}
+		return row, nil
+	}
+	return &Reader{next: next}, nil
+}
+
+// jsonRow is one line of a JSON Lines file.
+type jsonRow struct {
+	Title  string `json:"title"`
+	Author string `json:"author"`
+	Year   int32  `json:"year"`
+	ISBN   string `json:"isbn"`
+	Copies int32  `json:"copies"`
+}
+
+func newJSONReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	var line int32
+	next := func() (*pb.ImportRow, error) {
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var v jsonRow
+			if err := json.Unmarshal([]byte(text), &v); err != nil {
+				return &pb.ImportRow{Line: line, Error: "invalid json: " + err.Error()}, nil
+			}
+			return &pb.ImportRow{
+				Line:   line,
+				Title:  strings.TrimSpace(v.Title),
+				Author: strings.TrimSpace(v.Author),
+				Year:   v.Year,
+				Isbn:   strings.TrimSpace(v.ISBN),
+				Copies: v.Copies,
+			}, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("read line %d: %w", line+1, err)
+		}
+		return nil, io.EOF
+	}
+	return &Reader{next: next}
+}
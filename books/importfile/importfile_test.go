@@ -0,0 +1,139 @@
+package importfile
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func readAll(t *testing.T, r *Reader) []*pb.ImportRow {
+	t.Helper()
+	var rows []*pb.ImportRow
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+		require.NoError(t, err)
+		rows = append(rows, row)
+	}
+}
+
+func assertRows(t *testing.T, want, got []*pb.ImportRow) {
+	t.Helper()
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.True(t, proto.Equal(want[i], got[i]), "row %d: want %v, got %v", i, want[i], got[i])
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		want Format
+	}{
+		{name: "catalog.csv", want: CSV},
+		{name: "CATALOG.CSV", want: CSV},
+		{name: "/tmp/books.jsonl", want: JSONLines},
+		{name: "books.ndjson", want: JSONLines},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat(tt.name)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := DetectFormat("books.xlsx")
+	assert.Error(t, err)
+	_, err = DetectFormat("-")
+	assert.Error(t, err)
+}
+
+func TestReader_CSV(t *testing.T) {
+	input := "\ufeffISBN, Title ,author,Year,copies,shelf\n" +
+		"978-5-17-118366-1,Мастер и Маргарита,Михаил Булгаков,1967,3,A1\n" +
+		"\n" +
+		"0306406152,\"Войти в IT, или\",  Иван Петров ,,,\n" +
+		"9785171183661,Без года,Автор,около 1900,,\n" +
+		"9785171183661,Too few\n" +
+		"9785171183661,\"bad \"quote\",Автор,2000,1,\n" +
+		"9780306406157,\"Многострочное\nназвание\",Автор,2001,,B2\n"
+
+	r, err := NewReader(strings.NewReader(input), CSV)
+	require.NoError(t, err)
+	rows := readAll(t, r)
+
+	assertRows(t, []*pb.ImportRow{
+		{Line: 2, Isbn: "978-5-17-118366-1", Title: "Мастер и Маргарита", Author: "Михаил Булгаков", Year: 1967, Copies: 3},
+		{Line: 4, Isbn: "0306406152", Title: "Войти в IT, или", Author: "Иван Петров"},
+		{Line: 5, Isbn: "9785171183661", Title: "Без года", Author: "Автор", Error: `year is not a number: "около 1900"`},
+		{Line: 6, Error: "expected 6 fields, got 2"},
+		{Line: 7, Error: rows[4].Error},
+		{Line: 8, Isbn: "9780306406157", Title: "Многострочное\nназвание", Author: "Автор", Year: 2001},
+	}, rows)
+	assert.NotEmpty(t, rows[4].Error)
+}
+
+func TestReader_CSVHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "empty file", input: ""},
+		{name: "missing isbn", input: "title,author,year\nA,B,2000\n"},
+		{name: "missing author", input: "isbn,title\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReader(strings.NewReader(tt.input), CSV)
+			assert.Error(t, err)
+		})
+	}
+
+	r, err := NewReader(strings.NewReader("title,author,isbn\n"), CSV)
+	require.NoError(t, err)
+	assert.Empty(t, readAll(t, r))
+}
+
+func TestReader_JSONLines(t *testing.T) {
+	input := `{"title": "Мастер и Маргарита", "author": "Михаил Булгаков", "year": 1967, "isbn": "9785171183661", "copies": 2}
+
+{"title": " Пикник на обочине ", "author": "Аркадий Стругацкий", "isbn": "0306406152", "publisher": "Молодая гвардия"}
+{"title": "broken",
+{"title": "Год строкой", "author": "Автор", "year": "1999", "isbn": "9780306406157"}
+`
+	r, err := NewReader(strings.NewReader(input), JSONLines)
+	require.NoError(t, err)
+	rows := readAll(t, r)
+
+	require.Len(t, rows, 4)
+	assertRows(t, []*pb.ImportRow{
+		{Line: 1, Title: "Мастер и Маргарита", Author: "Михаил Булгаков", Year: 1967, Isbn: "9785171183661", Copies: 2},
+		{Line: 3, Title: "Пикник на обочине", Author: "Аркадий Стругацкий", Isbn: "0306406152"},
+	}, rows[:2])
+	assert.Equal(t, int32(4), rows[2].Line)
+	assert.Contains(t, rows[2].Error, "invalid json")
+	assert.Equal(t, int32(5), rows[3].Line)
+	assert.Contains(t, rows[3].Error, "invalid json")
+}
+
+func TestReader_JSONLinesTooLong(t *testing.T) {
+	input := `{"title": "ok", "author": "a", "isbn": "9785171183661"}` + "\n" +
+		`{"title": "` + strings.Repeat("x", maxLineSize) + `"}` + "\n"
+	r, err := NewReader(strings.NewReader(input), JSONLines)
+	require.NoError(t, err)
+
+	_, err = r.Read()
+	require.NoError(t, err)
+	_, err = r.Read()
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, io.EOF)
+}
@@ -6,13 +6,20 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/ViktorOHJ/library-system/auth"
 	bookserver "github.com/ViktorOHJ/library-system/books/server"
+	"github.com/ViktorOHJ/library-system/config"
+	"github.com/ViktorOHJ/library-system/healthcheck"
+	"github.com/ViktorOHJ/library-system/observability"
 	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/ViktorOHJ/library-system/requestid"
+	"github.com/ViktorOHJ/library-system/tlsconfig"
+	userclient "github.com/ViktorOHJ/library-system/users/client"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 )
@@ -39,19 +46,30 @@ func main() {
 		ForceColors:     true,
 	})
 
-	err := godotenv.Load(".env")
+	cfg, err := config.LoadBooks(os.Args[1:])
 	if err != nil {
-		logger.Fatal("Error loading .env file")
+		logger.Fatal(err)
 	}
-	dbUrl := os.Getenv("BOOKS_DBURL")
-	if dbUrl == "" {
-		logger.Fatal("BOOKS_DBURL not set in .env file")
+	tlsCreds, err := tlsconfig.Load(cfg.TLS, "books", logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure TLS: %v", err)
+	}
+	if cfg.Server.Healthcheck {
+		os.Exit(healthcheck.RunProbe("localhost:"+cfg.Server.Port, tlsCreds.DialOption()))
 	}
+	tlsCreds.Start(context.Background())
 
-	path := os.Getenv("BOOKS_MIGRATIONS_PATH")
-	if path == "" {
-		path = "file://books/migrations"
+	shutdownTracing, err := observability.InitTracing(context.Background(), observability.TracingConfig{
+		ServiceName: "books",
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+	}, logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure tracing: %v", err)
 	}
+	defer shutdownTracing()
+	dbUrl := cfg.Database.URL
+	path := cfg.Database.MigrationsPath
 	runMigrations(dbUrl, path)
 	logger.Info("Running migrations...")
 
@@ -62,17 +80,44 @@ func main() {
 	}
 	logger.Info("Database connection established")
 	defer db.Close()
+	if err := observability.RegisterPool("books", db); err != nil {
+		logger.Fatalf("Failed to register pool metrics: %v", err)
+	}
+
+	tokens, err := auth.NewTokenManager(cfg.Auth.JWTSecret, cfg.Auth.AccessTTL, cfg.Auth.RefreshTTL)
+	if err != nil {
+		logger.Fatalf("Failed to configure authentication: %v", err)
+	}
+
+	usersClient, err := userclient.NewUserClient(cfg.Services.Users, 5*time.Second, logger, tlsCreds.DialOption())
+	if err != nil {
+		logger.Fatalf("Failed to create users client: %v", err)
+	}
+	defer usersClient.Close()
+	keys := auth.CacheKeys(usersClient, time.Minute)
 
 	booksServer := bookserver.NewBooksServer(db, logger)
-	server := grpc.NewServer()
+	if err := booksServer.RegisterMetrics(); err != nil {
+		logger.Fatalf("Failed to register metrics: %v", err)
+	}
+	suggestCtx, stopSuggest := context.WithCancel(ctx)
+	defer stopSuggest()
+	booksServer.StartSuggest(suggestCtx)
+	server := grpc.NewServer(
+		tlsCreds.ServerOption(),
+		grpc.ChainUnaryInterceptor(observability.UnaryServerInterceptor(), requestid.UnaryServerInterceptor(logger)),
+		observability.GRPCServerHandler(),
+		auth.ServerInterceptors(tokens, keys, auth.Permissions),
+		auth.StreamServerInterceptors(tokens, keys, auth.Permissions),
+	)
 	pb.RegisterBookServiceServer(server, booksServer)
 
-	PORT := os.Getenv("BOOKS_PORT")
-	if PORT == "" {
-		PORT = "50052"
-		logger.Infof("BOOKS_PORT not set, using default port %s", PORT)
-	}
+	checker := healthcheck.New(logger, pb.BookService_ServiceDesc.ServiceName)
+	checker.Add("database", db.Ping)
+	checker.Register(server)
+	checker.Start(ctx)
 
+	PORT := cfg.Server.Port
 	lis, err := net.Listen("tcp", ":"+PORT)
 	if err != nil {
 		logger.Fatalf("Failed to listen: %v", err)
@@ -80,6 +125,8 @@ func main() {
 
 	logger.Infof("Listening on port %s", PORT)
 
+	metricsServer := observability.Serve(cfg.Server.MetricsPort, logger)
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	go func() {
@@ -90,6 +137,8 @@ func main() {
 
 	<-stop
 	logger.Info("Received shutdown signal, stopping server gracefully...")
+	checker.Shutdown()
 	server.GracefulStop()
+	observability.Shutdown(metricsServer)
 	logger.Info("Server stopped gracefully")
 }
@@ -0,0 +1,263 @@
+package marc
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Book holds the fields the catalog takes from a bibliographic record:
+//
+//	001      ID
+//	020 $a   ISBN
+//	100, 700 Contributors: $a name, role from $4 (aut, trl, edt) or $e
+//	245 $a $b Title
+//	264 $c   Year, or 260 $c in older records
+//	650 $a   Subjects
+//
+// Deleted is leader/05 = d.
+type Book struct {
+	ID           string
+	Title        string
+	Contributors []Contributor
+	Year         int32
+	ISBN         string
+	Subjects     []string
+	Deleted      bool
+}
+
+// Contributor roles are the catalog's: "author", "translator" or "editor".
+type Contributor struct {
+	Name string
+	Role string
+}
+
+var relatorCodes = map[string]string{
+	"aut": "author",
+	"trl": "translator",
+	"edt": "editor",
+}
+
+var roleCodes = map[string]string{
+	"author":     "aut",
+	"translator": "trl",
+	"editor":     "edt",
+}
+
+// ToBook maps a record to a book. 700 fields with a role the catalog does not keep, such as
+// an illustrator, are left out.
+func ToBook(r *Record) (Book, error) {
+	b := Book{
+		ID:      strings.TrimSpace(r.Control("001")),
+		Deleted: len(r.Leader) > 5 && r.Leader[5] == 'd',
+	}
+
+	for _, f := range r.Fields("020") {
+		if isbn := firstToken(f.Subfield('a')); isbn != "" {
+			b.ISBN = isbn
+			break
+		}
+	}
+
+	if fields := r.Fields("245"); len(fields) > 0 {
+		title := trimPunctuation(fields[0].Subfield('a'))
+		if sub := trimPunctuation(fields[0].Subfield('b')); sub != "" && title != "" {
+			title += " : " + sub
+		}
+		b.Title = title
+	}
+	if b.Title == "" {
+		return Book{}, errors.New("record has no title (245 $a)")
+	}
+
+	for _, f := range r.Fields("100") {
+		if name := personalName(f); name != "" {
+			b.Contributors = append(b.Contributors, Contributor{Name: name, Role: "author"})
+		}
+	}
+	for _, f := range r.Fields("700") {
+		name := personalName(f)
+		role, ok := relatorRole(f)
+		if name != "" && ok {
+			b.Contributors = append(b.Contributors, Contributor{Name: name, Role: role})
+		}
+	}
+
+	b.Year = publicationYear(r)
+
+	seen := make(map[string]bool)
+	for _, f := range r.Fields("650") {
+		subject := trimPunctuation(f.Subfield('a'))
+		if subject == "" || seen[strings.ToLower(subject)] {
+			continue
+		}
+		seen[strings.ToLower(subject)] = true
+		b.Subjects = append(b.Subjects, subject)
+	}
+	return b, nil
+}
+
+// FromBook builds a minimal record for a printed monograph. Names are written in direct
+// order (first indicator 0), as the catalog keeps them.
+func FromBook(b Book) *Record {
+	status := byte('n')
+	if b.Deleted {
+		status = 'd'
+	}
+	r := &Record{Leader: "00000" + string(status) + "am a2200000 i 4500"}
+	if b.ID != "" {
+		r.ControlFields = append(r.ControlFields, ControlField{Tag: "001", Value: b.ID})
+	}
+	if b.ISBN != "" {
+		r.DataFields = append(r.DataFields, DataField{Tag: "020", Ind1: ' ', Ind2: ' ',
+			Subfields: []Subfield{{Code: 'a', Value: b.ISBN}}})
+	}
+
+	var main *Contributor
+	var added []Contributor
+	for i, c := range b.Contributors {
+		if main == nil && c.Role == "author" {
+			main = &b.Contributors[i]
+			continue
+		}
+		added = append(added, c)
+	}
+	if main != nil {
+		r.DataFields = append(r.DataFields, nameField("100", *main))
+	}
+
+	// 245 first indicator: 1 when the record has a main entry.
+	titleInd := byte('0')
+	if main != nil {
+		titleInd = '1'
+	}
+	r.DataFields = append(r.DataFields, DataField{Tag: "245", Ind1: titleInd, Ind2: '0',
+		Subfields: []Subfield{{Code: 'a', Value: b.Title}}})
+	if b.Year > 0 {
+		r.DataFields = append(r.DataFields, DataField{Tag: "264", Ind1: ' ', Ind2: '1',
+			Subfields: []Subfield{{Code: 'c', Value: strconv.Itoa(int(b.Year))}}})
+	}
+	for _, s := range b.Subjects {
+		r.DataFields = append(r.DataFields, DataField{Tag: "650", Ind1: ' ', Ind2: '4',
+			Subfields: []Subfield{{Code: 'a', Value: s}}})
+	}
+	for _, c := range added {
+		r.DataFields = append(r.DataFields, nameField("700", c))
+	}
+	return r
+}
+
+func nameField(tag string, c Contributor) DataField {
+	code, ok := roleCodes[c.Role]
+	if !ok {
+		code = "aut"
+	}
+	return DataField{Tag: tag, Ind1: '0', Ind2: ' ', Subfields: []Subfield{
+		{Code: 'a', Value: c.Name},
+		{Code: 'e', Value: relatorCodes[code]},
+		{Code: '4', Value: code},
+	}}
+}
+
+// relatorRole takes the role from $4, then $e; a field with neither is an author.
+func relatorRole(f DataField) (string, bool) {
+	code, term := f.Subfield('4'), f.Subfield('e')
+	if code == "" && term == "" {
+		return "author", true
+	}
+	if code != "" {
+		role, ok := relatorCodes[strings.ToLower(strings.TrimSpace(code))]
+		return role, ok
+	}
+	role := strings.ToLower(trimPunctuation(term))
+	_, ok := roleCodes[role]
+	return role, ok
+}
+
+// personalName turns an inverted "Surname, Forename" heading (first indicator 1) into
+// the direct order the catalog uses.
+func personalName(f DataField) string {
+	name := trimPunctuation(f.Subfield('a'))
+	if f.Ind1 != '1' {
+		return name
+	}
+	surname, forename, ok := strings.Cut(name, ",")
+	if !ok {
+		return name
+	}
+	forename = strings.TrimSpace(forename)
+	if forename == "" {
+		return strings.TrimSpace(surname)
+	}
+	return forename + " " + strings.TrimSpace(surname)
+}
+
+// publicationYear prefers the publication statement (264 second indicator 1), then any
+// 264, then 260.
+func publicationYear(r *Record) int32 {
+	var candidates []DataField
+	for _, f := range r.Fields("264") {
+		if f.Ind2 == '1' {
+			candidates = append(candidates, f)
+		}
+	}
+	candidates = append(candidates, r.Fields("264")...)
+	candidates = append(candidates, r.Fields("260")...)
+	for _, f := range candidates {
+		if year := firstYear(f.Subfield('c')); year > 0 {
+			return year
+		}
+	}
+	return 0
+}
+
+// firstYear finds the first run of exactly four digits, so "c2005" and "[1999?]" give a year.
+func firstYear(s string) int32 {
+	run := 0
+	for i, r := range s + " " {
+		if r >= '0' && r <= '9' {
+			run++
+			continue
+		}
+		if run == 4 {
+			year, _ := strconv.Atoi(s[i-4 : i])
+			return int32(year)
+		}
+		run = 0
+	}
+	return 0
+}
+
+// firstToken drops qualifiers such as "(hardcover)" that follow the ISBN in 020 $a.
+func firstToken(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// trimPunctuation removes the ISBD punctuation that ends a subfield: " /", " :", ",", ";"
+// and a final period, but not the period after an initial ("Tolkien, J. R. R.").
+func trimPunctuation(s string) string {
+	s = strings.TrimSpace(s)
+	for {
+		trimmed := strings.TrimRightFunc(strings.TrimRight(s, "/:;,="), unicode.IsSpace)
+		if strings.HasSuffix(trimmed, ".") && !endsWithInitial(trimmed) {
+			trimmed = strings.TrimRightFunc(strings.TrimSuffix(trimmed, "."), unicode.IsSpace)
+		}
+		if trimmed == s {
+			return s
+		}
+		s = trimmed
+	}
+}
+
+func endsWithInitial(s string) bool {
+	word := []rune(s[strings.LastIndexFunc(s, unicode.IsSpace)+1:])
+	if len(word) >= 3 && string(word[len(word)-3:]) == "..." {
+		return true
+	}
+	return len(word) == 2 && unicode.IsUpper(word[0])
+}
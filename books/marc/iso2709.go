@@ -0,0 +1,189 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// ISO 2709 record layout: a 24-byte leader, a directory of 12-byte entries (tag, field
+// length, offset from the base address) and the fields, each ending with a field terminator.
+const (
+	leaderLength     = 24
+	entryLength      = 12
+	fieldTerminator  = 0x1E
+	recordTerminator = 0x1D
+	subfieldMark     = 0x1F
+	maxRecordLength  = 99999
+	maxFieldLength   = 9999
+)
+
+func newBinaryReader(r io.Reader) *Reader {
+	br := bufio.NewReader(r)
+	next := func() (*Record, error) {
+		// Some files put a line break between records.
+		for {
+			b, err := br.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if b != '\n' && b != '\r' {
+				br.UnreadByte()
+				break
+			}
+		}
+		prefix, err := br.Peek(5)
+		if err != nil {
+			return nil, fmt.Errorf("truncated record: %w", io.ErrUnexpectedEOF)
+		}
+		length, ok := parseDigits(prefix)
+		if !ok || length < leaderLength+2 {
+			return nil, fmt.Errorf("invalid record length %q", prefix)
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, fmt.Errorf("truncated record: %w", io.ErrUnexpectedEOF)
+		}
+		rec, err := decodeBinary(data)
+		if err != nil {
+			return nil, &RecordError{Err: err}
+		}
+		return rec, nil
+	}
+	return &Reader{next: next}
+}
+
+func decodeBinary(data []byte) (*Record, error) {
+	if data[len(data)-1] != recordTerminator {
+		return nil, errors.New("missing record terminator")
+	}
+	leader := string(data[:leaderLength])
+	if leader[9] != 'a' {
+		return nil, errUnsupportedEncoding
+	}
+	base, ok := parseDigits(data[12:17])
+	if !ok || base <= leaderLength || base > len(data)-1 || data[base-1] != fieldTerminator {
+		return nil, fmt.Errorf("invalid base address %q", leader[12:17])
+	}
+	directory := data[leaderLength : base-1]
+	if len(directory)%entryLength != 0 {
+		return nil, errors.New("invalid directory length")
+	}
+
+	rec := &Record{Leader: leader}
+	fields := data[base : len(data)-1]
+	for i := 0; i < len(directory); i += entryLength {
+		entry := directory[i : i+entryLength]
+		tag := string(entry[:3])
+		length, ok1 := parseDigits(entry[3:7])
+		start, ok2 := parseDigits(entry[7:12])
+		if !ok1 || !ok2 || length < 1 || start < 0 || start+length > len(fields) {
+			return nil, fmt.Errorf("invalid directory entry for field %s", tag)
+		}
+		field := fields[start : start+length]
+		if field[len(field)-1] != fieldTerminator {
+			return nil, fmt.Errorf("field %s has no terminator", tag)
+		}
+		field = field[:len(field)-1]
+		if !utf8.Valid(field) {
+			return nil, fmt.Errorf("field %s is not valid UTF-8", tag)
+		}
+		if isControlTag(tag) {
+			rec.ControlFields = append(rec.ControlFields, ControlField{Tag: tag, Value: string(field)})
+			continue
+		}
+		if len(field) < 2 {
+			return nil, fmt.Errorf("field %s has no indicators", tag)
+		}
+		f := DataField{Tag: tag, Ind1: field[0], Ind2: field[1]}
+		for _, part := range bytes.Split(field[2:], []byte{subfieldMark})[1:] {
+			if len(part) == 0 {
+				return nil, fmt.Errorf("field %s has an empty subfield", tag)
+			}
+			f.Subfields = append(f.Subfields, Subfield{Code: part[0], Value: string(part[1:])})
+		}
+		rec.DataFields = append(rec.DataFields, f)
+	}
+	if err := validate(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// parseDigits reads a fixed-width number of the leader or directory. Unlike strconv.Atoi it
+// takes ASCII digits only, so a sign or spaces cannot turn an offset negative.
+func parseDigits(b []byte) (int, bool) {
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, len(b) > 0
+}
+
+func newBinaryWriter(w io.Writer) *Writer {
+	return &Writer{
+		write: func(r *Record) error {
+			data, err := encodeBinary(r)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		},
+		close: func() error { return nil },
+	}
+}
+
+// encodeBinary lays out a record, filling in the lengths, the base address and the
+// fixed parts of the leader.
+func encodeBinary(r *Record) ([]byte, error) {
+	var directory, fields bytes.Buffer
+	add := func(tag string, field []byte) error {
+		if len(field) > maxFieldLength {
+			return fmt.Errorf("field %s is longer than %d bytes", tag, maxFieldLength)
+		}
+		fmt.Fprintf(&directory, "%s%04d%05d", tag, len(field), fields.Len())
+		fields.Write(field)
+		return nil
+	}
+	for _, f := range r.ControlFields {
+		if err := add(f.Tag, append([]byte(f.Value), fieldTerminator)); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range r.DataFields {
+		field := []byte{f.Ind1, f.Ind2}
+		for _, s := range f.Subfields {
+			field = append(field, subfieldMark, s.Code)
+			field = append(field, s.Value...)
+		}
+		if err := add(f.Tag, append(field, fieldTerminator)); err != nil {
+			return nil, err
+		}
+	}
+	directory.WriteByte(fieldTerminator)
+
+	base := leaderLength + directory.Len()
+	length := base + fields.Len() + 1
+	if length > maxRecordLength {
+		return nil, fmt.Errorf("record is longer than %d bytes", maxRecordLength)
+	}
+	leader := []byte(r.Leader)
+	copy(leader[0:5], fmt.Sprintf("%05d", length))
+	leader[9] = 'a'
+	copy(leader[10:12], "22")
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+	copy(leader[20:24], "4500")
+
+	data := make([]byte, 0, length)
+	data = append(data, leader...)
+	data = append(data, directory.Bytes()...)
+	data = append(data, fields.Bytes()...)
+	return append(data, recordTerminator), nil
+}
@@ -0,0 +1,190 @@
+// Package marc reads and writes MARC 21 bibliographic records, in the ISO 2709 exchange
+// format and in MARCXML, and maps them to the fields the catalog keeps about a book.
+// Only Unicode records are supported; MARC-8 records are rejected one by one.
+package marc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type Format string
+
+const (
+	ISO2709 Format = "iso2709"
+	MARCXML Format = "marcxml"
+)
+
+// ContentType is the media type of a file in format.
+func (f Format) ContentType() string {
+	if f == MARCXML {
+		return "application/marcxml+xml"
+	}
+	return "application/marc"
+}
+
+// ParseFormat accepts "iso2709" or "marcxml"; empty detects the format from data.
+func ParseFormat(value string, data []byte) (Format, error) {
+	switch Format(value) {
+	case ISO2709, MARCXML:
+		return Format(value), nil
+	case "":
+		if bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff"))), []byte("<")) {
+			return MARCXML, nil
+		}
+		return ISO2709, nil
+	default:
+		return "", fmt.Errorf("format must be %s or %s", ISO2709, MARCXML)
+	}
+}
+
+type Record struct {
+	Leader        string
+	ControlFields []ControlField
+	DataFields    []DataField
+}
+
+// ControlField is a field 001-009: a tag and a value without indicators or subfields.
+type ControlField struct {
+	Tag   string
+	Value string
+}
+
+type DataField struct {
+	Tag       string
+	Ind1      byte
+	Ind2      byte
+	Subfields []Subfield
+}
+
+type Subfield struct {
+	Code  byte
+	Value string
+}
+
+// Control returns the value of the first control field with tag.
+func (r *Record) Control(tag string) string {
+	for _, f := range r.ControlFields {
+		if f.Tag == tag {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Fields returns the data fields with tag in record order.
+func (r *Record) Fields(tag string) []DataField {
+	var fields []DataField
+	for _, f := range r.DataFields {
+		if f.Tag == tag {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// Subfield returns the value of the first subfield with code.
+func (f DataField) Subfield(code byte) string {
+	for _, s := range f.Subfields {
+		if s.Code == code {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// RecordError is a record that cannot be decoded. Reading goes on with the next record.
+type RecordError struct {
+	Err error
+}
+
+func (e *RecordError) Error() string {
+	return "invalid record: " + e.Err.Error()
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+func isControlTag(tag string) bool {
+	return len(tag) == 3 && tag[0] == '0' && tag[1] == '0'
+}
+
+func validate(r *Record) error {
+	if len(r.Leader) != leaderLength {
+		return fmt.Errorf("leader must be %d characters, got %d", leaderLength, len(r.Leader))
+	}
+	for _, f := range r.ControlFields {
+		if !isControlTag(f.Tag) {
+			return fmt.Errorf("%q is not a control field tag", f.Tag)
+		}
+	}
+	for _, f := range r.DataFields {
+		if len(f.Tag) != 3 || isControlTag(f.Tag) {
+			return fmt.Errorf("%q is not a data field tag", f.Tag)
+		}
+		if !isIndicator(f.Ind1) || !isIndicator(f.Ind2) {
+			return fmt.Errorf("field %s has invalid indicators", f.Tag)
+		}
+	}
+	return nil
+}
+
+func isIndicator(b byte) bool {
+	return b == ' ' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z'
+}
+
+// Reader yields the records of a file one at a time.
+type Reader struct {
+	next func() (*Record, error)
+}
+
+// NewReader reads records in format. Read returns a *RecordError for a record that
+// cannot be decoded, io.EOF after the last record and other errors when the file itself
+// is broken.
+func NewReader(r io.Reader, format Format) (*Reader, error) {
+	switch format {
+	case ISO2709:
+		return newBinaryReader(r), nil
+	case MARCXML:
+		return newXMLReader(r), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func (r *Reader) Read() (*Record, error) {
+	return r.next()
+}
+
+// Writer writes records in one format; Close finishes the file.
+type Writer struct {
+	write func(*Record) error
+	close func() error
+}
+
+func NewWriter(w io.Writer, format Format) (*Writer, error) {
+	switch format {
+	case ISO2709:
+		return newBinaryWriter(w), nil
+	case MARCXML:
+		return newXMLWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func (w *Writer) Write(r *Record) error {
+	if err := validate(r); err != nil {
+		return err
+	}
+	return w.write(r)
+}
+
+func (w *Writer) Close() error {
+	return w.close()
+}
+
+var errUnsupportedEncoding = errors.New("MARC-8 records are not supported, only Unicode (leader/09 = a)")
@@ -0,0 +1,320 @@
+package marc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The samples in testdata hold the same three records in both formats.
+var sampleBooks = []Book{
+	{
+		ID:           "bk-1",
+		Title:        "Мастер и Маргарита : роман",
+		Contributors: []Contributor{{Name: "Михаил Афанасьевич Булгаков", Role: "author"}},
+		Year:         2020,
+		ISBN:         "9785171183661",
+		Subjects:     []string{"Русская литература", "Классика"},
+	},
+	{
+		ID:    "ocm0001",
+		Title: "The fellowship of the ring",
+		Contributors: []Contributor{
+			{Name: "J. R. R. Tolkien", Role: "author"},
+			{Name: "Jane Smith", Role: "editor"},
+			{Name: "Андрей Кистяковский", Role: "translator"},
+		},
+		Year:     1954,
+		ISBN:     "0306406152",
+		Subjects: []string{"Fantasy fiction"},
+	},
+	{ID: "old-7", Title: "Снятая с учёта книга", Deleted: true},
+}
+
+func readAll(t *testing.T, data []byte, format Format) []*Record {
+	t.Helper()
+	r, err := NewReader(bytes.NewReader(data), format)
+	require.NoError(t, err)
+	var records []*Record
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, rec)
+	}
+}
+
+func writeAll(t *testing.T, records []*Record, format Format) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format)
+	require.NoError(t, err)
+	for _, rec := range records {
+		require.NoError(t, w.Write(rec))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func sample(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+	return data
+}
+
+func TestReader_Samples(t *testing.T) {
+	for _, tt := range []struct {
+		file   string
+		format Format
+	}{
+		{file: "books.mrc", format: ISO2709},
+		{file: "books.xml", format: MARCXML},
+	} {
+		t.Run(string(tt.format), func(t *testing.T) {
+			records := readAll(t, sample(t, tt.file), tt.format)
+			require.Len(t, records, len(sampleBooks))
+			for i, rec := range records {
+				book, err := ToBook(rec)
+				require.NoError(t, err)
+				assert.Equal(t, sampleBooks[i], book)
+			}
+		})
+	}
+
+	binary := readAll(t, sample(t, "books.mrc"), ISO2709)
+	xml := readAll(t, sample(t, "books.xml"), MARCXML)
+	for i := range binary {
+		assert.Equal(t, xml[i].ControlFields, binary[i].ControlFields)
+		assert.Equal(t, xml[i].DataFields, binary[i].DataFields)
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	data := sample(t, "books.mrc")
+	records := readAll(t, data, ISO2709)
+
+	// Lengths and the base address are recomputed, so the sample comes back byte for byte.
+	assert.Equal(t, data, writeAll(t, records, ISO2709))
+
+	xml := writeAll(t, records, MARCXML)
+	assert.True(t, bytes.HasPrefix(xml, []byte(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<collection xmlns="http://www.loc.gov/MARC21/slim">`)))
+	assert.Equal(t, records, readAll(t, xml, MARCXML))
+}
+
+func TestBook_RoundTrip(t *testing.T) {
+	books := append([]Book{{
+		Title: "Двенадцать стульев",
+		Contributors: []Contributor{
+			{Name: "Илья Ильф", Role: "author"},
+			{Name: "Евгений Петров", Role: "author"},
+			{Name: "Составитель", Role: "editor"},
+		},
+		Year: 1928,
+	}, {
+		Title:        "Антология",
+		Contributors: []Contributor{{Name: "Редактор", Role: "editor"}},
+	}}, sampleBooks...)
+
+	var records []*Record
+	for _, b := range books {
+		records = append(records, FromBook(b))
+	}
+	for _, format := range []Format{ISO2709, MARCXML} {
+		t.Run(string(format), func(t *testing.T) {
+			decoded := readAll(t, writeAll(t, records, format), format)
+			require.Len(t, decoded, len(books))
+			for i, rec := range decoded {
+				got, err := ToBook(rec)
+				require.NoError(t, err)
+				assert.Equal(t, books[i], got)
+			}
+		})
+	}
+
+	rec := FromBook(books[0])
+	assert.Equal(t, "100", rec.DataFields[0].Tag)
+	assert.Equal(t, byte('1'), rec.Fields("245")[0].Ind1)
+	assert.Equal(t, byte('0'), FromBook(books[1]).Fields("245")[0].Ind1)
+	assert.Equal(t, byte('d'), FromBook(Book{Title: "T", Deleted: true}).Leader[5])
+}
+
+func TestReader_BadRecords(t *testing.T) {
+	good := writeAll(t, []*Record{FromBook(Book{Title: "Хорошая"})}, ISO2709)
+	marc8 := bytes.Clone(good)
+	marc8[9] = ' '
+	broken := bytes.Clone(good)
+	broken[len(broken)-1] = 'x'
+
+	// Bad records are reported one by one and reading goes on; line breaks are skipped.
+	data := bytes.Join([][]byte{marc8, broken, good}, []byte("\n"))
+	r, err := NewReader(bytes.NewReader(data), ISO2709)
+	require.NoError(t, err)
+	for _, want := range []string{"MARC-8", "terminator"} {
+		_, err = r.Read()
+		var recErr *RecordError
+		require.ErrorAs(t, err, &recErr)
+		assert.Contains(t, err.Error(), want)
+	}
+	rec, err := r.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "Хорошая", rec.Fields("245")[0].Subfield('a'))
+	_, err = r.Read()
+	assert.ErrorIs(t, err, io.EOF)
+
+	// Numbers in the leader and directory are unsigned digits; a signed offset is a bad
+	// record, not a slice out of range.
+	signedOffset := bytes.Clone(good)
+	copy(signedOffset[leaderLength+7:leaderLength+12], "-0001")
+	signedBase := bytes.Clone(good)
+	copy(signedBase[12:17], "+0037")
+	for _, data := range [][]byte{signedOffset, signedBase} {
+		r, _ := NewReader(bytes.NewReader(data), ISO2709)
+		_, err := r.Read()
+		var recErr *RecordError
+		require.ErrorAs(t, err, &recErr)
+	}
+
+	// A broken length or a short file stops the reader.
+	signedLength := append([]byte("+0"), good[2:]...)
+	for _, data := range [][]byte{[]byte("abcde12345"), signedLength, good[:40], good[:3]} {
+		r, _ := NewReader(bytes.NewReader(data), ISO2709)
+		_, err := r.Read()
+		require.Error(t, err)
+		var recErr *RecordError
+		assert.False(t, errors.As(err, &recErr))
+		assert.NotErrorIs(t, err, io.EOF)
+	}
+}
+
+func TestReader_BadXML(t *testing.T) {
+	record := func(body string) string {
+		return "<record><leader>00000nam a2200000 i 4500</leader>" + body + "</record>"
+	}
+	input := `<collection xmlns="http://www.loc.gov/MARC21/slim">` +
+		record(`<datafield tag="245" ind1="10"><subfield code="a">T</subfield></datafield>`) +
+		record(`<datafield tag="245"><subfield code="ab">T</subfield></datafield>`) +
+		record(`<controlfield tag="245">T</controlfield>`) +
+		"<record><leader>short</leader></record>" +
+		record(`<datafield tag="245" ind1="1"><subfield code="a">Без ind2</subfield></datafield>`) +
+		"</collection>"
+
+	r, err := NewReader(strings.NewReader(input), MARCXML)
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		_, err := r.Read()
+		var recErr *RecordError
+		require.ErrorAs(t, err, &recErr, "record %d", i)
+	}
+	rec, err := r.Read()
+	require.NoError(t, err)
+	assert.Equal(t, byte(' '), rec.DataFields[0].Ind2)
+	_, err = r.Read()
+	assert.ErrorIs(t, err, io.EOF)
+
+	r, _ = NewReader(strings.NewReader("<collection><record><leader>"), MARCXML)
+	_, err = r.Read()
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, io.EOF)
+}
+
+func TestWriter_Invalid(t *testing.T) {
+	w, err := NewWriter(io.Discard, ISO2709)
+	require.NoError(t, err)
+	assert.Error(t, w.Write(&Record{Leader: "short"}))
+	assert.Error(t, w.Write(&Record{Leader: FromBook(Book{}).Leader, DataFields: []DataField{{Tag: "001", Ind1: ' ', Ind2: ' '}}}))
+	assert.Error(t, w.Write(FromBook(Book{Title: strings.Repeat("x", maxFieldLength)})))
+
+	var buf bytes.Buffer
+	w, _ = NewWriter(&buf, MARCXML)
+	require.NoError(t, w.Close())
+	assert.Empty(t, readAll(t, buf.Bytes(), MARCXML))
+}
+
+func TestToBook(t *testing.T) {
+	field := func(tag string, ind1 byte, subfields ...string) DataField {
+		f := DataField{Tag: tag, Ind1: ind1, Ind2: ' '}
+		for i := 0; i+1 < len(subfields); i += 2 {
+			f.Subfields = append(f.Subfields, Subfield{Code: subfields[i][0], Value: subfields[i+1]})
+		}
+		return f
+	}
+	title := field("245", '0', "a", "Title")
+
+	tests := []struct {
+		name   string
+		fields []DataField
+		check  func(t *testing.T, b Book)
+	}{
+		{
+			name:   "inverted name without forename",
+			fields: []DataField{title, field("100", '1', "a", "Homer,")},
+			check: func(t *testing.T, b Book) {
+				assert.Equal(t, []Contributor{{Name: "Homer", Role: "author"}}, b.Contributors)
+			},
+		},
+		{
+			name:   "700 without relator is an author",
+			fields: []DataField{title, field("700", '1', "a", "Петров, Евгений.")},
+			check: func(t *testing.T, b Book) {
+				assert.Equal(t, []Contributor{{Name: "Евгений Петров", Role: "author"}}, b.Contributors)
+			},
+		},
+		{
+			name:   "264 without publication indicator",
+			fields: []DataField{title, field("264", ' ', "c", "[1999?]"), field("260", ' ', "c", "2001")},
+			check:  func(t *testing.T, b Book) { assert.Equal(t, int32(1999), b.Year) },
+		},
+		{
+			name:   "year needs four digits",
+			fields: []DataField{title, field("260", ' ', "c", "19th century, 123456")},
+			check:  func(t *testing.T, b Book) { assert.Zero(t, b.Year) },
+		},
+		{
+			name:   "title keeps an ellipsis and a final initial",
+			fields: []DataField{field("245", '0', "a", "Жизнь и судьба... /"), field("650", ' ', "a", "Война, 1941-1945 г.")},
+			check: func(t *testing.T, b Book) {
+				assert.Equal(t, "Жизнь и судьба...", b.Title)
+				assert.Equal(t, []string{"Война, 1941-1945 г"}, b.Subjects)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ToBook(&Record{Leader: FromBook(Book{}).Leader, DataFields: tt.fields})
+			require.NoError(t, err)
+			tt.check(t, b)
+		})
+	}
+
+	_, err := ToBook(&Record{DataFields: []DataField{field("245", '0', "b", "only subtitle")}})
+	assert.Error(t, err)
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		value string
+		data  string
+		want  Format
+	}{
+		{value: "iso2709", want: ISO2709},
+		{value: "marcxml", want: MARCXML},
+		{data: "\ufeff  <?xml version=\"1.0\"?>", want: MARCXML},
+		{data: "00530nam a2200133 i 4500", want: ISO2709},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.value, []byte(tt.data))
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+	_, err := ParseFormat("unimarc", nil)
+	assert.Error(t, err)
+}
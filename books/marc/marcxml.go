@@ -0,0 +1,157 @@
+package marc
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const xmlNamespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"record"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// newXMLReader accepts a <collection> of records as well as a single <record> document.
+func newXMLReader(r io.Reader) *Reader {
+	dec := xml.NewDecoder(r)
+	next := func() (*Record, error) {
+		for {
+			tok, err := dec.Token()
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			if err != nil {
+				return nil, fmt.Errorf("read marcxml: %w", err)
+			}
+			start, ok := tok.(xml.StartElement)
+			if !ok || start.Name.Local != "record" {
+				continue
+			}
+			var v xmlRecord
+			if err := dec.DecodeElement(&v, &start); err != nil {
+				return nil, fmt.Errorf("read marcxml: %w", err)
+			}
+			rec, err := fromXML(v)
+			if err != nil {
+				return nil, &RecordError{Err: err}
+			}
+			return rec, nil
+		}
+	}
+	return &Reader{next: next}
+}
+
+func fromXML(v xmlRecord) (*Record, error) {
+	rec := &Record{Leader: v.Leader}
+	if len(rec.Leader) == leaderLength && rec.Leader[9] != 'a' {
+		return nil, errUnsupportedEncoding
+	}
+	for _, f := range v.ControlFields {
+		rec.ControlFields = append(rec.ControlFields, ControlField{Tag: f.Tag, Value: f.Value})
+	}
+	for _, f := range v.DataFields {
+		ind1, err := xmlIndicator(f.Ind1)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Tag, err)
+		}
+		ind2, err := xmlIndicator(f.Ind2)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Tag, err)
+		}
+		field := DataField{Tag: f.Tag, Ind1: ind1, Ind2: ind2}
+		for _, s := range f.Subfields {
+			if len(s.Code) != 1 {
+				return nil, fmt.Errorf("field %s has invalid subfield code %q", f.Tag, s.Code)
+			}
+			field.Subfields = append(field.Subfields, Subfield{Code: s.Code[0], Value: s.Value})
+		}
+		rec.DataFields = append(rec.DataFields, field)
+	}
+	if err := validate(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// xmlIndicator treats a missing indicator as blank.
+func xmlIndicator(value string) (byte, error) {
+	switch len(value) {
+	case 0:
+		return ' ', nil
+	case 1:
+		return value[0], nil
+	default:
+		return 0, fmt.Errorf("invalid indicator %q", value)
+	}
+}
+
+func newXMLWriter(w io.Writer) *Writer {
+	enc := xml.NewEncoder(w)
+	enc.Indent("  ", "  ")
+	started, empty := false, true
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		_, err := fmt.Fprintf(w, "%s<collection xmlns=%q>\n", xml.Header, xmlNamespace)
+		return err
+	}
+	return &Writer{
+		write: func(r *Record) error {
+			if err := start(); err != nil {
+				return err
+			}
+			empty = false
+			return enc.Encode(toXML(r))
+		},
+		close: func() error {
+			if err := start(); err != nil {
+				return err
+			}
+			end := "\n</collection>\n"
+			if empty {
+				end = end[1:]
+			}
+			_, err := io.WriteString(w, end)
+			return err
+		},
+	}
+}
+
+func toXML(r *Record) xmlRecord {
+	v := xmlRecord{Leader: r.Leader}
+	for _, f := range r.ControlFields {
+		v.ControlFields = append(v.ControlFields, xmlControlField{Tag: f.Tag, Value: f.Value})
+	}
+	for _, f := range r.DataFields {
+		field := xmlDataField{Tag: f.Tag, Ind1: string(f.Ind1), Ind2: string(f.Ind2)}
+		for _, s := range f.Subfields {
+			field.Subfields = append(field.Subfields, xmlSubfield{Code: string(s.Code), Value: s.Value})
+		}
+		v.DataFields = append(v.DataFields, field)
+	}
+	return v
+}
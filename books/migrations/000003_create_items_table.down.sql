@@ -0,0 +1,8 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS is_available BOOLEAN NOT NULL DEFAULT TRUE;
+
+UPDATE books SET is_available = EXISTS (
+    SELECT 1 FROM items WHERE items.book_id = books.id AND items.status = 'available'
+);
+
+DROP TABLE IF EXISTS items;
+DROP SEQUENCE IF EXISTS item_barcode_seq;
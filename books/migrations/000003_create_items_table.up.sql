@@ -0,0 +1,23 @@
+-- A book is the bibliographic record; items are its physical copies.
+CREATE SEQUENCE IF NOT EXISTS item_barcode_seq;
+
+CREATE TABLE IF NOT EXISTS items (
+    id SERIAL PRIMARY KEY,
+    book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    barcode VARCHAR(32) NOT NULL DEFAULT 'LIB' || lpad(nextval('item_barcode_seq')::text, 8, '0'),
+    condition VARCHAR(16) NOT NULL DEFAULT 'good',
+    location VARCHAR(100) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'available',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT items_barcode_key UNIQUE (barcode),
+    CONSTRAINT items_condition_check CHECK (condition IN ('new', 'good', 'fair', 'poor', 'damaged')),
+    CONSTRAINT items_status_check CHECK (status IN ('available', 'on_loan', 'in_repair', 'lost', 'withdrawn'))
+);
+
+CREATE INDEX IF NOT EXISTS items_book_status_idx ON items (book_id, status);
+
+-- Every existing book becomes one copy in the state the book was in.
+INSERT INTO items (book_id, status)
+SELECT id, CASE WHEN is_available THEN 'available' ELSE 'on_loan' END FROM books ORDER BY id;
+
+ALTER TABLE books DROP COLUMN IF EXISTS is_available;
@@ -0,0 +1,6 @@
+-- ISBNs are stored in the ISBN-13 form; the ISBN-10 is derived from it.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS isbn13 CHAR(13);
+
+ALTER TABLE books ADD CONSTRAINT books_isbn13_check CHECK (isbn13 ~ '^97[89][0-9]{10}$');
+
+CREATE UNIQUE INDEX IF NOT EXISTS books_isbn13_key ON books (isbn13);
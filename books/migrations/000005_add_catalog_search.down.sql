@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS books_search_text_trgm_idx;
+DROP INDEX IF EXISTS books_search_vector_idx;
+
+ALTER TABLE books DROP COLUMN IF EXISTS search_text;
+ALTER TABLE books DROP COLUMN IF EXISTS search_vector;
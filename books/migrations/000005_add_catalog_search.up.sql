@@ -0,0 +1,15 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- The catalog mixes Russian and English titles, so words are indexed without stemming.
+-- Title matches weigh more than author matches.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', author), 'B')
+    ) STORED;
+
+-- Trigram matching tolerates misspelled titles and author names.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS search_text TEXT
+    GENERATED ALWAYS AS (title || ' ' || author) STORED;
+
+CREATE INDEX IF NOT EXISTS books_search_vector_idx ON books USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS books_search_text_trgm_idx ON books USING GIN (search_text gin_trgm_ops);
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS books_notify_event ON books;
+DROP FUNCTION IF EXISTS notify_book_event();
+
+DROP TRIGGER IF EXISTS items_count_loan ON items;
+DROP FUNCTION IF EXISTS count_book_loan();
+
+ALTER TABLE books DROP COLUMN IF EXISTS loan_count;
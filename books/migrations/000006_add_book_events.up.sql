@@ -0,0 +1,37 @@
+-- loan_count ranks search suggestions; it counts checkouts from now on, plus copies on loan today.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS loan_count INT NOT NULL DEFAULT 0;
+
+UPDATE books b SET loan_count = i.on_loan
+FROM (SELECT book_id, COUNT(*) AS on_loan FROM items WHERE status = 'on_loan' GROUP BY book_id) i
+WHERE i.book_id = b.id;
+
+CREATE OR REPLACE FUNCTION count_book_loan() RETURNS trigger AS $$
+BEGIN
+    UPDATE books SET loan_count = loan_count + 1 WHERE id = NEW.book_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER items_count_loan
+    AFTER UPDATE OF status ON items
+    FOR EACH ROW
+    WHEN (NEW.status = 'on_loan' AND OLD.status <> 'on_loan')
+    EXECUTE FUNCTION count_book_loan();
+
+-- Every change to a book is announced on the book_events channel with the book id,
+-- so that each books replica can refresh its in-memory suggest index.
+CREATE OR REPLACE FUNCTION notify_book_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('book_events', OLD.id::text);
+    ELSE
+        PERFORM pg_notify('book_events', NEW.id::text);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER books_notify_event
+    AFTER INSERT OR UPDATE OR DELETE ON books
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_book_event();
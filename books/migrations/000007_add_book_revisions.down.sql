@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS book_revisions;
+
+ALTER TABLE books DROP COLUMN IF EXISTS withdrawal_reason;
+ALTER TABLE books DROP COLUMN IF EXISTS withdrawn_at;
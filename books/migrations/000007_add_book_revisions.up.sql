@@ -0,0 +1,18 @@
+-- Withdrawn books stay in the table so that loans and history can still show them.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS withdrawn_at TIMESTAMP;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS withdrawal_reason VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS book_revisions (
+    id SERIAL PRIMARY KEY,
+    book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    action VARCHAR(16) NOT NULL,
+    -- [{"field": "title", "old": "...", "new": "..."}]
+    changes JSONB NOT NULL DEFAULT '[]',
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    -- User id, "api-key:<id>" or empty for changes made by the system
+    actor VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT book_revisions_action_check CHECK (action IN ('create', 'update', 'withdraw'))
+);
+
+CREATE INDEX IF NOT EXISTS book_revisions_book_idx ON book_revisions (book_id, id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS book_authors;
+DROP TABLE IF EXISTS author_variants;
+DROP TABLE IF EXISTS authors;
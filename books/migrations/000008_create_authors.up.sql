@@ -0,0 +1,40 @@
+-- Authority control: one canonical heading per person, with the other spellings as variants.
+CREATE TABLE IF NOT EXISTS authors (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS authors_name_key ON authors (lower(name));
+CREATE INDEX IF NOT EXISTS authors_name_trgm_idx ON authors USING GIN (name gin_trgm_ops);
+
+-- Variants resolve to exactly one author; merged authors leave their name here as an alias.
+CREATE TABLE IF NOT EXISTS author_variants (
+    id SERIAL PRIMARY KEY,
+    author_id INT NOT NULL REFERENCES authors (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS author_variants_name_key ON author_variants (lower(name));
+CREATE INDEX IF NOT EXISTS author_variants_author_idx ON author_variants (author_id);
+CREATE INDEX IF NOT EXISTS author_variants_name_trgm_idx ON author_variants USING GIN (name gin_trgm_ops);
+
+CREATE TABLE IF NOT EXISTS book_authors (
+    book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    author_id INT NOT NULL REFERENCES authors (id),
+    role VARCHAR(16) NOT NULL DEFAULT 'author',
+    position INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (book_id, author_id, role),
+    CONSTRAINT book_authors_role_check CHECK (role IN ('author', 'translator', 'editor'))
+);
+
+CREATE INDEX IF NOT EXISTS book_authors_author_idx ON book_authors (author_id);
+
+-- Every distinct author text becomes an author; books.author stays as the display name.
+INSERT INTO authors (name)
+SELECT DISTINCT ON (lower(author)) author FROM books ORDER BY lower(author), id
+ON CONFLICT DO NOTHING;
+
+INSERT INTO book_authors (book_id, author_id)
+SELECT b.id, a.id FROM books b JOIN authors a ON lower(a.name) = lower(b.author)
+ON CONFLICT DO NOTHING;
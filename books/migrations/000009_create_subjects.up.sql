@@ -0,0 +1,29 @@
+-- Subjects form a tree: "Fiction" > "Science fiction" > "Cyberpunk".
+CREATE TABLE IF NOT EXISTS subjects (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    parent_id INT REFERENCES subjects (id),
+    class_number VARCHAR(32) NOT NULL DEFAULT '', -- UDC or Dewey, optional
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Sibling subjects have distinct names; the same name may appear under different parents.
+CREATE UNIQUE INDEX IF NOT EXISTS subjects_name_key ON subjects (COALESCE(parent_id, 0), lower(name));
+CREATE INDEX IF NOT EXISTS subjects_parent_idx ON subjects (parent_id);
+
+CREATE TABLE IF NOT EXISTS book_subjects (
+    book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    subject_id INT NOT NULL REFERENCES subjects (id),
+    PRIMARY KEY (book_id, subject_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_subjects_subject_idx ON book_subjects (subject_id);
+
+-- Tags are free-form and stored lowercased.
+CREATE TABLE IF NOT EXISTS book_tags (
+    book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    tag VARCHAR(50) NOT NULL,
+    PRIMARY KEY (book_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS book_tags_tag_idx ON book_tags (tag);
@@ -0,0 +1,527 @@
+package bookserver
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ViktorOHJ/library-system/dberr"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Authors are kept under one canonical name each; other spellings are variants that
+// resolve to it. books.author holds the display name derived from a book's contributors.
+
+const (
+	RoleAuthor     = "author"
+	RoleTranslator = "translator"
+	RoleEditor     = "editor"
+)
+
+var contributorRoles = []string{RoleAuthor, RoleTranslator, RoleEditor}
+
+const (
+	maxAuthorName   = 100
+	maxContributors = 20
+	maxVariants     = 20
+	maxMergeSources = 20
+)
+
+// contributor is a resolved book contributor; it is also how book_authors rows are read as JSON.
+type contributor struct {
+	AuthorID int    `json:"id"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+}
+
+func toContributors(list []contributor) []*pb.Contributor {
+	res := make([]*pb.Contributor, 0, len(list))
+	for _, c := range list {
+		res = append(res, &pb.Contributor{AuthorId: strconv.Itoa(c.AuthorID), Name: c.Name, Role: c.Role})
+	}
+	return res
+}
+
+func validateContributors(list []*pb.Contributor) error {
+	if len(list) == 0 {
+		return status.Error(codes.InvalidArgument, "author cannot be empty")
+	}
+	if len(list) > maxContributors {
+		return status.Errorf(codes.InvalidArgument, "at most %d contributors per book", maxContributors)
+	}
+	for _, c := range list {
+		if c.Role != "" && !slices.Contains(contributorRoles, c.Role) {
+			return status.Errorf(codes.InvalidArgument, "role must be one of %s", strings.Join(contributorRoles, ", "))
+		}
+		if c.AuthorId != "" {
+			if _, err := parseID(c.AuthorId, "author id"); err != nil {
+				return err
+			}
+			continue
+		}
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			return status.Error(codes.InvalidArgument, "author cannot be empty")
+		}
+		if utf8.RuneCountInString(name) > maxAuthorName {
+			return status.Error(codes.InvalidArgument, "author name too long")
+		}
+	}
+	return nil
+}
+
+// resolveContributors finds the authors of validated contributors by id or by name,
+// creating authors for names that are neither a canonical name nor a variant.
+func (s *BooksServer) resolveContributors(ctx context.Context, tx pgx.Tx, list []*pb.Contributor) ([]contributor, error) {
+	res := make([]contributor, 0, len(list))
+	for _, c := range list {
+		role := c.Role
+		if role == "" {
+			role = RoleAuthor
+		}
+		var resolved contributor
+		var err error
+		if c.AuthorId != "" {
+			id, _ := parseID(c.AuthorId, "author id")
+			resolved.AuthorID = id
+			err = tx.QueryRow(ctx, "SELECT name FROM authors WHERE id = $1", id).Scan(&resolved.Name)
+		} else {
+			resolved, err = resolveAuthor(ctx, tx, strings.TrimSpace(c.Name))
+		}
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				s.logger.Errorf("Database error: %v", err)
+			}
+			return nil, dberr.ToStatus(err, "author")
+		}
+		resolved.Role = role
+		if slices.ContainsFunc(res, func(r contributor) bool { return r.AuthorID == resolved.AuthorID && r.Role == role }) {
+			return nil, status.Errorf(codes.InvalidArgument, "%s is listed twice as %s", resolved.Name, role)
+		}
+		res = append(res, resolved)
+	}
+	return res, nil
+}
+
+func resolveAuthor(ctx context.Context, tx pgx.Tx, name string) (contributor, error) {
+	var c contributor
+	err := tx.QueryRow(ctx, `SELECT id, name FROM authors WHERE lower(name) = lower($1)
+	UNION ALL
+	SELECT a.id, a.name FROM author_variants v JOIN authors a ON a.id = v.author_id WHERE lower(v.name) = lower($1)
+	LIMIT 1`, name).Scan(&c.AuthorID, &c.Name)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return c, err
+	}
+	err = tx.QueryRow(ctx, `INSERT INTO authors (name) VALUES ($1)
+	ON CONFLICT ((lower(name))) DO UPDATE SET name = authors.name RETURNING id, name`, name).Scan(&c.AuthorID, &c.Name)
+	return c, err
+}
+
+func setContributors(ctx context.Context, tx pgx.Tx, bookID int, list []contributor) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM book_authors WHERE book_id = $1", bookID); err != nil {
+		return err
+	}
+	ids := make([]int, len(list))
+	roles := make([]string, len(list))
+	for i, c := range list {
+		ids[i], roles[i] = c.AuthorID, c.Role
+	}
+	_, err := tx.Exec(ctx, `INSERT INTO book_authors (book_id, author_id, role, position)
+	SELECT $1::int, t.author_id, t.role, t.position - 1
+	FROM unnest($2::int[], $3::text[]) WITH ORDINALITY AS t(author_id, role, position)`, bookID, ids, roles)
+	return err
+}
+
+// displayAuthors is the books.author text: the authors, or every contributor of a book
+// without authors such as an anthology, shortened to fit the column.
+func displayAuthors(list []contributor) string {
+	var names []string
+	for _, c := range list {
+		if c.Role == RoleAuthor {
+			names = append(names, c.Name)
+		}
+	}
+	if len(names) == 0 {
+		for _, c := range list {
+			names = append(names, c.Name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	if display := strings.Join(names, ", "); utf8.RuneCountInString(display) <= maxAuthorName {
+		return display
+	}
+	if display := names[0] + " et al."; utf8.RuneCountInString(display) <= maxAuthorName {
+		return display
+	}
+	return names[0]
+}
+
+// contributorsText renders contributors for book revisions.
+func contributorsText(list []contributor) string {
+	parts := make([]string, len(list))
+	for i, c := range list {
+		parts[i] = c.Name + " (" + c.Role + ")"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// authorQuery selects authors with their variants and the number of their books.
+const authorQuery = `SELECT a.id, a.name,
+	COALESCE((SELECT array_agg(v.name ORDER BY v.name) FROM author_variants v WHERE v.author_id = a.id), '{}'),
+	(SELECT COUNT(DISTINCT ba.book_id) FROM book_authors ba WHERE ba.author_id = a.id)
+FROM authors a`
+
+func scanAuthor(row pgx.Row) (*pb.Author, error) {
+	var id int
+	author := &pb.Author{}
+	if err := row.Scan(&id, &author.Name, &author.Variants, &author.BookCount); err != nil {
+		return nil, err
+	}
+	author.Id = strconv.Itoa(id)
+	return author, nil
+}
+
+func (s *BooksServer) getAuthor(ctx context.Context, db queryer, id int) (*pb.Author, error) {
+	author, err := scanAuthor(db.QueryRow(ctx, authorQuery+" WHERE a.id = $1", id))
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("Database error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "author")
+	}
+	return author, nil
+}
+
+func validateAuthorName(name string) error {
+	if name == "" {
+		return status.Error(codes.InvalidArgument, "name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxAuthorName {
+		return status.Error(codes.InvalidArgument, "name too long")
+	}
+	return nil
+}
+
+func (s *BooksServer) CreateAuthor(parentCtx context.Context, req *pb.CreateAuthorRequest) (*pb.Author, error) {
+	s.logger.Info("CreateAuthor called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	name := strings.TrimSpace(req.Name)
+	if err := validateAuthorName(name); err != nil {
+		return nil, err
+	}
+	if len(req.Variants) > maxVariants {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d variants", maxVariants)
+	}
+	seen := map[string]bool{strings.ToLower(name): true}
+	var variants, lowered []string
+	for _, v := range req.Variants {
+		v = strings.TrimSpace(v)
+		if err := validateAuthorName(v); err != nil {
+			return nil, err
+		}
+		if seen[strings.ToLower(v)] {
+			continue
+		}
+		seen[strings.ToLower(v)] = true
+		variants = append(variants, v)
+		lowered = append(lowered, strings.ToLower(v))
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "author")
+	}
+	defer tx.Rollback(ctx)
+
+	// A name may be either one author's heading or one author's variant, never both.
+	var taken string
+	err = tx.QueryRow(ctx, `SELECT name FROM author_variants WHERE lower(name) = lower($1)
+	UNION ALL
+	SELECT name FROM authors WHERE lower(name) = ANY($2)
+	LIMIT 1`, name, lowered).Scan(&taken)
+	if err == nil {
+		return nil, status.Errorf(codes.AlreadyExists, "%q already names another author", taken)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "author")
+	}
+
+	var id int
+	if err := tx.QueryRow(ctx, "INSERT INTO authors (name) VALUES ($1) RETURNING id", name).Scan(&id); err != nil {
+		return nil, s.authorNameError(err)
+	}
+	if len(variants) > 0 {
+		_, err = tx.Exec(ctx, "INSERT INTO author_variants (author_id, name) SELECT $1::int, unnest($2::text[])", id, variants)
+		if err != nil {
+			return nil, s.authorNameError(err)
+		}
+	}
+	author, err := s.getAuthor(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "author")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"author_id": author.Id,
+		"name":      author.Name,
+	}).Info("Author created")
+	return author, nil
+}
+
+// authorNameError reports a taken name; the unique indexes are on lower(name), which
+// the generic constraint message would not name well.
+func (s *BooksServer) authorNameError(err error) error {
+	if dberr.IsUniqueViolation(err) {
+		return status.Error(codes.AlreadyExists, "author name already in use")
+	}
+	s.logger.Errorf("Database error: %v", err)
+	return dberr.ToStatus(err, "author")
+}
+
+func (s *BooksServer) GetAuthor(parentCtx context.Context, req *pb.GetAuthorRequest) (*pb.Author, error) {
+	s.logger.Info("GetAuthor called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := parseID(req.AuthorId, "author id")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	return s.getAuthor(ctx, s.db, id)
+}
+
+func (s *BooksServer) SearchAuthors(parentCtx context.Context, req *pb.SearchAuthorsRequest) (*pb.SearchAuthorsResponse, error) {
+	s.logger.Info("SearchAuthors called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return nil, status.Error(codes.InvalidArgument, "query cannot be empty")
+	}
+	if utf8.RuneCountInString(query) > maxAuthorName {
+		return nil, status.Error(codes.InvalidArgument, "query too long")
+	}
+	pageSize := req.PageSize
+	if pageSize < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size cannot be negative")
+	}
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	// Names containing the query come first, then names that are merely similar.
+	rows, err := s.db.Query(ctx, `WITH matches AS (
+		SELECT id AS author_id, name FROM authors WHERE name ILIKE $2 OR $1 <% name
+		UNION ALL
+		SELECT author_id, name FROM author_variants WHERE name ILIKE $2 OR $1 <% name
+	)
+	SELECT author_id FROM matches
+	GROUP BY author_id
+	ORDER BY bool_or(name ILIKE $2) DESC, MAX(word_similarity($1, name)) DESC, author_id
+	LIMIT $3`, query, "%"+escapeLike(query)+"%", pageSize)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "author")
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "author")
+	}
+
+	res := &pb.SearchAuthorsResponse{}
+	for _, id := range ids {
+		author, err := s.getAuthor(ctx, s.db, id)
+		if status.Code(err) == codes.NotFound {
+			continue // Merged away since the search
+		}
+		if err != nil {
+			return nil, err
+		}
+		res.Authors = append(res.Authors, author)
+	}
+	return res, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (s *BooksServer) MergeAuthors(parentCtx context.Context, req *pb.MergeAuthorsRequest) (*pb.Author, error) {
+	s.logger.Info("MergeAuthors called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	targetID, err := parseID(req.AuthorId, "author id")
+	if err != nil {
+		return nil, err
+	}
+	if len(req.SourceIds) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "source_ids cannot be empty")
+	}
+	if len(req.SourceIds) > maxMergeSources {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d authors per merge", maxMergeSources)
+	}
+	var sourceIDs []int
+	for _, value := range req.SourceIds {
+		id, err := parseID(value, "source id")
+		if err != nil {
+			return nil, err
+		}
+		if id == targetID {
+			return nil, status.Error(codes.InvalidArgument, "an author cannot be merged into itself")
+		}
+		if !slices.Contains(sourceIDs, id) {
+			sourceIDs = append(sourceIDs, id)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 30*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "author")
+	}
+	defer tx.Rollback(ctx)
+
+	var locked int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM (
+		SELECT id FROM authors WHERE id = $1 OR id = ANY($2) ORDER BY id FOR UPDATE
+	) a`, targetID, sourceIDs).Scan(&locked)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "author")
+	}
+	if locked != len(sourceIDs)+1 {
+		return nil, dberr.ToStatus(pgx.ErrNoRows, "author")
+	}
+
+	rows, err := tx.Query(ctx, "SELECT DISTINCT book_id FROM book_authors WHERE author_id = ANY($1)", sourceIDs)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	bookIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	before, err := s.loadBooks(ctx, tx, bookIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// A book that already lists the target, or several of the sources, in the same role keeps one entry.
+	for _, query := range []string{
+		`DELETE FROM book_authors s USING book_authors t
+		WHERE s.author_id = ANY($2) AND t.author_id = $1 AND t.book_id = s.book_id AND t.role = s.role`,
+		`DELETE FROM book_authors x USING book_authors y
+		WHERE x.author_id = ANY($2) AND y.author_id = ANY($2) AND x.book_id = y.book_id AND x.role = y.role
+		AND (x.position, x.author_id) > (y.position, y.author_id)`,
+		"UPDATE book_authors SET author_id = $1 WHERE author_id = ANY($2)",
+		"UPDATE author_variants SET author_id = $1 WHERE author_id = ANY($2)",
+		// The merged names stay findable as aliases of the target.
+		"INSERT INTO author_variants (author_id, name) SELECT $1::int, name FROM authors WHERE id = ANY($2) ON CONFLICT DO NOTHING",
+		"DELETE FROM authors WHERE id = ANY($2)",
+	} {
+		if _, err := tx.Exec(ctx, query, targetID, sourceIDs); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "author")
+		}
+	}
+
+	reason := "merged authors " + strings.Join(req.SourceIds, ", ") + " into " + req.AuthorId
+	if err := s.refreshDisplayAuthors(ctx, tx, before, reason, actor(parentCtx)); err != nil {
+		return nil, err
+	}
+	author, err := s.getAuthor(ctx, tx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "author")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"author_id": author.Id,
+		"merged":    req.SourceIds,
+		"books":     len(bookIDs),
+	}).Info("Authors merged")
+	return author, nil
+}
+
+// refreshDisplayAuthors rewrites books.author of books whose contributors changed
+// and records the change in their history.
+func (s *BooksServer) refreshDisplayAuthors(ctx context.Context, tx pgx.Tx, before map[int]*pb.BookResponse, reason, actor string) error {
+	ids := make([]int, 0, len(before))
+	for id := range before {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	after, err := s.loadBooks(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		book, ok := after[id]
+		if !ok {
+			continue
+		}
+		updated := metadata(book)
+		updated.author = displayAuthors(updated.contributors)
+		changes := metadata(before[id]).diff(updated)
+		if len(changes) == 0 {
+			continue
+		}
+		if updated.author != book.Author {
+			if _, err := tx.Exec(ctx, "UPDATE books SET author = $2 WHERE id = $1", id, updated.author); err != nil {
+				s.logger.Errorf("Database error: %v", err)
+				return dberr.ToStatus(err, "book")
+			}
+		}
+		if err := recordRevision(ctx, tx, id, revisionUpdate, changes, reason, actor); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "book")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,409 @@
+package bookserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/dberr"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// An import is a client stream of row chunks. Rows are validated as they arrive and upserted
+// by ISBN in batches, one transaction per batch: the batch is copied into a temporary table,
+// matched against the catalog there and written back with a few set-based statements.
+
+const (
+	importBatchSize = 1000
+	// maxImportRows keeps the per-row report of one import well under the default message size.
+	maxImportRows = 50000
+	importReason  = "import"
+)
+
+// Row outcomes in the import report.
+const (
+	importCreated   = "created"
+	importUpdated   = "updated"
+	importUnchanged = "unchanged"
+	importRejected  = "rejected"
+)
+
+// importRow is a validated row waiting for its batch.
+type importRow struct {
+	title  string
+	author string
+	year   int32
+	isbn13 string
+	copies int32
+	result *pb.ImportRowResult
+}
+
+// validateImportRow applies the CreateBook rules to a row; the ISBN is required here.
+func validateImportRow(row *pb.ImportRow) (importRow, error) {
+	if row.Error != "" {
+		return importRow{}, status.Error(codes.InvalidArgument, row.Error)
+	}
+	if row.Isbn == "" {
+		return importRow{}, status.Error(codes.InvalidArgument, "isbn cannot be empty")
+	}
+	isbn13, err := normalizeISBN(row.Isbn)
+	if err != nil {
+		return importRow{}, err
+	}
+	valid := importRow{title: row.Title, author: strings.TrimSpace(row.Author), year: row.Year, isbn13: isbn13, copies: row.Copies}
+	if err := validateBook(valid.title, valid.year, []*pb.Contributor{{Name: valid.author}}); err != nil {
+		return valid, err
+	}
+	if valid.copies == 0 {
+		valid.copies = 1
+	}
+	if valid.copies < 0 || valid.copies > maxCopies {
+		return valid, status.Errorf(codes.InvalidArgument, "copies must be between 0 and %d", maxCopies)
+	}
+	return valid, nil
+}
+
+func (s *BooksServer) ImportBooks(stream pb.BookService_ImportBooksServer) error {
+	s.logger.Info("ImportBooks called")
+	ctx := stream.Context()
+
+	res := &pb.ImportBooksResponse{}
+	seen := make(map[string]int32)
+	batch := make([]importRow, 0, importBatchSize)
+	var count int32
+	for first := true; ; first = false {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if first {
+			res.DryRun = req.DryRun
+		}
+		for _, row := range req.Rows {
+			if count++; count > maxImportRows {
+				return status.Errorf(codes.InvalidArgument, "at most %d rows per import", maxImportRows)
+			}
+			result := &pb.ImportRowResult{Line: row.Line}
+			if result.Line == 0 {
+				result.Line = count
+			}
+			res.Results = append(res.Results, result)
+
+			valid, err := validateImportRow(row)
+			result.Isbn13 = valid.isbn13
+			if err == nil {
+				if line, ok := seen[valid.isbn13]; ok {
+					err = status.Errorf(codes.InvalidArgument, "duplicate isbn, first seen on line %d", line)
+				}
+			}
+			if err != nil {
+				result.Status = importRejected
+				result.Reason = status.Convert(err).Message()
+				continue
+			}
+			seen[valid.isbn13] = result.Line
+			valid.result = result
+			if batch = append(batch, valid); len(batch) == importBatchSize {
+				if err := s.importBatch(ctx, batch, res.DryRun); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := s.importBatch(ctx, batch, res.DryRun); err != nil {
+			return err
+		}
+	}
+
+	countOutcomes(res)
+	s.logger.WithFields(logrus.Fields{
+		"created":   res.Created,
+		"updated":   res.Updated,
+		"unchanged": res.Unchanged,
+		"rejected":  res.Rejected,
+		"dry_run":   res.DryRun,
+	}).Info("Books imported")
+	return stream.SendAndClose(res)
+}
+
+// countOutcomes fills in the totals of an import report from its results.
+func countOutcomes(res *pb.ImportBooksResponse) {
+	for _, result := range res.Results {
+		switch result.Status {
+		case importCreated:
+			res.Created++
+		case importUpdated:
+			res.Updated++
+		case importUnchanged:
+			res.Unchanged++
+		case importRejected:
+			res.Rejected++
+		}
+	}
+}
+
+// importBatch upserts rows by ISBN and fills in their results. A dry run goes through the
+// same statements and rolls them back. A database error fails the whole import; batches
+// committed before it stay, and importing the file again is safe.
+func (s *BooksServer) importBatch(parentCtx context.Context, rows []importRow, dryRun bool) error {
+	ctx, cancel := context.WithTimeout(parentCtx, time.Minute)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "book")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `CREATE TEMP TABLE import_rows (
+		pos INT PRIMARY KEY, title TEXT, author TEXT, year INT, isbn13 TEXT, copies INT
+	) ON COMMIT DROP`)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "book")
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"import_rows"},
+		[]string{"pos", "title", "author", "year", "isbn13", "copies"},
+		pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+			r := rows[i]
+			return []any{i, r.title, r.author, r.year, r.isbn13, r.copies}, nil
+		}))
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "book")
+	}
+
+	// Names that are neither an author nor a variant become authors, as in CreateBook.
+	_, err = tx.Exec(ctx, `INSERT INTO authors (name)
+	SELECT DISTINCT ON (lower(r.author)) r.author FROM import_rows r
+	WHERE NOT EXISTS (SELECT 1 FROM authors a WHERE lower(a.name) = lower(r.author))
+		AND NOT EXISTS (SELECT 1 FROM author_variants v WHERE lower(v.name) = lower(r.author))
+	ORDER BY lower(r.author), r.pos
+	ON CONFLICT DO NOTHING`)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "author")
+	}
+	_, err = tx.Exec(ctx, "SELECT b.id FROM books b JOIN import_rows r ON r.isbn13 = b.isbn13 ORDER BY b.id FOR UPDATE OF b")
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "book")
+	}
+
+	authors := make([]contributor, len(rows))
+	bookIDs := make([]int, len(rows))
+	matched, err := tx.Query(ctx, `SELECT r.pos, a.id, a.name, COALESCE(b.id, 0) FROM import_rows r
+	JOIN LATERAL (
+		SELECT id, name FROM authors WHERE lower(name) = lower(r.author)
+		UNION ALL
+		SELECT a.id, a.name FROM author_variants v JOIN authors a ON a.id = v.author_id WHERE lower(v.name) = lower(r.author)
+		LIMIT 1
+	) a ON true
+	LEFT JOIN books b ON b.isbn13 = r.isbn13`)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "book")
+	}
+	var existing []int
+	for matched.Next() {
+		var pos, bookID int
+		author := contributor{Role: RoleAuthor}
+		if err := matched.Scan(&pos, &author.AuthorID, &author.Name, &bookID); err != nil {
+			matched.Close()
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "book")
+		}
+		authors[pos], bookIDs[pos] = author, bookID
+		if bookID != 0 {
+			existing = append(existing, bookID)
+		}
+	}
+	matched.Close()
+	if err := matched.Err(); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "book")
+	}
+	books, err := s.loadBooks(ctx, tx, existing)
+	if err != nil {
+		return err
+	}
+
+	var updates struct {
+		ids, years      []int32
+		titles, authors []string
+	}
+	var links struct {
+		bookIDs, authorIDs, positions []int
+		roles                         []string
+	}
+	link := func(bookID int, list []contributor) {
+		for i, c := range list {
+			links.bookIDs = append(links.bookIDs, bookID)
+			links.authorIDs = append(links.authorIDs, c.AuthorID)
+			links.roles = append(links.roles, c.Role)
+			links.positions = append(links.positions, i)
+		}
+	}
+	var revisions [][]any
+	var relinked []int
+	var created []int
+	who := actor(parentCtx)
+	for i := range rows {
+		row := &rows[i]
+		if bookIDs[i] == 0 {
+			created = append(created, i)
+			continue
+		}
+		current := books[bookIDs[i]]
+		row.result.BookId = current.Id
+		if current.Withdrawn {
+			row.result.Status = importRejected
+			row.result.Reason = "book is withdrawn"
+			continue
+		}
+
+		// Like an UpdateBook of title, author and year: translators and editors stay.
+		old := metadata(current)
+		updated := old
+		updated.title, updated.year = row.title, row.year
+		updated.contributors = []contributor{authors[i]}
+		for _, c := range old.contributors {
+			if c.Role != RoleAuthor {
+				updated.contributors = append(updated.contributors, c)
+			}
+		}
+		updated.author = displayAuthors(updated.contributors)
+		changes := old.diff(updated)
+		if len(changes) == 0 {
+			row.result.Status = importUnchanged
+			continue
+		}
+		row.result.Status = importUpdated
+		updates.ids = append(updates.ids, int32(bookIDs[i]))
+		updates.titles = append(updates.titles, updated.title)
+		updates.authors = append(updates.authors, updated.author)
+		updates.years = append(updates.years, updated.year)
+		if contributorsText(old.contributors) != contributorsText(updated.contributors) {
+			relinked = append(relinked, bookIDs[i])
+			link(bookIDs[i], updated.contributors)
+		}
+		revisions = append(revisions, []any{bookIDs[i], revisionUpdate, changes, importReason, who})
+	}
+
+	if len(updates.ids) > 0 {
+		_, err = tx.Exec(ctx, `UPDATE books b SET title = u.title, author = u.author, published_year = u.year
+		FROM unnest($1::int[], $2::text[], $3::text[], $4::int[]) AS u(id, title, author, year)
+		WHERE b.id = u.id`, updates.ids, updates.titles, updates.authors, updates.years)
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "book")
+		}
+	}
+	if len(created) > 0 {
+		titles := make([]string, len(created))
+		names := make([]string, len(created))
+		years := make([]int32, len(created))
+		isbns := make([]string, len(created))
+		for j, i := range created {
+			titles[j], names[j], years[j], isbns[j] = rows[i].title, authors[i].Name, rows[i].year, rows[i].isbn13
+		}
+		inserted, err := tx.Query(ctx, `INSERT INTO books (title, author, published_year, isbn13)
+		SELECT * FROM unnest($1::text[], $2::text[], $3::int[], $4::text[])
+		RETURNING id, isbn13`, titles, names, years, isbns)
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "book")
+		}
+		ids := make(map[string]int, len(created))
+		for inserted.Next() {
+			var id int
+			var isbn13 string
+			if err := inserted.Scan(&id, &isbn13); err != nil {
+				inserted.Close()
+				s.logger.Errorf("Database error: %v", err)
+				return dberr.ToStatus(err, "book")
+			}
+			ids[isbn13] = id
+		}
+		inserted.Close()
+		if err := inserted.Err(); err != nil {
+			if !dberr.IsUniqueViolation(err) {
+				s.logger.Errorf("Database error: %v", err)
+			}
+			return dberr.ToStatus(err, "book")
+		}
+
+		itemBooks := make([]int, len(created))
+		itemCopies := make([]int32, len(created))
+		for j, i := range created {
+			row := &rows[i]
+			id := ids[row.isbn13]
+			row.result.Status = importCreated
+			if !dryRun {
+				row.result.BookId = strconv.Itoa(id)
+			}
+			itemBooks[j], itemCopies[j] = id, row.copies
+			list := []contributor{authors[i]}
+			link(id, list)
+			revisions = append(revisions, []any{id, revisionCreate, []fieldChange{
+				{Field: "title", New: row.title},
+				{Field: "author", New: authors[i].Name},
+				{Field: "contributors", New: contributorsText(list)},
+				{Field: "year", New: strconv.Itoa(int(row.year))},
+				{Field: "isbn", New: row.isbn13},
+			}, importReason, who})
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO items (book_id)
+		SELECT u.id FROM unnest($1::int[], $2::int[]) AS u(id, copies), generate_series(1, u.copies)`,
+			itemBooks, itemCopies)
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "item")
+		}
+	}
+	if len(relinked) > 0 {
+		if _, err := tx.Exec(ctx, "DELETE FROM book_authors WHERE book_id = ANY($1)", relinked); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "author")
+		}
+	}
+	if len(links.bookIDs) > 0 {
+		_, err = tx.Exec(ctx, `INSERT INTO book_authors (book_id, author_id, role, position)
+		SELECT * FROM unnest($1::int[], $2::int[], $3::text[], $4::int[])`,
+			links.bookIDs, links.authorIDs, links.roles, links.positions)
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "author")
+		}
+	}
+	if len(revisions) > 0 {
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"book_revisions"},
+			[]string{"book_id", "action", "changes", "reason", "actor"}, pgx.CopyFromRows(revisions))
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "book")
+		}
+	}
+
+	if dryRun {
+		return nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "book")
+	}
+	return nil
+}
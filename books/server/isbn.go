@@ -0,0 +1,108 @@
+package bookserver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/dberr"
+	"github.com/ViktorOHJ/library-system/isbn"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func normalizeISBN(value string) (string, error) {
+	isbn13, err := isbn.Normalize(value)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid isbn: %v", err)
+	}
+	return isbn13, nil
+}
+
+// setISBN fills both ISBN forms of a book from the stored ISBN-13.
+func setISBN(book *pb.BookResponse, isbn13 string) {
+	book.Isbn13 = isbn13
+	book.Isbn10, _ = isbn.To10(isbn13)
+}
+
+func (s *BooksServer) GetBookByISBN(parentCtx context.Context, req *pb.GetBookByISBNRequest) (*pb.BookResponse, error) {
+	s.logger.Info("GetBookByISBN called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	isbn13, err := normalizeISBN(req.Isbn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	book, err := scanBook(s.db.QueryRow(ctx, bookQuery+" WHERE b.isbn13 = $1 GROUP BY b.id", isbn13))
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("db error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "book")
+	}
+	return book, nil
+}
+
+func (s *BooksServer) LookupBooksByISBN(parentCtx context.Context, req *pb.LookupBooksByISBNRequest) (*pb.LookupBooksByISBNResponse, error) {
+	s.logger.Info("LookupBooksByISBN called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	if len(req.Isbns) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d isbns per request", maxBatchSize)
+	}
+
+	res := &pb.LookupBooksByISBNResponse{Results: make([]*pb.ISBNLookupResult, 0, len(req.Isbns))}
+	valid := make([]string, 0, len(req.Isbns))
+	for _, raw := range req.Isbns {
+		result := &pb.ISBNLookupResult{Isbn: raw}
+		if isbn13, err := isbn.Normalize(raw); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Isbn13 = isbn13
+			valid = append(valid, isbn13)
+		}
+		res.Results = append(res.Results, result)
+	}
+	if len(valid) == 0 {
+		return res, nil
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, bookQuery+" WHERE b.isbn13 = ANY($1) GROUP BY b.id", valid)
+	if err != nil {
+		s.logger.Errorf("db error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	defer rows.Close()
+
+	found := make(map[string]*pb.BookResponse, len(valid))
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			s.logger.Errorf("db error: %v", err)
+			return nil, dberr.ToStatus(err, "book")
+		}
+		found[book.Isbn13] = book
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.Errorf("db error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+
+	for _, result := range res.Results {
+		result.Book = found[result.Isbn13]
+	}
+	return res, nil
+}
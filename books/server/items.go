@@ -0,0 +1,390 @@
+package bookserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/dberr"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Items are the physical copies of a book. A copy is lent through CheckoutItem and
+// comes back through CheckinItem; the other statuses are set by librarians.
+
+const (
+	ItemAvailable = "available"
+	ItemOnLoan    = "on_loan"
+	ItemInRepair  = "in_repair"
+	ItemLost      = "lost"
+	ItemWithdrawn = "withdrawn"
+)
+
+var (
+	itemConditions = []string{"new", "good", "fair", "poor", "damaged"}
+	// itemStatuses can be set with UpdateItem; on_loan only comes from CheckoutItem.
+	itemStatuses = []string{ItemAvailable, ItemInRepair, ItemLost, ItemWithdrawn}
+)
+
+const itemColumns = "id, book_id, barcode, condition, location, status"
+
+func scanItem(row pgx.Row) (*pb.Item, error) {
+	var id, bookID int
+	item := &pb.Item{}
+	if err := row.Scan(&id, &bookID, &item.Barcode, &item.Condition, &item.Location, &item.Status); err != nil {
+		return nil, err
+	}
+	item.Id = strconv.Itoa(id)
+	item.BookId = strconv.Itoa(bookID)
+	return item, nil
+}
+
+func parseID(value, name string) (int, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s format", name)
+	}
+	return id, nil
+}
+
+func validateItemFields(condition, location string) error {
+	if condition != "" && !slices.Contains(itemConditions, condition) {
+		return status.Errorf(codes.InvalidArgument, "condition must be one of %s", strings.Join(itemConditions, ", "))
+	}
+	if len(location) > 100 {
+		return status.Error(codes.InvalidArgument, "location too long")
+	}
+	return nil
+}
+
+func (s *BooksServer) AddItem(parentCtx context.Context, req *pb.AddItemRequest) (*pb.Item, error) {
+	s.logger.Info("AddItem called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	bookID, err := parseID(req.BookId, "book id")
+	if err != nil {
+		return nil, err
+	}
+	barcode := strings.TrimSpace(req.Barcode)
+	if len(barcode) > 32 {
+		return nil, status.Error(codes.InvalidArgument, "barcode too long")
+	}
+	if err := validateItemFields(req.Condition, req.Location); err != nil {
+		return nil, err
+	}
+	condition := req.Condition
+	if condition == "" {
+		condition = "good"
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	// Selecting from books reports a missing or withdrawn book as no rows instead of a foreign key error.
+	columns, values := "book_id, condition, location", "id, $2, $3"
+	args := []any{bookID, condition, req.Location}
+	if barcode != "" {
+		columns, values = columns+", barcode", values+", $4"
+		args = append(args, barcode)
+	}
+	query := fmt.Sprintf("INSERT INTO items (%s) SELECT %s FROM books WHERE id = $1 AND withdrawn_at IS NULL RETURNING %s",
+		columns, values, itemColumns)
+	item, err := scanItem(s.db.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		if err := s.bookExists(ctx, bookID); err != nil {
+			return nil, err
+		}
+		return nil, status.Error(codes.FailedPrecondition, "book is withdrawn")
+	}
+	if err != nil {
+		if !dberr.IsUniqueViolation(err) {
+			s.logger.Errorf("Database error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "item")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"item_id": item.Id,
+		"book_id": item.BookId,
+		"barcode": item.Barcode,
+	}).Info("Item added")
+	return item, nil
+}
+
+func (s *BooksServer) GetItem(parentCtx context.Context, req *pb.GetItemRequest) (*pb.Item, error) {
+	s.logger.Info("GetItem called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	if (req.ItemId == "") == (req.Barcode == "") {
+		return nil, status.Error(codes.InvalidArgument, "exactly one of item id and barcode is required")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	var row pgx.Row
+	if req.ItemId != "" {
+		id, err := parseID(req.ItemId, "item id")
+		if err != nil {
+			return nil, err
+		}
+		row = s.db.QueryRow(ctx, "SELECT "+itemColumns+" FROM items WHERE id = $1", id)
+	} else {
+		row = s.db.QueryRow(ctx, "SELECT "+itemColumns+" FROM items WHERE barcode = $1", strings.TrimSpace(req.Barcode))
+	}
+	item, err := scanItem(row)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("Database error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "item")
+	}
+	return item, nil
+}
+
+func (s *BooksServer) ListItems(parentCtx context.Context, req *pb.ListItemsRequest) (*pb.ListItemsResponse, error) {
+	s.logger.Info("ListItems called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	bookID, err := parseID(req.BookId, "book id")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, "SELECT "+itemColumns+" FROM items WHERE book_id = $1 ORDER BY id", bookID)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "item")
+	}
+	defer rows.Close()
+
+	res := &pb.ListItemsResponse{}
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "item")
+		}
+		res.Items = append(res.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "item")
+	}
+	if len(res.Items) == 0 {
+		if err := s.bookExists(ctx, bookID); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (s *BooksServer) UpdateItem(parentCtx context.Context, req *pb.UpdateItemRequest) (*pb.Item, error) {
+	s.logger.Info("UpdateItem called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := parseID(req.ItemId, "item id")
+	if err != nil {
+		return nil, err
+	}
+	if err := validateItemFields(req.Condition, req.Location); err != nil {
+		return nil, err
+	}
+	if req.Status != "" && !slices.Contains(itemStatuses, req.Status) {
+		return nil, status.Errorf(codes.InvalidArgument, "status must be one of %s", strings.Join(itemStatuses, ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "item")
+	}
+	defer tx.Rollback(ctx)
+
+	// The book row is locked too, so a concurrent WithdrawBook is seen before the check.
+	var (
+		current   string
+		withdrawn bool
+	)
+	err = tx.QueryRow(ctx, `SELECT i.status, b.withdrawn_at IS NOT NULL
+	FROM items i JOIN books b ON b.id = i.book_id
+	WHERE i.id = $1 FOR UPDATE OF i FOR SHARE OF b`, id).Scan(&current, &withdrawn)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("Database error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "item")
+	}
+	// The loan of a copy ends with CheckinItem, so that loans and copies stay in step.
+	if current == ItemOnLoan && req.Status != "" {
+		return nil, status.Error(codes.FailedPrecondition, "item is on loan, check it in first")
+	}
+	// Copies of a withdrawn book can only be written off.
+	if withdrawn && req.Status != "" && req.Status != ItemLost && req.Status != ItemWithdrawn {
+		return nil, status.Error(codes.FailedPrecondition, "book is withdrawn")
+	}
+
+	item, err := scanItem(tx.QueryRow(ctx, `UPDATE items SET
+		condition = COALESCE(NULLIF($2, ''), condition),
+		location = COALESCE(NULLIF($3, ''), location),
+		status = COALESCE(NULLIF($4, ''), status)
+	WHERE id = $1 RETURNING `+itemColumns, id, req.Condition, req.Location, req.Status))
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "item")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "item")
+	}
+	return item, nil
+}
+
+// CheckoutItem marks a copy as on loan. Concurrent checkouts of the same book get
+// different copies; SKIP LOCKED keeps them from waiting on each other. Copies of a
+// withdrawn book are never lent, whatever their status.
+func (s *BooksServer) CheckoutItem(parentCtx context.Context, req *pb.CheckoutItemRequest) (*pb.Item, error) {
+	s.logger.Info("CheckoutItem called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	if req.ItemId == "" {
+		bookID, err := parseID(req.BookId, "book id")
+		if err != nil {
+			return nil, err
+		}
+		return s.moveItem(parentCtx, `UPDATE items SET status = 'on_loan' WHERE id = (
+			SELECT i.id FROM items i JOIN books b ON b.id = i.book_id
+			WHERE i.book_id = $1 AND i.status = 'available' AND b.withdrawn_at IS NULL
+			ORDER BY i.id LIMIT 1 FOR UPDATE OF i SKIP LOCKED
+		) RETURNING `+itemColumns, 0, bookID, "no copy of the book is available")
+	}
+
+	itemID, err := parseID(req.ItemId, "item id")
+	if err != nil {
+		return nil, err
+	}
+	bookID := 0
+	if req.BookId != "" {
+		if bookID, err = parseID(req.BookId, "book id"); err != nil {
+			return nil, err
+		}
+	}
+	return s.moveItem(parentCtx, `UPDATE items SET status = 'on_loan'
+		WHERE id = $1 AND status = 'available' AND ($2 = 0 OR book_id = $2)
+			AND book_id IN (SELECT id FROM books WHERE withdrawn_at IS NULL)
+		RETURNING `+itemColumns, itemID, bookID, "item is not available")
+}
+
+// CheckinItem puts a copy back on the shelf. Loans made before copies were tracked
+// have no item id and return any copy of their book that is on loan.
+func (s *BooksServer) CheckinItem(parentCtx context.Context, req *pb.CheckinItemRequest) (*pb.Item, error) {
+	s.logger.Info("CheckinItem called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	if req.ItemId == "" {
+		bookID, err := parseID(req.BookId, "book id")
+		if err != nil {
+			return nil, err
+		}
+		return s.moveItem(parentCtx, `UPDATE items SET status = 'available' WHERE id = (
+			SELECT id FROM items WHERE book_id = $1 AND status = 'on_loan'
+			ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED
+		) RETURNING `+itemColumns, 0, bookID, "no copy of the book is on loan")
+	}
+
+	itemID, err := parseID(req.ItemId, "item id")
+	if err != nil {
+		return nil, err
+	}
+	bookID := 0
+	if req.BookId != "" {
+		if bookID, err = parseID(req.BookId, "book id"); err != nil {
+			return nil, err
+		}
+	}
+	return s.moveItem(parentCtx, `UPDATE items SET status = 'available'
+		WHERE id = $1 AND status = 'on_loan' AND ($2 = 0 OR book_id = $2)
+		RETURNING `+itemColumns, itemID, bookID, "item is not on loan")
+}
+
+// moveItem runs a status change of one item. The query takes the item id and the book
+// id, or only the book id when itemID is zero. When nothing changes it tells a missing
+// item or book (NOT_FOUND) from one in the wrong state (FAILED_PRECONDITION).
+func (s *BooksServer) moveItem(parentCtx context.Context, query string, itemID, bookID int, conflict string) (*pb.Item, error) {
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	args := []any{bookID}
+	if itemID != 0 {
+		args = []any{itemID, bookID}
+	}
+	item, err := scanItem(s.db.QueryRow(ctx, query, args...))
+	if err == nil {
+		s.logger.WithFields(logrus.Fields{
+			"item_id": item.Id,
+			"book_id": item.BookId,
+			"status":  item.Status,
+		}).Info("Item status changed")
+		return item, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "item")
+	}
+
+	if itemID == 0 {
+		if err := s.bookExists(ctx, bookID); err != nil {
+			return nil, err
+		}
+		return nil, status.Error(codes.FailedPrecondition, conflict)
+	}
+	var itemBookID int
+	if err := s.db.QueryRow(ctx, "SELECT book_id FROM items WHERE id = $1", itemID).Scan(&itemBookID); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("Database error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "item")
+	}
+	if bookID != 0 && itemBookID != bookID {
+		return nil, status.Error(codes.InvalidArgument, "item is a copy of another book")
+	}
+	return nil, status.Error(codes.FailedPrecondition, conflict)
+}
+
+func (s *BooksServer) bookExists(ctx context.Context, id int) error {
+	var exists bool
+	if err := s.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM books WHERE id = $1)", id).Scan(&exists); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "book")
+	}
+	if !exists {
+		return dberr.ToStatus(pgx.ErrNoRows, "book")
+	}
+	return nil
+}
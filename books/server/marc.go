@@ -0,0 +1,409 @@
+package bookserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ViktorOHJ/library-system/books/marc"
+	"github.com/ViktorOHJ/library-system/dberr"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MARC 21 records are how other library systems exchange catalog data. A record is upserted
+// by ISBN like an ImportBooks row, and also brings translators, editors and subjects.
+// Records are written one by one in a single transaction, each under its own savepoint, so
+// that a record the catalog cannot take is rejected without losing the others.
+
+const (
+	maxMarcRecords = 1000
+	marcReason     = "marc import"
+)
+
+// marcRecord is a validated record waiting to be written.
+type marcRecord struct {
+	title        string
+	year         int32
+	isbn13       string
+	contributors []*pb.Contributor
+	subjects     []string
+	result       *pb.ImportRowResult
+}
+
+// validateMarcRecord applies the CreateBook rules to a record; the ISBN is required here.
+func validateMarcRecord(rec *marc.Record) (marcRecord, error) {
+	book, err := marc.ToBook(rec)
+	if err != nil {
+		return marcRecord{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if book.Deleted {
+		return marcRecord{}, status.Error(codes.InvalidArgument, "record is marked as deleted")
+	}
+	if book.ISBN == "" {
+		return marcRecord{}, status.Error(codes.InvalidArgument, "isbn cannot be empty")
+	}
+	isbn13, err := normalizeISBN(book.ISBN)
+	if err != nil {
+		return marcRecord{}, err
+	}
+	valid := marcRecord{title: book.Title, year: book.Year, isbn13: isbn13, subjects: book.Subjects}
+	for _, c := range book.Contributors {
+		valid.contributors = append(valid.contributors, &pb.Contributor{Name: c.Name, Role: c.Role})
+	}
+	if err := validateBook(valid.title, valid.year, valid.contributors); err != nil {
+		return valid, err
+	}
+	if len(valid.subjects) > maxBookSubjects {
+		return valid, status.Errorf(codes.InvalidArgument, "at most %d subjects per book", maxBookSubjects)
+	}
+	for _, name := range valid.subjects {
+		if utf8.RuneCountInString(name) > maxSubjectName {
+			return valid, status.Error(codes.InvalidArgument, "subject name too long")
+		}
+	}
+	return valid, nil
+}
+
+func (s *BooksServer) ImportMarc(parentCtx context.Context, req *pb.ImportMarcRequest) (*pb.ImportBooksResponse, error) {
+	s.logger.Info("ImportMarc called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	format, err := marc.ParseFormat(req.Format, req.Data)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	reader, err := marc.NewReader(bytes.NewReader(req.Data), format)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	res := &pb.ImportBooksResponse{DryRun: req.DryRun}
+	seen := make(map[string]int32)
+	var records []marcRecord
+	for n := int32(1); ; n++ {
+		rec, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var recordErr *marc.RecordError
+		if err != nil && !errors.As(err, &recordErr) {
+			return nil, status.Errorf(codes.InvalidArgument, "record %d: %v", n, err)
+		}
+		if n > maxMarcRecords {
+			return nil, status.Errorf(codes.InvalidArgument, "at most %d records per import", maxMarcRecords)
+		}
+		result := &pb.ImportRowResult{Line: n}
+		res.Results = append(res.Results, result)
+		if err != nil {
+			result.Status = importRejected
+			result.Reason = err.Error()
+			continue
+		}
+
+		valid, err := validateMarcRecord(rec)
+		result.Isbn13 = valid.isbn13
+		if err == nil {
+			if first, ok := seen[valid.isbn13]; ok {
+				err = status.Errorf(codes.InvalidArgument, "duplicate isbn, first seen in record %d", first)
+			}
+		}
+		if err != nil {
+			result.Status = importRejected
+			result.Reason = status.Convert(err).Message()
+			continue
+		}
+		seen[valid.isbn13] = n
+		valid.result = result
+		records = append(records, valid)
+	}
+	if len(records) > 0 {
+		if err := s.importMarc(parentCtx, records, req.DryRun); err != nil {
+			return nil, err
+		}
+	}
+
+	countOutcomes(res)
+	s.logger.WithFields(logrus.Fields{
+		"format":    format,
+		"created":   res.Created,
+		"updated":   res.Updated,
+		"unchanged": res.Unchanged,
+		"rejected":  res.Rejected,
+		"dry_run":   res.DryRun,
+	}).Info("MARC records imported")
+	return res, nil
+}
+
+// importMarc writes records and fills in their results. A record that breaks a catalog
+// rule is rolled back to its savepoint and rejected; any other error fails the import.
+func (s *BooksServer) importMarc(parentCtx context.Context, records []marcRecord, dryRun bool) error {
+	ctx, cancel := context.WithTimeout(parentCtx, time.Minute)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "book")
+	}
+	defer tx.Rollback(ctx)
+
+	who := actor(parentCtx)
+	for i := range records {
+		r := &records[i]
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "book")
+		}
+		err = s.importMarcRecord(ctx, savepoint, r, who, dryRun)
+		if code := status.Code(err); code == codes.InvalidArgument || code == codes.FailedPrecondition {
+			if err := savepoint.Rollback(ctx); err != nil {
+				s.logger.Errorf("Database error: %v", err)
+				return dberr.ToStatus(err, "book")
+			}
+			r.result.Status = importRejected
+			r.result.Reason = status.Convert(err).Message()
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := savepoint.Commit(ctx); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "book")
+		}
+	}
+
+	if dryRun {
+		return nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "book")
+	}
+	return nil
+}
+
+// importMarcRecord creates the book of a record with one copy, or updates the book with its
+// ISBN: the contributors are replaced, and so are the subjects when the record has any.
+func (s *BooksServer) importMarcRecord(ctx context.Context, tx pgx.Tx, r *marcRecord, who string, dryRun bool) error {
+	var id int
+	err := tx.QueryRow(ctx, "SELECT id FROM books WHERE isbn13 = $1 FOR UPDATE", r.isbn13).Scan(&id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "book")
+	}
+	resolved, err := s.resolveContributors(ctx, tx, r.contributors)
+	if err != nil {
+		return err
+	}
+	subjectIDs, err := s.resolveSubjects(ctx, tx, r.subjects)
+	if err != nil {
+		return err
+	}
+	author := displayAuthors(resolved)
+
+	if id == 0 {
+		err = tx.QueryRow(ctx, `INSERT INTO books (title, author, published_year, isbn13)
+		VALUES ($1, $2, $3, $4) RETURNING id`, r.title, author, r.year, r.isbn13).Scan(&id)
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "book")
+		}
+		if err := setContributors(ctx, tx, id, resolved); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "author")
+		}
+		if err := setSubjects(ctx, tx, id, subjectIDs); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "subject")
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO items (book_id) VALUES ($1)", id); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "item")
+		}
+		created := []fieldChange{
+			{Field: "title", New: r.title},
+			{Field: "author", New: author},
+			{Field: "contributors", New: contributorsText(resolved)},
+			{Field: "year", New: strconv.Itoa(int(r.year))},
+			{Field: "isbn", New: r.isbn13},
+		}
+		if len(r.subjects) > 0 {
+			book, err := s.getBook(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			created = append(created, fieldChange{Field: "subjects", New: subjectNames(book.Subjects)})
+		}
+		if err := recordRevision(ctx, tx, id, revisionCreate, created, marcReason, who); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "book")
+		}
+		r.result.Status = importCreated
+		if !dryRun {
+			r.result.BookId = strconv.Itoa(id)
+		}
+		return nil
+	}
+
+	current, err := s.getBook(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	r.result.BookId = current.Id
+	if current.Withdrawn {
+		return status.Error(codes.FailedPrecondition, "book is withdrawn")
+	}
+	old := metadata(current)
+	updated := old
+	updated.title, updated.year, updated.author, updated.contributors = r.title, r.year, author, resolved
+	changes := old.diff(updated)
+	if len(changes) > 0 {
+		_, err := tx.Exec(ctx, "UPDATE books SET title = $1, author = $2, published_year = $3 WHERE id = $4",
+			r.title, author, r.year, id)
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "book")
+		}
+	}
+	if contributorsText(old.contributors) != contributorsText(resolved) {
+		if err := setContributors(ctx, tx, id, resolved); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "author")
+		}
+	}
+	if len(subjectIDs) > 0 {
+		if err := setSubjects(ctx, tx, id, subjectIDs); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return dberr.ToStatus(err, "subject")
+		}
+		book, err := s.getBook(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if before, after := subjectNames(current.Subjects), subjectNames(book.Subjects); before != after {
+			changes = append(changes, fieldChange{Field: "subjects", Old: before, New: after})
+		}
+	}
+	if len(changes) == 0 {
+		r.result.Status = importUnchanged
+		return nil
+	}
+	if err := recordRevision(ctx, tx, id, revisionUpdate, changes, marcReason, who); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return dberr.ToStatus(err, "book")
+	}
+	r.result.Status = importUpdated
+	return nil
+}
+
+// resolveSubjects finds subjects by name, preferring a top-level one, and creates the
+// missing ones at the top level for librarians to file later.
+func (s *BooksServer) resolveSubjects(ctx context.Context, tx pgx.Tx, names []string) ([]int, error) {
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		var id int
+		err := tx.QueryRow(ctx, `SELECT id FROM subjects WHERE lower(name) = lower($1)
+		ORDER BY parent_id IS NOT NULL, id LIMIT 1`, name).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = tx.QueryRow(ctx, `INSERT INTO subjects (name) VALUES ($1)
+			ON CONFLICT ((COALESCE(parent_id, 0)), (lower(name))) DO UPDATE SET name = subjects.name
+			RETURNING id`, name).Scan(&id)
+		}
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "subject")
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *BooksServer) ExportMarc(parentCtx context.Context, req *pb.ExportMarcRequest) (*pb.ExportMarcResponse, error) {
+	s.logger.Info("ExportMarc called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	format := marc.ISO2709
+	if req.Format != "" {
+		var err error
+		if format, err = marc.ParseFormat(req.Format, nil); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if len(req.BookIds) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "book ids cannot be empty")
+	}
+	if len(req.BookIds) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d book ids per request", maxBatchSize)
+	}
+	var ids []int
+	for _, value := range req.BookIds {
+		id, err := parseID(value, "book id")
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	found, err := s.loadBooks(ctx, s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w, err := marc.NewWriter(&buf, format)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	for _, id := range ids {
+		book, ok := found[id]
+		if !ok {
+			return nil, dberr.ToStatus(pgx.ErrNoRows, "book")
+		}
+		if err := w.Write(marc.FromBook(marcBook(book))); err != nil {
+			s.logger.Errorf("Failed to encode book %d: %v", id, err)
+			return nil, status.Errorf(codes.Internal, "failed to encode book %d", id)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"format": format,
+		"books":  len(ids),
+	}).Info("MARC records exported")
+	return &pb.ExportMarcResponse{Data: buf.Bytes(), Format: string(format), ContentType: format.ContentType()}, nil
+}
+
+// marcBook is what a record says about a book; a withdrawn book is exported as deleted.
+func marcBook(book *pb.BookResponse) marc.Book {
+	b := marc.Book{ID: book.Id, Title: book.Title, Year: book.Year, ISBN: book.Isbn13, Deleted: book.Withdrawn}
+	for _, c := range book.Contributors {
+		b.Contributors = append(b.Contributors, marc.Contributor{Name: c.Name, Role: c.Role})
+	}
+	for _, subject := range book.Subjects {
+		b.Subjects = append(b.Subjects, subject.Name)
+	}
+	return b
+}
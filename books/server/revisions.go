@@ -0,0 +1,373 @@
+package bookserver
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/dberr"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Every change to a book's metadata is kept in book_revisions with who made it and when.
+
+const (
+	revisionCreate   = "create"
+	revisionUpdate   = "update"
+	revisionWithdraw = "withdraw"
+)
+
+const maxReasonLength = 255
+
+// fieldChange is stored as JSON in book_revisions.changes.
+type fieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func recordRevision(ctx context.Context, db execer, bookID int, action string, changes []fieldChange, reason, actor string) error {
+	if changes == nil {
+		changes = []fieldChange{}
+	}
+	_, err := db.Exec(ctx, `INSERT INTO book_revisions (book_id, action, changes, reason, actor)
+	VALUES ($1, $2, $3, $4, $5)`, bookID, action, changes, reason, actor)
+	return err
+}
+
+// actor identifies the caller in revisions: a user id, an API key or nobody for the system.
+func actor(ctx context.Context) string {
+	p, ok := auth.FromContext(ctx)
+	switch {
+	case !ok:
+		return ""
+	case p.IsAPIKey():
+		return "api-key:" + p.APIKeyID
+	default:
+		return p.UserID
+	}
+}
+
+func (s *BooksServer) UpdateBook(parentCtx context.Context, req *pb.UpdateBookRequest) (*pb.BookResponse, error) {
+	s.logger.Info("UpdateBook called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := parseID(req.BookId, "book id")
+	if err != nil {
+		return nil, err
+	}
+
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		if req.Title != "" {
+			paths = append(paths, "title")
+		}
+		if req.Author != "" {
+			paths = append(paths, "author")
+		}
+		if req.Year != 0 {
+			paths = append(paths, "year")
+		}
+		if req.Isbn != "" {
+			paths = append(paths, "isbn")
+		}
+		if len(req.Contributors) > 0 {
+			paths = append(paths, "contributors")
+		}
+	}
+	if len(paths) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "nothing to update")
+	}
+	isbn13 := ""
+	for _, path := range paths {
+		switch path {
+		case "title", "author", "year", "contributors":
+		case "isbn":
+			if req.Isbn != "" {
+				if isbn13, err = normalizeISBN(req.Isbn); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unknown update_mask path %q", path)
+		}
+	}
+	if slices.Contains(paths, "author") && slices.Contains(paths, "contributors") {
+		return nil, status.Error(codes.InvalidArgument, "update either author or contributors, not both")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := s.lockBook(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Withdrawn {
+		return nil, status.Error(codes.FailedPrecondition, "book is withdrawn")
+	}
+
+	old := metadata(current)
+	updated := old
+	contributors := current.Contributors
+	if len(contributors) == 0 {
+		contributors = []*pb.Contributor{{Name: current.Author, Role: RoleAuthor}}
+	}
+	for _, path := range paths {
+		switch path {
+		case "title":
+			updated.title = req.Title
+		case "author":
+			// Translators and editors stay when only the author is replaced.
+			contributors = []*pb.Contributor{{Name: req.Author, Role: RoleAuthor}}
+			for _, c := range current.Contributors {
+				if c.Role != RoleAuthor {
+					contributors = append(contributors, &pb.Contributor{AuthorId: c.AuthorId, Role: c.Role})
+				}
+			}
+		case "contributors":
+			contributors = req.Contributors
+		case "year":
+			updated.year = req.Year
+		case "isbn":
+			updated.isbn13 = isbn13
+		}
+	}
+	if err := validateBook(updated.title, updated.year, contributors); err != nil {
+		return nil, err
+	}
+	if updated.contributors, err = s.resolveContributors(ctx, tx, contributors); err != nil {
+		return nil, err
+	}
+	updated.author = displayAuthors(updated.contributors)
+	changes := old.diff(updated)
+	if len(changes) == 0 {
+		return current, nil
+	}
+
+	var newISBN *string
+	if updated.isbn13 != "" {
+		newISBN = &updated.isbn13
+	}
+	_, err = tx.Exec(ctx, "UPDATE books SET title = $2, author = $3, published_year = $4, isbn13 = $5 WHERE id = $1",
+		id, updated.title, updated.author, updated.year, newISBN)
+	if err != nil {
+		if !dberr.IsUniqueViolation(err) {
+			s.logger.Errorf("Database error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "book")
+	}
+	if err := setContributors(ctx, tx, id, updated.contributors); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "author")
+	}
+	if err := recordRevision(ctx, tx, id, revisionUpdate, changes, "", actor(parentCtx)); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	book, err := s.getBook(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"book_id": book.Id,
+		"fields":  paths,
+	}).Info("Book updated")
+	return book, nil
+}
+
+// bookMetadata holds the fields UpdateBook can change.
+type bookMetadata struct {
+	title        string
+	author       string
+	year         int32
+	isbn13       string
+	contributors []contributor
+}
+
+func metadata(book *pb.BookResponse) bookMetadata {
+	m := bookMetadata{title: book.Title, author: book.Author, year: book.Year, isbn13: book.Isbn13}
+	for _, c := range book.Contributors {
+		id, _ := strconv.Atoi(c.AuthorId)
+		m.contributors = append(m.contributors, contributor{AuthorID: id, Name: c.Name, Role: c.Role})
+	}
+	return m
+}
+
+// diff lists the fields that differ in updated.
+func (m bookMetadata) diff(updated bookMetadata) []fieldChange {
+	var changes []fieldChange
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, fieldChange{Field: field, Old: old, New: new})
+		}
+	}
+	add("title", m.title, updated.title)
+	add("author", m.author, updated.author)
+	add("contributors", contributorsText(m.contributors), contributorsText(updated.contributors))
+	add("year", strconv.Itoa(int(m.year)), strconv.Itoa(int(updated.year)))
+	add("isbn", m.isbn13, updated.isbn13)
+	return changes
+}
+
+func (s *BooksServer) WithdrawBook(parentCtx context.Context, req *pb.WithdrawBookRequest) (*pb.BookResponse, error) {
+	s.logger.Info("WithdrawBook called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := parseID(req.BookId, "book id")
+	if err != nil {
+		return nil, err
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, status.Error(codes.InvalidArgument, "reason cannot be empty")
+	}
+	if len(reason) > maxReasonLength {
+		return nil, status.Error(codes.InvalidArgument, "reason too long")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := s.lockBook(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Withdrawn {
+		return nil, status.Error(codes.FailedPrecondition, "book is already withdrawn")
+	}
+	// Locking the copies keeps a concurrent checkout from lending one while the book is withdrawn.
+	var onLoan int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM (
+		SELECT status FROM items WHERE book_id = $1 FOR UPDATE
+	) i WHERE status = 'on_loan'`, id).Scan(&onLoan)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "item")
+	}
+	if onLoan > 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "book has %d copies on loan", onLoan)
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE items SET status = 'withdrawn' WHERE book_id = $1 AND status IN ('available', 'in_repair')", id)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "item")
+	}
+	_, err = tx.Exec(ctx, "UPDATE books SET withdrawn_at = CURRENT_TIMESTAMP, withdrawal_reason = $2 WHERE id = $1", id, reason)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	if err := recordRevision(ctx, tx, id, revisionWithdraw, nil, reason, actor(parentCtx)); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	book, err := s.getBook(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"book_id": book.Id,
+		"reason":  reason,
+	}).Info("Book withdrawn")
+	return book, nil
+}
+
+// lockBook reads a book and locks its row until the end of tx.
+func (s *BooksServer) lockBook(ctx context.Context, tx pgx.Tx, id int) (*pb.BookResponse, error) {
+	if _, err := tx.Exec(ctx, "SELECT 1 FROM books WHERE id = $1 FOR UPDATE", id); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	return s.getBook(ctx, tx, id)
+}
+
+func (s *BooksServer) GetBookHistory(parentCtx context.Context, req *pb.GetBookHistoryRequest) (*pb.GetBookHistoryResponse, error) {
+	s.logger.Info("GetBookHistory called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := parseID(req.BookId, "book id")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	if err := s.bookExists(ctx, id); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(ctx, `SELECT id, action, changes, reason, actor, created_at
+	FROM book_revisions WHERE book_id = $1 ORDER BY id`, id)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book revision")
+	}
+	defer rows.Close()
+
+	res := &pb.GetBookHistoryResponse{}
+	for rows.Next() {
+		var revisionID int
+		var changes []fieldChange
+		var createdAt time.Time
+		revision := &pb.BookRevision{BookId: strconv.Itoa(id)}
+		if err := rows.Scan(&revisionID, &revision.Action, &changes, &revision.Reason, &revision.Actor, &createdAt); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "book revision")
+		}
+		revision.Id = strconv.Itoa(revisionID)
+		revision.CreatedAt = createdAt.Format(time.RFC3339)
+		for _, c := range changes {
+			revision.Changes = append(revision.Changes, &pb.FieldChange{Field: c.Field, OldValue: c.Old, NewValue: c.New})
+		}
+		res.Revisions = append(res.Revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book revision")
+	}
+	return res, nil
+}
@@ -0,0 +1,355 @@
+package bookserver
+
+import (
+	"context"
+	"encoding/base64"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ViktorOHJ/library-system/dberr"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	maxQueryLength  = 200
+	maxAuthorFacets = 10
+)
+
+// searchMatches is the common part of the search queries: every book matching the query
+// ($1), ranked by full-text relevance, where title words weigh more than author words, plus
+// trigram similarity so that misspelled queries still find their books. A subject ($2, 0 for
+// any) or a tag ($3, empty for any) narrows the matches before facets are counted. The author
+// ($4), decade ($5) and availability ($6) filters become flags, so that each facet can be
+// counted over the whole match set without its own filter.
+const searchMatches = `WITH RECURSIVE q AS (SELECT websearch_to_tsquery('simple', $1) AS tsq),
+tree AS (
+	SELECT id FROM subjects WHERE id = $2
+	UNION ALL
+	SELECT s.id FROM subjects s JOIN tree t ON s.parent_id = t.id
+),
+matches AS (
+	SELECT b.id, b.author, b.published_year / 10 * 10 AS decade,
+		EXISTS (SELECT 1 FROM items i WHERE i.book_id = b.id AND i.status = 'available') AS available,
+		ts_rank_cd(b.search_vector, q.tsq) + word_similarity($1, b.search_text) AS score
+	FROM books b, q
+	WHERE (b.search_vector @@ q.tsq OR $1 <% b.search_text) AND b.withdrawn_at IS NULL
+	AND ($2 = 0 OR EXISTS (SELECT 1 FROM book_subjects bs JOIN tree t ON t.id = bs.subject_id WHERE bs.book_id = b.id))
+	AND ($3::text = '' OR EXISTS (SELECT 1 FROM book_tags bt WHERE bt.book_id = b.id AND bt.tag = $3))
+),
+m AS (
+	SELECT *,
+		($4::text = '' OR author = $4) AS by_author,
+		($5::int = 0 OR decade = $5) AS by_decade,
+		(NOT $6::bool OR available) AS by_availability
+	FROM matches
+)
+`
+
+// searchPageQuery returns one page ($7 rows from offset $8) of the matches that pass all filters.
+const searchPageQuery = searchMatches + `SELECT id, score FROM m
+WHERE by_author AND by_decade AND by_availability
+ORDER BY score DESC, id
+LIMIT $7 OFFSET $8`
+
+// searchFacetsQuery counts each facet over the matches that pass the other filters, so that
+// choosing an author still shows the other authors one could switch to, and the total over
+// the matches that pass them all.
+const searchFacetsQuery = searchMatches + `SELECT 'author', author, count(*) FROM m
+WHERE by_decade AND by_availability GROUP BY author
+UNION ALL
+SELECT 'decade', decade::text, count(*) FROM m
+WHERE by_author AND by_availability GROUP BY decade
+UNION ALL
+SELECT 'availability', CASE WHEN available THEN 'available' ELSE 'unavailable' END, count(*) FROM m
+WHERE by_author AND by_decade GROUP BY available
+UNION ALL
+SELECT 'total', '', count(*) FROM m
+WHERE by_author AND by_decade AND by_availability`
+
+type searchMatch struct {
+	id    int
+	score float32
+}
+
+func (s *BooksServer) SearchCatalog(parentCtx context.Context, req *pb.SearchCatalogRequest) (*pb.SearchCatalogResponse, error) {
+	s.logger.Info("SearchCatalog called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return nil, status.Error(codes.InvalidArgument, "query cannot be empty")
+	}
+	if len(query) > maxQueryLength {
+		return nil, status.Error(codes.InvalidArgument, "query too long")
+	}
+	if req.Decade < 0 || req.Decade%10 != 0 {
+		return nil, status.Error(codes.InvalidArgument, "decade must be a year divisible by 10")
+	}
+	pageSize := req.PageSize
+	if pageSize < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size cannot be negative")
+	}
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	offset, err := decodeSearchToken(req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+	subjectID := 0
+	if req.SubjectId != "" {
+		if subjectID, err = parseID(req.SubjectId, "subject id"); err != nil {
+			return nil, err
+		}
+	}
+	tag := ""
+	if req.Tag != "" {
+		if tag, err = normalizeTag(req.Tag); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	args := []any{query, subjectID, tag, req.Author, req.Decade, req.AvailableOnly}
+	res, err := s.searchFacets(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= int(res.TotalMatches) {
+		return res, nil
+	}
+	page, err := s.searchPage(ctx, append(args, pageSize, offset))
+	if err != nil {
+		return nil, err
+	}
+	if offset+int(pageSize) < int(res.TotalMatches) {
+		res.NextPageToken = encodeSearchToken(offset + int(pageSize))
+	}
+
+	books, err := s.loadBooks(ctx, s.db, matchIDs(page))
+	if err != nil {
+		return nil, err
+	}
+	terms := queryTerms(query)
+	for _, m := range page {
+		book, ok := books[m.id]
+		if !ok {
+			continue
+		}
+		res.Hits = append(res.Hits, &pb.SearchHit{
+			Book:            book,
+			Score:           m.score,
+			TitleHighlight:  highlight(book.Title, terms),
+			AuthorHighlight: highlight(book.Author, terms),
+		})
+	}
+	return res, nil
+}
+
+// searchFacets runs searchFacetsQuery and returns a response with the total and the facets filled in.
+func (s *BooksServer) searchFacets(ctx context.Context, args []any) (*pb.SearchCatalogResponse, error) {
+	rows, err := s.db.Query(ctx, searchFacetsQuery, args...)
+	if err != nil {
+		s.logger.Errorf("db error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	defer rows.Close()
+
+	res := &pb.SearchCatalogResponse{}
+	counts := map[string]map[string]int32{"author": {}, "decade": {}, "availability": {}}
+	for rows.Next() {
+		var (
+			facet, value string
+			count        int32
+		)
+		if err := rows.Scan(&facet, &value, &count); err != nil {
+			s.logger.Errorf("db error: %v", err)
+			return nil, dberr.ToStatus(err, "book")
+		}
+		if facet == "total" {
+			res.TotalMatches = count
+			continue
+		}
+		counts[facet][value] = count
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.Errorf("db error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	res.Facets = &pb.SearchFacets{
+		Authors:      facetCounts(counts["author"], maxAuthorFacets),
+		Decades:      facetCounts(counts["decade"], 0),
+		Availability: facetCounts(counts["availability"], 0),
+	}
+	return res, nil
+}
+
+func (s *BooksServer) searchPage(ctx context.Context, args []any) ([]searchMatch, error) {
+	rows, err := s.db.Query(ctx, searchPageQuery, args...)
+	if err != nil {
+		s.logger.Errorf("db error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	defer rows.Close()
+
+	var page []searchMatch
+	for rows.Next() {
+		var m searchMatch
+		if err := rows.Scan(&m.id, &m.score); err != nil {
+			s.logger.Errorf("db error: %v", err)
+			return nil, dberr.ToStatus(err, "book")
+		}
+		page = append(page, m)
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.Errorf("db error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	return page, nil
+}
+
+// facetCounts orders values by count, then by value; limit 0 keeps them all.
+func facetCounts(counts map[string]int32, limit int) []*pb.FacetCount {
+	facets := make([]*pb.FacetCount, 0, len(counts))
+	for value, count := range counts {
+		facets = append(facets, &pb.FacetCount{Value: value, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	if limit > 0 && len(facets) > limit {
+		facets = facets[:limit]
+	}
+	return facets
+}
+
+func matchIDs(matches []searchMatch) []int {
+	ids := make([]int, len(matches))
+	for i, m := range matches {
+		ids[i] = m.id
+	}
+	return ids
+}
+
+// queryTerms returns the lowercased words of a web search query, without OR and excluded words.
+func queryTerms(query string) []string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		field = strings.TrimLeft(field, `"`)
+		if strings.HasPrefix(field, "-") || field == "OR" {
+			continue
+		}
+		for _, word := range strings.FieldsFunc(field, isSeparator) {
+			terms = append(terms, strings.ToLower(word))
+		}
+	}
+	return terms
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// highlight escapes text for HTML and wraps the words that match a query term,
+// allowing for typos, in <mark></mark>.
+func highlight(text string, terms []string) string {
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && isSeparator(runes[j]) == isSeparator(runes[i]) {
+			j++
+		}
+		part := string(runes[i:j])
+		if !isSeparator(runes[i]) && matchesAny(strings.ToLower(part), terms) {
+			b.WriteString("<mark>" + html.EscapeString(part) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(part))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func matchesAny(word string, terms []string) bool {
+	for _, term := range terms {
+		if word == term ||
+			len([]rune(term)) >= 3 && strings.HasPrefix(word, term) ||
+			editDistance(word, term) <= allowedTypos(term) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedTypos grows with the term: short words must match exactly.
+func allowedTypos(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance is the Levenshtein distance between two words, counted in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// Search page tokens are opaque to clients; they carry the offset of the next match.
+func encodeSearchToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeSearchToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(raw), "offset:") {
+		return 0, status.Error(codes.InvalidArgument, "invalid page token")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), "offset:"))
+	if err != nil || offset <= 0 {
+		return 0, status.Error(codes.InvalidArgument, "invalid page token")
+	}
+	return offset, nil
+}
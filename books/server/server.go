@@ -6,8 +6,11 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/ViktorOHJ/library-system/books/suggest"
+	"github.com/ViktorOHJ/library-system/dberr"
+	"github.com/ViktorOHJ/library-system/observability"
 	pb "github.com/ViktorOHJ/library-system/protos/pb"
-	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
@@ -16,53 +19,113 @@ import (
 
 type BooksServer struct {
 	pb.UnimplementedBookServiceServer
-	db     *pgxpool.Pool
-	logger *logrus.Logger
+	db          *pgxpool.Pool
+	logger      *logrus.Logger
+	suggestions *suggest.Index
 }
 
 func NewBooksServer(db *pgxpool.Pool, logger *logrus.Logger) *BooksServer {
 	return &BooksServer{db: db,
-		logger: logger}
+		logger:      logger,
+		suggestions: suggest.NewIndex()}
 }
 
 func (s *BooksServer) CreateBook(parentCtx context.Context, req *pb.CreateBookRequest) (res *pb.BookResponse, err error) {
 	s.logger.Info("CreateBook called")
 
-	if req.Title == "" {
-		s.logger.Error("CreateBook called with empty title")
-		return nil, status.Error(codes.InvalidArgument, "title cannot be empty")
+	if req == nil {
+		s.logger.Error("CreateBook called with nil request")
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
 	}
-	if len(req.Title) > 255 {
-		s.logger.Error("CreateBook called with too long title")
-		return nil, status.Error(codes.InvalidArgument, "title too long")
+	contributors := req.Contributors
+	if len(contributors) == 0 {
+		contributors = []*pb.Contributor{{Name: req.Author, Role: RoleAuthor}}
+	} else if req.Author != "" {
+		return nil, status.Error(codes.InvalidArgument, "set either author or contributors, not both")
 	}
-	if req.Author == "" {
-		s.logger.Error("CreateBook called with empty author")
-		return nil, status.Error(codes.InvalidArgument, "author cannot be empty")
+	if err := validateBook(req.Title, req.Year, contributors); err != nil {
+		s.logger.Errorf("CreateBook called with invalid book: %v", err)
+		return nil, err
 	}
-	if len(req.Author) > 100 {
-		s.logger.Error("CreateBook called with too long author name")
-		return nil, status.Error(codes.InvalidArgument, "author name too long")
+	var isbn13 *string
+	if req.Isbn != "" {
+		normalized, err := normalizeISBN(req.Isbn)
+		if err != nil {
+			return nil, err
+		}
+		isbn13 = &normalized
 	}
-	if req.Year < 0 || req.Year > int32(time.Now().Year()+10) {
-		s.logger.Error("CreateBook called with invalid year")
-		return nil, status.Error(codes.InvalidArgument, "invalid year")
+	copies := req.Copies
+	if copies == 0 {
+		copies = 1
 	}
-	if req == nil {
-		s.logger.Error("CreateBook called with nil request")
-		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	if copies < 0 || copies > maxCopies {
+		return nil, status.Errorf(codes.InvalidArgument, "copies must be between 0 and %d", maxCopies)
 	}
 
 	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
 	defer cancel()
 
-	book := &pb.BookResponse{}
-	err = s.db.QueryRow(ctx, `INSERT INTO books (title, author, published_year, is_available)
-	VALUES ($1, $2, $3, $4) RETURNING id, title, author, published_year, is_available`,
-		req.Title, req.Author, req.Year, true).Scan(&book.Id, &book.Title, &book.Author, &book.Year, &book.Available)
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	defer tx.Rollback(ctx)
+
+	resolved, err := s.resolveContributors(ctx, tx, contributors)
+	if err != nil {
+		return nil, err
+	}
+	author := displayAuthors(resolved)
+
+	var id int
+	err = tx.QueryRow(ctx, `INSERT INTO books (title, author, published_year, isbn13)
+	VALUES ($1, $2, $3, $4) RETURNING id`,
+		req.Title, author, req.Year, isbn13).Scan(&id)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	if err := setContributors(ctx, tx, id, resolved); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "author")
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO items (book_id) SELECT $1::int FROM generate_series(1, $2::int)", id, copies)
 	if err != nil {
 		s.logger.Errorf("Database error: %v", err)
-		return nil, status.Errorf(codes.Internal, "server error")
+		return nil, dberr.ToStatus(err, "item")
+	}
+	created := []fieldChange{
+		{Field: "title", New: req.Title},
+		{Field: "author", New: author},
+		{Field: "contributors", New: contributorsText(resolved)},
+		{Field: "year", New: strconv.Itoa(int(req.Year))},
+	}
+	if isbn13 != nil {
+		created = append(created, fieldChange{Field: "isbn", New: *isbn13})
+	}
+	if err := recordRevision(ctx, tx, id, revisionCreate, created, "", actor(parentCtx)); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+
+	book := &pb.BookResponse{
+		Id:              strconv.Itoa(id),
+		Title:           req.Title,
+		Author:          author,
+		Year:            req.Year,
+		Available:       true,
+		AvailableCopies: copies,
+		TotalCopies:     copies,
+		Contributors:    toContributors(resolved),
+	}
+	if isbn13 != nil {
+		setISBN(book, *isbn13)
 	}
 	return book, nil
 }
@@ -87,25 +150,150 @@ func (s *BooksServer) GetBook(parentCtx context.Context, req *pb.GetBookRequest)
 		return nil, status.Error(codes.InvalidArgument, "Invalid BookId format")
 	}
 
-	res = &pb.BookResponse{}
-	row := s.db.QueryRow(ctx, "SELECT id, title, author, published_year, is_available FROM books WHERE id=$1", id)
-	err = row.Scan(&res.Id, &res.Title, &res.Author, &res.Year, &res.Available)
+	return s.getBook(ctx, s.db, id)
+}
+
+func (s *BooksServer) getBook(ctx context.Context, db queryer, id int) (*pb.BookResponse, error) {
+	book, err := scanBook(db.QueryRow(ctx, bookQuery+" WHERE b.id = $1 GROUP BY b.id", id))
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, status.Error(codes.NotFound, "book not found")
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("db error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "book")
+	}
+	return book, nil
+}
+
+// queryer is satisfied by both the pool and a transaction.
+type queryer interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// validateBook applies the rules every book must satisfy, however it enters the catalog.
+func validateBook(title string, year int32, contributors []*pb.Contributor) error {
+	if title == "" {
+		return status.Error(codes.InvalidArgument, "title cannot be empty")
+	}
+	if len(title) > 255 {
+		return status.Error(codes.InvalidArgument, "title too long")
+	}
+	if year < 0 || year > int32(time.Now().Year()+10) {
+		return status.Error(codes.InvalidArgument, "invalid year")
+	}
+	return validateContributors(contributors)
+}
+
+const maxBatchSize = 100
+
+// maxCopies bounds the copies created with a book; more are added with AddItem.
+const maxCopies = 100
+
+// bookQuery selects books with their contributors in order, subjects, tags and the number
+// of copies on the shelf and in the collection. Lost and withdrawn copies are not counted.
+const bookQuery = `SELECT b.id, b.title, b.author, b.published_year, COALESCE(b.isbn13, ''),
+	b.withdrawn_at, b.withdrawal_reason,
+	COALESCE((SELECT json_agg(json_build_object('id', a.id, 'name', a.name, 'role', ba.role) ORDER BY ba.position)
+		FROM book_authors ba JOIN authors a ON a.id = ba.author_id WHERE ba.book_id = b.id), '[]'),
+	COALESCE((SELECT json_agg(json_build_object('id', s.id, 'name', s.name, 'parent_id', s.parent_id, 'class_number', s.class_number)
+		ORDER BY lower(s.name)) FROM book_subjects bs JOIN subjects s ON s.id = bs.subject_id WHERE bs.book_id = b.id), '[]'),
+	COALESCE((SELECT array_agg(bt.tag ORDER BY bt.tag) FROM book_tags bt WHERE bt.book_id = b.id), '{}'),
+	COUNT(i.id) FILTER (WHERE i.status = 'available'),
+	COUNT(i.id) FILTER (WHERE i.status NOT IN ('lost', 'withdrawn'))
+FROM books b LEFT JOIN items i ON i.book_id = b.id`
+
+func scanBook(row pgx.Row) (*pb.BookResponse, error) {
+	var id int
+	book := &pb.BookResponse{}
+	var isbn13 string
+	var withdrawnAt *time.Time
+	var contributors []contributor
+	var subjects []subjectRow
+	if err := row.Scan(&id, &book.Title, &book.Author, &book.Year, &isbn13, &withdrawnAt, &book.WithdrawalReason,
+		&contributors, &subjects, &book.Tags, &book.AvailableCopies, &book.TotalCopies); err != nil {
+		return nil, err
+	}
+	book.Contributors = toContributors(contributors)
+	for _, subject := range subjects {
+		book.Subjects = append(book.Subjects, subject.toProto())
+	}
+	book.Id = strconv.Itoa(id)
+	book.Available = book.AvailableCopies > 0
+	setISBN(book, isbn13)
+	if withdrawnAt != nil {
+		book.Withdrawn = true
+		book.WithdrawnAt = withdrawnAt.Format(time.RFC3339)
+	}
+	return book, nil
+}
+
+func (s *BooksServer) GetBooks(parentCtx context.Context, req *pb.GetBooksRequest) (*pb.GetBooksResponse, error) {
+	s.logger.Info("GetBooks called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	if len(req.BookIds) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d book ids per request", maxBatchSize)
+	}
+	ids := make([]int, 0, len(req.BookIds))
+	for _, bookID := range req.BookIds {
+		id, err := strconv.Atoi(bookID)
+		if err != nil || id <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid BookId format: %q", bookID)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return &pb.GetBooksResponse{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	found, err := s.loadBooks(ctx, s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.GetBooksResponse{}
+	for _, id := range ids {
+		if book, ok := found[id]; ok {
+			res.Books = append(res.Books, book)
+			delete(found, id)
 		}
-		s.logger.Errorf("db error: %v", err)
-		return nil, status.Error(codes.Internal, "server error")
 	}
 	return res, nil
 }
 
-func (s *BooksServer) UpdateBookStatus(ctx context.Context, req *pb.UpdateBookRequest) (res *pb.BookResponse, err error) {
-	s.logger.Info("updateBookStatus called")
-	_, err = s.db.Exec(ctx, `UPDATE books SET is_available = NOT is_available WHERE id = $1;`, req.BookId)
+// loadBooks returns the existing books among ids, keyed by id.
+func (s *BooksServer) loadBooks(ctx context.Context, db queryer, ids []int) (map[int]*pb.BookResponse, error) {
+	rows, err := db.Query(ctx, bookQuery+" WHERE b.id = ANY($1) GROUP BY b.id", ids)
 	if err != nil {
 		s.logger.Errorf("db error: %v", err)
-		return nil, status.Error(codes.Internal, "server error")
+		return nil, dberr.ToStatus(err, "book")
+	}
+	defer rows.Close()
+
+	found := make(map[int]*pb.BookResponse, len(ids))
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			s.logger.Errorf("db error: %v", err)
+			return nil, dberr.ToStatus(err, "book")
+		}
+		id, _ := strconv.Atoi(book.Id)
+		found[id] = book
 	}
-	return &pb.BookResponse{}, nil
+	if err := rows.Err(); err != nil {
+		s.logger.Errorf("db error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	return found, nil
+}
+
+// RegisterMetrics exports the number of copies on the shelf, read from the database on every scrape.
+func (s *BooksServer) RegisterMetrics() error {
+	return observability.RegisterQueryGauge(s.db, s.logger, "books_available",
+		"Copies currently available for borrowing.",
+		"SELECT COUNT(*) FROM items WHERE status = 'available'")
 }
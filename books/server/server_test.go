@@ -2,18 +2,29 @@ package bookserver
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 
 	"time"
 
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/books/marc"
+	"github.com/ViktorOHJ/library-system/books/suggest"
+	"github.com/ViktorOHJ/library-system/isbn"
 	pb "github.com/ViktorOHJ/library-system/protos/pb"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func setupTestDB(t *testing.T, logger *logrus.Logger) *pgxpool.Pool {
@@ -31,8 +42,73 @@ func setupTestDB(t *testing.T, logger *logrus.Logger) *pgxpool.Pool {
 			id SERIAL PRIMARY KEY,
 			title VARCHAR(255) NOT NULL,
 			author VARCHAR(100) NOT NULL,
-			published_year INT NOT NULL,
-			is_available BOOLEAN NOT NULL
+			published_year INT NOT NULL
+		);
+		CREATE SEQUENCE IF NOT EXISTS item_barcode_seq;
+		CREATE TABLE IF NOT EXISTS items (
+			id SERIAL PRIMARY KEY,
+			book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+			barcode VARCHAR(32) NOT NULL UNIQUE DEFAULT 'LIB' || lpad(nextval('item_barcode_seq')::text, 8, '0'),
+			condition VARCHAR(16) NOT NULL DEFAULT 'good',
+			location VARCHAR(100) NOT NULL DEFAULT '',
+			status VARCHAR(16) NOT NULL DEFAULT 'available',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		ALTER TABLE books ADD COLUMN IF NOT EXISTS isbn13 CHAR(13);
+		CREATE UNIQUE INDEX IF NOT EXISTS books_isbn13_key ON books (isbn13);
+		CREATE EXTENSION IF NOT EXISTS pg_trgm;
+		ALTER TABLE books ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', author), 'B')
+		) STORED;
+		ALTER TABLE books ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS (title || ' ' || author) STORED;
+		ALTER TABLE books ADD COLUMN IF NOT EXISTS loan_count INT NOT NULL DEFAULT 0;
+		ALTER TABLE books ADD COLUMN IF NOT EXISTS withdrawn_at TIMESTAMP;
+		ALTER TABLE books ADD COLUMN IF NOT EXISTS withdrawal_reason VARCHAR(255) NOT NULL DEFAULT '';
+		CREATE TABLE IF NOT EXISTS book_revisions (
+			id SERIAL PRIMARY KEY,
+			book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+			action VARCHAR(16) NOT NULL,
+			changes JSONB NOT NULL DEFAULT '[]',
+			reason VARCHAR(255) NOT NULL DEFAULT '',
+			actor VARCHAR(64) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS authors (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS authors_name_key ON authors (lower(name));
+		CREATE TABLE IF NOT EXISTS author_variants (
+			id SERIAL PRIMARY KEY,
+			author_id INT NOT NULL REFERENCES authors (id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS author_variants_name_key ON author_variants (lower(name));
+		CREATE TABLE IF NOT EXISTS book_authors (
+			book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+			author_id INT NOT NULL REFERENCES authors (id),
+			role VARCHAR(16) NOT NULL DEFAULT 'author',
+			position INT NOT NULL DEFAULT 0,
+			PRIMARY KEY (book_id, author_id, role)
+		);
+		CREATE TABLE IF NOT EXISTS subjects (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			parent_id INT REFERENCES subjects (id),
+			class_number VARCHAR(32) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS subjects_name_key ON subjects (COALESCE(parent_id, 0), lower(name));
+		CREATE TABLE IF NOT EXISTS book_subjects (
+			book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+			subject_id INT NOT NULL REFERENCES subjects (id),
+			PRIMARY KEY (book_id, subject_id)
+		);
+		CREATE TABLE IF NOT EXISTS book_tags (
+			book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+			tag VARCHAR(50) NOT NULL,
+			PRIMARY KEY (book_id, tag)
 		)
 	`)
 	require.NoError(t, err)
@@ -62,6 +138,944 @@ func TestBooksServer_CreateBook_Success(t *testing.T) {
 	assert.Equal(t, "Test Author", resp.Author)
 	assert.Equal(t, int32(2023), resp.Year)
 	assert.True(t, resp.Available)
+	assert.Equal(t, int32(1), resp.TotalCopies)
+}
+
+func TestBooksServer_Items(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	db := setupTestDB(t, logger)
+	defer db.Close()
+	server := NewBooksServer(db, logger)
+	ctx := context.Background()
+
+	book, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "Copies", Author: "Author", Year: 2020, Copies: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), book.AvailableCopies)
+
+	first, err := server.CheckoutItem(ctx, &pb.CheckoutItemRequest{BookId: book.Id})
+	require.NoError(t, err)
+	assert.Equal(t, ItemOnLoan, first.Status)
+	second, err := server.CheckoutItem(ctx, &pb.CheckoutItemRequest{BookId: book.Id})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Id, second.Id)
+
+	_, err = server.CheckoutItem(ctx, &pb.CheckoutItemRequest{BookId: book.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	got, err := server.GetBook(ctx, &pb.GetBookRequest{BookId: book.Id})
+	require.NoError(t, err)
+	assert.False(t, got.Available)
+	assert.Equal(t, int32(0), got.AvailableCopies)
+	assert.Equal(t, int32(2), got.TotalCopies)
+
+	_, err = server.UpdateItem(ctx, &pb.UpdateItemRequest{ItemId: first.Id, Status: ItemLost})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "a copy on loan is checked in first")
+
+	returned, err := server.CheckinItem(ctx, &pb.CheckinItemRequest{ItemId: first.Id})
+	require.NoError(t, err)
+	assert.Equal(t, ItemAvailable, returned.Status)
+	_, err = server.CheckinItem(ctx, &pb.CheckinItemRequest{ItemId: first.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	added, err := server.AddItem(ctx, &pb.AddItemRequest{BookId: book.Id, Barcode: "TEST-" + book.Id, Location: "A-1"})
+	require.NoError(t, err)
+	_, err = server.AddItem(ctx, &pb.AddItemRequest{BookId: book.Id, Barcode: added.Barcode})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	byBarcode, err := server.GetItem(ctx, &pb.GetItemRequest{Barcode: added.Barcode})
+	require.NoError(t, err)
+	assert.Equal(t, added.Id, byBarcode.Id)
+
+	_, err = server.CheckoutItem(ctx, &pb.CheckoutItemRequest{BookId: "1", ItemId: added.Id})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "copy of another book")
+}
+
+// newISBN returns an ISBN-13 that is unique across test runs.
+func newISBN(t *testing.T) string {
+	body := fmt.Sprintf("978%09d", time.Now().UnixNano()%1e9)
+	for d := 0; d < 10; d++ {
+		if normalized, err := isbn.Normalize(body + strconv.Itoa(d)); err == nil {
+			return normalized
+		}
+	}
+	t.Fatal("no check digit found")
+	return ""
+}
+
+func TestBooksServer_ISBN(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	db := setupTestDB(t, logger)
+	defer db.Close()
+	server := NewBooksServer(db, logger)
+	ctx := context.Background()
+
+	isbn13 := newISBN(t)
+	isbn10, ok := isbn.To10(isbn13)
+	require.True(t, ok)
+
+	book, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "Scanned", Author: "Author", Year: 2020, Isbn: isbn10})
+	require.NoError(t, err)
+	assert.Equal(t, isbn13, book.Isbn13)
+	assert.Equal(t, isbn10, book.Isbn10)
+
+	_, err = server.CreateBook(ctx, &pb.CreateBookRequest{Title: "Duplicate", Author: "Author", Year: 2020, Isbn: isbn13})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	got, err := server.GetBookByISBN(ctx, &pb.GetBookByISBNRequest{Isbn: isbn13[:3] + "-" + isbn13[3:]})
+	require.NoError(t, err)
+	assert.Equal(t, book.Id, got.Id)
+	assert.Equal(t, int32(1), got.AvailableCopies)
+
+	_, err = server.GetBookByISBN(ctx, &pb.GetBookByISBNRequest{Isbn: "979-10-90636-07-1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	res, err := server.LookupBooksByISBN(ctx, &pb.LookupBooksByISBNRequest{Isbns: []string{isbn10, "9791090636071", "123", isbn13}})
+	require.NoError(t, err)
+	require.Len(t, res.Results, 4)
+	assert.Equal(t, book.Id, res.Results[0].Book.GetId())
+	assert.Equal(t, isbn10, res.Results[0].Isbn)
+	assert.Nil(t, res.Results[1].Book)
+	assert.Empty(t, res.Results[1].Error)
+	assert.NotEmpty(t, res.Results[2].Error)
+	assert.Empty(t, res.Results[2].Isbn13)
+	assert.Equal(t, book.Id, res.Results[3].Book.GetId())
+}
+
+func TestBooksServer_ISBN_InvalidInput(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewBooksServer(nil, logger)
+	ctx := context.Background()
+
+	tooMany := make([]string, maxBatchSize+1)
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "CreateBook bad checksum", call: func() error {
+			_, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "T", Author: "A", Year: 2000, Isbn: "9780134190441"})
+			return err
+		}},
+		{name: "GetBookByISBN empty", call: func() error { _, err := server.GetBookByISBN(ctx, &pb.GetBookByISBNRequest{}); return err }},
+		{name: "GetBookByISBN not an isbn", call: func() error {
+			_, err := server.GetBookByISBN(ctx, &pb.GetBookByISBNRequest{Isbn: "4006381333931"})
+			return err
+		}},
+		{name: "LookupBooksByISBN too many", call: func() error {
+			_, err := server.LookupBooksByISBN(ctx, &pb.LookupBooksByISBNRequest{Isbns: tooMany})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, codes.InvalidArgument, status.Code(tt.call()))
+		})
+	}
+
+	res, err := server.LookupBooksByISBN(ctx, &pb.LookupBooksByISBNRequest{Isbns: []string{"", "0134190441"}})
+	require.NoError(t, err, "invalid isbns are reported per result without a query")
+	require.Len(t, res.Results, 2)
+	assert.Equal(t, isbn.ErrLength.Error(), res.Results[0].Error)
+	assert.Equal(t, isbn.ErrChecksum.Error(), res.Results[1].Error)
+}
+
+func TestBooksServer_SearchCatalog(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	db := setupTestDB(t, logger)
+	defer db.Close()
+	server := NewBooksServer(db, logger)
+	ctx := context.Background()
+
+	author := fmt.Sprintf("Zamyatinsky%d", time.Now().UnixNano()%1e6)
+	first, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "We", Author: author, Year: 1924})
+	require.NoError(t, err)
+	second, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "The Cave", Author: author, Year: 1929, Copies: 2})
+	require.NoError(t, err)
+	_, err = server.CheckoutItem(ctx, &pb.CheckoutItemRequest{BookId: first.Id})
+	require.NoError(t, err)
+
+	misspelled := author[:3] + "i" + author[4:]
+	res, err := server.SearchCatalog(ctx, &pb.SearchCatalogRequest{Query: misspelled})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), res.TotalMatches)
+	assert.Contains(t, res.Hits[0].AuthorHighlight, "<mark>"+author+"</mark>")
+	assert.Equal(t, []*pb.FacetCount{{Value: author, Count: 2}}, res.Facets.Authors)
+
+	res, err = server.SearchCatalog(ctx, &pb.SearchCatalogRequest{Query: author, AvailableOnly: true, PageSize: 1})
+	require.NoError(t, err)
+	require.Len(t, res.Hits, 1)
+	assert.Equal(t, second.Id, res.Hits[0].Book.Id)
+	assert.Empty(t, res.NextPageToken)
+	assert.ElementsMatch(t, []*pb.FacetCount{{Value: "available", Count: 1}, {Value: "unavailable", Count: 1}}, res.Facets.Availability)
+
+	// Each facet is counted with the other filters but not its own.
+	res, err = server.SearchCatalog(ctx, &pb.SearchCatalogRequest{Query: author, Decade: 1920, Author: author, AvailableOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), res.TotalMatches)
+	assert.Equal(t, []*pb.FacetCount{{Value: author, Count: 1}}, res.Facets.Authors)
+	assert.Equal(t, []*pb.FacetCount{{Value: "1920", Count: 1}}, res.Facets.Decades)
+	assert.ElementsMatch(t, []*pb.FacetCount{{Value: "available", Count: 1}, {Value: "unavailable", Count: 1}}, res.Facets.Availability)
+
+	res, err = server.SearchCatalog(ctx, &pb.SearchCatalogRequest{Query: author, Decade: 1910})
+	require.NoError(t, err)
+	assert.Zero(t, res.TotalMatches)
+	assert.Empty(t, res.Hits)
+	assert.Equal(t, []*pb.FacetCount{{Value: "1920", Count: 2}}, res.Facets.Decades)
+}
+
+func TestBooksServer_SearchCatalog_InvalidInput(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewBooksServer(nil, logger)
+
+	tests := []struct {
+		name string
+		req  *pb.SearchCatalogRequest
+	}{
+		{name: "Nil Request", req: nil},
+		{name: "Empty Query", req: &pb.SearchCatalogRequest{Query: "  "}},
+		{name: "Long Query", req: &pb.SearchCatalogRequest{Query: string(make([]byte, maxQueryLength+1))}},
+		{name: "Bad Decade", req: &pb.SearchCatalogRequest{Query: "go", Decade: 1995}},
+		{name: "Negative Page Size", req: &pb.SearchCatalogRequest{Query: "go", PageSize: -1}},
+		{name: "Bad Page Token", req: &pb.SearchCatalogRequest{Query: "go", PageToken: "!!!"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.SearchCatalog(context.Background(), tt.req)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  string
+	}{
+		{name: "exact", text: "War and Peace", query: "peace", want: "War and <mark>Peace</mark>"},
+		{name: "typo", text: "Leo Tolstoy", query: "tolstoj", want: "Leo <mark>Tolstoy</mark>"},
+		{name: "prefix", text: "Programming in Go", query: "program", want: "<mark>Programming</mark> in Go"},
+		{name: "short words exact only", text: "Go or Rust", query: "to", want: "Go or Rust"},
+		{name: "cyrillic", text: "Война и мир", query: "вайна", want: "<mark>Война</mark> и мир"},
+		{name: "excluded word", text: "War and Peace", query: "war -peace", want: "<mark>War</mark> and Peace"},
+		{name: "escaped", text: "<b>Go</b> & C", query: "go", want: "&lt;b&gt;<mark>Go</mark>&lt;/b&gt; &amp; C"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, highlight(tt.text, queryTerms(tt.query)))
+		})
+	}
+}
+
+func TestFacetCounts(t *testing.T) {
+	counts := map[string]int32{"Tolstoy": 2, "Dostoevsky": 1, "Chekhov": 1, "Gogol": 3}
+	assert.Equal(t, []*pb.FacetCount{
+		{Value: "Gogol", Count: 3},
+		{Value: "Tolstoy", Count: 2},
+		{Value: "Chekhov", Count: 1},
+	}, facetCounts(counts, 3), "by count, then by value")
+	assert.Len(t, facetCounts(counts, 0), 4)
+	assert.Empty(t, facetCounts(map[string]int32{}, 0))
+}
+
+func TestSearchToken_RoundTrip(t *testing.T) {
+	offset, err := decodeSearchToken(encodeSearchToken(40))
+	require.NoError(t, err)
+	assert.Equal(t, 40, offset)
+
+	offset, err = decodeSearchToken("")
+	require.NoError(t, err)
+	assert.Zero(t, offset)
+
+	_, err = decodeSearchToken(encodeSearchToken(0))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestBooksServer_Suggest(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewBooksServer(nil, logger)
+	ctx := context.Background()
+
+	_, err := server.Suggest(ctx, &pb.SuggestRequest{Prefix: "war"})
+	assert.Equal(t, codes.Unavailable, status.Code(err), "until the index is loaded")
+
+	server.suggestions.Replace([]suggest.Book{
+		{ID: 1, Title: "War and Peace", Author: "Leo Tolstoy", Loans: 2},
+		{ID: 2, Title: "Warlock", Author: "Oakley Hall"},
+	})
+	res, err := server.Suggest(ctx, &pb.SuggestRequest{Prefix: "war", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []*pb.Suggestion{{Text: "War and Peace", Kind: "title", BookId: "1", Loans: 2}}, res.Suggestions)
+
+	res, err = server.Suggest(ctx, &pb.SuggestRequest{Prefix: "leo"})
+	require.NoError(t, err)
+	assert.Equal(t, []*pb.Suggestion{{Text: "Leo Tolstoy", Kind: "author", Loans: 2}}, res.Suggestions)
+
+	for _, req := range []*pb.SuggestRequest{nil, {Prefix: " "}, {Prefix: "war", Limit: -1}, {Prefix: string(make([]byte, maxPrefixLength+1))}} {
+		_, err := server.Suggest(ctx, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+}
+
+func TestBooksServer_UpdateAndWithdraw(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	db := setupTestDB(t, logger)
+	defer db.Close()
+	server := NewBooksServer(db, logger)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "7", Role: auth.RoleLibrarian})
+
+	book, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "Draft", Author: "Author", Year: 2020})
+	require.NoError(t, err)
+
+	updated, err := server.UpdateBook(ctx, &pb.UpdateBookRequest{
+		BookId:     book.Id,
+		Title:      "Final",
+		Year:       1999,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Final", updated.Title)
+	assert.Equal(t, int32(2020), updated.Year, "fields outside the mask are kept")
+
+	_, err = server.UpdateBook(ctx, &pb.UpdateBookRequest{BookId: book.Id, Title: "Final"})
+	require.NoError(t, err, "an update without changes is not recorded")
+
+	item, err := server.CheckoutItem(ctx, &pb.CheckoutItemRequest{BookId: book.Id})
+	require.NoError(t, err)
+	_, err = server.WithdrawBook(ctx, &pb.WithdrawBookRequest{BookId: book.Id, Reason: "lost"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "a copy is on loan")
+	_, err = server.CheckinItem(ctx, &pb.CheckinItemRequest{ItemId: item.Id})
+	require.NoError(t, err)
+
+	withdrawn, err := server.WithdrawBook(ctx, &pb.WithdrawBookRequest{BookId: book.Id, Reason: "lost"})
+	require.NoError(t, err)
+	assert.True(t, withdrawn.Withdrawn)
+	assert.Equal(t, "lost", withdrawn.WithdrawalReason)
+	assert.NotEmpty(t, withdrawn.WithdrawnAt)
+	assert.Equal(t, int32(0), withdrawn.TotalCopies)
+
+	_, err = server.WithdrawBook(ctx, &pb.WithdrawBookRequest{BookId: book.Id, Reason: "again"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = server.UpdateBook(ctx, &pb.UpdateBookRequest{BookId: book.Id, Title: "After"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = server.AddItem(ctx, &pb.AddItemRequest{BookId: book.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Copies of a withdrawn book can be written off but not put back into circulation.
+	_, err = server.UpdateItem(ctx, &pb.UpdateItemRequest{ItemId: item.Id, Status: ItemAvailable})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	lost, err := server.UpdateItem(ctx, &pb.UpdateItemRequest{ItemId: item.Id, Status: ItemLost, Location: "B-2"})
+	require.NoError(t, err)
+	assert.Equal(t, ItemLost, lost.Status)
+	itemID, err := strconv.Atoi(item.Id)
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, "UPDATE items SET status = 'available' WHERE id = $1", itemID)
+	require.NoError(t, err)
+	_, err = server.CheckoutItem(ctx, &pb.CheckoutItemRequest{ItemId: item.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = server.CheckoutItem(ctx, &pb.CheckoutItemRequest{BookId: book.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	history, err := server.GetBookHistory(ctx, &pb.GetBookHistoryRequest{BookId: book.Id})
+	require.NoError(t, err)
+	require.Len(t, history.Revisions, 3)
+	assert.Equal(t, revisionCreate, history.Revisions[0].Action)
+	assert.Equal(t, revisionUpdate, history.Revisions[1].Action)
+	assert.Equal(t, []*pb.FieldChange{{Field: "title", OldValue: "Draft", NewValue: "Final"}}, history.Revisions[1].Changes)
+	assert.Equal(t, "7", history.Revisions[1].Actor)
+	assert.Equal(t, revisionWithdraw, history.Revisions[2].Action)
+	assert.Equal(t, "lost", history.Revisions[2].Reason)
+}
+
+func TestBooksServer_UpdateAndWithdraw_InvalidInput(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewBooksServer(nil, logger)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "UpdateBook nil", call: func() error { _, err := server.UpdateBook(ctx, nil); return err }},
+		{name: "UpdateBook bad id", call: func() error {
+			_, err := server.UpdateBook(ctx, &pb.UpdateBookRequest{BookId: "x", Title: "T"})
+			return err
+		}},
+		{name: "UpdateBook nothing", call: func() error { _, err := server.UpdateBook(ctx, &pb.UpdateBookRequest{BookId: "1"}); return err }},
+		{name: "UpdateBook unknown path", call: func() error {
+			_, err := server.UpdateBook(ctx, &pb.UpdateBookRequest{BookId: "1", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"id"}}})
+			return err
+		}},
+		{name: "UpdateBook bad isbn", call: func() error {
+			_, err := server.UpdateBook(ctx, &pb.UpdateBookRequest{BookId: "1", Isbn: "123"})
+			return err
+		}},
+		{name: "WithdrawBook no reason", call: func() error {
+			_, err := server.WithdrawBook(ctx, &pb.WithdrawBookRequest{BookId: "1", Reason: " "})
+			return err
+		}},
+		{name: "WithdrawBook long reason", call: func() error {
+			_, err := server.WithdrawBook(ctx, &pb.WithdrawBookRequest{BookId: "1", Reason: strings.Repeat("a", maxReasonLength+1)})
+			return err
+		}},
+		{name: "GetBookHistory bad id", call: func() error {
+			_, err := server.GetBookHistory(ctx, &pb.GetBookHistoryRequest{BookId: "0"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, codes.InvalidArgument, status.Code(tt.call()))
+		})
+	}
+}
+
+func TestBooksServer_Authors(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	db := setupTestDB(t, logger)
+	defer db.Close()
+	server := NewBooksServer(db, logger)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "7", Role: auth.RoleLibrarian})
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	tolstoy, err := server.CreateAuthor(ctx, &pb.CreateAuthorRequest{Name: "Leo Tolstoy " + suffix, Variants: []string{"Толстой " + suffix}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Толстой " + suffix}, tolstoy.Variants)
+	_, err = server.CreateAuthor(ctx, &pb.CreateAuthorRequest{Name: "толстой " + suffix})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err), "a variant cannot be another heading")
+
+	book, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "War and Peace", Year: 1869, Contributors: []*pb.Contributor{
+		{Name: "толстой " + suffix},
+		{Name: "Louise Maude " + suffix, Role: RoleTranslator},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, tolstoy.Name, book.Author, "the variant resolves to the heading")
+	require.Len(t, book.Contributors, 2)
+	assert.Equal(t, tolstoy.Id, book.Contributors[0].AuthorId)
+	assert.Equal(t, RoleTranslator, book.Contributors[1].Role)
+
+	duplicate, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "Anna Karenina", Author: "L. Tolstoy " + suffix, Year: 1878})
+	require.NoError(t, err)
+	updated, err := server.UpdateBook(ctx, &pb.UpdateBookRequest{BookId: book.Id, Author: "L. Tolstoy " + suffix})
+	require.NoError(t, err)
+	assert.Equal(t, "L. Tolstoy "+suffix, updated.Author)
+	require.Len(t, updated.Contributors, 2, "the translator is kept")
+	sourceID := updated.Contributors[0].AuthorId
+
+	merged, err := server.MergeAuthors(ctx, &pb.MergeAuthorsRequest{AuthorId: tolstoy.Id, SourceIds: []string{sourceID}})
+	require.NoError(t, err)
+	assert.Contains(t, merged.Variants, "L. Tolstoy "+suffix)
+	assert.Equal(t, int32(2), merged.BookCount)
+	_, err = server.GetAuthor(ctx, &pb.GetAuthorRequest{AuthorId: sourceID})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	got, err := server.GetBook(ctx, &pb.GetBookRequest{BookId: duplicate.Id})
+	require.NoError(t, err)
+	assert.Equal(t, tolstoy.Name, got.Author, "the display name follows the merge")
+	history, err := server.GetBookHistory(ctx, &pb.GetBookHistoryRequest{BookId: duplicate.Id})
+	require.NoError(t, err)
+	assert.Equal(t, "7", history.Revisions[len(history.Revisions)-1].Actor)
+
+	found, err := server.SearchAuthors(ctx, &pb.SearchAuthorsRequest{Query: "tolstoy " + suffix})
+	require.NoError(t, err)
+	require.NotEmpty(t, found.Authors)
+	assert.Equal(t, tolstoy.Id, found.Authors[0].Id)
+}
+
+func TestBooksServer_Authors_InvalidInput(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewBooksServer(nil, logger)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "CreateAuthor empty name", call: func() error {
+			_, err := server.CreateAuthor(ctx, &pb.CreateAuthorRequest{Name: " "})
+			return err
+		}},
+		{name: "CreateAuthor long variant", call: func() error {
+			_, err := server.CreateAuthor(ctx, &pb.CreateAuthorRequest{Name: "A", Variants: []string{strings.Repeat("a", maxAuthorName+1)}})
+			return err
+		}},
+		{name: "GetAuthor bad id", call: func() error { _, err := server.GetAuthor(ctx, &pb.GetAuthorRequest{AuthorId: "x"}); return err }},
+		{name: "SearchAuthors empty query", call: func() error { _, err := server.SearchAuthors(ctx, &pb.SearchAuthorsRequest{}); return err }},
+		{name: "MergeAuthors no sources", call: func() error {
+			_, err := server.MergeAuthors(ctx, &pb.MergeAuthorsRequest{AuthorId: "1"})
+			return err
+		}},
+		{name: "MergeAuthors into itself", call: func() error {
+			_, err := server.MergeAuthors(ctx, &pb.MergeAuthorsRequest{AuthorId: "1", SourceIds: []string{"2", "1"}})
+			return err
+		}},
+		{name: "CreateBook author and contributors", call: func() error {
+			_, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "T", Author: "A", Year: 2020, Contributors: []*pb.Contributor{{Name: "B"}}})
+			return err
+		}},
+		{name: "CreateBook unknown role", call: func() error {
+			_, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "T", Year: 2020, Contributors: []*pb.Contributor{{Name: "B", Role: "illustrator"}}})
+			return err
+		}},
+		{name: "CreateBook contributor without name", call: func() error {
+			_, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "T", Year: 2020, Contributors: []*pb.Contributor{{Role: RoleEditor}}})
+			return err
+		}},
+		{name: "UpdateBook author and contributors", call: func() error {
+			_, err := server.UpdateBook(ctx, &pb.UpdateBookRequest{BookId: "1", Author: "A", Contributors: []*pb.Contributor{{Name: "B"}}})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, codes.InvalidArgument, status.Code(tt.call()))
+		})
+	}
+}
+
+func TestBooksServer_Subjects(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	db := setupTestDB(t, logger)
+	defer db.Close()
+	server := NewBooksServer(db, logger)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "7", Role: auth.RoleLibrarian})
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	fiction, err := server.CreateSubject(ctx, &pb.CreateSubjectRequest{Name: "Fiction " + suffix})
+	require.NoError(t, err)
+	sf, err := server.CreateSubject(ctx, &pb.CreateSubjectRequest{Name: "Science fiction", ParentId: fiction.Id, ClassNumber: "821.161.1-312.9"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{fiction.Name, "Science fiction"}, sf.Path)
+	_, err = server.CreateSubject(ctx, &pb.CreateSubjectRequest{Name: "science FICTION", ParentId: fiction.Id})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	book, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "Solaris " + suffix, Author: "Stanisław Lem", Year: 1961})
+	require.NoError(t, err)
+	book, err = server.SetBookSubjects(ctx, &pb.SetBookSubjectsRequest{BookId: book.Id, SubjectIds: []string{sf.Id, sf.Id}})
+	require.NoError(t, err)
+	require.Len(t, book.Subjects, 1)
+	assert.Equal(t, fiction.Id, book.Subjects[0].ParentId)
+	book, err = server.SetBookTags(ctx, &pb.SetBookTagsRequest{BookId: book.Id, Tags: []string{" Space ", "classic", "space"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"classic", "space"}, book.Tags)
+	_, err = server.SetBookSubjects(ctx, &pb.SetBookSubjectsRequest{BookId: book.Id, SubjectIds: []string{"999999999"}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	children, err := server.ListSubjects(ctx, &pb.ListSubjectsRequest{ParentId: fiction.Id})
+	require.NoError(t, err)
+	require.Len(t, children.Subjects, 1)
+	assert.Equal(t, int32(1), children.Subjects[0].BookCount)
+	assert.Equal(t, int32(1), children.Parent.BookCount, "books of subsubjects are counted")
+
+	shelf, err := server.ListBooksBySubject(ctx, &pb.ListBooksBySubjectRequest{SubjectId: fiction.Id, Tag: "Space"})
+	require.NoError(t, err)
+	require.Len(t, shelf.Books, 1)
+	assert.Equal(t, book.Id, shelf.Books[0].Id)
+	exact, err := server.ListBooksBySubject(ctx, &pb.ListBooksBySubjectRequest{SubjectId: fiction.Id, Exact: true})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), exact.TotalCount)
+
+	found, err := server.SearchCatalog(ctx, &pb.SearchCatalogRequest{Query: "solaris " + suffix, SubjectId: fiction.Id})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), found.TotalMatches)
+
+	history, err := server.GetBookHistory(ctx, &pb.GetBookHistoryRequest{BookId: book.Id})
+	require.NoError(t, err)
+	require.Len(t, history.Revisions, 3)
+	assert.Equal(t, []*pb.FieldChange{{Field: "tags", OldValue: "", NewValue: "classic, space"}}, history.Revisions[2].Changes)
+}
+
+func TestBooksServer_Subjects_InvalidInput(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewBooksServer(nil, logger)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "CreateSubject empty name", call: func() error {
+			_, err := server.CreateSubject(ctx, &pb.CreateSubjectRequest{Name: " "})
+			return err
+		}},
+		{name: "CreateSubject bad class number", call: func() error {
+			_, err := server.CreateSubject(ctx, &pb.CreateSubjectRequest{Name: "Poetry", ClassNumber: "poetry"})
+			return err
+		}},
+		{name: "CreateSubject bad parent", call: func() error {
+			_, err := server.CreateSubject(ctx, &pb.CreateSubjectRequest{Name: "Poetry", ParentId: "x"})
+			return err
+		}},
+		{name: "ListSubjects bad parent", call: func() error {
+			_, err := server.ListSubjects(ctx, &pb.ListSubjectsRequest{ParentId: "-1"})
+			return err
+		}},
+		{name: "ListBooksBySubject no subject", call: func() error {
+			_, err := server.ListBooksBySubject(ctx, &pb.ListBooksBySubjectRequest{})
+			return err
+		}},
+		{name: "ListBooksBySubject bad token", call: func() error {
+			_, err := server.ListBooksBySubject(ctx, &pb.ListBooksBySubjectRequest{SubjectId: "1", PageToken: "x"})
+			return err
+		}},
+		{name: "SetBookSubjects bad subject", call: func() error {
+			_, err := server.SetBookSubjects(ctx, &pb.SetBookSubjectsRequest{BookId: "1", SubjectIds: []string{"x"}})
+			return err
+		}},
+		{name: "SetBookTags empty tag", call: func() error {
+			_, err := server.SetBookTags(ctx, &pb.SetBookTagsRequest{BookId: "1", Tags: []string{"  "}})
+			return err
+		}},
+		{name: "SetBookTags too many", call: func() error {
+			_, err := server.SetBookTags(ctx, &pb.SetBookTagsRequest{BookId: "1", Tags: make([]string, maxBookTags+1)})
+			return err
+		}},
+		{name: "ListTags negative limit", call: func() error { _, err := server.ListTags(ctx, &pb.ListTagsRequest{Limit: -1}); return err }},
+		{name: "SearchCatalog bad subject", call: func() error {
+			_, err := server.SearchCatalog(ctx, &pb.SearchCatalogRequest{Query: "q", SubjectId: "x"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, codes.InvalidArgument, status.Code(tt.call()))
+		})
+	}
+}
+
+// importStream feeds requests to ImportBooks and keeps its response.
+type importStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	requests []*pb.ImportBooksRequest
+	res      *pb.ImportBooksResponse
+}
+
+func (s *importStream) Context() context.Context { return s.ctx }
+
+func (s *importStream) Recv() (*pb.ImportBooksRequest, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+	return req, nil
+}
+
+func (s *importStream) SendAndClose(res *pb.ImportBooksResponse) error {
+	s.res = res
+	return nil
+}
+
+func TestBooksServer_ImportBooks(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	db := setupTestDB(t, logger)
+	defer db.Close()
+	server := NewBooksServer(db, logger)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "7", Role: auth.RoleLibrarian})
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	first, second := newISBN(t), newISBN(t)
+
+	run := func(dryRun bool, rows ...*pb.ImportRow) *pb.ImportBooksResponse {
+		stream := &importStream{ctx: ctx, requests: []*pb.ImportBooksRequest{{DryRun: dryRun, Rows: rows[:1]}, {Rows: rows[1:]}}}
+		require.NoError(t, server.ImportBooks(stream))
+		return stream.res
+	}
+
+	res := run(true, &pb.ImportRow{Line: 2, Title: "Roadside Picnic " + suffix, Author: "Arkady Strugatsky", Year: 1972, Isbn: first})
+	assert.Equal(t, int32(1), res.Created)
+	assert.Empty(t, res.Results[0].BookId, "a dry run saves nothing")
+	_, err := server.GetBookByISBN(ctx, &pb.GetBookByISBNRequest{Isbn: first})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	res = run(false,
+		&pb.ImportRow{Line: 2, Title: "Roadside Picnic " + suffix, Author: "Arkady Strugatsky", Year: 1972, Isbn: first, Copies: 2},
+		&pb.ImportRow{Line: 3, Title: "Hard to Be a God " + suffix, Author: "Arkady Strugatsky", Year: 1964, Isbn: second},
+		&pb.ImportRow{Line: 4, Title: "Duplicate", Author: "Someone", Year: 1964, Isbn: second},
+	)
+	assert.Equal(t, []int32{2, 0, 0, 1}, []int32{res.Created, res.Updated, res.Unchanged, res.Rejected})
+	assert.Equal(t, "duplicate isbn, first seen on line 3", res.Results[2].Reason)
+	book, err := server.GetBookByISBN(ctx, &pb.GetBookByISBNRequest{Isbn: first})
+	require.NoError(t, err)
+	assert.Equal(t, res.Results[0].BookId, book.Id)
+	assert.Equal(t, int32(2), book.TotalCopies)
+	require.Len(t, book.Contributors, 1)
+	assert.Equal(t, "Arkady Strugatsky", book.Contributors[0].Name)
+
+	res = run(false,
+		&pb.ImportRow{Line: 2, Title: "Roadside Picnic " + suffix, Author: "Arkady Strugatsky", Year: 1972, Isbn: first},
+		&pb.ImportRow{Line: 3, Title: "Hard to Be a God " + suffix, Author: "Boris Strugatsky", Year: 1964, Isbn: second},
+	)
+	assert.Equal(t, []string{importUnchanged, importUpdated}, []string{res.Results[0].Status, res.Results[1].Status})
+	history, err := server.GetBookHistory(ctx, &pb.GetBookHistoryRequest{BookId: res.Results[1].BookId})
+	require.NoError(t, err)
+	require.Len(t, history.Revisions, 2)
+	assert.Equal(t, "import", history.Revisions[1].Reason)
+	assert.Equal(t, "7", history.Revisions[1].Actor)
+	assert.Equal(t, "author", history.Revisions[1].Changes[0].Field)
+
+	_, err = server.WithdrawBook(ctx, &pb.WithdrawBookRequest{BookId: book.Id, Reason: "lost"})
+	require.NoError(t, err)
+	res = run(false, &pb.ImportRow{Title: "Roadside Picnic", Author: "Arkady Strugatsky", Year: 1972, Isbn: first})
+	assert.Equal(t, importRejected, res.Results[0].Status)
+	assert.Equal(t, "book is withdrawn", res.Results[0].Reason)
+}
+
+func TestBooksServer_ImportBooks_Rejected(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewBooksServer(nil, logger)
+
+	rows := []*pb.ImportRow{
+		{Title: "No isbn", Author: "A", Year: 2000},
+		{Title: "Bad isbn", Author: "A", Year: 2000, Isbn: "978-0-306-40615-8"},
+		{Title: "", Author: "A", Year: 2000, Isbn: "9780306406157"},
+		{Title: "No author", Author: "  ", Year: 2000, Isbn: "9780306406157"},
+		{Title: "Future", Author: "A", Year: 9999, Isbn: "9780306406157"},
+		{Title: "Copies", Author: "A", Year: 2000, Isbn: "9780306406157", Copies: maxCopies + 1},
+		{Line: 42, Error: "expected 5 fields, got 2"},
+	}
+	stream := &importStream{ctx: context.Background(), requests: []*pb.ImportBooksRequest{{DryRun: true, Rows: rows[:3]}, {Rows: rows[3:]}}}
+	require.NoError(t, server.ImportBooks(stream))
+
+	res := stream.res
+	assert.True(t, res.DryRun)
+	assert.Equal(t, int32(len(rows)), res.Rejected)
+	var lines []int32
+	var reasons []string
+	for _, r := range res.Results {
+		assert.Equal(t, importRejected, r.Status)
+		lines = append(lines, r.Line)
+		reasons = append(reasons, r.Reason)
+	}
+	assert.Equal(t, []int32{1, 2, 3, 4, 5, 6, 42}, lines)
+	assert.Equal(t, "isbn cannot be empty", reasons[0])
+	assert.True(t, strings.HasPrefix(reasons[1], "invalid isbn"))
+	assert.Equal(t, []string{"title cannot be empty", "author cannot be empty", "invalid year"}, reasons[2:5])
+	assert.Equal(t, fmt.Sprintf("copies must be between 0 and %d", maxCopies), reasons[5])
+	assert.Equal(t, "expected 5 fields, got 2", reasons[6])
+	assert.Equal(t, "9780306406157", res.Results[2].Isbn13)
+}
+
+func marcFile(t *testing.T, format marc.Format, books ...marc.Book) []byte {
+	t.Helper()
+	var buf strings.Builder
+	w, err := marc.NewWriter(&buf, format)
+	require.NoError(t, err)
+	for _, b := range books {
+		require.NoError(t, w.Write(marc.FromBook(b)))
+	}
+	require.NoError(t, w.Close())
+	return []byte(buf.String())
+}
+
+func TestBooksServer_Marc(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	db := setupTestDB(t, logger)
+	defer db.Close()
+	server := NewBooksServer(db, logger)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "7", Role: auth.RoleLibrarian})
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	first, second := newISBN(t), newISBN(t)
+
+	solaris := marc.Book{
+		Title: "Solaris " + suffix,
+		Contributors: []marc.Contributor{
+			{Name: "Stanisław Lem", Role: RoleAuthor},
+			{Name: "Joanna Kilmartin " + suffix, Role: RoleTranslator},
+		},
+		Year:     1961,
+		ISBN:     first,
+		Subjects: []string{"Science fiction " + suffix},
+	}
+	eden := marc.Book{Title: "Eden " + suffix, Contributors: []marc.Contributor{{Name: "Stanisław Lem", Role: RoleAuthor}}, Year: 1959, ISBN: second}
+
+	res, err := server.ImportMarc(ctx, &pb.ImportMarcRequest{Data: marcFile(t, marc.MARCXML, solaris), DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), res.Created)
+	assert.Empty(t, res.Results[0].BookId, "a dry run saves nothing")
+	_, err = server.GetBookByISBN(ctx, &pb.GetBookByISBNRequest{Isbn: first})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	res, err = server.ImportMarc(ctx, &pb.ImportMarcRequest{Data: marcFile(t, marc.ISO2709, solaris, eden, eden)})
+	require.NoError(t, err)
+	assert.Equal(t, []int32{2, 0, 0, 1}, []int32{res.Created, res.Updated, res.Unchanged, res.Rejected})
+	assert.Equal(t, "duplicate isbn, first seen in record 2", res.Results[2].Reason)
+	book, err := server.GetBookByISBN(ctx, &pb.GetBookByISBNRequest{Isbn: first})
+	require.NoError(t, err)
+	assert.Equal(t, res.Results[0].BookId, book.Id)
+	assert.Equal(t, "Stanisław Lem", book.Author)
+	require.Len(t, book.Contributors, 2)
+	assert.Equal(t, RoleTranslator, book.Contributors[1].Role)
+	require.Len(t, book.Subjects, 1)
+	assert.Equal(t, solaris.Subjects[0], book.Subjects[0].Name)
+
+	// Exported records read back as the books that were imported.
+	export, err := server.ExportMarc(ctx, &pb.ExportMarcRequest{BookIds: []string{book.Id, res.Results[1].BookId}})
+	require.NoError(t, err)
+	assert.Equal(t, "iso2709", export.Format)
+	assert.Equal(t, "application/marc", export.ContentType)
+	r, err := marc.NewReader(strings.NewReader(string(export.Data)), marc.ISO2709)
+	require.NoError(t, err)
+	for i, want := range []marc.Book{solaris, eden} {
+		rec, err := r.Read()
+		require.NoError(t, err)
+		got, err := marc.ToBook(rec)
+		require.NoError(t, err)
+		want.ID = res.Results[i].BookId
+		assert.Equal(t, want, got)
+	}
+
+	solaris.Year, eden.Subjects = 1962, nil
+	res, err = server.ImportMarc(ctx, &pb.ImportMarcRequest{Data: marcFile(t, marc.MARCXML, solaris, eden), Format: "marcxml"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{importUpdated, importUnchanged}, []string{res.Results[0].Status, res.Results[1].Status})
+	history, err := server.GetBookHistory(ctx, &pb.GetBookHistoryRequest{BookId: book.Id})
+	require.NoError(t, err)
+	require.Len(t, history.Revisions, 2)
+	assert.Equal(t, "marc import", history.Revisions[1].Reason)
+	assert.Equal(t, []*pb.FieldChange{{Field: "year", OldValue: "1961", NewValue: "1962"}}, history.Revisions[1].Changes)
+
+	_, err = server.ExportMarc(ctx, &pb.ExportMarcRequest{BookIds: []string{book.Id, "999999999"}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = server.WithdrawBook(ctx, &pb.WithdrawBookRequest{BookId: book.Id, Reason: "lost"})
+	require.NoError(t, err)
+	export, err = server.ExportMarc(ctx, &pb.ExportMarcRequest{BookIds: []string{book.Id}, Format: "marcxml"})
+	require.NoError(t, err)
+	assert.Contains(t, string(export.Data), "<leader>00000dam a2200000 i 4500</leader>")
+	res, err = server.ImportMarc(ctx, &pb.ImportMarcRequest{Data: marcFile(t, marc.ISO2709, solaris)})
+	require.NoError(t, err)
+	assert.Equal(t, importRejected, res.Results[0].Status)
+	assert.Equal(t, "book is withdrawn", res.Results[0].Reason)
+}
+
+func TestBooksServer_Marc_InvalidInput(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewBooksServer(nil, logger)
+	ctx := context.Background()
+
+	author := []marc.Contributor{{Name: "A", Role: RoleAuthor}}
+	subjects := make([]string, maxBookSubjects+1)
+	for i := range subjects {
+		subjects[i] = fmt.Sprintf("Subject %d", i)
+	}
+	data := marcFile(t, marc.ISO2709,
+		marc.Book{Title: "No isbn", Contributors: author, Year: 2000},
+		marc.Book{Title: "Deleted", Contributors: author, Year: 2000, ISBN: "9780306406157", Deleted: true},
+		marc.Book{Title: "No author", Year: 2000, ISBN: "9780306406157"},
+		marc.Book{Title: "Bad isbn", Contributors: author, ISBN: "978-0-306-40615-8"},
+		marc.Book{Title: "Subjects", Contributors: author, ISBN: "0306406152", Subjects: subjects},
+	)
+	marc8 := marcFile(t, marc.ISO2709, marc.Book{Title: "MARC-8", Contributors: author, ISBN: "9780306406157"})
+	marc8[9] = ' '
+	res, err := server.ImportMarc(ctx, &pb.ImportMarcRequest{Data: append(data, marc8...)})
+	require.NoError(t, err)
+	assert.Equal(t, int32(6), res.Rejected)
+	var reasons []string
+	for i, r := range res.Results {
+		assert.Equal(t, int32(i+1), r.Line)
+		reasons = append(reasons, r.Reason)
+	}
+	assert.Equal(t, []string{"isbn cannot be empty", "record is marked as deleted", "author cannot be empty"}, reasons[:3])
+	assert.True(t, strings.HasPrefix(reasons[3], "invalid isbn"))
+	assert.Equal(t, fmt.Sprintf("at most %d subjects per book", maxBookSubjects), reasons[4])
+	assert.Contains(t, reasons[5], "MARC-8")
+	assert.Equal(t, "9780306406157", res.Results[4].Isbn13)
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "nil import", call: func() error { _, err := server.ImportMarc(ctx, nil); return err }},
+		{name: "unknown format", call: func() error {
+			_, err := server.ImportMarc(ctx, &pb.ImportMarcRequest{Data: data, Format: "unimarc"})
+			return err
+		}},
+		{name: "broken file", call: func() error {
+			_, err := server.ImportMarc(ctx, &pb.ImportMarcRequest{Data: []byte("not a marc file")})
+			return err
+		}},
+		{name: "nil export", call: func() error { _, err := server.ExportMarc(ctx, nil); return err }},
+		{name: "no ids", call: func() error { _, err := server.ExportMarc(ctx, &pb.ExportMarcRequest{}); return err }},
+		{name: "bad id", call: func() error {
+			_, err := server.ExportMarc(ctx, &pb.ExportMarcRequest{BookIds: []string{"abc"}})
+			return err
+		}},
+		{name: "too many ids", call: func() error {
+			_, err := server.ExportMarc(ctx, &pb.ExportMarcRequest{BookIds: make([]string, maxBatchSize+1)})
+			return err
+		}},
+		{name: "export format", call: func() error {
+			_, err := server.ExportMarc(ctx, &pb.ExportMarcRequest{BookIds: []string{"1"}, Format: "json"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, codes.InvalidArgument, status.Code(tt.call()))
+		})
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	tag, err := normalizeTag("  Hard   SF ")
+	require.NoError(t, err)
+	assert.Equal(t, "hard sf", tag)
+	_, err = normalizeTag(strings.Repeat("я", maxTagLength+1))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.True(t, classNumberPattern.MatchString("94(470)"))
+	assert.True(t, classNumberPattern.MatchString("813.54"))
+}
+
+func TestDisplayAuthors(t *testing.T) {
+	long := strings.Repeat("x", 60)
+	tests := []struct {
+		name string
+		list []contributor
+		want string
+	}{
+		{name: "empty", want: ""},
+		{name: "authors only", list: []contributor{
+			{Name: "Ilf", Role: RoleAuthor}, {Name: "Someone", Role: RoleTranslator}, {Name: "Petrov", Role: RoleAuthor},
+		}, want: "Ilf, Petrov"},
+		{name: "anthology", list: []contributor{{Name: "Editor", Role: RoleEditor}}, want: "Editor"},
+		{name: "too long", list: []contributor{{Name: long, Role: RoleAuthor}, {Name: long, Role: RoleAuthor}}, want: long + " et al."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, displayAuthors(tt.list))
+		})
+	}
+}
+
+func TestBookMetadata_Diff(t *testing.T) {
+	old := bookMetadata{title: "Draft", author: "Author", year: 2020}
+	assert.Empty(t, old.diff(old))
+	assert.Equal(t, []fieldChange{
+		{Field: "year", Old: "2020", New: "2021"},
+		{Field: "isbn", Old: "", New: "9780134190440"},
+	}, old.diff(bookMetadata{title: "Draft", author: "Author", year: 2021, isbn13: "9780134190440"}))
+}
+
+func TestActor(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", actor(ctx))
+	assert.Equal(t, "7", actor(auth.WithPrincipal(ctx, &auth.Principal{UserID: "7"})))
+	assert.Equal(t, "api-key:3", actor(auth.WithPrincipal(ctx, &auth.Principal{UserID: "7", APIKeyID: "3"})))
 }
 
 func TestBooksServer_CreateBook_InvalidInput(t *testing.T) {
@@ -215,3 +1229,80 @@ func TestGetBook_InvalidInput(t *testing.T) {
 		})
 	}
 }
+
+func TestBooksServer_GetBooks_InvalidInput(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewBooksServer(nil, logger)
+
+	tooMany := make([]string, maxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = "1"
+	}
+	tests := []struct {
+		name string
+		req  *pb.GetBooksRequest
+	}{
+		{name: "Nil Request", req: nil},
+		{name: "Invalid Id", req: &pb.GetBooksRequest{BookIds: []string{"1", "abc"}}},
+		{name: "Negative Id", req: &pb.GetBooksRequest{BookIds: []string{"-1"}}},
+		{name: "Too Many Ids", req: &pb.GetBooksRequest{BookIds: tooMany}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.GetBooks(context.Background(), tt.req)
+			assert.Nil(t, resp)
+			assert.Equal(t, "InvalidArgument", status.Code(err).String())
+		})
+	}
+
+	resp, err := server.GetBooks(context.Background(), &pb.GetBooksRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Books)
+}
+
+func TestBooksServer_Items_InvalidInput(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewBooksServer(nil, logger)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "AddItem without book", call: func() error { _, err := server.AddItem(ctx, &pb.AddItemRequest{}); return err }},
+		{name: "AddItem bad condition", call: func() error {
+			_, err := server.AddItem(ctx, &pb.AddItemRequest{BookId: "1", Condition: "mint"})
+			return err
+		}},
+		{name: "AddItem long barcode", call: func() error {
+			_, err := server.AddItem(ctx, &pb.AddItemRequest{BookId: "1", Barcode: string(make([]byte, 33))})
+			return err
+		}},
+		{name: "GetItem without id", call: func() error { _, err := server.GetItem(ctx, &pb.GetItemRequest{}); return err }},
+		{name: "GetItem with id and barcode", call: func() error {
+			_, err := server.GetItem(ctx, &pb.GetItemRequest{ItemId: "1", Barcode: "LIB00000001"})
+			return err
+		}},
+		{name: "ListItems bad book", call: func() error { _, err := server.ListItems(ctx, &pb.ListItemsRequest{BookId: "x"}); return err }},
+		{name: "UpdateItem on_loan", call: func() error {
+			_, err := server.UpdateItem(ctx, &pb.UpdateItemRequest{ItemId: "1", Status: ItemOnLoan})
+			return err
+		}},
+		{name: "CheckoutItem without ids", call: func() error { _, err := server.CheckoutItem(ctx, &pb.CheckoutItemRequest{}); return err }},
+		{name: "CheckinItem bad item", call: func() error {
+			_, err := server.CheckinItem(ctx, &pb.CheckinItemRequest{ItemId: "-1"})
+			return err
+		}},
+		{name: "CreateBook too many copies", call: func() error {
+			_, err := server.CreateBook(ctx, &pb.CreateBookRequest{Title: "T", Author: "A", Year: 2000, Copies: maxCopies + 1})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, codes.InvalidArgument, status.Code(tt.call()))
+		})
+	}
+}
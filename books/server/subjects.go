@@ -0,0 +1,509 @@
+package bookserver
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ViktorOHJ/library-system/dberr"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Subjects are a tree of headings and genres that librarians maintain; tags are free-form
+// words on a book. A book listed under a subject is also found under every subject above it.
+
+const (
+	maxSubjectName  = 100
+	maxClassNumber  = 32
+	maxBookSubjects = 20
+	maxTagLength    = 50
+	maxBookTags     = 20
+	defaultTagLimit = 50
+	maxTagLimit     = 200
+)
+
+// classNumberPattern accepts Dewey ("813.54") and UDC ("821.161.1-31", "94(470)") numbers.
+var classNumberPattern = regexp.MustCompile(`^[0-9][0-9A-Za-z.:/()+=\-"' ]*$`)
+
+// subjectTree selects subject $1 and, unless $2 is true, every subject below it.
+const subjectTree = `WITH RECURSIVE tree AS (
+	SELECT id FROM subjects WHERE id = $1
+	UNION ALL
+	SELECT s.id FROM subjects s JOIN tree t ON s.parent_id = t.id WHERE NOT $2
+)`
+
+// subjectRow is also how a book's subjects are read as JSON.
+type subjectRow struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	ParentID    *int   `json:"parent_id"`
+	ClassNumber string `json:"class_number"`
+}
+
+func (r subjectRow) toProto() *pb.Subject {
+	subject := &pb.Subject{Id: strconv.Itoa(r.ID), Name: r.Name, ClassNumber: r.ClassNumber}
+	if r.ParentID != nil {
+		subject.ParentId = strconv.Itoa(*r.ParentID)
+	}
+	return subject
+}
+
+func subjectNames(subjects []*pb.Subject) string {
+	names := make([]string, len(subjects))
+	for i, subject := range subjects {
+		names[i] = subject.Name
+	}
+	return strings.Join(names, "; ")
+}
+
+// normalizeTag lowercases a tag and collapses its spaces, so that "Sci-Fi " and "sci-fi" are one tag.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+	if tag == "" {
+		return "", status.Error(codes.InvalidArgument, "tag cannot be empty")
+	}
+	if utf8.RuneCountInString(tag) > maxTagLength {
+		return "", status.Error(codes.InvalidArgument, "tag too long")
+	}
+	return tag, nil
+}
+
+func (s *BooksServer) getSubject(ctx context.Context, db queryer, id int) (*pb.Subject, error) {
+	var row subjectRow
+	var count int32
+	err := db.QueryRow(ctx, subjectTree+`
+	SELECT s.id, s.name, s.parent_id, s.class_number,
+		(SELECT COUNT(DISTINCT bs.book_id) FROM book_subjects bs
+		JOIN tree t ON t.id = bs.subject_id JOIN books b ON b.id = bs.book_id
+		WHERE b.withdrawn_at IS NULL)
+	FROM subjects s WHERE s.id = $1`, id, false).Scan(&row.ID, &row.Name, &row.ParentID, &row.ClassNumber, &count)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("Database error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "subject")
+	}
+	subject := row.toProto()
+	subject.BookCount = count
+
+	rows, err := db.Query(ctx, `WITH RECURSIVE up AS (
+		SELECT id, parent_id, name, 0 AS depth FROM subjects WHERE id = $1
+		UNION ALL
+		SELECT s.id, s.parent_id, s.name, up.depth + 1 FROM subjects s JOIN up ON s.id = up.parent_id
+	)
+	SELECT name FROM up ORDER BY depth DESC`, id)
+	if err == nil {
+		subject.Path, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	}
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "subject")
+	}
+	return subject, nil
+}
+
+func (s *BooksServer) CreateSubject(parentCtx context.Context, req *pb.CreateSubjectRequest) (*pb.Subject, error) {
+	s.logger.Info("CreateSubject called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxSubjectName {
+		return nil, status.Error(codes.InvalidArgument, "name too long")
+	}
+	classNumber := strings.TrimSpace(req.ClassNumber)
+	if len(classNumber) > maxClassNumber || classNumber != "" && !classNumberPattern.MatchString(classNumber) {
+		return nil, status.Error(codes.InvalidArgument, "invalid class number")
+	}
+	var parentID *int
+	if req.ParentId != "" {
+		id, err := parseID(req.ParentId, "parent id")
+		if err != nil {
+			return nil, err
+		}
+		parentID = &id
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	if parentID != nil {
+		if _, err := s.getSubject(ctx, s.db, *parentID); err != nil {
+			return nil, err
+		}
+	}
+	var id int
+	err := s.db.QueryRow(ctx, "INSERT INTO subjects (name, parent_id, class_number) VALUES ($1, $2, $3) RETURNING id",
+		name, parentID, classNumber).Scan(&id)
+	if err != nil {
+		// The unique index is on an expression, which the generic constraint message would not name well.
+		if dberr.IsUniqueViolation(err) {
+			return nil, status.Errorf(codes.AlreadyExists, "subject %q already exists here", name)
+		}
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "subject")
+	}
+	subject, err := s.getSubject(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"subject_id": subject.Id,
+		"path":       subject.Path,
+	}).Info("Subject created")
+	return subject, nil
+}
+
+func (s *BooksServer) ListSubjects(parentCtx context.Context, req *pb.ListSubjectsRequest) (*pb.ListSubjectsResponse, error) {
+	s.logger.Info("ListSubjects called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	var parentID *int
+	if req.ParentId != "" {
+		id, err := parseID(req.ParentId, "parent id")
+		if err != nil {
+			return nil, err
+		}
+		parentID = &id
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	res := &pb.ListSubjectsResponse{}
+	if parentID != nil {
+		parent, err := s.getSubject(ctx, s.db, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		res.Parent = parent
+	}
+
+	// Each child counts the books of its whole subtree once, however many of its subjects they have.
+	rows, err := s.db.Query(ctx, `WITH RECURSIVE tree AS (
+		SELECT id AS root, id FROM subjects WHERE parent_id IS NOT DISTINCT FROM $1::int
+		UNION ALL
+		SELECT t.root, s.id FROM subjects s JOIN tree t ON s.parent_id = t.id
+	)
+	SELECT s.id, s.name, s.parent_id, s.class_number,
+		(SELECT COUNT(DISTINCT bs.book_id) FROM tree t
+		JOIN book_subjects bs ON bs.subject_id = t.id JOIN books b ON b.id = bs.book_id
+		WHERE t.root = s.id AND b.withdrawn_at IS NULL)
+	FROM subjects s WHERE s.parent_id IS NOT DISTINCT FROM $1::int
+	ORDER BY NULLIF(s.class_number, '') NULLS LAST, lower(s.name)`, parentID)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "subject")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row subjectRow
+		var count int32
+		if err := rows.Scan(&row.ID, &row.Name, &row.ParentID, &row.ClassNumber, &count); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "subject")
+		}
+		subject := row.toProto()
+		subject.BookCount = count
+		subject.Path = append(slices.Clone(res.Parent.GetPath()), row.Name)
+		res.Subjects = append(res.Subjects, subject)
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "subject")
+	}
+	return res, nil
+}
+
+func (s *BooksServer) ListBooksBySubject(parentCtx context.Context, req *pb.ListBooksBySubjectRequest) (*pb.ListBooksBySubjectResponse, error) {
+	s.logger.Info("ListBooksBySubject called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := parseID(req.SubjectId, "subject id")
+	if err != nil {
+		return nil, err
+	}
+	tag := ""
+	if req.Tag != "" {
+		if tag, err = normalizeTag(req.Tag); err != nil {
+			return nil, err
+		}
+	}
+	pageSize := req.PageSize
+	if pageSize < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size cannot be negative")
+	}
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	offset, err := decodeSearchToken(req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	subject, err := s.getSubject(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.ListBooksBySubjectResponse{Subject: subject}
+
+	const shelf = `
+	FROM books b
+	WHERE b.withdrawn_at IS NULL
+	AND EXISTS (SELECT 1 FROM book_subjects bs JOIN tree t ON t.id = bs.subject_id WHERE bs.book_id = b.id)
+	AND ($3::text = '' OR EXISTS (SELECT 1 FROM book_tags bt WHERE bt.book_id = b.id AND bt.tag = $3))`
+	if err := s.db.QueryRow(ctx, subjectTree+" SELECT COUNT(*)"+shelf, id, req.Exact, tag).Scan(&res.TotalCount); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	if offset >= int(res.TotalCount) {
+		return res, nil
+	}
+	rows, err := s.db.Query(ctx, subjectTree+" SELECT b.id"+shelf+" ORDER BY lower(b.title), b.id LIMIT $4 OFFSET $5",
+		id, req.Exact, tag, pageSize, offset)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	books, err := s.loadBooks(ctx, s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if book, ok := books[id]; ok {
+			res.Books = append(res.Books, book)
+		}
+	}
+	if next := offset + len(ids); next < int(res.TotalCount) {
+		res.NextPageToken = encodeSearchToken(next)
+	}
+	return res, nil
+}
+
+func (s *BooksServer) SetBookSubjects(parentCtx context.Context, req *pb.SetBookSubjectsRequest) (*pb.BookResponse, error) {
+	s.logger.Info("SetBookSubjects called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := parseID(req.BookId, "book id")
+	if err != nil {
+		return nil, err
+	}
+	if len(req.SubjectIds) > maxBookSubjects {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d subjects per book", maxBookSubjects)
+	}
+	var subjectIDs []int
+	for _, value := range req.SubjectIds {
+		subjectID, err := parseID(value, "subject id")
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(subjectIDs, subjectID) {
+			subjectIDs = append(subjectIDs, subjectID)
+		}
+	}
+
+	return s.editBook(parentCtx, id, func(ctx context.Context, tx pgx.Tx, current *pb.BookResponse) ([]fieldChange, error) {
+		var found int
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM subjects WHERE id = ANY($1)", subjectIDs).Scan(&found); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "subject")
+		}
+		if found != len(subjectIDs) {
+			return nil, dberr.ToStatus(pgx.ErrNoRows, "subject")
+		}
+		if err := setSubjects(ctx, tx, id, subjectIDs); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "subject")
+		}
+		updated, err := s.getBook(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		before, after := subjectNames(current.Subjects), subjectNames(updated.Subjects)
+		if before == after {
+			return nil, nil
+		}
+		return []fieldChange{{Field: "subjects", Old: before, New: after}}, nil
+	})
+}
+
+func setSubjects(ctx context.Context, tx pgx.Tx, bookID int, ids []int) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM book_subjects WHERE book_id = $1", bookID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, "INSERT INTO book_subjects (book_id, subject_id) SELECT $1::int, unnest($2::int[])", bookID, ids)
+	return err
+}
+
+func (s *BooksServer) SetBookTags(parentCtx context.Context, req *pb.SetBookTagsRequest) (*pb.BookResponse, error) {
+	s.logger.Info("SetBookTags called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := parseID(req.BookId, "book id")
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Tags) > maxBookTags {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d tags per book", maxBookTags)
+	}
+	tags := []string{}
+	for _, value := range req.Tags {
+		tag, err := normalizeTag(value)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	slices.Sort(tags)
+
+	return s.editBook(parentCtx, id, func(ctx context.Context, tx pgx.Tx, current *pb.BookResponse) ([]fieldChange, error) {
+		before, after := strings.Join(current.Tags, ", "), strings.Join(tags, ", ")
+		if before == after {
+			return nil, nil
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM book_tags WHERE book_id = $1", id); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "tag")
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO book_tags (book_id, tag) SELECT $1::int, unnest($2::text[])", id, tags); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "tag")
+		}
+		return []fieldChange{{Field: "tags", Old: before, New: after}}, nil
+	})
+}
+
+// editBook runs edit on a locked book that is still in the catalog and records the
+// changes it reports as a revision. With no changes, the book is left as it was.
+func (s *BooksServer) editBook(parentCtx context.Context, id int,
+	edit func(ctx context.Context, tx pgx.Tx, current *pb.BookResponse) ([]fieldChange, error)) (*pb.BookResponse, error) {
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := s.lockBook(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Withdrawn {
+		return nil, status.Error(codes.FailedPrecondition, "book is withdrawn")
+	}
+	changes, err := edit(ctx, tx, current)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return current, nil
+	}
+	if err := recordRevision(ctx, tx, id, revisionUpdate, changes, "", actor(parentCtx)); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+	book, err := s.getBook(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "book")
+	}
+
+	fields := make([]string, len(changes))
+	for i, c := range changes {
+		fields[i] = c.Field
+	}
+	s.logger.WithFields(logrus.Fields{
+		"book_id": book.Id,
+		"fields":  fields,
+	}).Info("Book updated")
+	return book, nil
+}
+
+func (s *BooksServer) ListTags(parentCtx context.Context, req *pb.ListTagsRequest) (*pb.ListTagsResponse, error) {
+	s.logger.Info("ListTags called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	prefix := strings.Join(strings.Fields(strings.ToLower(req.Prefix)), " ")
+	if utf8.RuneCountInString(prefix) > maxTagLength {
+		return nil, status.Error(codes.InvalidArgument, "prefix too long")
+	}
+	limit := req.Limit
+	if limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit cannot be negative")
+	}
+	if limit == 0 {
+		limit = defaultTagLimit
+	}
+	if limit > maxTagLimit {
+		limit = maxTagLimit
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, `SELECT bt.tag, COUNT(*) FROM book_tags bt JOIN books b ON b.id = bt.book_id
+	WHERE b.withdrawn_at IS NULL AND bt.tag LIKE $1
+	GROUP BY bt.tag ORDER BY COUNT(*) DESC, bt.tag LIMIT $2`, escapeLike(prefix)+"%", limit)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "tag")
+	}
+	defer rows.Close()
+	res := &pb.ListTagsResponse{}
+	for rows.Next() {
+		tag := &pb.FacetCount{}
+		if err := rows.Scan(&tag.Value, &tag.Count); err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "tag")
+		}
+		res.Tags = append(res.Tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "tag")
+	}
+	return res, nil
+}
@@ -0,0 +1,60 @@
+package bookserver
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/ViktorOHJ/library-system/books/suggest"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultSuggestions = 8
+	maxSuggestions     = 20
+	maxPrefixLength    = 100
+)
+
+// StartSuggest loads the suggest index and keeps it fresh until ctx is done.
+func (s *BooksServer) StartSuggest(ctx context.Context) {
+	suggest.Start(ctx, s.db, s.suggestions, s.logger)
+}
+
+// Suggest answers from memory only, so it stays fast while the database is busy.
+func (s *BooksServer) Suggest(_ context.Context, req *pb.SuggestRequest) (*pb.SuggestResponse, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	prefix := strings.TrimSpace(req.Prefix)
+	if prefix == "" {
+		return nil, status.Error(codes.InvalidArgument, "prefix cannot be empty")
+	}
+	if len(prefix) > maxPrefixLength {
+		return nil, status.Error(codes.InvalidArgument, "prefix too long")
+	}
+	limit := req.Limit
+	if limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit cannot be negative")
+	}
+	if limit == 0 {
+		limit = defaultSuggestions
+	}
+	if limit > maxSuggestions {
+		limit = maxSuggestions
+	}
+	if !s.suggestions.Loaded() {
+		return nil, status.Error(codes.Unavailable, "suggestions are loading")
+	}
+
+	res := &pb.SuggestResponse{}
+	for _, sg := range s.suggestions.Suggest(prefix, int(limit)) {
+		out := &pb.Suggestion{Text: sg.Text, Kind: string(sg.Kind), Loans: int32(sg.Loans)}
+		if sg.BookID != 0 {
+			out.BookId = strconv.Itoa(sg.BookID)
+		}
+		res.Suggestions = append(res.Suggestions, out)
+	}
+	return res, nil
+}
@@ -0,0 +1,240 @@
+// Package suggest completes what patrons type into the catalog search box with book
+// titles and authors, ranked by how often the books were borrowed. The index lives in
+// memory so that a suggestion never waits for the database.
+package suggest
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+type Kind string
+
+const (
+	KindTitle  Kind = "title"
+	KindAuthor Kind = "author"
+)
+
+type Book struct {
+	ID     int
+	Title  string
+	Author string
+	Loans  int
+}
+
+type Suggestion struct {
+	Text   string
+	Kind   Kind
+	BookID int // Zero for authors
+	Loans  int // For authors, the loans of all their books
+}
+
+// entry completes one word position of a title or author: "war and peace" is found
+// by "war", "and" and "peace".
+type entry struct {
+	key    string
+	kind   Kind
+	text   string
+	bookID int
+	first  bool // key starts at the first word
+}
+
+type author struct {
+	books int
+	loans int
+}
+
+// Index is safe for concurrent use.
+type Index struct {
+	mu      sync.RWMutex
+	loaded  bool
+	books   map[int]Book
+	authors map[string]*author
+	entries []entry // Sorted by key
+}
+
+func NewIndex() *Index {
+	return &Index{books: map[int]Book{}, authors: map[string]*author{}}
+}
+
+// Loaded reports whether Replace has been called, i.e. whether the index has ever held the catalog.
+func (x *Index) Loaded() bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.loaded
+}
+
+// Replace swaps the whole contents of the index.
+func (x *Index) Replace(books []Book) {
+	fresh := NewIndex()
+	for _, b := range books {
+		fresh.entries = append(fresh.entries, fresh.add(b)...)
+	}
+	sortEntries(fresh.entries)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.books, x.authors, x.entries = fresh.books, fresh.authors, fresh.entries
+	x.loaded = true
+}
+
+// Upsert adds a book or applies changes to it.
+func (x *Index) Upsert(b Book) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	old, ok := x.books[b.ID]
+	if ok && old.Title == b.Title && old.Author == b.Author {
+		x.books[b.ID] = b
+		x.authors[b.Author].loans += b.Loans - old.Loans
+		return
+	}
+	if ok {
+		x.remove(old)
+	}
+	for _, e := range x.add(b) {
+		i := sort.Search(len(x.entries), func(i int) bool { return !less(x.entries[i], e) })
+		x.entries = slices.Insert(x.entries, i, e)
+	}
+}
+
+func (x *Index) Remove(id int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if b, ok := x.books[id]; ok {
+		x.remove(b)
+	}
+}
+
+// Suggest returns up to limit completions of prefix, the most borrowed first.
+func (x *Index) Suggest(prefix string, limit int) []Suggestion {
+	key := normalize(prefix)
+	if key == "" || limit <= 0 {
+		return nil
+	}
+
+	x.mu.RLock()
+	type candidate struct {
+		Suggestion
+		atStart bool
+	}
+	var candidates []candidate
+	seen := map[entry]bool{}
+	for i := sort.Search(len(x.entries), func(i int) bool { return x.entries[i].key >= key }); i < len(x.entries); i++ {
+		e := x.entries[i]
+		if !strings.HasPrefix(e.key, key) {
+			break
+		}
+		id := entry{kind: e.kind, text: e.text, bookID: e.bookID}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		s := Suggestion{Text: e.text, Kind: e.kind, BookID: e.bookID}
+		if e.kind == KindTitle {
+			s.Loans = x.books[e.bookID].Loans
+		} else {
+			s.Loans = x.authors[e.text].loans
+		}
+		candidates = append(candidates, candidate{Suggestion: s, atStart: e.first})
+	}
+	x.mu.RUnlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Loans != b.Loans {
+			return a.Loans > b.Loans
+		}
+		if a.atStart != b.atStart {
+			return a.atStart
+		}
+		if a.Text != b.Text {
+			return a.Text < b.Text
+		}
+		return a.BookID < b.BookID
+	})
+	res := make([]Suggestion, 0, min(limit, len(candidates)))
+	for _, c := range candidates[:min(limit, len(candidates))] {
+		res = append(res, c.Suggestion)
+	}
+	return res
+}
+
+// add records b and returns its new entries for the caller to place in order.
+func (x *Index) add(b Book) []entry {
+	x.books[b.ID] = b
+	added := entries(KindTitle, b.Title, b.ID)
+	a, ok := x.authors[b.Author]
+	if !ok {
+		a = &author{}
+		x.authors[b.Author] = a
+		added = append(added, entries(KindAuthor, b.Author, 0)...)
+	}
+	a.books++
+	a.loans += b.Loans
+	return added
+}
+
+func (x *Index) remove(b Book) {
+	delete(x.books, b.ID)
+	a := x.authors[b.Author]
+	a.books--
+	a.loans -= b.Loans
+	gone := a.books == 0
+	if gone {
+		delete(x.authors, b.Author)
+	}
+	x.entries = slices.DeleteFunc(x.entries, func(e entry) bool {
+		return e.kind == KindTitle && e.bookID == b.ID || gone && e.kind == KindAuthor && e.text == b.Author
+	})
+}
+
+func entries(kind Kind, text string, bookID int) []entry {
+	words := strings.Fields(normalize(text))
+	res := make([]entry, 0, len(words))
+	for i := range words {
+		res = append(res, entry{key: strings.Join(words[i:], " "), kind: kind, text: text, bookID: bookID, first: i == 0})
+	}
+	return res
+}
+
+func sortEntries(entries []entry) {
+	sort.Slice(entries, func(i, j int) bool { return less(entries[i], entries[j]) })
+}
+
+func less(a, b entry) bool {
+	if a.key != b.key {
+		return a.key < b.key
+	}
+	if a.kind != b.kind {
+		return a.kind < b.kind
+	}
+	if a.text != b.text {
+		return a.text < b.text
+	}
+	return a.bookID < b.bookID
+}
+
+// normalize lowercases text, folds ё into е and keeps only words separated by single spaces,
+// so that "Tolstoy, L." and "tolstoy l" complete alike.
+func normalize(text string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(text) {
+		if r == 'ё' {
+			r = 'е'
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package suggest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func texts(suggestions []Suggestion) []string {
+	res := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		res[i] = s.Text
+	}
+	return res
+}
+
+func newTestIndex() *Index {
+	x := NewIndex()
+	x.Replace([]Book{
+		{ID: 1, Title: "War and Peace", Author: "Leo Tolstoy", Loans: 5},
+		{ID: 2, Title: "Anna Karenina", Author: "Leo Tolstoy", Loans: 3},
+		{ID: 3, Title: "Warlock", Author: "Oakley Hall", Loans: 7},
+		{ID: 4, Title: "Ёлка", Author: "Фёдор Достоевский"},
+	})
+	return x
+}
+
+func TestIndex_Suggest(t *testing.T) {
+	x := newTestIndex()
+
+	tests := []struct {
+		name   string
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{name: "by loans", prefix: "war", limit: 10, want: []string{"Warlock", "War and Peace"}},
+		{name: "any word", prefix: "pea", limit: 10, want: []string{"War and Peace"}},
+		{name: "several words", prefix: "war and p", limit: 10, want: []string{"War and Peace"}},
+		{name: "author with loans of all books", prefix: "tol", limit: 10, want: []string{"Leo Tolstoy"}},
+		{name: "authors and titles", prefix: "leo", limit: 10, want: []string{"Leo Tolstoy"}},
+		{name: "case and punctuation", prefix: "  WAR, AND", limit: 10, want: []string{"War and Peace"}},
+		{name: "yo folded", prefix: "елк", limit: 10, want: []string{"Ёлка"}},
+		{name: "yo in prefix", prefix: "фё", limit: 10, want: []string{"Фёдор Достоевский"}},
+		{name: "limit", prefix: "a", limit: 1, want: []string{"War and Peace"}},
+		{name: "no match", prefix: "xyz", limit: 10, want: []string{}},
+		{name: "empty prefix", prefix: " ,", limit: 10, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, texts(x.Suggest(tt.prefix, tt.limit)))
+		})
+	}
+
+	got := x.Suggest("tolstoy", 1)
+	assert.Equal(t, []Suggestion{{Text: "Leo Tolstoy", Kind: KindAuthor, Loans: 8}}, got)
+}
+
+func TestIndex_Updates(t *testing.T) {
+	x := newTestIndex()
+
+	x.Upsert(Book{ID: 1, Title: "War and Peace", Author: "Leo Tolstoy", Loans: 9})
+	assert.Equal(t, []string{"War and Peace", "Warlock"}, texts(x.Suggest("war", 10)), "more loans rank higher")
+	assert.Equal(t, 12, x.Suggest("tolstoy", 1)[0].Loans)
+
+	x.Upsert(Book{ID: 3, Title: "The Warlock", Author: "Oakley Hall", Loans: 7})
+	assert.Equal(t, []string{"War and Peace", "The Warlock"}, texts(x.Suggest("war", 10)))
+	assert.Empty(t, x.Suggest("warlock", 10)[1:], "the old title is gone")
+
+	x.Upsert(Book{ID: 5, Title: "Hadji Murat", Author: "Leo Tolstoy"})
+	assert.Equal(t, []Suggestion{{Text: "Hadji Murat", Kind: KindTitle, BookID: 5}}, x.Suggest("hadji", 10))
+
+	x.Remove(3)
+	assert.Empty(t, x.Suggest("oakley", 10), "an author without books is gone")
+	x.Remove(1)
+	x.Remove(2)
+	x.Remove(5)
+	assert.Empty(t, x.Suggest("leo", 10))
+	x.Remove(42)
+}
+
+func TestIndex_Loaded(t *testing.T) {
+	x := NewIndex()
+	assert.False(t, x.Loaded())
+	x.Upsert(Book{ID: 1, Title: "Go", Author: "Donovan"})
+	assert.False(t, x.Loaded(), "events before the first load do not make the index complete")
+	x.Replace(nil)
+	assert.True(t, x.Loaded())
+	assert.Empty(t, x.Suggest("go", 10))
+}
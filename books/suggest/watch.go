@@ -0,0 +1,120 @@
+package suggest
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// Channel is notified with a book id by the books table trigger on every insert, update and delete.
+const Channel = "book_events"
+
+const (
+	// batchWindow collects the notifications of a burst, such as an import, into one query.
+	batchWindow  = 100 * time.Millisecond
+	maxBatchSize = 1000
+	retryDelay   = 5 * time.Second
+)
+
+// Withdrawn books are not suggested.
+const bookQuery = "SELECT id, title, author, loan_count FROM books WHERE withdrawn_at IS NULL"
+
+// Start loads the catalog into index and keeps it fresh from book events until ctx is done.
+// It listens before loading, so no change is missed; after a lost connection it reloads everything.
+func Start(ctx context.Context, db *pgxpool.Pool, index *Index, logger *logrus.Logger) {
+	go func() {
+		for {
+			err := watch(ctx, db, index, logger)
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Errorf("Suggest index stopped following book events: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+		}
+	}()
+}
+
+func watch(ctx context.Context, db *pgxpool.Pool, index *Index, logger *logrus.Logger) error {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+
+	books, err := load(ctx, db, bookQuery)
+	if err != nil {
+		return err
+	}
+	index.Replace(books)
+	logger.Infof("Suggest index loaded with %d books", len(books))
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		ids := map[int]bool{}
+		for n != nil && len(ids) < maxBatchSize {
+			if id, err := strconv.Atoi(n.Payload); err == nil {
+				ids[id] = true
+			}
+			waitCtx, cancel := context.WithTimeout(ctx, batchWindow)
+			n, err = conn.Conn().WaitForNotification(waitCtx)
+			cancel()
+			if err != nil && !pgconn.Timeout(err) {
+				return err
+			}
+		}
+		if err := refresh(ctx, db, index, ids); err != nil {
+			return err
+		}
+	}
+}
+
+// refresh reads the changed books again; the ones no longer found are removed.
+func refresh(ctx context.Context, db *pgxpool.Pool, index *Index, ids map[int]bool) error {
+	list := make([]int, 0, len(ids))
+	for id := range ids {
+		list = append(list, id)
+	}
+	books, err := load(ctx, db, bookQuery+" AND id = ANY($1)", list)
+	if err != nil {
+		return err
+	}
+	for _, b := range books {
+		index.Upsert(b)
+		delete(ids, b.ID)
+	}
+	for id := range ids {
+		index.Remove(id)
+	}
+	return nil
+}
+
+func load(ctx context.Context, db *pgxpool.Pool, query string, args ...any) ([]Book, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var books []Book
+	for rows.Next() {
+		var b Book
+		if err := rows.Scan(&b.ID, &b.Title, &b.Author, &b.Loans); err != nil {
+			return nil, err
+		}
+		books = append(books, b)
+	}
+	return books, rows.Err()
+}
@@ -0,0 +1,116 @@
+package calendar
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	ContentType     = "text/calendar; charset=utf-8; method=PUBLISH"
+	DefaultReminder = 24 * time.Hour
+	dateLayout      = "20060102"
+	stampLayout     = "20060102T150405Z"
+	prodID          = "-//ViktorOHJ//library-system//EN"
+)
+
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Date        time.Time
+	Reminder    time.Duration
+}
+
+func LoanDueEvent(loanID, title, author string, due time.Time) Event {
+	return Event{
+		UID:         fmt.Sprintf("loan-%s@library-system", loanID),
+		Summary:     fmt.Sprintf("Return \"%s\"", title),
+		Description: fmt.Sprintf("Library loan #%s: \"%s\" by %s is due on %s.", loanID, title, author, due.Format("2006-01-02")),
+		Date:        due,
+		Reminder:    DefaultReminder,
+	}
+}
+
+// Encode renders events as an all-day VCALENDAR document (RFC 5545).
+func Encode(name string, events []Event, now time.Time) []byte {
+	var b strings.Builder
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+prodID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "METHOD:PUBLISH")
+	if name != "" {
+		writeLine(&b, "X-WR-CALNAME:"+escape(name))
+	}
+	for _, e := range events {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+escape(e.UID))
+		writeLine(&b, "DTSTAMP:"+now.UTC().Format(stampLayout))
+		writeLine(&b, "DTSTART;VALUE=DATE:"+e.Date.Format(dateLayout))
+		writeLine(&b, "DTEND;VALUE=DATE:"+e.Date.AddDate(0, 0, 1).Format(dateLayout))
+		writeLine(&b, "SUMMARY:"+escape(e.Summary))
+		if e.Description != "" {
+			writeLine(&b, "DESCRIPTION:"+escape(e.Description))
+		}
+		if e.Reminder > 0 {
+			writeLine(&b, "BEGIN:VALARM")
+			writeLine(&b, "ACTION:DISPLAY")
+			writeLine(&b, "DESCRIPTION:"+escape(e.Summary))
+			writeLine(&b, "TRIGGER:"+trigger(e.Reminder))
+			writeLine(&b, "END:VALARM")
+		}
+		writeLine(&b, "END:VEVENT")
+	}
+	writeLine(&b, "END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// FeedToken returns the token that protects a user's calendar feed.
+func FeedToken(secret, userID string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("ical-feed:" + userID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func ValidFeedToken(secret, userID, token string) bool {
+	if secret == "" || token == "" {
+		return false
+	}
+	return hmac.Equal([]byte(FeedToken(secret, userID)), []byte(token))
+}
+
+func trigger(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("-P%dD", d/(24*time.Hour))
+	}
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("-PT%dH", d/time.Hour)
+	}
+	return fmt.Sprintf("-PT%dM", d/time.Minute)
+}
+
+func escape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// writeLine folds content lines longer than 75 octets without splitting UTF-8 sequences.
+func writeLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
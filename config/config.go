@@ -0,0 +1,463 @@
+// Package config loads the typed configuration of every binary. Values are layered,
+// each source overriding the previous one: defaults, the YAML file given with -config
+// (or CONFIG_FILE), the environment (a .env file in the working directory is read if
+// present, without overriding real variables) and finally command-line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const minJWTSecretLength = 32
+
+type Server struct {
+	Port        string `yaml:"port"`
+	MetricsPort string `yaml:"metrics_port"`
+	// Healthcheck is set by the -healthcheck flag: probe a running instance instead of serving.
+	Healthcheck bool `yaml:"-"`
+}
+
+type Database struct {
+	URL            string `yaml:"url"`
+	MigrationsPath string `yaml:"migrations_path"`
+}
+
+type Auth struct {
+	JWTSecret  string        `yaml:"jwt_secret"`
+	AccessTTL  time.Duration `yaml:"access_ttl"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+}
+
+type Tracing struct {
+	// Exporter is one of none, otlp, stdout or file.
+	Exporter string `yaml:"exporter"`
+	File     string `yaml:"file"`
+}
+
+type RabbitMQ struct {
+	URL string `yaml:"url"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Services holds the addresses of the other services. Each one is a port on localhost,
+// host:port, a gRPC target such as dns:///books:50052, or a comma-separated list of replicas.
+type Services struct {
+	Users         string `yaml:"users"`
+	Books         string `yaml:"books"`
+	Loans         string `yaml:"loans"`
+	Notifications string `yaml:"notifications"`
+}
+
+// TLS secures gRPC traffic. It is enabled by a certificate, a CA bundle or dev mode; the
+// same certificate serves the gRPC server and identifies the service to its peers.
+type TLS struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	CAFile   string `yaml:"ca_file"`
+	// ClientAuth turns on mutual TLS: servers require a client certificate signed by the CA
+	// and clients present their own.
+	ClientAuth bool `yaml:"client_auth"`
+	// AllowedClients restricts mutual TLS to certificates whose common name or DNS name is
+	// listed. Empty accepts every certificate signed by the CA.
+	AllowedClients []string `yaml:"allowed_clients"`
+	// Dev generates a local CA and a certificate for the service in DevDir.
+	Dev    bool   `yaml:"dev"`
+	DevDir string `yaml:"dev_dir"`
+}
+
+// Enabled reports whether gRPC connections use TLS.
+func (t *TLS) Enabled() bool {
+	return t.Dev || t.CertFile != "" || t.CAFile != ""
+}
+
+type CalendarFeed struct {
+	// Secret signs feed tokens. The feed is disabled when it is empty.
+	Secret string `yaml:"secret"`
+	Port   string `yaml:"port"`
+	URL    string `yaml:"url"`
+}
+
+// field binds one configuration value to its environment variable and flag.
+type field struct {
+	key      string // YAML path, used in error messages
+	env      string
+	flag     string
+	usage    string
+	value    any // *string, *int, *bool, *time.Duration or *[]string
+	required bool
+}
+
+func (s *Server) fields(prefix string) []field {
+	return []field{
+		{key: "server.port", env: prefix + "_PORT", flag: "port", usage: "gRPC port", value: &s.Port, required: true},
+		{key: "server.metrics_port", env: prefix + "_METRICS_PORT", flag: "metrics-port", usage: "port of the /metrics endpoint", value: &s.MetricsPort, required: true},
+		{flag: "healthcheck", usage: "check whether a running instance is serving and exit", value: &s.Healthcheck},
+	}
+}
+
+func (d *Database) fields(urlEnv, migrationsEnv string) []field {
+	return []field{
+		{key: "database.url", env: urlEnv, flag: "db-url", usage: "PostgreSQL connection string", value: &d.URL, required: true},
+		{key: "database.migrations_path", env: migrationsEnv, flag: "migrations-path", usage: "migrations source URL", value: &d.MigrationsPath, required: true},
+	}
+}
+
+func (a *Auth) fields() []field {
+	return []field{
+		{key: "auth.jwt_secret", env: "JWT_SECRET", usage: "JWT signing key", value: &a.JWTSecret, required: true},
+		{key: "auth.access_ttl", env: "JWT_ACCESS_TTL", flag: "jwt-access-ttl", usage: "access token lifetime", value: &a.AccessTTL},
+		{key: "auth.refresh_ttl", env: "JWT_REFRESH_TTL", flag: "jwt-refresh-ttl", usage: "refresh token lifetime", value: &a.RefreshTTL},
+	}
+}
+
+func (t *Tracing) fields() []field {
+	return []field{
+		{key: "tracing.exporter", env: "OTEL_TRACES_EXPORTER", flag: "traces-exporter", usage: "span exporter: none, otlp, stdout or file", value: &t.Exporter},
+		{key: "tracing.file", env: "OTEL_TRACES_FILE", flag: "traces-file", usage: "file for the file exporter", value: &t.File},
+	}
+}
+
+func (r *RabbitMQ) fields() []field {
+	return []field{
+		{key: "rabbitmq.url", env: "RABBIT_URL", flag: "rabbit-url", usage: "RabbitMQ URL", value: &r.URL, required: true},
+	}
+}
+
+func (s *SMTP) fields() []field {
+	return []field{
+		{key: "smtp.host", env: "SMTP_HOST", flag: "smtp-host", usage: "SMTP server", value: &s.Host, required: true},
+		{key: "smtp.port", env: "SMTP_PORT", flag: "smtp-port", usage: "SMTP port (implicit TLS)", value: &s.Port},
+		{key: "smtp.username", env: "EMAIL", usage: "sender address and SMTP login", value: &s.Username, required: true},
+		{key: "smtp.password", env: "MAIL_PASS", usage: "SMTP password", value: &s.Password, required: true},
+	}
+}
+
+// fields binds the TLS settings. Certificates and allowed clients are per service, so
+// that one .env can hold them for every binary; the CA and the switches are shared.
+func (t *TLS) fields(prefix string) []field {
+	return []field{
+		{key: "tls.cert_file", env: prefix + "_TLS_CERT_FILE", flag: "tls-cert", usage: "PEM certificate of the service", value: &t.CertFile},
+		{key: "tls.key_file", env: prefix + "_TLS_KEY_FILE", flag: "tls-key", usage: "PEM private key of the service", value: &t.KeyFile},
+		{key: "tls.ca_file", env: "TLS_CA_FILE", flag: "tls-ca", usage: "PEM bundle of CAs trusted for peers", value: &t.CAFile},
+		{key: "tls.client_auth", env: "TLS_CLIENT_AUTH", flag: "tls-client-auth", usage: "require and present client certificates (mutual TLS)", value: &t.ClientAuth},
+		{key: "tls.allowed_clients", env: prefix + "_TLS_ALLOWED_CLIENTS", flag: "tls-allowed-clients", usage: "comma-separated client certificate names accepted with mutual TLS", value: &t.AllowedClients},
+		{key: "tls.dev", env: "TLS_DEV", flag: "tls-dev", usage: "generate a local CA and certificates for development", value: &t.Dev},
+		{key: "tls.dev_dir", env: "TLS_DEV_DIR", flag: "tls-dev-dir", usage: "directory of the generated development certificates", value: &t.DevDir},
+	}
+}
+
+func defaultTLS() TLS {
+	return TLS{DevDir: ".dev-certs"}
+}
+
+func (s *Services) usersField() field {
+	return field{key: "services.users", env: "USERS_ADDR", flag: "users-addr", usage: "address of the users service", value: &s.Users, required: true}
+}
+
+func (s *Services) booksField() field {
+	return field{key: "services.books", env: "BOOKS_ADDR", flag: "books-addr", usage: "address of the books service", value: &s.Books, required: true}
+}
+
+func (s *Services) loansField() field {
+	return field{key: "services.loans", env: "LOANS_ADDR", flag: "loans-addr", usage: "address of the loans service", value: &s.Loans, required: true}
+}
+
+func (s *Services) notificationsField() field {
+	return field{key: "services.notifications", env: "NOTIFICATIONS_ADDR", flag: "notifications-addr", usage: "address of the notifications service", value: &s.Notifications, required: true}
+}
+
+func defaultServices() Services {
+	return Services{
+		Users:         "localhost:50051",
+		Books:         "localhost:50052",
+		Loans:         "localhost:50053",
+		Notifications: "localhost:50054",
+	}
+}
+
+func defaultAuth() Auth {
+	return Auth{AccessTTL: 15 * time.Minute, RefreshTTL: 30 * 24 * time.Hour}
+}
+
+func defaultTracing(service string) Tracing {
+	return Tracing{Exporter: "none", File: service + "-traces.json"}
+}
+
+// load applies the file, environment and flag layers on top of the defaults already in cfg.
+// Flags are parsed twice: first to find -config, then again so that they win over the other sources.
+func load(name string, args []string, cfg any, fields []field) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	configFile := fs.String("config", "", "path to a YAML config file (or CONFIG_FILE)")
+	for _, f := range fields {
+		if f.flag != "" {
+			bindFlag(fs, f)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return err
+		}
+	}
+
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read .env: %w", err)
+	}
+	for _, f := range fields {
+		if err := applyEnv(f); err != nil {
+			return err
+		}
+	}
+
+	return fs.Parse(args)
+}
+
+func loadFile(path string, cfg any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open config file: %w", err)
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func bindFlag(fs *flag.FlagSet, f field) {
+	switch v := f.value.(type) {
+	case *string:
+		fs.StringVar(v, f.flag, *v, f.usage)
+	case *int:
+		fs.IntVar(v, f.flag, *v, f.usage)
+	case *bool:
+		fs.BoolVar(v, f.flag, *v, f.usage)
+	case *time.Duration:
+		fs.DurationVar(v, f.flag, *v, f.usage)
+	case *[]string:
+		fs.Func(f.flag, f.usage, func(raw string) error {
+			*v = splitList(raw)
+			return nil
+		})
+	}
+}
+
+func splitList(raw string) []string {
+	var list []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func applyEnv(f field) error {
+	if f.env == "" {
+		return nil
+	}
+	raw, ok := os.LookupEnv(f.env)
+	if !ok || raw == "" {
+		return nil
+	}
+	switch v := f.value.(type) {
+	case *string:
+		*v = raw
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %q is not a number", f.env, raw)
+		}
+		*v = n
+	case *bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %q is not a boolean", f.env, raw)
+		}
+		*v = b
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %q is not a duration", f.env, raw)
+		}
+		*v = d
+	case *[]string:
+		*v = splitList(raw)
+	}
+	return nil
+}
+
+// describe names a field the way an operator would set it.
+func (f field) describe() string {
+	var sources []string
+	if f.env != "" {
+		sources = append(sources, f.env)
+	}
+	if f.flag != "" {
+		sources = append(sources, "-"+f.flag)
+	}
+	if len(sources) == 0 {
+		return f.key
+	}
+	return fmt.Sprintf("%s (%s)", f.key, strings.Join(sources, ", "))
+}
+
+// validator collects every problem so that a misconfigured service reports all of them at once.
+type validator struct {
+	errs []error
+}
+
+func (v *validator) fail(f field, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("%s %s", f.describe(), fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) required(fields []field) {
+	for _, f := range fields {
+		if s, ok := f.value.(*string); ok && f.required && *s == "" {
+			v.fail(f, "is required")
+		}
+	}
+}
+
+func (v *validator) port(f field) {
+	s := *f.value.(*string)
+	if s == "" {
+		return
+	}
+	if n, err := strconv.Atoi(s); err != nil || n < 1 || n > 65535 {
+		v.fail(f, "must be a port number, got %q", s)
+	}
+}
+
+func (v *validator) ports(fields []field) {
+	for _, f := range fields {
+		if strings.HasSuffix(f.key, "port") {
+			if _, ok := f.value.(*string); ok {
+				v.port(f)
+			}
+		}
+	}
+}
+
+// targets checks the addresses of other services. Targets with a scheme are left to gRPC.
+func (v *validator) targets(fields []field) {
+	for _, f := range fields {
+		if !strings.HasPrefix(f.key, "services.") {
+			continue
+		}
+		addr := *f.value.(*string)
+		if addr == "" || strings.Contains(addr, ":///") {
+			continue
+		}
+		for _, a := range strings.Split(addr, ",") {
+			port := strings.TrimSpace(a)
+			if host, p, err := net.SplitHostPort(port); err == nil && host != "" {
+				port = p
+			}
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				v.fail(f, "must be a port, host:port, a gRPC target or a comma-separated list, got %q", addr)
+				break
+			}
+		}
+	}
+}
+
+func (v *validator) auth(a *Auth) {
+	fields := a.fields()
+	if a.JWTSecret != "" && len(a.JWTSecret) < minJWTSecretLength {
+		v.fail(fields[0], "must be at least %d bytes", minJWTSecretLength)
+	}
+	if a.AccessTTL <= 0 {
+		v.fail(fields[1], "must be positive")
+	}
+	if a.RefreshTTL <= 0 {
+		v.fail(fields[2], "must be positive")
+	}
+}
+
+func (v *validator) tracing(t *Tracing) {
+	switch t.Exporter {
+	case "none", "otlp", "stdout", "file":
+	default:
+		v.fail(t.fields()[0], "must be one of none, otlp, stdout or file, got %q", t.Exporter)
+	}
+}
+
+func (v *validator) rabbitMQ(r *RabbitMQ) {
+	if r.URL != "" && !strings.HasPrefix(r.URL, "amqp://") && !strings.HasPrefix(r.URL, "amqps://") {
+		v.fail(r.fields()[0], "must be an amqp:// or amqps:// URL")
+	}
+}
+
+func (v *validator) smtp(s *SMTP) {
+	if s.Port < 1 || s.Port > 65535 {
+		v.fail(s.fields()[1], "must be a port number, got %d", s.Port)
+	}
+}
+
+// tls checks that the files form a usable setup. Servers need a certificate; clients need
+// one only for mutual TLS.
+func (v *validator) tls(t *TLS, prefix string, server bool) {
+	fields := t.fields(prefix)
+	certFile, keyFile, caFile, clientAuth, allowedClients, dev, devDir := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]
+
+	if len(t.AllowedClients) > 0 && !t.ClientAuth {
+		v.fail(allowedClients, "requires mutual TLS (%s)", clientAuth.describe())
+	}
+	if t.Dev {
+		if t.CertFile != "" || t.KeyFile != "" || t.CAFile != "" {
+			v.fail(dev, "cannot be combined with certificate files")
+		}
+		if t.DevDir == "" {
+			v.fail(devDir, "is required in dev mode")
+		}
+		return
+	}
+	if t.CertFile != "" && t.KeyFile == "" {
+		v.fail(keyFile, "is required with a certificate")
+	}
+	if t.KeyFile != "" && t.CertFile == "" {
+		v.fail(certFile, "is required with a private key")
+	}
+	if t.Enabled() && t.CAFile == "" {
+		v.fail(caFile, "is required when TLS is enabled")
+	}
+	if t.Enabled() && t.CertFile == "" && t.KeyFile == "" && (server || t.ClientAuth) {
+		v.fail(certFile, "is required when TLS is enabled")
+	}
+	if t.ClientAuth && !t.Enabled() {
+		v.fail(clientAuth, "requires TLS certificates or dev mode")
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration: %w", errors.Join(v.errs...))
+}
@@ -0,0 +1,356 @@
+package config
+
+type Users struct {
+	Server   Server   `yaml:"server"`
+	Database Database `yaml:"database"`
+	Auth     Auth     `yaml:"auth"`
+	Tracing  Tracing  `yaml:"tracing"`
+	TLS      TLS      `yaml:"tls"`
+	Services Services `yaml:"services"`
+}
+
+func (c *Users) fields() []field {
+	return concat(
+		c.Server.fields("USERS"),
+		c.Database.fields("USR_DBURL", "USERS_MIGRATIONS_PATH"),
+		c.Auth.fields(),
+		c.Tracing.fields(),
+		c.TLS.fields("USERS"),
+		[]field{c.Services.loansField()},
+	)
+}
+
+// LoadUsers loads the configuration of the users service from args (without the program name).
+func LoadUsers(args []string) (*Users, error) {
+	c := &Users{
+		Server:   Server{Port: "50051", MetricsPort: "9051"},
+		Database: Database{MigrationsPath: "file://users/migrations"},
+		Auth:     defaultAuth(),
+		Tracing:  defaultTracing("users"),
+		TLS:      defaultTLS(),
+		Services: defaultServices(),
+	}
+	fields := c.fields()
+	if err := load("users", args, c, fields); err != nil {
+		return nil, err
+	}
+	if c.Server.Healthcheck {
+		return c, nil
+	}
+	v := &validator{}
+	v.required(fields)
+	v.ports(fields)
+	v.targets(fields)
+	v.auth(&c.Auth)
+	v.tracing(&c.Tracing)
+	v.tls(&c.TLS, "USERS", true)
+	return c, v.err()
+}
+
+type Books struct {
+	Server   Server   `yaml:"server"`
+	Database Database `yaml:"database"`
+	Auth     Auth     `yaml:"auth"`
+	Tracing  Tracing  `yaml:"tracing"`
+	TLS      TLS      `yaml:"tls"`
+	Services Services `yaml:"services"`
+}
+
+func (c *Books) fields() []field {
+	return concat(
+		c.Server.fields("BOOKS"),
+		c.Database.fields("BOOKS_DBURL", "BOOKS_MIGRATIONS_PATH"),
+		c.Auth.fields(),
+		c.Tracing.fields(),
+		c.TLS.fields("BOOKS"),
+		[]field{c.Services.usersField()},
+	)
+}
+
+func LoadBooks(args []string) (*Books, error) {
+	c := &Books{
+		Server:   Server{Port: "50052", MetricsPort: "9052"},
+		Database: Database{MigrationsPath: "file://books/migrations"},
+		Auth:     defaultAuth(),
+		Tracing:  defaultTracing("books"),
+		TLS:      defaultTLS(),
+		Services: defaultServices(),
+	}
+	fields := c.fields()
+	if err := load("books", args, c, fields); err != nil {
+		return nil, err
+	}
+	if c.Server.Healthcheck {
+		return c, nil
+	}
+	v := &validator{}
+	v.required(fields)
+	v.ports(fields)
+	v.targets(fields)
+	v.auth(&c.Auth)
+	v.tracing(&c.Tracing)
+	v.tls(&c.TLS, "BOOKS", true)
+	return c, v.err()
+}
+
+type Loans struct {
+	Server   Server   `yaml:"server"`
+	Database Database `yaml:"database"`
+	Auth     Auth     `yaml:"auth"`
+	Tracing  Tracing  `yaml:"tracing"`
+	TLS      TLS      `yaml:"tls"`
+	RabbitMQ RabbitMQ `yaml:"rabbitmq"`
+	Services Services `yaml:"services"`
+	// APIKey is used for calls to the other services. It is required: checking out and
+	// checking in copies is staff-only, so forwarding a patron's token would fail.
+	APIKey       string       `yaml:"api_key"`
+	CalendarFeed CalendarFeed `yaml:"calendar_feed"`
+}
+
+func (c *Loans) fields() []field {
+	return concat(
+		c.Server.fields("LOANS"),
+		c.Database.fields("LOANS_DBURL", "LOANS_MIGRATIONS_PATH"),
+		c.Auth.fields(),
+		c.Tracing.fields(),
+		c.TLS.fields("LOANS"),
+		c.RabbitMQ.fields(),
+		[]field{
+			c.Services.usersField(),
+			c.Services.booksField(),
+			c.Services.notificationsField(),
+			{key: "api_key", env: "LOANS_API_KEY", usage: "API key for calls to other services", value: &c.APIKey, required: true},
+			{key: "calendar_feed.secret", env: "ICAL_FEED_SECRET", usage: "secret for iCal feed tokens", value: &c.CalendarFeed.Secret},
+			{key: "calendar_feed.port", env: "LOANS_HTTP_PORT", flag: "feed-port", usage: "HTTP port of the iCal feed", value: &c.CalendarFeed.Port},
+			{key: "calendar_feed.url", env: "ICAL_FEED_URL", flag: "feed-url", usage: "public URL of the iCal feed", value: &c.CalendarFeed.URL},
+		},
+	)
+}
+
+func LoadLoans(args []string) (*Loans, error) {
+	c := &Loans{
+		Server:       Server{Port: "50053", MetricsPort: "9053"},
+		Database:     Database{MigrationsPath: "file://loans/migrations"},
+		Auth:         defaultAuth(),
+		Tracing:      defaultTracing("loans"),
+		TLS:          defaultTLS(),
+		Services:     defaultServices(),
+		CalendarFeed: CalendarFeed{Port: "8053"},
+	}
+	fields := c.fields()
+	if err := load("loans", args, c, fields); err != nil {
+		return nil, err
+	}
+	if c.CalendarFeed.URL == "" {
+		c.CalendarFeed.URL = "http://localhost:" + c.CalendarFeed.Port
+	}
+	if c.Server.Healthcheck {
+		return c, nil
+	}
+	v := &validator{}
+	v.required(fields)
+	v.ports(fields)
+	v.targets(fields)
+	v.auth(&c.Auth)
+	v.tracing(&c.Tracing)
+	v.tls(&c.TLS, "LOANS", true)
+	v.rabbitMQ(&c.RabbitMQ)
+	return c, v.err()
+}
+
+type Notifications struct {
+	Server   Server   `yaml:"server"`
+	Auth     Auth     `yaml:"auth"`
+	Tracing  Tracing  `yaml:"tracing"`
+	TLS      TLS      `yaml:"tls"`
+	RabbitMQ RabbitMQ `yaml:"rabbitmq"`
+	SMTP     SMTP     `yaml:"smtp"`
+	Services Services `yaml:"services"`
+}
+
+func (c *Notifications) fields() []field {
+	return concat(
+		c.Server.fields("NOTIFICATIONS"),
+		c.Auth.fields(),
+		c.Tracing.fields(),
+		c.TLS.fields("NOTIFICATIONS"),
+		c.RabbitMQ.fields(),
+		c.SMTP.fields(),
+		[]field{c.Services.usersField()},
+	)
+}
+
+func LoadNotifications(args []string) (*Notifications, error) {
+	c := &Notifications{
+		Server:   Server{Port: "50054", MetricsPort: "9054"},
+		Auth:     defaultAuth(),
+		Tracing:  defaultTracing("notifications"),
+		TLS:      defaultTLS(),
+		SMTP:     SMTP{Host: "smtp.gmail.com", Port: 465},
+		Services: defaultServices(),
+	}
+	fields := c.fields()
+	if err := load("notifications", args, c, fields); err != nil {
+		return nil, err
+	}
+	if c.Server.Healthcheck {
+		return c, nil
+	}
+	v := &validator{}
+	v.required(fields)
+	v.ports(fields)
+	v.targets(fields)
+	v.auth(&c.Auth)
+	v.tracing(&c.Tracing)
+	v.tls(&c.TLS, "NOTIFICATIONS", true)
+	v.rabbitMQ(&c.RabbitMQ)
+	v.smtp(&c.SMTP)
+	return c, v.err()
+}
+
+type Gateway struct {
+	Port     string   `yaml:"port"`
+	Tracing  Tracing  `yaml:"tracing"`
+	TLS      TLS      `yaml:"tls"`
+	Services Services `yaml:"services"`
+}
+
+func (c *Gateway) fields() []field {
+	return concat(
+		[]field{{key: "port", env: "GATEWAY_PORT", flag: "port", usage: "HTTP port", value: &c.Port, required: true}},
+		c.Tracing.fields(),
+		c.TLS.fields("GATEWAY"),
+		[]field{
+			c.Services.usersField(),
+			c.Services.booksField(),
+			c.Services.loansField(),
+			c.Services.notificationsField(),
+		},
+	)
+}
+
+func LoadGateway(args []string) (*Gateway, error) {
+	c := &Gateway{
+		Port:     "8080",
+		Tracing:  defaultTracing("gateway"),
+		TLS:      defaultTLS(),
+		Services: defaultServices(),
+	}
+	fields := c.fields()
+	if err := load("gateway", args, c, fields); err != nil {
+		return nil, err
+	}
+	v := &validator{}
+	v.required(fields)
+	v.ports(fields)
+	v.targets(fields)
+	v.tracing(&c.Tracing)
+	v.tls(&c.TLS, "GATEWAY", false)
+	return c, v.err()
+}
+
+type GraphQL struct {
+	Port     string   `yaml:"port"`
+	Auth     Auth     `yaml:"auth"`
+	Tracing  Tracing  `yaml:"tracing"`
+	TLS      TLS      `yaml:"tls"`
+	Services Services `yaml:"services"`
+}
+
+func (c *GraphQL) fields() []field {
+	return concat(
+		[]field{{key: "port", env: "GRAPHQL_PORT", flag: "port", usage: "HTTP port", value: &c.Port, required: true}},
+		c.Auth.fields(),
+		c.Tracing.fields(),
+		c.TLS.fields("GRAPHQL"),
+		[]field{
+			c.Services.usersField(),
+			c.Services.booksField(),
+			c.Services.loansField(),
+		},
+	)
+}
+
+func LoadGraphQL(args []string) (*GraphQL, error) {
+	c := &GraphQL{
+		Port:     "8081",
+		Auth:     defaultAuth(),
+		Tracing:  defaultTracing("graphql"),
+		TLS:      defaultTLS(),
+		Services: defaultServices(),
+	}
+	fields := c.fields()
+	if err := load("graphql", args, c, fields); err != nil {
+		return nil, err
+	}
+	v := &validator{}
+	v.required(fields)
+	v.ports(fields)
+	v.targets(fields)
+	v.auth(&c.Auth)
+	v.tracing(&c.Tracing)
+	v.tls(&c.TLS, "GRAPHQL", false)
+	return c, v.err()
+}
+
+func concat(groups ...[]field) []field {
+	var fields []field
+	for _, g := range groups {
+		fields = append(fields, g...)
+	}
+	return fields
+}
+
+// BookImport configures the catalog import command, a client of the books service.
+type BookImport struct {
+	File      string   `yaml:"file"`
+	Format    string   `yaml:"format"`
+	DryRun    bool     `yaml:"dry_run"`
+	ChunkSize int      `yaml:"chunk_size"`
+	Token     string   `yaml:"token"`
+	TLS       TLS      `yaml:"tls"`
+	Services  Services `yaml:"services"`
+}
+
+func (c *BookImport) fields() []field {
+	return concat(
+		[]field{
+			{key: "file", flag: "file", usage: "catalog file to import, - for standard input", value: &c.File, required: true},
+			{key: "format", flag: "format", usage: "csv or jsonl; taken from the file extension by default", value: &c.Format},
+			{key: "dry_run", flag: "dry-run", usage: "validate and match the rows without saving anything", value: &c.DryRun},
+			{key: "chunk_size", flag: "chunk-size", usage: "rows per stream message", value: &c.ChunkSize},
+			{key: "token", env: "LIBRARY_TOKEN", usage: "librarian access token or API key with books:write", value: &c.Token, required: true},
+		},
+		c.TLS.fields("IMPORT"),
+		[]field{c.Services.booksField()},
+	)
+}
+
+func LoadBookImport(args []string) (*BookImport, error) {
+	c := &BookImport{
+		ChunkSize: 500,
+		TLS:       defaultTLS(),
+		Services:  defaultServices(),
+	}
+	fields := c.fields()
+	if err := load("import", args, c, fields); err != nil {
+		return nil, err
+	}
+	v := &validator{}
+	v.required(fields)
+	v.targets(fields)
+	v.tls(&c.TLS, "IMPORT", false)
+	switch c.Format {
+	case "csv", "jsonl":
+	case "":
+		if c.File == "-" {
+			v.fail(fields[1], "is required when reading standard input")
+		}
+	default:
+		v.fail(fields[1], "must be csv or jsonl, got %q", c.Format)
+	}
+	if c.ChunkSize <= 0 {
+		v.fail(fields[3], "must be positive")
+	}
+	return c, v.err()
+}
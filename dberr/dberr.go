@@ -0,0 +1,157 @@
+package dberr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+const Domain = "library-system"
+
+// SQLSTATE codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	UniqueViolation      = "23505"
+	ForeignKeyViolation  = "23503"
+	CheckViolation       = "23514"
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+)
+
+// Reasons reported in google.rpc.ErrorInfo.
+const (
+	ReasonNotFound             = "NOT_FOUND"
+	ReasonUniqueViolation      = "UNIQUE_VIOLATION"
+	ReasonForeignKeyViolation  = "FOREIGN_KEY_VIOLATION"
+	ReasonCheckViolation       = "CHECK_VIOLATION"
+	ReasonSerializationFailure = "SERIALIZATION_FAILURE"
+)
+
+var keyColumns = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// ToStatus converts a database error into a gRPC status error.
+// entity is the singular resource name used in messages, e.g. "user" or "book".
+// Errors that are already gRPC statuses are returned unchanged; unknown errors become Internal.
+func ToStatus(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return withDetails(codes.NotFound, entity+" not found", &errdetails.ErrorInfo{
+			Reason:   ReasonNotFound,
+			Domain:   Domain,
+			Metadata: map[string]string{"entity": entity},
+		})
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, "database timeout")
+	}
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, "request canceled")
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	info := &errdetails.ErrorInfo{
+		Domain:   Domain,
+		Metadata: map[string]string{"entity": entity},
+	}
+	if pgErr.TableName != "" {
+		info.Metadata["table"] = pgErr.TableName
+	}
+	if pgErr.ConstraintName != "" {
+		info.Metadata["constraint"] = pgErr.ConstraintName
+	}
+	fields := Columns(pgErr)
+	if len(fields) > 0 {
+		info.Metadata["fields"] = strings.Join(fields, ",")
+	}
+
+	switch pgErr.Code {
+	case UniqueViolation:
+		info.Reason = ReasonUniqueViolation
+		msg := entity + " already exists"
+		if len(fields) > 0 {
+			msg = fmt.Sprintf("%s with this %s already exists", entity, strings.Join(fields, ", "))
+		}
+		return withDetails(codes.AlreadyExists, msg, info, badRequest(fields, "must be unique"))
+	case ForeignKeyViolation:
+		info.Reason = ReasonForeignKeyViolation
+		return withDetails(codes.FailedPrecondition, entity+" references a missing or still referenced record", info,
+			badRequest(fields, "references a missing or still referenced record"))
+	case CheckViolation:
+		info.Reason = ReasonCheckViolation
+		return withDetails(codes.InvalidArgument, fmt.Sprintf("%s violates constraint %s", entity, pgErr.ConstraintName), info,
+			badRequest(fields, "violates constraint "+pgErr.ConstraintName))
+	case SerializationFailure, DeadlockDetected:
+		info.Reason = ReasonSerializationFailure
+		return withDetails(codes.Aborted, "concurrent modification, please retry", info)
+	}
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// IsUniqueViolation reports whether err is a unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == UniqueViolation
+}
+
+// Columns returns the columns involved in a constraint violation.
+// Postgres only fills ColumnName for some errors, so the "Key (a, b)=(...)" detail is parsed as a fallback.
+func Columns(pgErr *pgconn.PgError) []string {
+	if pgErr.ColumnName != "" {
+		return []string{pgErr.ColumnName}
+	}
+	m := keyColumns.FindStringSubmatch(pgErr.Detail)
+	if m == nil {
+		return nil
+	}
+	cols := strings.Split(m[1], ",")
+	for i := range cols {
+		cols[i] = strings.TrimSpace(cols[i])
+	}
+	return cols
+}
+
+func badRequest(fields []string, description string) *errdetails.BadRequest {
+	if len(fields) == 0 {
+		return nil
+	}
+	br := &errdetails.BadRequest{}
+	for _, f := range fields {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       f,
+			Description: description,
+		})
+	}
+	return br
+}
+
+func withDetails(code codes.Code, msg string, info *errdetails.ErrorInfo, br ...*errdetails.BadRequest) error {
+	st := status.New(code, msg)
+	details := []protoadapt.MessageV1{info}
+	for _, b := range br {
+		if b != nil {
+			details = append(details, b)
+		}
+	}
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
@@ -0,0 +1,85 @@
+package dberr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus_Codes(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{name: "no rows", err: pgx.ErrNoRows, code: codes.NotFound, reason: ReasonNotFound},
+		{name: "wrapped no rows", err: fmt.Errorf("scan: %w", pgx.ErrNoRows), code: codes.NotFound, reason: ReasonNotFound},
+		{name: "unique violation", err: &pgconn.PgError{Code: UniqueViolation}, code: codes.AlreadyExists, reason: ReasonUniqueViolation},
+		{name: "foreign key violation", err: &pgconn.PgError{Code: ForeignKeyViolation}, code: codes.FailedPrecondition, reason: ReasonForeignKeyViolation},
+		{name: "check violation", err: &pgconn.PgError{Code: CheckViolation}, code: codes.InvalidArgument, reason: ReasonCheckViolation},
+		{name: "serialization failure", err: &pgconn.PgError{Code: SerializationFailure}, code: codes.Aborted, reason: ReasonSerializationFailure},
+		{name: "deadlock", err: &pgconn.PgError{Code: DeadlockDetected}, code: codes.Aborted, reason: ReasonSerializationFailure},
+		{name: "other pg error", err: &pgconn.PgError{Code: "42P01"}, code: codes.Internal},
+		{name: "timeout", err: context.DeadlineExceeded, code: codes.DeadlineExceeded},
+		{name: "unknown", err: errors.New("boom"), code: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(ToStatus(tt.err, "user"))
+			assert.Equal(t, tt.code, st.Code())
+			if tt.reason == "" {
+				assert.Empty(t, st.Details())
+				return
+			}
+			require.NotEmpty(t, st.Details())
+			info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+			require.True(t, ok)
+			assert.Equal(t, tt.reason, info.Reason)
+			assert.Equal(t, Domain, info.Domain)
+		})
+	}
+}
+
+func TestToStatus_UniqueViolationDetails(t *testing.T) {
+	err := ToStatus(&pgconn.PgError{
+		Code:           UniqueViolation,
+		TableName:      "users",
+		ConstraintName: "users_email_key",
+		Detail:         "Key (email)=(test@gmail.com) already exists.",
+	}, "user")
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.AlreadyExists, st.Code())
+	assert.Equal(t, "user with this email already exists", st.Message())
+	require.Len(t, st.Details(), 2)
+
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+	assert.Equal(t, "users_email_key", info.Metadata["constraint"])
+	assert.Equal(t, "users", info.Metadata["table"])
+
+	br := st.Details()[1].(*errdetails.BadRequest)
+	require.Len(t, br.FieldViolations, 1)
+	assert.Equal(t, "email", br.FieldViolations[0].Field)
+}
+
+func TestToStatus_PassesThroughStatus(t *testing.T) {
+	orig := status.Error(codes.PermissionDenied, "nope")
+	assert.Equal(t, orig, ToStatus(orig, "book"))
+	assert.NoError(t, ToStatus(nil, "book"))
+}
+
+func TestColumns(t *testing.T) {
+	assert.Equal(t, []string{"user_id", "book_id"}, Columns(&pgconn.PgError{Detail: "Key (user_id, book_id)=(1, 2) already exists."}))
+	assert.Equal(t, []string{"year"}, Columns(&pgconn.PgError{ColumnName: "year"}))
+	assert.Nil(t, Columns(&pgconn.PgError{Detail: "Failing row contains (1)."}))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/config"
+	gatewayserver "github.com/ViktorOHJ/library-system/gateway/server"
+	"github.com/ViktorOHJ/library-system/observability"
+	"github.com/ViktorOHJ/library-system/tlsconfig"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp:   true,
+		TimestampFormat: "2006-01-02 15:04:05",
+		ForceColors:     true,
+	})
+
+	cfg, err := config.LoadGateway(os.Args[1:])
+	if err != nil {
+		logger.Fatal(err)
+	}
+	tlsCreds, err := tlsconfig.Load(cfg.TLS, "gateway", logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure TLS: %v", err)
+	}
+	tlsCreds.Start(context.Background())
+
+	shutdownTracing, err := observability.InitTracing(context.Background(), observability.TracingConfig{
+		ServiceName: "gateway",
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+	}, logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure tracing: %v", err)
+	}
+	defer shutdownTracing()
+
+	endpoints := gatewayserver.Endpoints{
+		Users:         cfg.Services.Users,
+		Books:         cfg.Services.Books,
+		Loans:         cfg.Services.Loans,
+		Notifications: cfg.Services.Notifications,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler, err := gatewayserver.New(ctx, endpoints, logger,
+		tlsCreds.DialOption(),
+		observability.GRPCClientHandler())
+	if err != nil {
+		logger.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	PORT := cfg.Port
+	server := &http.Server{
+		Addr:              ":" + PORT,
+		Handler:           observability.HTTPHandler(handler, "gateway"),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatalf("Failed to serve: %v", err)
+		}
+	}()
+	logger.Infof("Gateway listening on port %s", PORT)
+
+	<-stop
+	logger.Info("Received shutdown signal, stopping gateway gracefully...")
+	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer shutdownCancel()
+	server.Shutdown(shutdownCtx)
+	logger.Info("Gateway stopped gracefully")
+}
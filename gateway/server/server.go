@@ -0,0 +1,112 @@
+package gatewayserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/ViktorOHJ/library-system/grpcclient"
+	"github.com/ViktorOHJ/library-system/protos/openapi"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/ViktorOHJ/library-system/requestid"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Endpoints are the addresses of the backend services, in any form accepted by grpcclient.
+type Endpoints struct {
+	Users         string
+	Books         string
+	Loans         string
+	Notifications string
+}
+
+// New returns the REST/JSON handler for all services and the OpenAPI spec at /openapi.json.
+// Connections to the backends are closed when ctx is done.
+func New(ctx context.Context, endpoints Endpoints, logger *logrus.Logger, opts ...grpc.DialOption) (http.Handler, error) {
+	gw := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeader),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeader),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithErrorHandler(errorHandler(logger)),
+	)
+
+	register := []struct {
+		endpoint string
+		fn       func(context.Context, *runtime.ServeMux, string, []grpc.DialOption) error
+	}{
+		{endpoints.Users, pb.RegisterUserServiceHandlerFromEndpoint},
+		{endpoints.Books, pb.RegisterBookServiceHandlerFromEndpoint},
+		{endpoints.Loans, pb.RegisterLoanServiceHandlerFromEndpoint},
+		{endpoints.Notifications, pb.RegisterNotificationServiceHandlerFromEndpoint},
+	}
+	for _, r := range register {
+		target, resolveOpts := grpcclient.Resolve(r.endpoint)
+		if err := r.fn(ctx, gw, target, append(resolveOpts, opts...)); err != nil {
+			return nil, err
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.json", serveSpec)
+	mux.Handle("/", withRequestID(gw))
+	return mux, nil
+}
+
+// withRequestID makes sure every request carries a valid X-Request-Id and echoes it back.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+			r.Header.Set(requestid.Header, id)
+		}
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// incomingHeader forwards the request id to the backends.
+// The Authorization header is always forwarded by the runtime as "authorization".
+func incomingHeader(key string) (string, bool) {
+	if strings.ToLower(key) == requestid.Header {
+		return requestid.Header, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeader drops the request id the backend echoes, withRequestID has already set it.
+func outgoingHeader(key string) (string, bool) {
+	if strings.ToLower(key) == requestid.Header {
+		return "", false
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// errorHandler logs server side failures and writes the status as JSON;
+// the HTTP status comes from runtime.HTTPStatusFromCode (NotFound -> 404, PermissionDenied -> 403, ...).
+func errorHandler(logger *logrus.Logger) runtime.ErrorHandlerFunc {
+	return func(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+		switch status.Code(err) {
+		case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+			logger.WithFields(logrus.Fields{
+				"method":     r.Method,
+				"path":       r.URL.Path,
+				"request_id": r.Header.Get(requestid.Header),
+			}).Errorf("Backend call failed: %v", err)
+		}
+		runtime.DefaultHTTPErrorHandler(ctx, mux, m, w, r, err)
+	}
+}
+
+func serveSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.Spec)
+}
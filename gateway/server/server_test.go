@@ -0,0 +1,157 @@
+package gatewayserver
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/ViktorOHJ/library-system/requestid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeBookServer struct {
+	pb.UnimplementedBookServiceServer
+	md metadata.MD
+}
+
+func (s *fakeBookServer) GetBook(ctx context.Context, req *pb.GetBookRequest) (*pb.BookResponse, error) {
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	switch req.BookId {
+	case "1":
+		return &pb.BookResponse{Id: "1", Title: "Dune", Author: "Frank Herbert", Year: 1965}, nil
+	case "403":
+		return nil, status.Error(codes.PermissionDenied, "librarian role required")
+	}
+	return nil, status.Error(codes.NotFound, "book not found")
+}
+
+func (s *fakeBookServer) CreateBook(ctx context.Context, req *pb.CreateBookRequest) (*pb.BookResponse, error) {
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	if req.Title == "" {
+		return nil, status.Error(codes.InvalidArgument, "title cannot be empty")
+	}
+	return &pb.BookResponse{Id: "2", Title: req.Title, Author: req.Author, Year: req.Year, Available: true}, nil
+}
+
+func newTestGateway(t *testing.T) (http.Handler, *fakeBookServer) {
+	lis := bufconn.Listen(1 << 20)
+	books := &fakeBookServer{}
+	srv := grpc.NewServer()
+	pb.RegisterBookServiceServer(srv, books)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	endpoint := "passthrough:///bufnet"
+	handler, err := New(ctx, Endpoints{Users: endpoint, Books: endpoint, Loans: endpoint, Notifications: endpoint}, logger,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	require.NoError(t, err)
+	return handler, books
+}
+
+func TestGateway_ForwardsAuthAndRequestID(t *testing.T) {
+	handler, books := newTestGateway(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/books/1", nil)
+	req.Header.Set("Authorization", "Bearer token-123")
+	req.Header.Set("X-Request-Id", "req-42")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "req-42", rec.Header().Get("X-Request-Id"))
+	assert.Equal(t, []string{"Bearer token-123"}, books.md.Get("authorization"))
+	assert.Equal(t, []string{"req-42"}, books.md.Get(requestid.Header))
+
+	var book map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &book))
+	assert.Equal(t, "Dune", book["title"])
+	assert.Equal(t, false, book["available"], "unpopulated fields are emitted")
+}
+
+func TestGateway_GeneratesRequestID(t *testing.T) {
+	handler, books := newTestGateway(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/books", strings.NewReader(`{"title":"Solaris","author":"Stanislaw Lem","year":1961}`))
+	req.Header.Set("X-Request-Id", "bad id\n")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	id := rec.Header().Get("X-Request-Id")
+	assert.True(t, requestid.Valid(id))
+	assert.NotEqual(t, "bad id\n", id)
+	assert.Equal(t, []string{id}, books.md.Get(requestid.Header))
+	assert.Empty(t, books.md.Get("authorization"))
+}
+
+func TestGateway_MapsStatusCodes(t *testing.T) {
+	handler, _ := newTestGateway(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{name: "NotFound", method: http.MethodGet, path: "/v1/books/7", want: http.StatusNotFound},
+		{name: "PermissionDenied", method: http.MethodGet, path: "/v1/books/403", want: http.StatusForbidden},
+		{name: "InvalidArgument", method: http.MethodPost, path: "/v1/books", body: `{"author":"Nobody"}`, want: http.StatusBadRequest},
+		{name: "Unimplemented", method: http.MethodPost, path: "/v1/loans", body: `{"userId":"1","bookId":"1"}`, want: http.StatusNotImplemented},
+		{name: "Malformed JSON", method: http.MethodPost, path: "/v1/books", body: `{"title":`, want: http.StatusBadRequest},
+		{name: "Unknown Route", method: http.MethodGet, path: "/v1/nothing", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code, rec.Body.String())
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Contains(t, body, "code")
+			assert.Contains(t, body, "message")
+		})
+	}
+}
+
+func TestGateway_ServesOpenAPISpec(t *testing.T) {
+	handler, _ := newTestGateway(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var spec struct {
+		Swagger string                    `json:"swagger"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+	assert.Equal(t, "2.0", spec.Swagger)
+	assert.Contains(t, spec.Paths, "/v1/books/{bookId}")
+	assert.Contains(t, spec.Paths["/v1/loans"], "post")
+	assert.Contains(t, spec.Paths, "/v1/auth/login")
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/auth"
+	bookclient "github.com/ViktorOHJ/library-system/books/client"
+	"github.com/ViktorOHJ/library-system/config"
+	graphqlserver "github.com/ViktorOHJ/library-system/graphql/server"
+	"github.com/ViktorOHJ/library-system/loans/clients"
+	"github.com/ViktorOHJ/library-system/observability"
+	"github.com/ViktorOHJ/library-system/tlsconfig"
+	userclient "github.com/ViktorOHJ/library-system/users/client"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp:   true,
+		TimestampFormat: "2006-01-02 15:04:05",
+		ForceColors:     true,
+	})
+
+	cfg, err := config.LoadGraphQL(os.Args[1:])
+	if err != nil {
+		logger.Fatal(err)
+	}
+	tlsCreds, err := tlsconfig.Load(cfg.TLS, "graphql", logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure TLS: %v", err)
+	}
+	tlsCreds.Start(context.Background())
+
+	shutdownTracing, err := observability.InitTracing(context.Background(), observability.TracingConfig{
+		ServiceName: "graphql",
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+	}, logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure tracing: %v", err)
+	}
+	defer shutdownTracing()
+
+	tokens, err := auth.NewTokenManager(cfg.Auth.JWTSecret, cfg.Auth.AccessTTL, cfg.Auth.RefreshTTL)
+	if err != nil {
+		logger.Fatalf("Failed to configure authentication: %v", err)
+	}
+
+	usersClient, err := userclient.NewUserClient(cfg.Services.Users, 10*time.Second, logger, tlsCreds.DialOption())
+	if err != nil {
+		logger.Fatalf("Failed to create users client: %v", err)
+	}
+	defer usersClient.Close()
+
+	booksClient, err := bookclient.NewBookClient(cfg.Services.Books, 10*time.Second, logger, tlsCreds.DialOption())
+	if err != nil {
+		logger.Fatalf("Failed to create books client: %v", err)
+	}
+	defer booksClient.Close()
+
+	loansClient, err := clients.NewLoansClient(cfg.Services.Loans, 10*time.Second, logger, tlsCreds.DialOption())
+	if err != nil {
+		logger.Fatalf("Failed to create loans client: %v", err)
+	}
+	defer loansClient.Close()
+
+	graphqlServer, err := graphqlserver.NewGraphQLServer(usersClient, booksClient, loansClient, tokens, logger)
+	if err != nil {
+		logger.Fatalf("Failed to parse GraphQL schema: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", graphqlServer)
+
+	PORT := cfg.Port
+	server := &http.Server{
+		Addr:              ":" + PORT,
+		Handler:           observability.HTTPHandler(mux, "graphql"),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatalf("Failed to serve: %v", err)
+		}
+	}()
+	logger.Infof("GraphQL server listening on port %s", PORT)
+
+	<-stop
+	logger.Info("Received shutdown signal, stopping server gracefully...")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+	logger.Info("Server stopped gracefully")
+}
@@ -0,0 +1,85 @@
+package graphqlserver
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/graph-gophers/dataloader/v7"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBookBatch matches the limit of BookService.GetBooks.
+const maxBookBatch = 100
+
+// loaders are created per request, so cached entries never leak between callers.
+type loaders struct {
+	users *dataloader.Loader[string, *pb.UserResponse]
+	books *dataloader.Loader[string, *pb.BookResponse]
+}
+
+type loadersKey struct{}
+
+func (s *GraphQLServer) newLoaders() *loaders {
+	return &loaders{
+		users: dataloader.NewBatchedLoader(s.loadUsers, dataloader.WithWait[string, *pb.UserResponse](2*time.Millisecond)),
+		books: dataloader.NewBatchedLoader(s.loadBooks,
+			dataloader.WithWait[string, *pb.BookResponse](2*time.Millisecond),
+			dataloader.WithBatchCapacity[string, *pb.BookResponse](maxBookBatch)),
+	}
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// loadBooks fetches a batch of books with one GetBooks call. Missing books resolve to nil.
+func (s *GraphQLServer) loadBooks(ctx context.Context, ids []string) []*dataloader.Result[*pb.BookResponse] {
+	results := make([]*dataloader.Result[*pb.BookResponse], len(ids))
+	// A malformed id would fail the whole GetBooks call, so such ids simply resolve to nil.
+	valid := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if n, err := strconv.Atoi(id); err == nil && n > 0 {
+			valid = append(valid, id)
+		}
+	}
+	books, err := s.books.GetBatch(ctx, valid)
+	if err != nil {
+		for i := range results {
+			results[i] = &dataloader.Result[*pb.BookResponse]{Error: err}
+		}
+		return results
+	}
+
+	byID := make(map[string]*pb.BookResponse, len(books))
+	for _, b := range books {
+		byID[b.Id] = b
+	}
+	for i, id := range ids {
+		results[i] = &dataloader.Result[*pb.BookResponse]{Data: byID[id]}
+	}
+	return results
+}
+
+// loadUsers fetches users concurrently. UserService has no batch lookup, so the loader
+// only removes duplicates (every loan of a user shares one call) and runs the rest in parallel.
+func (s *GraphQLServer) loadUsers(ctx context.Context, ids []string) []*dataloader.Result[*pb.UserResponse] {
+	results := make([]*dataloader.Result[*pb.UserResponse], len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := s.users.Get(ctx, id)
+			if status.Code(err) == codes.NotFound {
+				user, err = nil, nil
+			}
+			results[i] = &dataloader.Result[*pb.UserResponse]{Data: user, Error: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,214 @@
+package graphqlserver
+
+import (
+	"context"
+
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/protos/pb"
+	graphql "github.com/graph-gophers/graphql-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// resolverError exposes the gRPC status code of a failed call as extensions.code.
+type resolverError struct {
+	message string
+	code    codes.Code
+}
+
+func (e *resolverError) Error() string { return e.message }
+
+func (e *resolverError) Extensions() map[string]any {
+	return map[string]any{"code": e.code.String()}
+}
+
+func toGraphQLError(err error) error {
+	if err == nil {
+		return nil
+	}
+	st := status.Convert(err)
+	return &resolverError{message: st.Message(), code: st.Code()}
+}
+
+type rootResolver struct {
+	s *GraphQLServer
+}
+
+func (r *rootResolver) Me(ctx context.Context) (*userResolver, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, &resolverError{message: "missing credentials", code: codes.Unauthenticated}
+	}
+	if p.UserID == "" {
+		return nil, nil
+	}
+	return r.s.user(ctx, p.UserID)
+}
+
+func (r *rootResolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	return r.s.user(ctx, string(args.ID))
+}
+
+func (r *rootResolver) Book(ctx context.Context, args struct{ ID graphql.ID }) (*bookResolver, error) {
+	return r.s.book(ctx, string(args.ID))
+}
+
+func (r *rootResolver) Books(ctx context.Context, args struct{ IDs []graphql.ID }) ([]*bookResolver, error) {
+	keys := make([]string, len(args.IDs))
+	for i, id := range args.IDs {
+		keys[i] = string(id)
+	}
+	books, errs := loadersFrom(ctx).books.LoadMany(ctx, keys)()
+	res := make([]*bookResolver, 0, len(books))
+	for i, b := range books {
+		if errs != nil && errs[i] != nil {
+			return nil, toGraphQLError(errs[i])
+		}
+		if b != nil {
+			res = append(res, &bookResolver{b})
+		}
+	}
+	return res, nil
+}
+
+func (s *GraphQLServer) user(ctx context.Context, id string) (*userResolver, error) {
+	u, err := loadersFrom(ctx).users.Load(ctx, id)()
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+	if u == nil {
+		return nil, nil
+	}
+	return &userResolver{u: u, s: s}, nil
+}
+
+func (s *GraphQLServer) book(ctx context.Context, id string) (*bookResolver, error) {
+	b, err := loadersFrom(ctx).books.Load(ctx, id)()
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+	if b == nil {
+		return nil, nil
+	}
+	return &bookResolver{b}, nil
+}
+
+type userResolver struct {
+	u *pb.UserResponse
+	s *GraphQLServer
+}
+
+func (r *userResolver) ID() graphql.ID { return graphql.ID(r.u.Id) }
+func (r *userResolver) Name() string   { return r.u.Name }
+func (r *userResolver) Email() string  { return r.u.Email }
+func (r *userResolver) Active() bool   { return r.u.Active }
+func (r *userResolver) Role() string   { return r.u.Role }
+
+func (r *userResolver) Loans(ctx context.Context) ([]*loanResolver, error) {
+	loans, err := r.s.loans.ListLoans(ctx, r.u.Id)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+	res := make([]*loanResolver, len(loans))
+	for i, l := range loans {
+		res[i] = &loanResolver{l: l, s: r.s}
+	}
+	return res, nil
+}
+
+func (r *userResolver) LoanSummary(ctx context.Context) (*loanSummaryResolver, error) {
+	summary, err := r.s.loans.UserLoanSummary(ctx, r.u.Id)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+	return &loanSummaryResolver{summary}, nil
+}
+
+type bookResolver struct {
+	b *pb.BookResponse
+}
+
+func (r *bookResolver) ID() graphql.ID         { return graphql.ID(r.b.Id) }
+func (r *bookResolver) Title() string          { return r.b.Title }
+func (r *bookResolver) Author() string         { return r.b.Author }
+func (r *bookResolver) Year() int32            { return r.b.Year }
+func (r *bookResolver) Available() bool        { return r.b.Available }
+func (r *bookResolver) AvailableCopies() int32 { return r.b.AvailableCopies }
+func (r *bookResolver) TotalCopies() int32     { return r.b.TotalCopies }
+func (r *bookResolver) Isbn13() *string        { return optional(r.b.Isbn13) }
+func (r *bookResolver) Isbn10() *string        { return optional(r.b.Isbn10) }
+func (r *bookResolver) Withdrawn() bool        { return r.b.Withdrawn }
+
+func (r *bookResolver) Contributors() []*contributorResolver {
+	res := make([]*contributorResolver, len(r.b.Contributors))
+	for i, c := range r.b.Contributors {
+		res[i] = &contributorResolver{c}
+	}
+	return res
+}
+
+func (r *bookResolver) Subjects() []*subjectResolver {
+	res := make([]*subjectResolver, len(r.b.Subjects))
+	for i, subject := range r.b.Subjects {
+		res[i] = &subjectResolver{subject}
+	}
+	return res
+}
+
+func (r *bookResolver) Tags() []string { return r.b.Tags }
+
+type subjectResolver struct {
+	s *pb.Subject
+}
+
+func (r *subjectResolver) ID() graphql.ID       { return graphql.ID(r.s.Id) }
+func (r *subjectResolver) Name() string         { return r.s.Name }
+func (r *subjectResolver) ClassNumber() *string { return optional(r.s.ClassNumber) }
+
+type contributorResolver struct {
+	c *pb.Contributor
+}
+
+func (r *contributorResolver) AuthorID() graphql.ID { return graphql.ID(r.c.AuthorId) }
+func (r *contributorResolver) Name() string         { return r.c.Name }
+func (r *contributorResolver) Role() string         { return r.c.Role }
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+type loanResolver struct {
+	l *pb.Loan
+	s *GraphQLServer
+}
+
+func (r *loanResolver) ID() graphql.ID       { return graphql.ID(r.l.Id) }
+func (r *loanResolver) BorrowedDate() string { return r.l.BorrowedDate }
+func (r *loanResolver) DueDate() string      { return r.l.DueDate }
+func (r *loanResolver) Overdue() bool        { return r.l.Overdue }
+
+func (r *loanResolver) ItemID() *graphql.ID {
+	if r.l.ItemId == "" {
+		return nil
+	}
+	id := graphql.ID(r.l.ItemId)
+	return &id
+}
+
+func (r *loanResolver) User(ctx context.Context) (*userResolver, error) {
+	return r.s.user(ctx, r.l.UserId)
+}
+
+func (r *loanResolver) Book(ctx context.Context) (*bookResolver, error) {
+	return r.s.book(ctx, r.l.BookId)
+}
+
+type loanSummaryResolver struct {
+	s *pb.UserLoanSummaryResponse
+}
+
+func (r *loanSummaryResolver) ActiveLoans() int32  { return r.s.ActiveLoans }
+func (r *loanSummaryResolver) OverdueLoans() int32 { return r.s.OverdueLoans }
@@ -0,0 +1,100 @@
+package graphqlserver
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+	"strings"
+
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/ViktorOHJ/library-system/requestid"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed schema.graphql
+var schema string
+
+const maxDepth = 8
+
+type UserService interface {
+	Get(ctx context.Context, id string) (*pb.UserResponse, error)
+}
+
+type BookService interface {
+	GetBatch(ctx context.Context, ids []string) ([]*pb.BookResponse, error)
+}
+
+type LoanService interface {
+	ListLoans(ctx context.Context, userID string) ([]*pb.Loan, error)
+	UserLoanSummary(ctx context.Context, userID string) (*pb.UserLoanSummaryResponse, error)
+}
+
+// GraphQLServer answers composite queries by calling the gRPC services with the caller's credentials.
+type GraphQLServer struct {
+	users  UserService
+	books  BookService
+	loans  LoanService
+	tokens *auth.TokenManager
+	logger *logrus.Logger
+	schema *graphql.Schema
+}
+
+func NewGraphQLServer(users UserService, books BookService, loans LoanService, tokens *auth.TokenManager, logger *logrus.Logger) (*GraphQLServer, error) {
+	s := &GraphQLServer{
+		users:  users,
+		books:  books,
+		loans:  loans,
+		tokens: tokens,
+		logger: logger,
+	}
+	var err error
+	s.schema, err = graphql.ParseSchema(schema, &rootResolver{s: s}, graphql.MaxDepth(maxDepth))
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ServeHTTP handles POST requests with a JSON body {"query", "operationName", "variables"}.
+func (s *GraphQLServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.Header.Get(requestid.Header)
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+	w.Header().Set(requestid.Header, id)
+
+	ctx := requestid.WithID(r.Context(), id)
+	if p := s.principal(r); p != nil {
+		ctx = auth.WithPrincipal(ctx, p)
+	}
+	ctx = context.WithValue(ctx, loadersKey{}, s.newLoaders())
+
+	(&relay.Handler{Schema: s.schema}).ServeHTTP(w, r.WithContext(ctx))
+}
+
+// principal returns the caller for the bearer token, or nil for anonymous requests.
+// Backends verify the token again; here it is only decoded to know who "me" is.
+func (s *GraphQLServer) principal(r *http.Request) *auth.Principal {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil
+	}
+	if auth.IsAPIKey(token) {
+		return &auth.Principal{Token: token}
+	}
+	claims, err := s.tokens.Verify(token, auth.AccessToken)
+	if err != nil {
+		// Let the backends reject it with a proper Unauthenticated error.
+		return &auth.Principal{Token: token}
+	}
+	return &auth.Principal{UserID: claims.Subject, Email: claims.Email, Role: claims.Role, Token: token}
+}
@@ -0,0 +1,173 @@
+package graphqlserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeUsers struct {
+	mu     sync.Mutex
+	calls  []string
+	tokens []string
+}
+
+func (f *fakeUsers) Get(ctx context.Context, id string) (*pb.UserResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, id)
+	if p, ok := auth.FromContext(ctx); ok {
+		f.tokens = append(f.tokens, p.Token)
+	}
+	if id != "1" {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &pb.UserResponse{Id: "1", Name: "Anna", Email: "anna@example.com", Active: true, Role: "patron"}, nil
+}
+
+type fakeBooks struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (f *fakeBooks) GetBatch(ctx context.Context, ids []string) ([]*pb.BookResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, ids)
+	var books []*pb.BookResponse
+	for _, id := range ids {
+		if id == "99" {
+			continue
+		}
+		books = append(books, &pb.BookResponse{Id: id, Title: "Book " + id, Author: "Author"})
+	}
+	return books, nil
+}
+
+type fakeLoans struct{}
+
+func (fakeLoans) ListLoans(ctx context.Context, userID string) ([]*pb.Loan, error) {
+	return []*pb.Loan{
+		{Id: "10", UserId: userID, BookId: "1", DueDate: "2026-01-01", Overdue: true},
+		{Id: "11", UserId: userID, BookId: "2", DueDate: "2026-02-01"},
+		{Id: "12", UserId: userID, BookId: "99", DueDate: "2026-03-01"},
+	}, nil
+}
+
+func (fakeLoans) UserLoanSummary(ctx context.Context, userID string) (*pb.UserLoanSummaryResponse, error) {
+	return nil, status.Error(codes.PermissionDenied, "cannot act on behalf of another user")
+}
+
+func newTestServer(t *testing.T) (*GraphQLServer, *fakeUsers, *fakeBooks, *auth.TokenManager) {
+	tokens, err := auth.NewTokenManager("0123456789abcdef0123456789abcdef", time.Minute, time.Hour)
+	require.NoError(t, err)
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	users, books := &fakeUsers{}, &fakeBooks{}
+	s, err := NewGraphQLServer(users, books, fakeLoans{}, tokens, logger)
+	require.NoError(t, err)
+	return s, users, books, tokens
+}
+
+type gqlResponse struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func query(t *testing.T, s *GraphQLServer, token, q string) gqlResponse {
+	body, _ := json.Marshal(map[string]string{"query": q})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NotEmpty(t, rec.Header().Get("X-Request-Id"))
+
+	var res gqlResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	return res
+}
+
+func TestGraphQL_MyLoansAreBatched(t *testing.T) {
+	s, users, books, tokens := newTestServer(t)
+	pair, err := tokens.IssuePair("1", "anna@example.com", auth.RolePatron, 0)
+	require.NoError(t, err)
+
+	res := query(t, s, pair.AccessToken, `{
+		me {
+			name
+			loans { id dueDate overdue user { name } book { title } }
+		}
+	}`)
+	require.Empty(t, res.Errors)
+
+	me := res.Data["me"].(map[string]any)
+	assert.Equal(t, "Anna", me["name"])
+	loans := me["loans"].([]any)
+	require.Len(t, loans, 3)
+	first := loans[0].(map[string]any)
+	assert.Equal(t, true, first["overdue"])
+	assert.Equal(t, "Book 1", first["book"].(map[string]any)["title"])
+	assert.Equal(t, "Anna", first["user"].(map[string]any)["name"])
+	assert.Nil(t, loans[2].(map[string]any)["book"], "missing books resolve to null")
+
+	assert.Equal(t, []string{"1"}, users.calls, "one user lookup for me and every loan")
+	assert.Equal(t, []string{pair.AccessToken}, users.tokens, "caller's token is forwarded")
+	require.Len(t, books.batches, 1, "books of all loans are fetched in one call")
+	assert.ElementsMatch(t, []string{"1", "2", "99"}, books.batches[0])
+}
+
+func TestGraphQL_Errors(t *testing.T) {
+	s, _, books, tokens := newTestServer(t)
+	pair, err := tokens.IssuePair("1", "anna@example.com", auth.RolePatron, 0)
+	require.NoError(t, err)
+
+	res := query(t, s, "", `{ me { name } }`)
+	require.Len(t, res.Errors, 1)
+	assert.Equal(t, "Unauthenticated", res.Errors[0].Extensions["code"])
+
+	res = query(t, s, pair.AccessToken, `{ user(id: "7") { name } book(id: "abc") { title } }`)
+	require.Empty(t, res.Errors)
+	assert.Nil(t, res.Data["user"])
+	assert.Nil(t, res.Data["book"])
+	assert.Equal(t, []string{}, books.batches[0], "malformed ids are not sent to the book service")
+
+	res = query(t, s, pair.AccessToken, `{ me { loanSummary { activeLoans } } }`)
+	require.Len(t, res.Errors, 1)
+	assert.Equal(t, "PermissionDenied", res.Errors[0].Extensions["code"])
+	assert.Equal(t, "cannot act on behalf of another user", res.Errors[0].Message)
+}
+
+func TestGraphQL_APIKeyHasNoMe(t *testing.T) {
+	s, _, _, _ := newTestServer(t)
+
+	res := query(t, s, auth.APIKeyPrefix+"0123abcd_secret", `{ me { name } books(ids: ["1", "99"]) { title } }`)
+	require.Empty(t, res.Errors)
+	assert.Nil(t, res.Data["me"])
+	assert.Len(t, res.Data["books"], 1)
+}
+
+func TestGraphQL_OnlyPost(t *testing.T) {
+	s, _, _, _ := newTestServer(t)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/graphql?query={me{name}}", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,152 @@
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/observability"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultCooldown         = 10 * time.Second
+
+	// healthMethodPrefix marks the health service's methods, which bypass the breaker.
+	healthMethodPrefix = "/grpc.health.v1.Health/"
+)
+
+type State int
+
+const (
+	Closed State = iota
+	HalfOpen
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "open"
+	}
+}
+
+// Breaker stops calling a downstream service after threshold consecutive failures.
+// While open it fails calls immediately with UNAVAILABLE; after the cooldown a single
+// call is let through and its outcome closes or reopens the circuit.
+type Breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewBreaker(name string, threshold int, cooldown time.Duration) *Breaker {
+	b := &Breaker{name: name, threshold: threshold, cooldown: cooldown, now: time.Now}
+	observability.CircuitState(name, int(Closed))
+	return b
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Check is a readiness check that fails while the circuit is open and calls are rejected.
+// After the cooldown it passes again so that traffic can reach the trial call.
+func (b *Breaker) Check(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open && b.now().Sub(b.openedAt) < b.cooldown {
+		return fmt.Errorf("%s circuit breaker open", b.name)
+	}
+	return nil
+}
+
+// UnaryClientInterceptor guards every method but the health service's: a successful health
+// check says nothing about the calls that failed and must not close the circuit.
+func (b *Breaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if strings.HasPrefix(method, healthMethodPrefix) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		if !b.allow() {
+			observability.CircuitRejected(b.name)
+			return status.Errorf(codes.Unavailable, "%s is unavailable: circuit breaker open", b.name)
+		}
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		b.record(err)
+		return err
+	}
+}
+
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case Closed:
+		return true
+	case Open:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(HalfOpen)
+	}
+	if b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == HalfOpen {
+		b.probing = false
+	}
+	if !isFailure(err) {
+		b.failures = 0
+		b.setState(Closed)
+		return
+	}
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(Open)
+	}
+}
+
+func (b *Breaker) setState(state State) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	observability.CircuitState(b.name, int(state))
+}
+
+// isFailure tells whether err says something about the health of the downstream service.
+// Application errors such as NOT_FOUND mean the service answered and keep the circuit closed.
+func isFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+// Package grpcclient creates the connections the services use to call each other.
+//
+// An address is one of:
+//   - a bare port such as "50051", meaning localhost;
+//   - host:port, resolved through DNS so that every A record becomes a backend;
+//   - a gRPC target with a scheme, such as "dns:///books.internal:50052";
+//   - a comma-separated list of host:port pairs, used as a static set of replicas.
+//
+// Calls are spread across all resolved backends with round_robin. Dial adds the
+// resilience policy of the target service: per-method deadlines, retries of idempotent
+// methods and a circuit breaker. Connections are plaintext unless TLS credentials are
+// passed as an option, see tlsconfig.
+package grpcclient
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/healthcheck"
+	"github.com/ViktorOHJ/library-system/observability"
+	"github.com/ViktorOHJ/library-system/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// Resolve turns addr into a target for grpc.NewClient and the dial options it needs:
+// a resolver for static lists and the round_robin policy.
+func Resolve(addr string) (string, []grpc.DialOption) {
+	return resolve(addr, Service{})
+}
+
+func resolve(addr string, svc Service) (string, []grpc.DialOption) {
+	opts := []grpc.DialOption{grpc.WithDefaultServiceConfig(svc.serviceConfig())}
+
+	if !strings.Contains(addr, ",") {
+		return Target(addr), opts
+	}
+
+	var addrs []resolver.Address
+	for _, a := range strings.Split(addr, ",") {
+		addrs = append(addrs, resolver.Address{Addr: Target(strings.TrimSpace(a))})
+	}
+	// Resolvers passed with WithResolvers are scoped to the connection, so the scheme can be shared.
+	r := manual.NewBuilderWithScheme("static")
+	r.InitialState(resolver.State{Addresses: addrs})
+	// The replicas share a name, and TLS verifies the servers against it.
+	host, _, err := net.SplitHostPort(addrs[0].Addr)
+	if err == nil {
+		opts = append(opts, grpc.WithAuthority(host))
+	}
+	return r.Scheme() + ":///" + addr, append(opts, grpc.WithResolvers(r))
+}
+
+// Target expands a bare port to localhost and leaves everything else to the default resolver.
+func Target(addr string) string {
+	if _, err := strconv.Atoi(addr); err == nil {
+		return "localhost:" + addr
+	}
+	return addr
+}
+
+// Conn is a shared connection to another service together with its circuit breaker.
+type Conn struct {
+	*grpc.ClientConn
+	Breaker *Breaker
+}
+
+// Dial connects to another service with the interceptors every internal call needs:
+// credential and request id forwarding, tracing and the circuit breaker of svc.
+// The connection is lazy and shared; callers create it once at startup and close it on shutdown.
+func Dial(addr string, svc Service, opts ...grpc.DialOption) (*Conn, error) {
+	breaker := NewBreaker(svc.Name, defaultFailureThreshold, defaultCooldown)
+	target, resolveOpts := resolve(addr, svc)
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(
+			auth.UnaryClientInterceptor(),
+			requestid.UnaryClientInterceptor(),
+			breaker.UnaryClientInterceptor(),
+		),
+		grpc.WithChainStreamInterceptor(auth.StreamClientInterceptor()),
+		observability.GRPCClientHandler(),
+	}, resolveOpts...)
+	conn, err := grpc.NewClient(target, append(dialOpts, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{ClientConn: conn, Breaker: breaker}, nil
+}
+
+// Ping is the readiness check of the service behind the connection: it fails while the
+// circuit breaker is open, and otherwise asks the service's health endpoint.
+func (c *Conn) Ping(ctx context.Context) error {
+	if err := c.Breaker.Check(ctx); err != nil {
+		return err
+	}
+	return healthcheck.GRPC(c.ClientConn)(ctx)
+}
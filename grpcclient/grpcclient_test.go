@@ -0,0 +1,223 @@
+package grpcclient
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+var healthService = healthpb.Health_ServiceDesc.ServiceName
+
+func TestTarget(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{addr: "50051", want: "localhost:50051"},
+		{addr: "users:50051", want: "users:50051"},
+		{addr: "dns:///users.internal:50051", want: "dns:///users.internal:50051"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, Target(tt.addr))
+		})
+	}
+}
+
+// startReplica serves the health service and counts the calls it receives.
+func startReplica(t *testing.T) (string, *atomic.Int64) {
+	return startServer(t, func(int64) error { return nil })
+}
+
+// startServer serves the health service; fail is called with the number of each call
+// and may return an error to send instead of the response.
+func startServer(t *testing.T, fail func(call int64) error) (string, *atomic.Int64) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	var calls atomic.Int64
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := fail(calls.Add(1)); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String(), &calls
+}
+
+func TestDial_BalancesAcrossReplicas(t *testing.T) {
+	addr1, calls1 := startReplica(t)
+	addr2, calls2 := startReplica(t)
+
+	conn, err := Dial(addr1+","+addr2, Service{})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := healthpb.NewHealthClient(conn)
+	for range 10 {
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int64(10), calls1.Load()+calls2.Load())
+	assert.Positive(t, calls1.Load(), "first replica receives calls")
+	assert.Positive(t, calls2.Load(), "second replica receives calls")
+}
+
+func TestDial_SingleAddress(t *testing.T) {
+	addr, calls := startReplica(t)
+	_, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	conn, err := Dial(port, Service{})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), calls.Load())
+}
+
+func TestDial_RetriesIdempotentMethods(t *testing.T) {
+	unavailable := func(call int64) error {
+		if call < 3 {
+			return status.Error(codes.Unavailable, "restarting")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name      string
+		svc       Service
+		wantCode  codes.Code
+		wantCalls int64
+	}{
+		{name: "idempotent", svc: Service{Name: healthService, Idempotent: []string{"Check"}}, wantCode: codes.OK, wantCalls: 3},
+		{name: "not idempotent", svc: Service{Name: healthService}, wantCode: codes.Unavailable, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, calls := startServer(t, unavailable)
+			conn, err := Dial(addr, tt.svc)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestDial_MethodDeadline(t *testing.T) {
+	addr, _ := startServer(t, func(int64) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	conn, err := Dial(addr, Service{
+		Name:     healthService,
+		Timeout:  time.Minute,
+		Timeouts: map[string]time.Duration{"Check": 50 * time.Millisecond},
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	start := time.Now()
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker("library.BookService", 2, 10*time.Second)
+	b.now = func() time.Time { return now }
+	intercept := b.UnaryClientInterceptor()
+
+	var invoked int
+	call := func(err error) error {
+		return intercept(context.Background(), "/library.BookService/GetBook", nil, nil, nil,
+			func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+				invoked++
+				return err
+			})
+	}
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	assert.Equal(t, codes.NotFound, status.Code(call(status.Error(codes.NotFound, "book not found"))))
+	assert.Equal(t, Closed, b.State(), "application errors do not count")
+
+	call(unavailable)
+	assert.Equal(t, Closed, b.State())
+	call(unavailable)
+	assert.Equal(t, Open, b.State())
+
+	invoked = 0
+	err := call(nil)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.ErrorContains(t, err, "circuit breaker open")
+	assert.Zero(t, invoked, "open circuit fails fast")
+
+	now = now.Add(10 * time.Second)
+	call(unavailable)
+	assert.Equal(t, 1, invoked, "one probe after the cooldown")
+	assert.Equal(t, Open, b.State(), "failed probe reopens")
+
+	now = now.Add(10 * time.Second)
+	assert.NoError(t, call(nil))
+	assert.Equal(t, Closed, b.State(), "successful probe closes")
+}
+
+func TestBreaker_HealthChecks(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker("library.BookService", 1, 10*time.Second)
+	b.now = func() time.Time { return now }
+	intercept := b.UnaryClientInterceptor()
+	call := func(method string, err error) error {
+		return intercept(context.Background(), method, nil, nil, nil,
+			func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error { return err })
+	}
+
+	require.NoError(t, b.Check(context.Background()))
+	call("/library.BookService/GetBook", status.Error(codes.Unavailable, "connection refused"))
+	assert.ErrorContains(t, b.Check(context.Background()), "circuit breaker open")
+
+	// Health probes are not rejected and do not close the circuit.
+	assert.NoError(t, call("/grpc.health.v1.Health/Check", nil))
+	assert.Equal(t, Open, b.State())
+
+	now = now.Add(10 * time.Second)
+	assert.NoError(t, b.Check(context.Background()), "passes once a trial call is allowed")
+	assert.Equal(t, Open, b.State())
+}
+
+func TestConn_Ping(t *testing.T) {
+	addr, calls := startReplica(t)
+	conn, err := Dial(addr, Service{Name: "library.BookService"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.Ping(context.Background()))
+	assert.Equal(t, int64(1), calls.Load())
+
+	for range defaultFailureThreshold {
+		conn.Breaker.record(status.Error(codes.Unavailable, "connection refused"))
+	}
+	require.Equal(t, Open, conn.Breaker.State())
+	assert.ErrorContains(t, conn.Ping(context.Background()), "circuit breaker open")
+	assert.Equal(t, int64(1), calls.Load(), "an open circuit fails the check without a call")
+}
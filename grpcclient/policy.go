@@ -0,0 +1,118 @@
+package grpcclient
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Retry policy for idempotent methods. gRPC retries only on UNAVAILABLE, which means
+// the request did not reach the server or was refused before any work was done.
+const (
+	maxAttempts       = 3
+	initialBackoff    = 100 * time.Millisecond
+	maxBackoff        = time.Second
+	backoffMultiplier = 2
+)
+
+// Service describes the calls made to one downstream service.
+type Service struct {
+	// Name is the full gRPC service name, such as library.BookService.
+	Name string
+	// Idempotent methods are safe to send again and are retried.
+	Idempotent []string
+	// Timeout bounds every call, retries included. Timeouts overrides it per method.
+	Timeout  time.Duration
+	Timeouts map[string]time.Duration
+}
+
+type methodName struct {
+	Service string `json:"service"`
+	Method  string `json:"method,omitempty"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+type methodConfig struct {
+	Name        []methodName `json:"name"`
+	Timeout     string       `json:"timeout,omitempty"`
+	RetryPolicy *retryPolicy `json:"retryPolicy,omitempty"`
+}
+
+type serviceConfig struct {
+	LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig"`
+	MethodConfig        []methodConfig        `json:"methodConfig,omitempty"`
+	// RetryThrottling stops retries when most calls fail, so that retries do not
+	// multiply the load on a service that is already struggling.
+	RetryThrottling *retryThrottling `json:"retryThrottling,omitempty"`
+}
+
+type retryThrottling struct {
+	MaxTokens  int     `json:"maxTokens"`
+	TokenRatio float64 `json:"tokenRatio"`
+}
+
+// serviceConfig renders the gRPC service config for svc: round_robin balancing,
+// per-method deadlines and retries for idempotent methods.
+func (svc Service) serviceConfig() string {
+	cfg := serviceConfig{
+		LoadBalancingConfig: []map[string]struct{}{{"round_robin": {}}},
+	}
+	if svc.Name != "" {
+		cfg.MethodConfig = append(cfg.MethodConfig, methodConfig{
+			Name:    []methodName{{Service: svc.Name}},
+			Timeout: duration(svc.Timeout),
+		})
+
+		methods := slices.Clone(svc.Idempotent)
+		for m := range svc.Timeouts {
+			if !slices.Contains(methods, m) {
+				methods = append(methods, m)
+			}
+		}
+		slices.Sort(methods)
+		for _, m := range methods {
+			mc := methodConfig{
+				Name:    []methodName{{Service: svc.Name, Method: m}},
+				Timeout: duration(svc.Timeout),
+			}
+			if t, ok := svc.Timeouts[m]; ok {
+				mc.Timeout = duration(t)
+			}
+			if slices.Contains(svc.Idempotent, m) {
+				mc.RetryPolicy = &retryPolicy{
+					MaxAttempts:          maxAttempts,
+					InitialBackoff:       duration(initialBackoff),
+					MaxBackoff:           duration(maxBackoff),
+					BackoffMultiplier:    backoffMultiplier,
+					RetryableStatusCodes: []string{"UNAVAILABLE"},
+				}
+			}
+			cfg.MethodConfig = append(cfg.MethodConfig, mc)
+		}
+		if len(svc.Idempotent) > 0 {
+			cfg.RetryThrottling = &retryThrottling{MaxTokens: 10, TokenRatio: 0.1}
+		}
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+// duration formats d the way the service config expects, e.g. "0.1s". Zero means no deadline.
+func duration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
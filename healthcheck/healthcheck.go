@@ -0,0 +1,186 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	defaultInterval = 10 * time.Second
+	defaultTimeout  = 3 * time.Second
+)
+
+// Check reports whether a dependency is usable. A nil error means healthy.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker serves grpc.health.v1 and keeps the status of the process and of every
+// registered service in line with its dependency checks: a single failing check
+// marks all of them NOT_SERVING.
+type Checker struct {
+	server   *health.Server
+	services []string
+	checks   []namedCheck
+	interval time.Duration
+	timeout  time.Duration
+	logger   *logrus.Logger
+
+	mu      sync.Mutex
+	failing map[string]error
+}
+
+// New creates a checker for the given gRPC service names. Until the first round
+// of checks completes, everything is reported as NOT_SERVING.
+func New(logger *logrus.Logger, services ...string) *Checker {
+	c := &Checker{
+		server:   health.NewServer(),
+		services: services,
+		interval: defaultInterval,
+		timeout:  defaultTimeout,
+		logger:   logger,
+	}
+	c.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	return c
+}
+
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+func (c *Checker) Register(s *grpc.Server) {
+	healthpb.RegisterHealthServer(s, c.server)
+}
+
+// Start runs the checks right away and then every interval until ctx is done.
+func (c *Checker) Start(ctx context.Context) {
+	c.Run(ctx)
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.Run(ctx)
+			}
+		}
+	}()
+}
+
+// Run performs one round of checks concurrently and updates the serving status.
+func (c *Checker) Run(ctx context.Context) {
+	errs := make([]error, len(c.checks))
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+			errs[i] = nc.check(checkCtx)
+		}()
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	failing := make(map[string]error)
+	for i, nc := range c.checks {
+		if errs[i] == nil {
+			if _, was := c.failing[nc.name]; was {
+				c.logger.Infof("Health check %s recovered", nc.name)
+			}
+			continue
+		}
+		failing[nc.name] = errs[i]
+		if _, was := c.failing[nc.name]; !was {
+			c.logger.Warnf("Health check %s failed: %v", nc.name, errs[i])
+		}
+	}
+	c.failing = failing
+
+	if len(failing) == 0 {
+		c.setStatus(healthpb.HealthCheckResponse_SERVING)
+	} else {
+		c.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+}
+
+// Err returns the failures of the last round, or nil if everything was healthy.
+func (c *Checker) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for _, nc := range c.checks {
+		if err, ok := c.failing[nc.name]; ok {
+			errs = append(errs, fmt.Errorf("%s: %w", nc.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown reports NOT_SERVING from now on, so that load balancers stop sending
+// traffic while the server drains.
+func (c *Checker) Shutdown() {
+	c.server.Shutdown()
+}
+
+func (c *Checker) setStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	c.server.SetServingStatus("", status)
+	for _, s := range c.services {
+		c.server.SetServingStatus(s, status)
+	}
+}
+
+// GRPC checks the health service of a downstream server over an existing connection.
+func GRPC(conn grpc.ClientConnInterface) Check {
+	client := healthpb.NewHealthClient(conn)
+	return func(ctx context.Context) error {
+		res, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			return err
+		}
+		if res.Status != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("status %s", res.Status)
+		}
+		return nil
+	}
+}
+
+// Probe asks the server at addr whether it is serving. It backs the -healthcheck flag.
+// Options given after the defaults override them, e.g. TLS credentials.
+func Probe(ctx context.Context, addr string, opts ...grpc.DialOption) error {
+	conn, err := grpc.NewClient(addr, append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return GRPC(conn)(ctx)
+}
+
+// RunProbe probes addr, prints the result and returns the exit code for main.
+func RunProbe(addr string, opts ...grpc.DialOption) int {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	if err := Probe(ctx, addr, opts...); err != nil {
+		fmt.Fprintf(os.Stderr, "%s is not serving: %v\n", addr, err)
+		return 1
+	}
+	fmt.Printf("%s is serving\n", addr)
+	return 0
+}
@@ -0,0 +1,89 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const testService = "library.BookService"
+
+func startServer(t *testing.T, checker *Checker) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	checker.Register(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func status(t *testing.T, addr, service string) healthpb.HealthCheckResponse_ServingStatus {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	res, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return res.Status
+}
+
+func TestChecker(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var dbErr error
+	checker := New(logger, testService)
+	checker.Add("database", func(ctx context.Context) error { return dbErr })
+	checker.Add("rabbitmq", func(ctx context.Context) error { return nil })
+	addr := startServer(t, checker)
+
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, addr, ""), "not ready before the first round")
+
+	checker.Run(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(t, addr, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(t, addr, testService))
+	assert.NoError(t, checker.Err())
+
+	dbErr = errors.New("connection refused")
+	checker.Run(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, addr, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, addr, testService))
+	assert.EqualError(t, checker.Err(), "database: connection refused")
+
+	dbErr = nil
+	checker.Run(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(t, addr, testService))
+
+	checker.Shutdown()
+	checker.Run(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, addr, testService), "stays down while draining")
+}
+
+func TestProbe(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	checker := New(logger, testService)
+	addr := startServer(t, checker)
+	assert.ErrorContains(t, Probe(context.Background(), addr), "status NOT_SERVING")
+	assert.Equal(t, 1, RunProbe(addr))
+
+	checker.Run(context.Background())
+	assert.NoError(t, Probe(context.Background(), addr))
+	assert.Equal(t, 0, RunProbe(addr))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closed := lis.Addr().String()
+	lis.Close()
+	assert.Error(t, Probe(context.Background(), closed))
+}
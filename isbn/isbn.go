@@ -0,0 +1,103 @@
+// Package isbn validates International Standard Book Numbers and converts between
+// the ten and thirteen digit forms. Books are stored by their ISBN-13.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrLength   = errors.New("ISBN must have 10 or 13 digits")
+	ErrChars    = errors.New("ISBN may contain only digits, hyphens and spaces, and X as the last ISBN-10 digit")
+	ErrPrefix   = errors.New("ISBN-13 must start with 978 or 979")
+	ErrChecksum = errors.New("ISBN check digit does not match")
+)
+
+// Normalize accepts an ISBN-10 or ISBN-13 as printed or scanned, with optional
+// "ISBN", "ISBN-10" or "ISBN-13" prefix, hyphens and spaces, and returns its ISBN-13 digits.
+func Normalize(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 4 && strings.EqualFold(s[:4], "ISBN") {
+		s = s[4:]
+		// The form is only a label when a colon or space follows; "ISBN-1306406153" is
+		// the ISBN-10 1306406153.
+		for _, form := range []string{"-10", "-13"} {
+			if rest, ok := strings.CutPrefix(s, form); ok && (rest == "" || rest[0] == ':' || rest[0] == ' ') {
+				s = rest
+				break
+			}
+		}
+		s = strings.TrimLeft(s, "-:")
+	}
+	digits := make([]byte, 0, 13)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case c == 'X' || c == 'x':
+			digits = append(digits, 'X')
+		case c == '-' || c == ' ':
+		default:
+			return "", ErrChars
+		}
+	}
+	if i := strings.IndexByte(string(digits), 'X'); i >= 0 && (len(digits) != 10 || i != 9) {
+		return "", ErrChars
+	}
+
+	switch len(digits) {
+	case 10:
+		if check10(digits[:9]) != digits[9] {
+			return "", ErrChecksum
+		}
+		isbn13 := append([]byte("978"), digits[:9]...)
+		return string(append(isbn13, check13(isbn13))), nil
+	case 13:
+		if prefix := string(digits[:3]); prefix != "978" && prefix != "979" {
+			return "", ErrPrefix
+		}
+		if check13(digits[:12]) != digits[12] {
+			return "", ErrChecksum
+		}
+		return string(digits), nil
+	default:
+		return "", ErrLength
+	}
+}
+
+// To10 returns the ISBN-10 of a normalized ISBN-13. Only 978 numbers have one.
+func To10(isbn13 string) (string, bool) {
+	if len(isbn13) != 13 || !strings.HasPrefix(isbn13, "978") {
+		return "", false
+	}
+	body := []byte(isbn13[3:12])
+	return string(append(body, check10(body))), true
+}
+
+// check10 computes the ISBN-10 check digit of the first nine digits (mod 11, 10 is X).
+func check10(body []byte) byte {
+	sum := 0
+	for i, c := range body {
+		sum += (10 - i) * int(c-'0')
+	}
+	switch r := (11 - sum%11) % 11; r {
+	case 10:
+		return 'X'
+	default:
+		return byte('0' + r)
+	}
+}
+
+// check13 computes the EAN-13 check digit of the first twelve digits.
+func check13(body []byte) byte {
+	sum := 0
+	for i, c := range body {
+		w := 1
+		if i%2 == 1 {
+			w = 3
+		}
+		sum += w * int(c-'0')
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,73 @@
+package isbn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		err   error
+	}{
+		{name: "isbn-13", input: "9780134190440", want: "9780134190440"},
+		{name: "isbn-13 hyphenated", input: "978-0-13-419044-0", want: "9780134190440"},
+		{name: "isbn-10", input: "0134190440", want: "9780134190440"},
+		{name: "isbn-10 with prefix and spaces", input: "ISBN: 0 13 419044 0", want: "9780134190440"},
+		{name: "isbn-13 with form prefix", input: "ISBN-13: 978-0-306-40615-7", want: "9780306406157"},
+		{name: "isbn-10 with form prefix", input: "ISBN-10: 0-306-40615-2", want: "9780306406157"},
+		{name: "form prefix without colon", input: "isbn-13 9780306406157", want: "9780306406157"},
+		{name: "hyphen prefix before digits", input: "ISBN-1306406153", want: "9781306406154"},
+		{name: "isbn-10 check digit X", input: "0-8044-2957-X", want: "9780804429573"},
+		{name: "isbn-10 lowercase x", input: "080442957x", want: "9780804429573"},
+		{name: "979 prefix", input: "979-10-90636-07-1", want: "9791090636071"},
+		{name: "bad isbn-13 checksum", input: "9780134190441", err: ErrChecksum},
+		{name: "bad isbn-10 checksum", input: "0134190441", err: ErrChecksum},
+		{name: "not a bookland prefix", input: "4006381333931", err: ErrPrefix},
+		{name: "too short", input: "978013419", err: ErrLength},
+		{name: "empty", input: "", err: ErrLength},
+		{name: "letters", input: "97801341904a0", err: ErrChars},
+		{name: "X not last", input: "08044X2957", err: ErrChars},
+		{name: "X in isbn-13", input: "978013419044X", err: ErrChars},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.input)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTo10(t *testing.T) {
+	tests := []struct {
+		isbn13 string
+		want   string
+		ok     bool
+	}{
+		{isbn13: "9780134190440", want: "0134190440", ok: true},
+		{isbn13: "9780804429573", want: "080442957X", ok: true},
+		{isbn13: "9791090636071"},
+		{isbn13: "978013419"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.isbn13, func(t *testing.T) {
+			got, ok := To10(tt.isbn13)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+			if ok {
+				back, err := Normalize(got)
+				require.NoError(t, err)
+				assert.Equal(t, tt.isbn13, back)
+			}
+		})
+	}
+}
@@ -4,21 +4,33 @@ import (
 	"context"
 	"time"
 
+	"github.com/ViktorOHJ/library-system/grpcclient"
 	"github.com/ViktorOHJ/library-system/protos/pb"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
+// service sets the deadlines and retries of every call made by this client.
+var service = grpcclient.Service{
+	Name:       pb.LoanService_ServiceDesc.ServiceName,
+	Idempotent: []string{"GetUserLoanSummary", "ListUserLoans"},
+	// BorrowBook and ReturnBook call three services themselves.
+	Timeout: 10 * time.Second,
+	Timeouts: map[string]time.Duration{
+		"GetUserLoanSummary": 3 * time.Second,
+		"ListUserLoans":      3 * time.Second,
+	},
+}
+
 type LoansClient struct {
 	client  pb.LoanServiceClient
 	timeout time.Duration
-	conn    *grpc.ClientConn
+	conn    *grpcclient.Conn
 	logger  *logrus.Logger
 }
 
-func NewLoansClient(addr string, timeout time.Duration, logger *logrus.Logger) (*LoansClient, error) {
-	conn, err := grpc.Dial("localhost:"+addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewLoansClient(addr string, timeout time.Duration, logger *logrus.Logger, opts ...grpc.DialOption) (*LoansClient, error) {
+	conn, err := grpcclient.Dial(addr, service, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +53,17 @@ func (c *LoansClient) Borrow(ctx context.Context, uID, bID string) (*pb.LoanResp
 	})
 }
 
+// BorrowItem lends a specific copy, e.g. one scanned at the desk.
+func (c *LoansClient) BorrowItem(ctx context.Context, uID, itemID string) (*pb.LoanResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.BorrowBook(ctx, &pb.BorrowRequest{
+		UserId: uID,
+		ItemId: itemID,
+	})
+}
+
 func (c *LoansClient) Return(ctx context.Context, id string) (*pb.LoanResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
@@ -50,6 +73,34 @@ func (c *LoansClient) Return(ctx context.Context, id string) (*pb.LoanResponse,
 	})
 }
 
+func (c *LoansClient) UserLoanSummary(ctx context.Context, userID string) (*pb.UserLoanSummaryResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.GetUserLoanSummary(ctx, &pb.UserLoanSummaryRequest{
+		UserId: userID,
+	})
+}
+
+func (c *LoansClient) ListLoans(ctx context.Context, userID string) ([]*pb.Loan, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.ListUserLoans(ctx, &pb.ListUserLoansRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Loans, nil
+}
+
+// Ping fails while the circuit breaker is open and otherwise asks the service's health
+// endpoint whether it is serving.
+func (c *LoansClient) Ping(ctx context.Context) error {
+	return c.conn.Ping(ctx)
+}
+
 func (c *LoansClient) Close() error {
 	return c.conn.Close()
 }
@@ -3,13 +3,24 @@ package main
 import (
 	"context"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/ViktorOHJ/library-system/auth"
+	bookclient "github.com/ViktorOHJ/library-system/books/client"
+	"github.com/ViktorOHJ/library-system/config"
+	"github.com/ViktorOHJ/library-system/healthcheck"
 	loansserver "github.com/ViktorOHJ/library-system/loans/server"
+	notificlient "github.com/ViktorOHJ/library-system/notifications/client"
+	"github.com/ViktorOHJ/library-system/observability"
 	pb "github.com/ViktorOHJ/library-system/protos/pb"
-	"github.com/joho/godotenv"
+	"github.com/ViktorOHJ/library-system/rabbit"
+	"github.com/ViktorOHJ/library-system/requestid"
+	"github.com/ViktorOHJ/library-system/tlsconfig"
+	userclient "github.com/ViktorOHJ/library-system/users/client"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 )
@@ -22,19 +33,30 @@ func main() {
 		ForceColors:     true,
 	})
 
-	err := godotenv.Load(".env")
+	cfg, err := config.LoadLoans(os.Args[1:])
 	if err != nil {
-		logger.Fatalf("Error loading .env file: %v", err)
+		logger.Fatal(err)
 	}
-	dbUrl := os.Getenv("LOANS_DBURL")
-	if dbUrl == "" {
-		logger.Fatalf("LOANS_DBURL not set in .env file: %v", err)
+	tlsCreds, err := tlsconfig.Load(cfg.TLS, "loans", logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure TLS: %v", err)
+	}
+	if cfg.Server.Healthcheck {
+		os.Exit(healthcheck.RunProbe("localhost:"+cfg.Server.Port, tlsCreds.DialOption()))
 	}
-	path := os.Getenv("LOANS_MIGRATIONS_PATH")
-	if path == "" {
-		logger.Info("use default value for loans migrations path")
-		path = "file://loans/migrations"
+	tlsCreds.Start(context.Background())
+
+	shutdownTracing, err := observability.InitTracing(context.Background(), observability.TracingConfig{
+		ServiceName: "loans",
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+	}, logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure tracing: %v", err)
 	}
+	defer shutdownTracing()
+	dbUrl := cfg.Database.URL
+	path := cfg.Database.MigrationsPath
 	runMigrations(path, dbUrl, logger)
 
 	logger.Info("Running migrations...")
@@ -45,23 +67,84 @@ func main() {
 	}
 	logger.Info("Database connection established")
 	defer db.Close()
+	if err := observability.RegisterPool("loans", db); err != nil {
+		logger.Fatalf("Failed to register pool metrics: %v", err)
+	}
 
-	loansServer := loansserver.NewLoansServer(db, logger)
-	server := grpc.NewServer()
-	pb.RegisterLoanServiceServer(server, loansServer)
+	tokens, err := auth.NewTokenManager(cfg.Auth.JWTSecret, cfg.Auth.AccessTTL, cfg.Auth.RefreshTTL)
+	if err != nil {
+		logger.Fatalf("Failed to configure authentication: %v", err)
+	}
 
-	PORT := os.Getenv("LOANS_PORT")
-	if PORT == "" {
-		PORT = "50053"
-		logger.Infof("LOANS_PORT not set, using default port %s", PORT)
+	// Connections to the other services and RabbitMQ are opened once and shared by all
+	// requests; loansServer.Shutdown closes them.
+	usersClient, err := userclient.NewUserClient(cfg.Services.Users, 10*time.Second, logger, tlsCreds.DialOption())
+	if err != nil {
+		logger.Fatalf("Failed to create users client: %v", err)
+	}
+	keys := auth.CacheKeys(usersClient, time.Minute)
+
+	booksClient, err := bookclient.NewBookClient(cfg.Services.Books, 10*time.Second, logger, tlsCreds.DialOption())
+	if err != nil {
+		logger.Fatalf("Failed to create books client: %v", err)
+	}
+
+	notificationsClient, err := notificlient.NewNotificationClient(cfg.Services.Notifications, 10*time.Second, logger, tlsCreds.DialOption())
+	if err != nil {
+		logger.Fatalf("Failed to create notifications client: %v", err)
 	}
 
+	publisher, err := rabbit.NewRabbitMQClient(logger, cfg.RabbitMQ.URL)
+	if err != nil {
+		logger.Fatalf("Failed to connect to RabbitMQ: %v", err)
+	}
+
+	loansServer := loansserver.NewLoansServer(db, logger, cfg, usersClient, booksClient, notificationsClient, publisher)
+	if err := loansServer.RegisterMetrics(); err != nil {
+		logger.Fatalf("Failed to register metrics: %v", err)
+	}
+	server := grpc.NewServer(
+		tlsCreds.ServerOption(),
+		grpc.ChainUnaryInterceptor(observability.UnaryServerInterceptor(), requestid.UnaryServerInterceptor(logger)),
+		observability.GRPCServerHandler(),
+		auth.ServerInterceptors(tokens, keys, auth.Permissions),
+	)
+	pb.RegisterLoanServiceServer(server, loansServer)
+
+	checker := healthcheck.New(logger, pb.LoanService_ServiceDesc.ServiceName)
+	checker.Add("database", db.Ping)
+	checker.Add("rabbitmq", publisher.Check)
+	checker.Add("users", usersClient.Ping)
+	checker.Add("books", booksClient.Ping)
+	checker.Add("notifications", notificationsClient.Ping)
+	checker.Register(server)
+	checker.Start(ctx)
+
+	PORT := cfg.Server.Port
 	lis, err := net.Listen("tcp", ":"+PORT)
 	if err != nil {
 		logger.Fatalf("Failed to listen: %v", err)
 	}
 	logger.Infof("Listening on port %s", PORT)
 
+	var feedServer *http.Server
+	if feedSecret := cfg.CalendarFeed.Secret; feedSecret != "" {
+		feedPort := cfg.CalendarFeed.Port
+		feedServer = &http.Server{
+			Addr:              ":" + feedPort,
+			Handler:           loansServer.EnableCalendarFeed(feedSecret, cfg.CalendarFeed.URL),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := feedServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatalf("Failed to serve calendar feed: %v", err)
+			}
+		}()
+		logger.Infof("Calendar feed listening on port %s", feedPort)
+	}
+
+	metricsServer := observability.Serve(cfg.Server.MetricsPort, logger)
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	go func() {
@@ -72,7 +155,14 @@ func main() {
 
 	<-stop
 	logger.Info("Received shutdown signal, stopping server gracefully...")
-	loansServer.Shutdown()
+	checker.Shutdown()
+	if feedServer != nil {
+		shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		feedServer.Shutdown(shutdownCtx)
+		cancel()
+	}
 	server.GracefulStop()
+	loansServer.Shutdown()
+	observability.Shutdown(metricsServer)
 	logger.Info("Server stopped gracefully")
 }
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS holds (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    book_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT holds_user_book_key UNIQUE (user_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS holds_book_queue_idx ON holds (book_id, created_at, id);
@@ -0,0 +1,2 @@
+-- The copy lent; NULL for loans made before copies were tracked.
+ALTER TABLE loans ADD COLUMN IF NOT EXISTS item_id INT;
@@ -0,0 +1,168 @@
+package loansserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/calendar"
+	"github.com/ViktorOHJ/library-system/protos/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBookBatch matches the limit of BookService.GetBooks.
+const maxBookBatch = 100
+
+type activeLoan struct {
+	id     int
+	bookID int
+	due    time.Time
+}
+
+type calendarFeed struct {
+	secret  string
+	baseURL string
+}
+
+// EnableCalendarFeed turns on the per-user iCal feed and returns its HTTP handler.
+// baseURL is the public address of the handler and is used to build subscription links.
+func (s *LoansServer) EnableCalendarFeed(secret, baseURL string) http.Handler {
+	s.calendarFeed = &calendarFeed{
+		secret:  secret,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{user_id}/loans.ics", s.serveCalendarFeed)
+	return mux
+}
+
+func (s *LoansServer) calendarURL(userID string) string {
+	if s.calendarFeed == nil || s.calendarFeed.baseURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/users/%s/loans.ics?token=%s",
+		s.calendarFeed.baseURL, userID, calendar.FeedToken(s.calendarFeed.secret, userID))
+}
+
+func (s *LoansServer) serveCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("user_id")
+	if id, err := strconv.Atoi(userID); err != nil || id <= 0 {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+	if s.calendarFeed == nil || !calendar.ValidFeedToken(s.calendarFeed.secret, userID, r.URL.Query().Get("token")) {
+		http.Error(w, "invalid token", http.StatusForbidden)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.serviceContext(r.Context()), 30*time.Second)
+	defer cancel()
+
+	// The token never changes, so it stays valid only while the account is active:
+	// deactivating or deleting the user revokes the feed.
+	user, err := s.userService.Get(ctx, userID)
+	switch {
+	case status.Code(err) == codes.NotFound:
+		http.Error(w, "invalid token", http.StatusForbidden)
+		return
+	case err != nil:
+		s.logger.Errorf("Failed to get user %s for calendar feed: %v", userID, err)
+		http.Error(w, "server error", feedErrorCode(err))
+		return
+	case !user.Active:
+		http.Error(w, "invalid token", http.StatusForbidden)
+		return
+	}
+
+	events, err := s.loanDueEvents(ctx, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to build calendar feed for user %s: %v", userID, err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", calendar.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	w.Write(calendar.Encode("Library loans", events, time.Now()))
+}
+
+func feedErrorCode(err error) int {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *LoansServer) loanDueEvents(ctx context.Context, userID string) ([]calendar.Event, error) {
+	loans, err := s.activeLoans(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(loans) == 0 {
+		return nil, nil
+	}
+
+	books := s.booksByID(ctx, loans)
+	events := make([]calendar.Event, 0, len(loans))
+	for _, l := range loans {
+		title, author := fmt.Sprintf("Book #%d", l.bookID), "unknown author"
+		if book, ok := books[strconv.Itoa(l.bookID)]; ok {
+			title, author = book.Title, book.Author
+		}
+		events = append(events, calendar.LoanDueEvent(strconv.Itoa(l.id), title, author, l.due))
+	}
+	return events, nil
+}
+
+func (s *LoansServer) listActiveLoans(ctx context.Context, userID string) ([]activeLoan, error) {
+	rows, err := s.db.Query(ctx,
+		"SELECT id, book_id, return_date FROM loans WHERE user_id = $1 ORDER BY return_date", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var loans []activeLoan
+	for rows.Next() {
+		var l activeLoan
+		if err := rows.Scan(&l.id, &l.bookID, &l.due); err != nil {
+			return nil, err
+		}
+		loans = append(loans, l)
+	}
+	return loans, rows.Err()
+}
+
+// booksByID loads the books of the given loans with as few GetBooks calls as the batch
+// limit allows. A failed batch is logged and its loans fall back to a placeholder title.
+func (s *LoansServer) booksByID(ctx context.Context, loans []activeLoan) map[string]*pb.BookResponse {
+	seen := make(map[string]bool, len(loans))
+	ids := make([]string, 0, len(loans))
+	for _, l := range loans {
+		id := strconv.Itoa(l.bookID)
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	books := make(map[string]*pb.BookResponse, len(ids))
+	for start := 0; start < len(ids); start += maxBookBatch {
+		batch := ids[start:min(start+maxBookBatch, len(ids))]
+		resp, err := s.bookService.GetBatch(ctx, batch)
+		if err != nil {
+			s.logger.WithField("book_ids", batch).Warnf("Failed to get books for calendar feed: %v", err)
+			continue
+		}
+		for _, b := range resp {
+			books[b.Id] = b
+		}
+	}
+	return books
+}
@@ -3,16 +3,19 @@ package loansserver
 import (
 	"context"
 	"errors"
-	"os"
 	"strconv"
 	"time"
 
-	"github.com/ViktorOHJ/library-system/loans/clients"
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/config"
+	"github.com/ViktorOHJ/library-system/dberr"
+	"github.com/ViktorOHJ/library-system/observability"
 	"github.com/ViktorOHJ/library-system/protos/pb"
 	"github.com/ViktorOHJ/library-system/rabbit"
+	"github.com/ViktorOHJ/library-system/requestid"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -24,7 +27,10 @@ type UserService interface {
 
 type BookService interface {
 	Get(ctx context.Context, id string) (*pb.BookResponse, error)
-	Update(ctx context.Context, id string) (*pb.BookResponse, error)
+	GetBatch(ctx context.Context, ids []string) ([]*pb.BookResponse, error)
+	GetItem(ctx context.Context, id string) (*pb.Item, error)
+	Checkout(ctx context.Context, bookID, itemID string) (*pb.Item, error)
+	Checkin(ctx context.Context, itemID, bookID string) (*pb.Item, error)
 	Close() error
 }
 
@@ -47,13 +53,24 @@ type LoansServer struct {
 	notificationService NotificationService
 	messagePublisher    MessagePublisher
 	getLoanInfo         func(ctx context.Context, loanID string) (*LoanInfo, error)
+	activeLoans         func(ctx context.Context, userID string) ([]activeLoan, error)
+	calendarFeed        *calendarFeed
+	serviceKey          string
 }
 
-func NewLoansServer(db *pgxpool.Pool, logger *logrus.Logger) *LoansServer {
-	return &LoansServer{
-		db:     db,
-		logger: logger,
-	}
+// NewLoansServer creates the server with connections opened once by main and shared by all requests.
+func NewLoansServer(
+	db *pgxpool.Pool,
+	logger *logrus.Logger,
+	cfg *config.Loans,
+	userService UserService,
+	bookService BookService,
+	notificationService NotificationService,
+	messagePublisher MessagePublisher,
+) *LoansServer {
+	s := NewLoansServerWithDeps(db, logger, userService, bookService, notificationService, messagePublisher)
+	s.serviceKey = cfg.APIKey
+	return s
 }
 
 func NewLoansServerWithDeps(
@@ -64,7 +81,7 @@ func NewLoansServerWithDeps(
 	notificationService NotificationService,
 	messagePublisher MessagePublisher,
 ) *LoansServer {
-	return &LoansServer{
+	s := &LoansServer{
 		db:                  db,
 		logger:              logger,
 		userService:         userService,
@@ -72,6 +89,25 @@ func NewLoansServerWithDeps(
 		notificationService: notificationService,
 		messagePublisher:    messagePublisher,
 	}
+	s.getLoanInfo = s.GetLoanInfo
+	s.activeLoans = s.listActiveLoans
+	return s
+}
+
+// serviceContext returns ctx carrying the service key for downstream calls.
+// Authorization checks must use the original context.
+func (s *LoansServer) serviceContext(ctx context.Context) context.Context {
+	if s.serviceKey == "" {
+		return ctx
+	}
+	return auth.WithToken(ctx, s.serviceKey)
+}
+
+// detach returns a context for background work that outlives the RPC. It keeps the
+// request id and the trace of the call, so asynchronous work shows up in the same trace.
+func (s *LoansServer) detach(ctx context.Context) context.Context {
+	detached := trace.ContextWithSpanContext(auth.Detach(ctx), trace.SpanContextFromContext(ctx))
+	return requestid.WithID(s.serviceContext(detached), requestid.FromContext(ctx))
 }
 
 func (s *LoansServer) BorrowBook(parentCtx context.Context, req *pb.BorrowRequest) (*pb.LoanResponse, error) {
@@ -80,50 +116,76 @@ func (s *LoansServer) BorrowBook(parentCtx context.Context, req *pb.BorrowReques
 	if err := s.validateBorrowRequest(req); err != nil {
 		return nil, err
 	}
-	if err := s.initServices(); err != nil {
-		return nil, status.Error(codes.Internal, "server error")
+	if err := auth.RequireSelfOrStaff(parentCtx, req.UserId); err != nil {
+		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(parentCtx, 30*time.Second)
+	ctx, cancel := context.WithTimeout(s.serviceContext(parentCtx), 30*time.Second)
 	defer cancel()
 
 	user, err := s.userService.Get(ctx, req.UserId)
 	if err != nil {
 		s.logger.Errorf("Failed to get user %s: %v", req.UserId, err)
-		return nil, status.Errorf(codes.NotFound, "user not found")
+		return nil, downstreamError(err, status.Error(codes.NotFound, "user not found"))
+	}
+
+	if !user.Active {
+		return nil, status.Error(codes.FailedPrecondition, "user is deactivated")
+	}
+
+	// A specific copy, e.g. scanned at the desk, names its book.
+	bookID := req.BookId
+	if req.ItemId != "" {
+		item, err := s.bookService.GetItem(ctx, req.ItemId)
+		if err != nil {
+			s.logger.Errorf("Failed to get item %s: %v", req.ItemId, err)
+			return nil, downstreamError(err, status.Error(codes.NotFound, "item not found"))
+		}
+		if bookID != "" && bookID != item.BookId {
+			return nil, status.Error(codes.InvalidArgument, "item is a copy of another book")
+		}
+		bookID = item.BookId
 	}
 
-	book, err := s.bookService.Get(ctx, req.BookId)
+	book, err := s.bookService.Get(ctx, bookID)
 	if err != nil {
-		s.logger.Errorf("Failed to get book %s: %v", req.BookId, err)
-		return nil, status.Errorf(codes.NotFound, "book not found")
+		s.logger.Errorf("Failed to get book %s: %v", bookID, err)
+		return nil, downstreamError(err, status.Error(codes.NotFound, "book not found"))
 	}
 
 	if !book.Available {
 		return nil, status.Error(codes.InvalidArgument, "book is not available")
 	}
 
-	loanID, err := s.createLoanRecord(ctx, req.UserId, req.BookId)
+	// The copy is claimed first: the books service hands each copy to one loan only.
+	item, err := s.bookService.Checkout(ctx, bookID, req.ItemId)
 	if err != nil {
-		s.logger.Errorf("Failed to create loan record: %v", err)
-		return nil, status.Error(codes.Internal, "failed to create loan")
+		s.logger.Errorf("Failed to check out a copy of book %s: %v", bookID, err)
+		return nil, downstreamError(err, checkoutError(err))
 	}
 
-	if err := s.updateBookAvailability(ctx, req.BookId); err != nil {
-		s.logger.Errorf("Failed to update book availability: %v", err)
-		return nil, status.Error(codes.Internal, "failed to update book status")
+	loanID, err := s.createLoanRecord(ctx, req.UserId, bookID, item.Id)
+	if err != nil {
+		s.logger.Errorf("Failed to create loan record: %v", err)
+		if _, err := s.bookService.Checkin(ctx, item.Id, bookID); err != nil {
+			s.logger.Errorf("Failed to put item %s back on the shelf: %v", item.Id, err)
+		}
+		return nil, dberr.ToStatus(err, "loan")
 	}
+	book.AvailableCopies--
+	book.Available = book.AvailableCopies > 0
 
-	if err := s.publishBorrowMessage(ctx, user, book, loanID); err != nil {
+	if err := s.publishBorrowMessage(ctx, req.UserId, user, book, loanID); err != nil {
 		s.logger.Errorf("Failed to publish message: %v", err)
 	}
 
-	go s.sendNotificationAsync(user, book, "borrow_queue")
+	go s.sendNotificationAsync(s.detach(parentCtx), user, book, "borrow_queue")
 
 	return &pb.LoanResponse{
 		Id:           strconv.Itoa(loanID),
 		User:         user,
 		Book:         book,
+		Item:         item,
 		BorrowedDate: time.Now().Format(time.RFC3339),
 		DueDate:      time.Now().AddDate(0, 0, 14).Format("2006-01-02"),
 	}, nil
@@ -135,48 +197,136 @@ func (s *LoansServer) ReturnBook(parentCtx context.Context, req *pb.ReturnReques
 	if err := s.validateReturnRequest(req); err != nil {
 		return nil, err
 	}
-	if err := s.initServices(); err != nil {
-		return nil, status.Error(codes.Internal, "server error")
-	}
 
-	ctx, cancel := context.WithTimeout(parentCtx, 30*time.Second)
+	ctx, cancel := context.WithTimeout(s.serviceContext(parentCtx), 30*time.Second)
 	defer cancel()
 
 	loanInfo, err := s.getLoanInfo(ctx, req.LoanId)
 	if err != nil {
 		s.logger.Errorf("Failed to get loan info: %v", err)
-		return nil, status.Error(codes.NotFound, "loan not found")
+		return nil, dberr.ToStatus(err, "loan")
+	}
+	if err := auth.RequireSelfOrStaff(parentCtx, loanInfo.UserID); err != nil {
+		return nil, err
 	}
 
 	user, err := s.userService.Get(ctx, loanInfo.UserID)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to get user")
+		return nil, downstreamError(err, status.Error(codes.Internal, "failed to get user"))
 	}
 
 	book, err := s.bookService.Get(ctx, loanInfo.BookID)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to get book")
+		return nil, downstreamError(err, status.Error(codes.Internal, "failed to get book"))
 	}
 
-	if err := s.returnBookTransaction(ctx, req.LoanId, loanInfo.BookID); err != nil {
+	item, err := s.returnBookTransaction(ctx, req.LoanId, loanInfo.BookID, loanInfo.ItemID)
+	if err != nil {
 		s.logger.Errorf("Failed to return book: %v", err)
-		return nil, status.Error(codes.Internal, "failed to return book")
+		// Check-in failures are already mapped by returnBookTransaction and pass through.
+		return nil, dberr.ToStatus(err, "loan")
 	}
 
 	if err := s.publishReturnMessage(ctx, user, book, req.LoanId); err != nil {
 		s.logger.Errorf("Failed to publish return message: %v", err)
 	}
 
-	go s.sendNotificationAsync(user, book, "return_queue")
+	go s.sendNotificationAsync(s.detach(parentCtx), user, book, "return_queue")
 
 	return &pb.LoanResponse{
 		Id:           req.LoanId,
 		User:         user,
 		Book:         book,
+		Item:         item,
 		ReturnedDate: time.Now().Format("2006-01-02"),
 	}, nil
 }
 
+func (s *LoansServer) GetUserLoanSummary(parentCtx context.Context, req *pb.UserLoanSummaryRequest) (*pb.UserLoanSummaryResponse, error) {
+	s.logger.Info("GetUserLoanSummary called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user id cannot be empty")
+	}
+	if _, err := strconv.Atoi(req.UserId); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid user id format")
+	}
+	if err := auth.RequireSelfOrStaff(parentCtx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	res := &pb.UserLoanSummaryResponse{UserId: req.UserId}
+	err := s.db.QueryRow(ctx,
+		`SELECT COUNT(*), COUNT(*) FILTER (WHERE return_date < NOW()) FROM loans WHERE user_id = $1`,
+		req.UserId).Scan(&res.ActiveLoans, &res.OverdueLoans)
+	if err != nil {
+		s.logger.Errorf("Failed to get loan summary: %v", err)
+		return nil, dberr.ToStatus(err, "loan")
+	}
+	return res, nil
+}
+
+func (s *LoansServer) ListUserLoans(parentCtx context.Context, req *pb.ListUserLoansRequest) (*pb.ListUserLoansResponse, error) {
+	s.logger.Info("ListUserLoans called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	if id, err := strconv.Atoi(req.UserId); err != nil || id <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid user id format")
+	}
+	if err := auth.RequireSelfOrStaff(parentCtx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx,
+		`SELECT id, book_id, COALESCE(item_id::text, ''), loan_date, return_date, return_date < NOW()
+		 FROM loans WHERE user_id = $1 ORDER BY return_date, id`,
+		req.UserId)
+	if err != nil {
+		s.logger.Errorf("Failed to list loans: %v", err)
+		return nil, dberr.ToStatus(err, "loan")
+	}
+	defer rows.Close()
+
+	res := &pb.ListUserLoansResponse{}
+	for rows.Next() {
+		var (
+			id, bookID        int
+			itemID            string
+			loanDate, dueDate time.Time
+			overdue           bool
+		)
+		if err := rows.Scan(&id, &bookID, &itemID, &loanDate, &dueDate, &overdue); err != nil {
+			s.logger.Errorf("Failed to list loans: %v", err)
+			return nil, dberr.ToStatus(err, "loan")
+		}
+		res.Loans = append(res.Loans, &pb.Loan{
+			Id:           strconv.Itoa(id),
+			UserId:       req.UserId,
+			BookId:       strconv.Itoa(bookID),
+			ItemId:       itemID,
+			BorrowedDate: loanDate.Format(time.RFC3339),
+			DueDate:      dueDate.Format("2006-01-02"),
+			Overdue:      overdue,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.Errorf("Failed to list loans: %v", err)
+		return nil, dberr.ToStatus(err, "loan")
+	}
+	return res, nil
+}
+
 func (s *LoansServer) validateBorrowRequest(req *pb.BorrowRequest) error {
 	if req == nil {
 		return status.Error(codes.InvalidArgument, "request cannot be nil")
@@ -184,16 +334,19 @@ func (s *LoansServer) validateBorrowRequest(req *pb.BorrowRequest) error {
 	if req.UserId == "" {
 		return status.Error(codes.InvalidArgument, "user id cannot be empty")
 	}
-	if req.BookId == "" {
+	if req.BookId == "" && req.ItemId == "" {
 		return status.Error(codes.InvalidArgument, "book id cannot be empty")
 	}
 
 	if _, err := strconv.Atoi(req.UserId); err != nil {
 		return status.Error(codes.InvalidArgument, "invalid user id format")
 	}
-	if _, err := strconv.Atoi(req.BookId); err != nil {
+	if _, err := strconv.Atoi(req.BookId); req.BookId != "" && err != nil {
 		return status.Error(codes.InvalidArgument, "invalid book id format")
 	}
+	if _, err := strconv.Atoi(req.ItemId); req.ItemId != "" && err != nil {
+		return status.Error(codes.InvalidArgument, "invalid item id format")
+	}
 
 	return nil
 }
@@ -211,12 +364,12 @@ func (s *LoansServer) validateReturnRequest(req *pb.ReturnRequest) error {
 	return nil
 }
 
-func (s *LoansServer) createLoanRecord(ctx context.Context, userID, bookID string) (int, error) {
+func (s *LoansServer) createLoanRecord(ctx context.Context, userID, bookID, itemID string) (int, error) {
 	var loanID int
 	err := s.db.QueryRow(ctx,
-		`INSERT INTO loans (user_id, book_id, loan_date, return_date)
-		 VALUES ($1, $2, $3, $4) RETURNING id`,
-		userID, bookID, time.Now(), time.Now().AddDate(0, 0, 14)).Scan(&loanID)
+		`INSERT INTO loans (user_id, book_id, item_id, loan_date, return_date)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		userID, bookID, itemID, time.Now(), time.Now().AddDate(0, 0, 14)).Scan(&loanID)
 
 	if err != nil {
 		return 0, err
@@ -226,25 +379,55 @@ func (s *LoansServer) createLoanRecord(ctx context.Context, userID, bookID strin
 		"loan_id": loanID,
 		"user_id": userID,
 		"book_id": bookID,
+		"item_id": itemID,
 	}).Info("Loan record created")
 
 	return loanID, nil
 }
 
-func (s *LoansServer) updateBookAvailability(ctx context.Context, bookID string) error {
-	_, err := s.bookService.Update(ctx, bookID)
-	return err
+// checkoutError keeps the reason a copy could not be lent, such as no copy on the shelf.
+func checkoutError(err error) error {
+	switch status.Code(err) {
+	case codes.NotFound, codes.InvalidArgument, codes.FailedPrecondition:
+		return err
+	}
+	return status.Error(codes.Internal, "failed to check out a copy")
+}
+
+// checkinError describes a copy that BookService refused to take back. Its statuses are
+// about the copy, not the loan the caller named, so none of them passes through as is.
+func checkinError(err error) error {
+	switch status.Code(err) {
+	case codes.FailedPrecondition:
+		return status.Error(codes.FailedPrecondition, "the copy of this loan is not on loan in the catalog")
+	case codes.NotFound:
+		return status.Error(codes.FailedPrecondition, "the copy of this loan is no longer in the catalog")
+	}
+	return status.Error(codes.Internal, "failed to check in the copy")
+}
+
+// downstreamError reports an outage of another service, including a call refused by its
+// circuit breaker, as UNAVAILABLE so that clients know to retry later. Other failures
+// become fallback.
+func downstreamError(err, fallback error) error {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return status.Error(codes.Unavailable, "a dependent service is unavailable, try again later")
+	}
+	return fallback
 }
 
 type LoanInfo struct {
 	UserID string
 	BookID string
+	// ItemID is empty for loans made before copies were tracked.
+	ItemID string
 }
 
 func (s *LoansServer) GetLoanInfo(ctx context.Context, loanID string) (*LoanInfo, error) {
-	var userID, bookID string
-	row := s.db.QueryRow(ctx, "SELECT user_id, book_id FROM loans WHERE id = $1", loanID)
-	err := row.Scan(&userID, &bookID)
+	var userID, bookID, itemID string
+	row := s.db.QueryRow(ctx, "SELECT user_id, book_id, COALESCE(item_id::text, '') FROM loans WHERE id = $1", loanID)
+	err := row.Scan(&userID, &bookID, &itemID)
 	if err != nil {
 		return nil, err
 	}
@@ -252,42 +435,44 @@ func (s *LoansServer) GetLoanInfo(ctx context.Context, loanID string) (*LoanInfo
 	return &LoanInfo{
 		UserID: userID,
 		BookID: bookID,
+		ItemID: itemID,
 	}, nil
 }
 
-func (s *LoansServer) returnBookTransaction(ctx context.Context, loanID, bookID string) error {
+func (s *LoansServer) returnBookTransaction(ctx context.Context, loanID, bookID, itemID string) (*pb.Item, error) {
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
 	_, err = tx.Exec(ctx, "DELETE FROM loans WHERE id = $1", loanID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = s.bookService.Update(ctx, bookID)
+	item, err := s.bookService.Checkin(ctx, itemID, bookID)
 	if err != nil {
-		return err
+		return nil, downstreamError(err, checkinError(err))
 	}
 
-	return tx.Commit(ctx)
+	return item, tx.Commit(ctx)
 }
 
-func (s *LoansServer) publishBorrowMessage(ctx context.Context, user *pb.UserResponse, book *pb.BookResponse, loanID int) error {
+func (s *LoansServer) publishBorrowMessage(ctx context.Context, userID string, user *pb.UserResponse, book *pb.BookResponse, loanID int) error {
 	if s.messagePublisher == nil {
 		return errors.New("message publisher not initialized")
 	}
 
 	message := &rabbit.TaskMessage{
-		Type:       "Borrow",
-		UserName:   user.Name,
-		BookTitle:  book.Title,
-		BookAuthor: book.Author,
-		DueDate:    time.Now().AddDate(0, 0, 14).Format("2006-01-02"),
-		LoanID:     strconv.Itoa(loanID),
-		Email:      user.Email,
+		Type:        "Borrow",
+		UserName:    user.Name,
+		BookTitle:   book.Title,
+		BookAuthor:  book.Author,
+		DueDate:     time.Now().AddDate(0, 0, 14).Format("2006-01-02"),
+		LoanID:      strconv.Itoa(loanID),
+		Email:       user.Email,
+		CalendarURL: s.calendarURL(userID),
 	}
 
 	return s.messagePublisher.PublishTask(ctx, s.logger, message)
@@ -311,13 +496,13 @@ func (s *LoansServer) publishReturnMessage(ctx context.Context, user *pb.UserRes
 	return s.messagePublisher.PublishTask(ctx, s.logger, message)
 }
 
-func (s *LoansServer) sendNotificationAsync(user *pb.UserResponse, book *pb.BookResponse, queueType string) {
+func (s *LoansServer) sendNotificationAsync(parentCtx context.Context, user *pb.UserResponse, book *pb.BookResponse, queueType string) {
 	if s.notificationService == nil {
 		s.logger.Warn("Notification service not initialized")
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
 	defer cancel()
 
 	_, err := s.notificationService.Send(ctx, queueType)
@@ -332,36 +517,6 @@ func (s *LoansServer) sendNotificationAsync(user *pb.UserResponse, book *pb.Book
 	}
 }
 
-func (s *LoansServer) initServices() (err error) {
-	err = godotenv.Load(".env")
-	if err != nil {
-		s.logger.Warn("No .env file found, relying on environment variables")
-		return err
-	}
-	s.userService, err = clients.GetUsersClient(os.Getenv("USERS_PORT"), s.logger)
-	if err != nil {
-		s.logger.Errorf("Failed to initialize user service: %v", err)
-		return err
-	}
-	s.bookService, err = clients.GetBookClient(os.Getenv("BOOKS_PORT"), s.logger)
-	if err != nil {
-		s.logger.Errorf("Failed to initialize book service: %v", err)
-		return err
-	}
-	s.notificationService, err = clients.GetNotificationsClient(os.Getenv("NOTIFICATIONS_PORT"), s.logger)
-	if err != nil {
-		s.logger.Errorf("Failed to initialize notification service: %v", err)
-		return err
-	}
-
-	s.messagePublisher, err = rabbit.NewRabbitMQClient(s.logger, os.Getenv("RABBIT_URL"))
-	if err != nil {
-		s.logger.Errorf("Failed to initialize RabbitMQ client: %v", err)
-		return err
-	}
-	return nil
-}
-
 func (s *LoansServer) Shutdown() {
 	if s.userService != nil {
 		s.userService.Close()
@@ -379,3 +534,15 @@ func (s *LoansServer) Shutdown() {
 		s.db.Close()
 	}
 }
+
+// RegisterMetrics exports the number of active and overdue loans, read from the database on every scrape.
+func (s *LoansServer) RegisterMetrics() error {
+	if err := observability.RegisterQueryGauge(s.db, s.logger, "loans_active",
+		"Books currently on loan.",
+		"SELECT COUNT(*) FROM loans"); err != nil {
+		return err
+	}
+	return observability.RegisterQueryGauge(s.db, s.logger, "loans_overdue",
+		"Loans past their due date.",
+		"SELECT COUNT(*) FROM loans WHERE return_date < NOW()")
+}
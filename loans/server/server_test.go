@@ -3,13 +3,21 @@ package loansserver
 import (
 	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/calendar"
 	"github.com/ViktorOHJ/library-system/protos/pb"
 	"github.com/ViktorOHJ/library-system/rabbit"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type mockUserService struct {
@@ -24,14 +32,31 @@ func (m *mockUserService) Close() error { return nil }
 
 type mockBookService struct {
 	book *pb.BookResponse
+	item *pb.Item
 	err  error
 }
 
 func (m *mockBookService) Get(ctx context.Context, id string) (*pb.BookResponse, error) {
 	return m.book, m.err
 }
-func (m *mockBookService) Update(ctx context.Context, id string) (*pb.BookResponse, error) {
-	return m.book, m.err
+func (m *mockBookService) GetBatch(ctx context.Context, ids []string) ([]*pb.BookResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	books := make([]*pb.BookResponse, 0, len(ids))
+	for _, id := range ids {
+		books = append(books, &pb.BookResponse{Id: id, Title: m.book.Title, Author: m.book.Author})
+	}
+	return books, nil
+}
+func (m *mockBookService) GetItem(ctx context.Context, id string) (*pb.Item, error) {
+	return m.item, m.err
+}
+func (m *mockBookService) Checkout(ctx context.Context, bookID, itemID string) (*pb.Item, error) {
+	return m.item, m.err
+}
+func (m *mockBookService) Checkin(ctx context.Context, itemID, bookID string) (*pb.Item, error) {
+	return m.item, m.err
 }
 func (m *mockBookService) Close() error { return nil }
 
@@ -57,7 +82,7 @@ func newTestLoansServer() *LoansServer {
 	return NewLoansServerWithDeps(
 		&pgxpool.Pool{},
 		logrus.New(),
-		&mockUserService{user: &pb.UserResponse{Name: "Test", Email: "test@mail.com"}, err: nil},
+		&mockUserService{user: &pb.UserResponse{Name: "Test", Email: "test@mail.com", Active: true}, err: nil},
 		&mockBookService{book: &pb.BookResponse{Title: "Book", Author: "Author", Available: true}, err: nil},
 		&mockNotificationService{err: nil},
 		&mockMessagePublisher{err: nil},
@@ -73,6 +98,9 @@ func TestValidateBorrowRequest(t *testing.T) {
 	assert.Error(t, s.validateBorrowRequest(&pb.BorrowRequest{UserId: "abc", BookId: "2"}))
 	assert.Error(t, s.validateBorrowRequest(&pb.BorrowRequest{UserId: "1", BookId: ""}))
 	assert.Error(t, s.validateBorrowRequest(&pb.BorrowRequest{UserId: "1", BookId: "xyz"}))
+	assert.NoError(t, s.validateBorrowRequest(&pb.BorrowRequest{UserId: "1", ItemId: "7"}))
+	assert.NoError(t, s.validateBorrowRequest(&pb.BorrowRequest{UserId: "1", BookId: "2", ItemId: "7"}))
+	assert.Error(t, s.validateBorrowRequest(&pb.BorrowRequest{UserId: "1", ItemId: "LIB00000007"}))
 }
 
 func TestValidateReturnRequest(t *testing.T) {
@@ -89,7 +117,7 @@ func TestBorrowBook_BookNotAvailable(t *testing.T) {
 	s := NewLoansServerWithDeps(
 		&pgxpool.Pool{},
 		logrus.New(),
-		&mockUserService{user: &pb.UserResponse{Name: "Test"}, err: nil},
+		&mockUserService{user: &pb.UserResponse{Name: "Test", Active: true}, err: nil},
 		&mockBookService{book: &pb.BookResponse{Title: "Book", Author: "Author", Available: false}, err: nil},
 		&mockNotificationService{err: nil},
 		&mockMessagePublisher{err: nil},
@@ -119,7 +147,7 @@ func TestBorrowBook_BookNotFound(t *testing.T) {
 	s := NewLoansServerWithDeps(
 		&pgxpool.Pool{},
 		logrus.New(),
-		&mockUserService{user: &pb.UserResponse{Name: "Test"}, err: nil},
+		&mockUserService{user: &pb.UserResponse{Name: "Test", Active: true}, err: nil},
 		&mockBookService{book: nil, err: errors.New("not found")},
 		&mockNotificationService{err: nil},
 		&mockMessagePublisher{err: nil},
@@ -130,6 +158,123 @@ func TestBorrowBook_BookNotFound(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestBorrowBook_DownstreamErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		userErr  error
+		bookErr  error
+		wantCode codes.Code
+	}{
+		{name: "users service down", userErr: status.Error(codes.Unavailable, "circuit breaker open"), wantCode: codes.Unavailable},
+		{name: "books service timed out", bookErr: status.Error(codes.DeadlineExceeded, "deadline exceeded"), wantCode: codes.Unavailable},
+		{name: "user does not exist", userErr: status.Error(codes.NotFound, "user not found"), wantCode: codes.NotFound},
+		{name: "book does not exist", bookErr: status.Error(codes.NotFound, "book not found"), wantCode: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewLoansServerWithDeps(
+				&pgxpool.Pool{},
+				logrus.New(),
+				&mockUserService{user: &pb.UserResponse{Name: "Test", Active: true}, err: tt.userErr},
+				&mockBookService{book: &pb.BookResponse{Available: true}, err: tt.bookErr},
+				&mockNotificationService{err: nil},
+				&mockMessagePublisher{err: nil},
+			)
+			staff := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "9", Role: auth.RoleLibrarian})
+			resp, err := s.BorrowBook(staff, &pb.BorrowRequest{UserId: "1", BookId: "2"})
+			assert.Nil(t, resp)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestBorrowBook_Item(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *pb.BorrowRequest
+		books    *mockBookService
+		wantCode codes.Code
+	}{
+		{
+			name:     "copy of another book",
+			req:      &pb.BorrowRequest{UserId: "1", BookId: "2", ItemId: "7"},
+			books:    &mockBookService{book: &pb.BookResponse{Available: true}, item: &pb.Item{Id: "7", BookId: "3"}},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "copy does not exist",
+			req:      &pb.BorrowRequest{UserId: "1", ItemId: "7"},
+			books:    &mockBookService{err: status.Error(codes.NotFound, "item not found")},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "no copy on the shelf",
+			req:      &pb.BorrowRequest{UserId: "1", ItemId: "7"},
+			books:    &mockBookService{book: &pb.BookResponse{Available: false}, item: &pb.Item{Id: "7", BookId: "2"}},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewLoansServerWithDeps(
+				&pgxpool.Pool{},
+				logrus.New(),
+				&mockUserService{user: &pb.UserResponse{Name: "Test", Active: true}},
+				tt.books,
+				&mockNotificationService{err: nil},
+				&mockMessagePublisher{err: nil},
+			)
+			staff := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "9", Role: auth.RoleLibrarian})
+			resp, err := s.BorrowBook(staff, tt.req)
+			assert.Nil(t, resp)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestCheckoutError(t *testing.T) {
+	unavailable := status.Error(codes.FailedPrecondition, "no copy of the book is available")
+	assert.Equal(t, unavailable, checkoutError(unavailable))
+	assert.Equal(t, codes.Internal, status.Code(checkoutError(errors.New("boom"))))
+}
+
+func TestCheckinError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode codes.Code
+	}{
+		{name: "copy not on loan", err: status.Error(codes.FailedPrecondition, "item is not on loan"), wantCode: codes.FailedPrecondition},
+		{name: "copy deleted", err: status.Error(codes.NotFound, "item not found"), wantCode: codes.FailedPrecondition},
+		{name: "copy of another book", err: status.Error(codes.InvalidArgument, "item is a copy of another book"), wantCode: codes.Internal},
+		{name: "service key rejected", err: status.Error(codes.PermissionDenied, "permission denied"), wantCode: codes.Internal},
+		{name: "unknown error", err: errors.New("boom"), wantCode: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkinError(tt.err)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.NotEqual(t, status.Convert(tt.err).Message(), status.Convert(err).Message())
+		})
+	}
+}
+
+func TestBorrowBook_OnBehalfOfAnotherUser(t *testing.T) {
+	s := newTestLoansServer()
+	req := &pb.BorrowRequest{UserId: "2", BookId: "3"}
+
+	patron := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "1", Role: auth.RolePatron})
+	resp, err := s.BorrowBook(patron, req)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	resp, err = s.BorrowBook(context.Background(), req)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
 func TestReturnBook_LoanNotFound(t *testing.T) {
 	s := newTestLoansServer()
 	s.getLoanInfo = func(ctx context.Context, loanID string) (*LoanInfo, error) {
@@ -140,3 +285,123 @@ func TestReturnBook_LoanNotFound(t *testing.T) {
 	assert.Nil(t, resp)
 	assert.Error(t, err)
 }
+
+func TestCalendarFeed_InvalidToken(t *testing.T) {
+	s := newTestLoansServer()
+	handler := s.EnableCalendarFeed("secret", "http://localhost:8053")
+
+	tests := []struct {
+		name string
+		url  string
+		code int
+	}{
+		{name: "missing token", url: "/users/1/loans.ics", code: http.StatusForbidden},
+		{name: "wrong token", url: "/users/1/loans.ics?token=" + calendar.FeedToken("other", "1"), code: http.StatusForbidden},
+		{name: "token of another user", url: "/users/1/loans.ics?token=" + calendar.FeedToken("secret", "2"), code: http.StatusForbidden},
+		{name: "invalid user id", url: "/users/abc/loans.ics?token=x", code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			assert.Equal(t, tt.code, rec.Code)
+		})
+	}
+}
+
+func TestCalendarFeed_ActiveLoans(t *testing.T) {
+	loans := []activeLoan{
+		{id: 7, bookID: 3, due: time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)},
+		{id: 8, bookID: 5, due: time.Date(2026, 11, 9, 0, 0, 0, 0, time.UTC)},
+	}
+	tests := []struct {
+		name   string
+		books  *mockBookService
+		first  string
+		second string
+	}{
+		{
+			name:   "books resolved",
+			books:  &mockBookService{book: &pb.BookResponse{Title: "Book", Author: "Author"}},
+			first:  `SUMMARY:Return "Book"`,
+			second: `SUMMARY:Return "Book"`,
+		},
+		{
+			name:   "books service down",
+			books:  &mockBookService{err: status.Error(codes.Unavailable, "unavailable")},
+			first:  `SUMMARY:Return "Book #3"`,
+			second: `SUMMARY:Return "Book #5"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestLoansServer()
+			s.bookService = tt.books
+			s.activeLoans = func(ctx context.Context, userID string) ([]activeLoan, error) {
+				assert.Equal(t, "1", userID)
+				return loans, nil
+			}
+			handler := s.EnableCalendarFeed("secret", "http://localhost:8053")
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1/loans.ics?token="+calendar.FeedToken("secret", "1"), nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, calendar.ContentType, rec.Header().Get("Content-Type"))
+
+			body := rec.Body.String()
+			assert.Equal(t, 2, strings.Count(body, "BEGIN:VEVENT"))
+			assert.Contains(t, body, "UID:loan-7@library-system")
+			assert.Contains(t, body, "DTSTART;VALUE=DATE:20261102")
+			assert.Contains(t, body, tt.first)
+			assert.Contains(t, body, "UID:loan-8@library-system")
+			assert.Contains(t, body, "DTSTART;VALUE=DATE:20261109")
+			assert.Contains(t, body, tt.second)
+		})
+	}
+}
+
+func TestCalendarFeed_RevokedUser(t *testing.T) {
+	tests := []struct {
+		name  string
+		users *mockUserService
+		code  int
+	}{
+		{name: "deactivated user", users: &mockUserService{user: &pb.UserResponse{Id: "1", Active: false}}, code: http.StatusForbidden},
+		{name: "deleted user", users: &mockUserService{err: status.Error(codes.NotFound, "user not found")}, code: http.StatusForbidden},
+		{name: "users service down", users: &mockUserService{err: status.Error(codes.Unavailable, "unavailable")}, code: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestLoansServer()
+			s.userService = tt.users
+			handler := s.EnableCalendarFeed("secret", "http://localhost:8053")
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1/loans.ics?token="+calendar.FeedToken("secret", "1"), nil))
+			assert.Equal(t, tt.code, rec.Code)
+		})
+	}
+}
+
+func TestCalendarURL(t *testing.T) {
+	s := newTestLoansServer()
+	assert.Empty(t, s.calendarURL("1"))
+
+	s.EnableCalendarFeed("secret", "https://library.example.com/")
+	assert.Equal(t, "https://library.example.com/users/1/loans.ics?token="+calendar.FeedToken("secret", "1"), s.calendarURL("1"))
+}
+
+func TestListUserLoans_Authorization(t *testing.T) {
+	s := newTestLoansServer()
+	patron := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "1", Role: auth.RolePatron})
+
+	_, err := s.ListUserLoans(patron, &pb.ListUserLoansRequest{UserId: "2"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestListUserLoans_InvalidInput(t *testing.T) {
+	s := newTestLoansServer()
+
+	_, err := s.ListUserLoans(context.Background(), &pb.ListUserLoansRequest{UserId: "abc"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
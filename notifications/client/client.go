@@ -4,21 +4,28 @@ import (
 	"context"
 	"time"
 
+	"github.com/ViktorOHJ/library-system/grpcclient"
 	"github.com/ViktorOHJ/library-system/protos/pb"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
+// service sets the deadlines and retries of every call made by this client.
+var service = grpcclient.Service{
+	Name: pb.NotificationService_ServiceDesc.ServiceName,
+	// SendNotification sends an email and is not retried.
+	Timeout: 10 * time.Second,
+}
+
 type NotificClient struct {
 	client  pb.NotificationServiceClient
 	timeout time.Duration
-	conn    *grpc.ClientConn
+	conn    *grpcclient.Conn
 	logger  *logrus.Logger
 }
 
-func NewNotificationClient(addr string, timeout time.Duration, logger *logrus.Logger) (*NotificClient, error) {
-	conn, err := grpc.Dial("localhost:"+addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewNotificationClient(addr string, timeout time.Duration, logger *logrus.Logger, opts ...grpc.DialOption) (*NotificClient, error) {
+	conn, err := grpcclient.Dial(addr, service, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -37,6 +44,12 @@ func (c *NotificClient) Send(ctx context.Context, nType string) (*pb.Notificatio
 	)
 }
 
+// Ping fails while the circuit breaker is open and otherwise asks the service's health
+// endpoint whether it is serving.
+func (c *NotificClient) Ping(ctx context.Context) error {
+	return c.conn.Ping(ctx)
+}
+
 func (c *NotificClient) Close() error {
 	return c.conn.Close()
 }
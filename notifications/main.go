@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
 	"net"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/config"
+	"github.com/ViktorOHJ/library-system/healthcheck"
 	notificserver "github.com/ViktorOHJ/library-system/notifications/server"
+	"github.com/ViktorOHJ/library-system/observability"
 	pb "github.com/ViktorOHJ/library-system/protos/pb"
-	"github.com/joho/godotenv"
+	"github.com/ViktorOHJ/library-system/requestid"
+	"github.com/ViktorOHJ/library-system/tlsconfig"
+	userclient "github.com/ViktorOHJ/library-system/users/client"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 )
@@ -21,20 +29,55 @@ func main() {
 		ForceColors:     true,
 	})
 
-	err := godotenv.Load(".env")
+	cfg, err := config.LoadNotifications(os.Args[1:])
 	if err != nil {
-		logger.Fatal("Error loading .env file")
+		logger.Fatal(err)
+	}
+	tlsCreds, err := tlsconfig.Load(cfg.TLS, "notifications", logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure TLS: %v", err)
+	}
+	if cfg.Server.Healthcheck {
+		os.Exit(healthcheck.RunProbe("localhost:"+cfg.Server.Port, tlsCreds.DialOption()))
+	}
+	tlsCreds.Start(context.Background())
+
+	shutdownTracing, err := observability.InitTracing(context.Background(), observability.TracingConfig{
+		ServiceName: "notifications",
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+	}, logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure tracing: %v", err)
+	}
+	defer shutdownTracing()
+	tokens, err := auth.NewTokenManager(cfg.Auth.JWTSecret, cfg.Auth.AccessTTL, cfg.Auth.RefreshTTL)
+	if err != nil {
+		logger.Fatalf("Failed to configure authentication: %v", err)
 	}
-	notificServer := notificserver.NewNotificServer(logger)
-	server := grpc.NewServer()
-	pb.RegisterNotificationServiceServer(server, notificServer)
 
-	PORT := os.Getenv("NOTIFICATIONS_PORT")
-	if PORT == "" {
-		PORT = "50054"
-		logger.Infof("NOTIFICATIONS_PORT not set, using default port %s", PORT)
+	usersClient, err := userclient.NewUserClient(cfg.Services.Users, 5*time.Second, logger, tlsCreds.DialOption())
+	if err != nil {
+		logger.Fatalf("Failed to create users client: %v", err)
 	}
+	defer usersClient.Close()
+	keys := auth.CacheKeys(usersClient, time.Minute)
 
+	notificServer := notificserver.NewNotificServer(logger, cfg)
+	server := grpc.NewServer(
+		tlsCreds.ServerOption(),
+		grpc.ChainUnaryInterceptor(observability.UnaryServerInterceptor(), requestid.UnaryServerInterceptor(logger)),
+		observability.GRPCServerHandler(),
+		auth.ServerInterceptors(tokens, keys, auth.Permissions),
+	)
+	pb.RegisterNotificationServiceServer(server, notificServer)
+
+	checker := healthcheck.New(logger, pb.NotificationService_ServiceDesc.ServiceName)
+	checker.Add("rabbitmq", notificServer.CheckRabbit)
+	checker.Register(server)
+	checker.Start(context.Background())
+
+	PORT := cfg.Server.Port
 	lis, err := net.Listen("tcp", ":"+PORT)
 	if err != nil {
 		logger.Fatalf("Failed to listen: %v", err)
@@ -42,6 +85,8 @@ func main() {
 
 	logger.Infof("Listening on port %s", PORT)
 
+	metricsServer := observability.Serve(cfg.Server.MetricsPort, logger)
+
 	shutdownChan := make(chan os.Signal, 1)
 	signal.Notify(shutdownChan, os.Interrupt, syscall.SIGTERM)
 	go func() {
@@ -52,7 +97,9 @@ func main() {
 
 	<-shutdownChan
 	logger.Info("Received shutdown signal, stopping server gracefully...")
+	checker.Shutdown()
 	notificServer.Shutdown()
 	server.GracefulStop()
+	observability.Shutdown(metricsServer)
 	logger.Info("Server stopped gracefully")
 }
@@ -4,23 +4,41 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"sync"
+	"time"
 
+	"github.com/ViktorOHJ/library-system/calendar"
+	"github.com/ViktorOHJ/library-system/config"
+	"github.com/ViktorOHJ/library-system/observability"
 	"github.com/ViktorOHJ/library-system/protos/pb"
 	"github.com/ViktorOHJ/library-system/rabbit"
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"gopkg.in/gomail.v2"
 )
 
+var tracer = otel.Tracer("github.com/ViktorOHJ/library-system/notifications/server")
+
 type EmailSender interface {
-	SendEmail(to, subject, body string) error
+	SendEmail(to, subject, body string, attachments ...Attachment) error
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
 }
 
 type MessageConsumer interface {
 	ConsumeFromQueue(queueName string) (<-chan amqp091.Delivery, error)
+	Check(ctx context.Context) error
 	Close()
 }
 
@@ -40,12 +58,22 @@ func NewSMTPEmailSender(host string, port int, username, password string) *SMTPE
 	}
 }
 
-func (s *SMTPEmailSender) SendEmail(to, subject, body string) error {
+func (s *SMTPEmailSender) SendEmail(to, subject, body string, attachments ...Attachment) error {
 	m := gomail.NewMessage()
 	m.SetHeader("From", s.username)
 	m.SetHeader("To", to)
 	m.SetHeader("Subject", subject)
 	m.SetBody("text/html", body)
+	for _, a := range attachments {
+		data := a.Data
+		m.Attach(a.Filename,
+			gomail.SetHeader(map[string][]string{"Content-Type": {a.ContentType}}),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			}),
+		)
+	}
 
 	d := gomail.NewDialer(s.host, s.port, s.username, s.password)
 	d.SSL = true
@@ -69,6 +97,10 @@ func (r *RabbitConsumer) ConsumeFromQueue(queueName string) (<-chan amqp091.Deli
 	return r.client.ConsumeFromQueue(queueName)
 }
 
+func (r *RabbitConsumer) Check(ctx context.Context) error {
+	return r.client.Check(ctx)
+}
+
 func (r *RabbitConsumer) Close() {
 	r.client.Close()
 }
@@ -76,13 +108,16 @@ func (r *RabbitConsumer) Close() {
 type NotificServer struct {
 	pb.UnimplementedNotificationServiceServer
 	logger          *logrus.Logger
+	mu              sync.Mutex // guards emailSender and messageConsumer while they are created
 	emailSender     EmailSender
 	messageConsumer MessageConsumer
+	cfg             *config.Notifications
 }
 
-func NewNotificServer(logger *logrus.Logger) *NotificServer {
+func NewNotificServer(logger *logrus.Logger, cfg *config.Notifications) *NotificServer {
 	return &NotificServer{
 		logger: logger,
+		cfg:    cfg,
 	}
 }
 
@@ -111,19 +146,26 @@ func (s *NotificServer) SendNotification(ctx context.Context, req *pb.Notificati
 	for msg := range msgs {
 		s.logger.Infof("Received: %s", msg.Body)
 
-		if err := s.processMessage(msg.Body); err != nil {
+		msgCtx, span := rabbit.StartConsumerSpan(ctx, req.NotificationType, msg)
+		err := s.processMessage(msgCtx, msg.Body)
+		observability.MessageConsumed(req.NotificationType, err)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+			span.End()
 			s.logger.Errorf("Error processing message: %v", err)
 			msg.Nack(false, true)
 			continue
 		}
 
+		span.End()
 		msg.Ack(false)
 	}
 
 	return &pb.NotificationResponse{Success: true}, nil
 }
 
-func (s *NotificServer) processMessage(messageBody []byte) error {
+func (s *NotificServer) processMessage(ctx context.Context, messageBody []byte) error {
 	var event rabbit.TaskMessage
 	if err := json.Unmarshal(messageBody, &event); err != nil {
 		return fmt.Errorf("failed to unmarshal message: %w", err)
@@ -135,7 +177,40 @@ func (s *NotificServer) processMessage(messageBody []byte) error {
 
 	subject, body := s.formatEmailContent(event)
 
-	return s.emailSender.SendEmail(event.Email, subject, body)
+	var attachments []Attachment
+	if event.Type == "Borrow" {
+		attachment, err := dueDateAttachment(event)
+		if err != nil {
+			s.logger.Warnf("Skipping calendar attachment for loan %s: %v", event.LoanID, err)
+		} else {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	_, span := tracer.Start(ctx, "SendEmail", trace.WithAttributes(attribute.String("email.type", event.Type)))
+	defer span.End()
+
+	start := time.Now()
+	err := s.emailSender.SendEmail(event.Email, subject, body, attachments...)
+	observability.EmailSent(start, err)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	return err
+}
+
+func dueDateAttachment(event rabbit.TaskMessage) (Attachment, error) {
+	due, err := time.Parse("2006-01-02", event.DueDate)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("invalid due date %q: %w", event.DueDate, err)
+	}
+	ev := calendar.LoanDueEvent(event.LoanID, event.BookTitle, event.BookAuthor, due)
+	return Attachment{
+		Filename:    "due-date.ics",
+		ContentType: calendar.ContentType,
+		Data:        calendar.Encode("Library loans", []calendar.Event{ev}, time.Now()),
+	}, nil
 }
 
 func (s *NotificServer) formatEmailContent(event rabbit.TaskMessage) (subject, body string) {
@@ -149,11 +224,12 @@ func (s *NotificServer) formatEmailContent(event rabbit.TaskMessage) (subject, b
 			<p>Dear %s,</p>
 			<p>You have successfully borrowed the book <strong>"%s"</strong> by %s.</p>
 			<p>Please remember to return it by <strong>%s</strong>.</p>
+			<p>The due date is attached as a calendar event.</p>%s
 			<p>Happy reading!</p>
 			<p>Library Team</p>
 			</body>
 			</html>
-		`, event.UserName, event.BookTitle, event.BookAuthor, event.DueDate)
+		`, event.UserName, event.BookTitle, event.BookAuthor, event.DueDate, calendarLink(event.CalendarURL))
 	case "Return":
 		subject = "Book Return Confirmation"
 		body = fmt.Sprintf(`
@@ -175,25 +251,33 @@ func (s *NotificServer) formatEmailContent(event rabbit.TaskMessage) (subject, b
 	return subject, body
 }
 
-func (s *NotificServer) initDependencies() error {
-	if s.emailSender == nil {
-		email := os.Getenv("EMAIL")
-		mailPass := os.Getenv("MAIL_PASS")
+func calendarLink(url string) string {
+	if url == "" {
+		return ""
+	}
+	return fmt.Sprintf(`
+			<p><a href="%s">Subscribe to your loans calendar</a> to see all due dates.</p>`, url)
+}
 
-		if email == "" || mailPass == "" {
-			return fmt.Errorf("EMAIL or MAIL_PASS environment variables not set")
-		}
+// CheckRabbit is the readiness check of the consumer's connection. It connects the consumer
+// if no notification has been sent yet; Shutdown closes it.
+func (s *NotificServer) CheckRabbit(ctx context.Context) error {
+	if err := s.initDependencies(); err != nil {
+		return err
+	}
+	return s.messageConsumer.Check(ctx)
+}
 
-		s.emailSender = NewSMTPEmailSender("smtp.gmail.com", 465, email, mailPass)
+func (s *NotificServer) initDependencies() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.emailSender == nil {
+		smtp := s.cfg.SMTP
+		s.emailSender = NewSMTPEmailSender(smtp.Host, smtp.Port, smtp.Username, smtp.Password)
 	}
 
 	if s.messageConsumer == nil {
-		rabbitURL := os.Getenv("RABBIT_URL")
-		if rabbitURL == "" {
-			return fmt.Errorf("RABBIT_URL environment variable not set")
-		}
-
-		consumer, err := NewRabbitConsumer(rabbitURL, s.logger)
+		consumer, err := NewRabbitConsumer(s.cfg.RabbitMQ.URL, s.logger)
 		if err != nil {
 			return fmt.Errorf("failed to create rabbit consumer: %w", err)
 		}
@@ -204,19 +288,9 @@ func (s *NotificServer) initDependencies() error {
 	return nil
 }
 
-func (s *NotificServer) ValidateConfig() error {
-	requiredEnvVars := []string{"EMAIL", "MAIL_PASS", "RABBIT_URL"}
-
-	for _, envVar := range requiredEnvVars {
-		if os.Getenv(envVar) == "" {
-			return fmt.Errorf("%s environment variable is not set", envVar)
-		}
-	}
-
-	return nil
-}
-
 func (s *NotificServer) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.messageConsumer != nil {
 		s.messageConsumer.Close()
 	}
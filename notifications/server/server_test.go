@@ -13,15 +13,21 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 type MockEmailSender struct {
 	mock.Mock
+	attachments []Attachment
 }
 
-func (m *MockEmailSender) SendEmail(to, subject, body string) error {
+func (m *MockEmailSender) SendEmail(to, subject, body string, attachments ...Attachment) error {
+	m.attachments = append(m.attachments, attachments...)
 	args := m.Called(to, subject, body)
 	return args.Error(0)
 }
@@ -39,6 +45,10 @@ func (m *MockMessageConsumer) ConsumeFromQueue(queueName string) (<-chan amqp091
 	return m.messages, nil
 }
 
+func (m *MockMessageConsumer) Check(ctx context.Context) error {
+	return m.Called().Error(0)
+}
+
 func (m *MockMessageConsumer) Close() {
 	m.Called()
 	if m.messages != nil {
@@ -104,6 +114,40 @@ func TestNotificServer_SendNotification_Success(t *testing.T) {
 	mockConsumer.AssertExpectations(t)
 }
 
+func TestNotificServer_SendNotification_ContinuesTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockEmailSender := new(MockEmailSender)
+	mockEmailSender.On("SendEmail", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockConsumer := new(MockMessageConsumer)
+	server := NewNotificServerWithDeps(logger, mockEmailSender, mockConsumer)
+
+	delivery := createMockDelivery(createTestMessage("Borrow", "John Doe", "john@example.com"))
+	delivery.Headers = amqp091.Table{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	messages := make(chan amqp091.Delivery, 1)
+	messages <- delivery
+	close(messages)
+	mockConsumer.messages = messages
+	mockConsumer.On("ConsumeFromQueue", "borrow_queue").Return(messages, nil)
+
+	_, err := server.SendNotification(context.Background(), &pb.NotificationRequest{NotificationType: "borrow_queue"})
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	email, process := spans[0], spans[1]
+	assert.Equal(t, "SendEmail", email.Name())
+	assert.Equal(t, "borrow_queue process", process.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", process.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", process.Parent().SpanID().String(), "publisher's span is the parent")
+	assert.Equal(t, process.SpanContext().SpanID(), email.Parent().SpanID())
+}
+
 func TestNotificServer_SendNotification_EmailFailure(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.FatalLevel)
@@ -182,11 +226,35 @@ func TestNotificServer_ProcessMessage_Success(t *testing.T) {
 				assert.Contains(t, body, "Test Book")
 		})).Return(nil)
 
-	err := server.processMessage(messageBytes)
+	err := server.processMessage(context.Background(), messageBytes)
 	require.NoError(t, err)
 	mockEmailSender.AssertExpectations(t)
 }
 
+func TestNotificServer_ProcessMessage_CalendarAttachment(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mockEmailSender := new(MockEmailSender)
+	mockEmailSender.On("SendEmail", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	server := NewNotificServerWithDeps(logger, mockEmailSender, nil)
+
+	for _, msgType := range []string{"Borrow", "Return"} {
+		messageBytes, _ := json.Marshal(createTestMessage(msgType, "Test User", "test@example.com"))
+		require.NoError(t, server.processMessage(context.Background(), messageBytes))
+	}
+
+	require.Len(t, mockEmailSender.attachments, 1, "only the borrow email carries the due date")
+	for _, a := range mockEmailSender.attachments {
+		assert.Equal(t, "due-date.ics", a.Filename)
+		ics := string(a.Data)
+		assert.Contains(t, ics, "BEGIN:VEVENT")
+		assert.Contains(t, ics, "UID:loan-123@library-system")
+		assert.Contains(t, ics, "DTSTART;VALUE=DATE:20241231")
+		assert.Contains(t, ics, "TRIGGER:-P1D")
+	}
+}
+
 func TestNotificServer_ProcessMessage_InvalidJSON(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.FatalLevel)
@@ -195,7 +263,7 @@ func TestNotificServer_ProcessMessage_InvalidJSON(t *testing.T) {
 
 	invalidJSON := []byte(`{"invalid": json}`)
 
-	err := server.processMessage(invalidJSON)
+	err := server.processMessage(context.Background(), invalidJSON)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to unmarshal message")
 }
@@ -210,7 +278,7 @@ func TestNotificServer_ProcessMessage_EmptyEmail(t *testing.T) {
 	testMessage := createTestMessage("Borrow", "Test User", "")
 	messageBytes, _ := json.Marshal(testMessage)
 
-	err := server.processMessage(messageBytes)
+	err := server.processMessage(context.Background(), messageBytes)
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "email is empty")
@@ -260,7 +328,7 @@ func TestNotificServer_EdgeCases(t *testing.T) {
 
 			server := NewNotificServerWithDeps(logger, mockEmailSender, nil)
 
-			err := server.processMessage([]byte(tc.messageJSON))
+			err := server.processMessage(context.Background(), []byte(tc.messageJSON))
 
 			if tc.expectError {
 				require.Error(t, err)
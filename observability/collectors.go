@@ -0,0 +1,112 @@
+package observability
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// poolCollector reads pgxpool statistics at scrape time.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired, idle, total, max             *prometheus.Desc
+	acquireCount, acquireDuration          *prometheus.Desc
+	emptyAcquire, canceledAcquire          *prometheus.Desc
+	newConns, lifetimeDestroy, idleDestroy *prometheus.Desc
+}
+
+// RegisterPool exports the statistics of pool, labelled with the database name.
+func RegisterPool(database string, pool *pgxpool.Pool) error {
+	labels := prometheus.Labels{"database": database}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, labels)
+	}
+	return Registry.Register(&poolCollector{
+		pool:            pool,
+		acquired:        desc("acquired_connections", "Connections currently in use."),
+		idle:            desc("idle_connections", "Idle connections in the pool."),
+		total:           desc("total_connections", "All connections in the pool, including those being established."),
+		max:             desc("max_connections", "Maximum size of the pool."),
+		acquireCount:    desc("acquires_total", "Successful acquires from the pool."),
+		acquireDuration: desc("acquire_seconds_total", "Total time spent waiting for a successful acquire."),
+		emptyAcquire:    desc("empty_acquires_total", "Acquires that had to wait because the pool was empty."),
+		canceledAcquire: desc("canceled_acquires_total", "Acquires cancelled by their context."),
+		newConns:        desc("new_connections_total", "Connections opened by the pool."),
+		lifetimeDestroy: desc("max_lifetime_destroys_total", "Connections closed for exceeding MaxConnLifetime."),
+		idleDestroy:     desc("max_idle_destroys_total", "Connections closed for exceeding MaxConnIdleTime."),
+	})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+	gauge(c.acquired, float64(s.AcquiredConns()))
+	gauge(c.idle, float64(s.IdleConns()))
+	gauge(c.total, float64(s.TotalConns()))
+	gauge(c.max, float64(s.MaxConns()))
+	counter(c.acquireCount, float64(s.AcquireCount()))
+	counter(c.acquireDuration, s.AcquireDuration().Seconds())
+	counter(c.emptyAcquire, float64(s.EmptyAcquireCount()))
+	counter(c.canceledAcquire, float64(s.CanceledAcquireCount()))
+	counter(c.newConns, float64(s.NewConnsCount()))
+	counter(c.lifetimeDestroy, float64(s.MaxLifetimeDestroyCount()))
+	counter(c.idleDestroy, float64(s.MaxIdleDestroyCount()))
+}
+
+// Querier is the part of pgxpool.Pool used by domain gauges.
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+const gaugeQueryTimeout = 5 * time.Second
+
+// queryGauge runs a single-value SQL query on every scrape. A failed query is logged
+// and the gauge is left out of that scrape rather than reported as zero.
+type queryGauge struct {
+	db     Querier
+	name   string
+	query  string
+	desc   *prometheus.Desc
+	logger *logrus.Logger
+}
+
+// RegisterQueryGauge exports library_<name> with the count returned by query.
+func RegisterQueryGauge(db Querier, logger *logrus.Logger, name, help, query string) error {
+	return Registry.Register(&queryGauge{
+		db:     db,
+		name:   name,
+		query:  query,
+		desc:   prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, nil, nil),
+		logger: logger,
+	})
+}
+
+func (g *queryGauge) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+func (g *queryGauge) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), gaugeQueryTimeout)
+	defer cancel()
+
+	var v int64
+	if err := g.db.QueryRow(ctx, g.query).Scan(&v); err != nil {
+		g.logger.Errorf("Failed to collect %s: %v", g.name, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, float64(v))
+}
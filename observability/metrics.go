@@ -0,0 +1,176 @@
+package observability
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "library"
+
+// Registry holds every metric of the process. Each binary serves it on its own /metrics port.
+var Registry = prometheus.NewRegistry()
+
+var (
+	grpcHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_server_handled_total",
+		Help:      "Number of gRPC calls completed by the server, by method and status code.",
+	}, []string{"grpc_service", "grpc_method", "grpc_code"})
+
+	grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_server_handling_seconds",
+		Help:      "Time spent handling gRPC calls, by method and status code.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"grpc_service", "grpc_method", "grpc_code"})
+
+	messagesPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rabbitmq_messages_published_total",
+		Help:      "Number of messages published to RabbitMQ, by queue.",
+	}, []string{"queue"})
+
+	messagesConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rabbitmq_messages_consumed_total",
+		Help:      "Number of messages received from RabbitMQ, by queue.",
+	}, []string{"queue"})
+
+	messagesFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rabbitmq_messages_failed_total",
+		Help:      "Number of messages that could not be published or processed, by queue and stage.",
+	}, []string{"queue", "stage"})
+
+	emailDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "email_send_seconds",
+		Help:      "Time spent sending notification emails, by result.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"result"})
+
+	circuitState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_circuit_state",
+		Help:      "State of the circuit breaker in front of a downstream service: 0 closed, 1 half-open, 2 open.",
+	}, []string{"downstream"})
+
+	circuitRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_circuit_rejected_total",
+		Help:      "Number of calls failed fast because the circuit breaker of a downstream service was open.",
+	}, []string{"downstream"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		grpcHandled,
+		grpcDuration,
+		messagesPublished,
+		messagesConsumed,
+		messagesFailed,
+		emailDuration,
+		circuitState,
+		circuitRejected,
+	)
+}
+
+// UnaryServerInterceptor counts and times every call by method and resulting status code.
+// It should be the outermost interceptor so that rejected calls are counted as well.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		service, method := splitMethod(info.FullMethod)
+		code := status.Code(err).String()
+		grpcHandled.WithLabelValues(service, method, code).Inc()
+		grpcDuration.WithLabelValues(service, method, code).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// splitMethod turns "/library.BookService/GetBook" into "library.BookService" and "GetBook".
+func splitMethod(fullMethod string) (string, string) {
+	service, method, found := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !found {
+		return "unknown", fullMethod
+	}
+	return service, method
+}
+
+// MessagePublished records the outcome of publishing a message to queue.
+func MessagePublished(queue string, err error) {
+	if err != nil {
+		messagesFailed.WithLabelValues(queue, "publish").Inc()
+		return
+	}
+	messagesPublished.WithLabelValues(queue).Inc()
+}
+
+// MessageConsumed records a received message and, if err is set, its failed processing.
+func MessageConsumed(queue string, err error) {
+	messagesConsumed.WithLabelValues(queue).Inc()
+	if err != nil {
+		messagesFailed.WithLabelValues(queue, "consume").Inc()
+	}
+}
+
+// EmailSent records how long sending an email took.
+func EmailSent(start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	emailDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+// CircuitState records the state of the circuit breaker in front of downstream.
+func CircuitState(downstream string, state int) {
+	circuitState.WithLabelValues(downstream).Set(float64(state))
+}
+
+// CircuitRejected counts a call that the open breaker of downstream refused.
+func CircuitRejected(downstream string) {
+	circuitRejected.WithLabelValues(downstream).Inc()
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Serve exposes /metrics on port in the background. The returned server is shut down by the caller.
+func Serve(port string, logger *logrus.Logger) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("Failed to serve metrics: %v", err)
+		}
+	}()
+	logger.Infof("Metrics listening on port %s", port)
+	return server
+}
+
+// Shutdown stops a server returned by Serve, waiting at most a few seconds for open scrapes.
+func Shutdown(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+}
@@ -0,0 +1,118 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/library.BookService/GetBook"}
+
+	tests := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{"ok", nil, "OK"},
+		{"not found", status.Error(codes.NotFound, "book not found"), "NotFound"},
+		{"plain error", errors.New("boom"), "Unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := grpcHandled.WithLabelValues("library.BookService", "GetBook", tt.code)
+			before := testutil.ToFloat64(counter)
+
+			_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+				return nil, tt.err
+			})
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, before+1, testutil.ToFloat64(counter))
+		})
+	}
+}
+
+func TestSplitMethod(t *testing.T) {
+	service, method := splitMethod("/library.UserService/GetUser")
+	assert.Equal(t, "library.UserService", service)
+	assert.Equal(t, "GetUser", method)
+
+	service, method = splitMethod("bogus")
+	assert.Equal(t, "unknown", service)
+	assert.Equal(t, "bogus", method)
+}
+
+func TestMessageCounters(t *testing.T) {
+	MessagePublished("test_queue", nil)
+	MessagePublished("test_queue", errors.New("channel closed"))
+	MessageConsumed("test_queue", nil)
+	MessageConsumed("test_queue", errors.New("email is empty"))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(messagesPublished.WithLabelValues("test_queue")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(messagesConsumed.WithLabelValues("test_queue")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(messagesFailed.WithLabelValues("test_queue", "publish")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(messagesFailed.WithLabelValues("test_queue", "consume")))
+}
+
+type fakeRow struct {
+	value int64
+	err   error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*int64) = r.value
+	return nil
+}
+
+type fakeQuerier struct {
+	row fakeRow
+}
+
+func (q *fakeQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return q.row
+}
+
+func TestQueryGauge(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	db := &fakeQuerier{row: fakeRow{value: 7}}
+	gauge := &queryGauge{
+		db:     db,
+		name:   "test_gauge",
+		query:  "SELECT 7",
+		desc:   prometheus.NewDesc("library_test_gauge", "Test gauge.", nil, nil),
+		logger: logger,
+	}
+
+	assert.Equal(t, float64(7), testutil.ToFloat64(gauge))
+
+	db.row = fakeRow{err: errors.New("connection refused")}
+	assert.Equal(t, 0, testutil.CollectAndCount(gauge), "failed queries are left out of the scrape")
+}
+
+func TestHandler(t *testing.T) {
+	EmailSent(time.Now(), nil)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, rec.Code)
+	assert.Contains(t, rec.Body.String(), `library_email_send_seconds_count{result="success"}`)
+	assert.Contains(t, rec.Body.String(), "go_goroutines")
+}
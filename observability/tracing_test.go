@@ -0,0 +1,35 @@
+package observability
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestInitTracing(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	_, err := InitTracing(context.Background(), TracingConfig{ServiceName: "test", Exporter: "jaeger"}, logger)
+	assert.ErrorContains(t, err, `unknown traces exporter "jaeger"`)
+
+	file := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := InitTracing(context.Background(), TracingConfig{ServiceName: "test", Exporter: ExporterFile, File: file}, logger)
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "BorrowBook")
+	span.End()
+	shutdown()
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"BorrowBook"`)
+	assert.Contains(t, string(data), `"Value":"test"`, "spans carry the service name")
+}
@@ -0,0 +1,7 @@
+// Package openapi embeds the OpenAPI spec generated from the service protos.
+package openapi
+
+import _ "embed"
+
+//go:embed library.swagger.json
+var Spec []byte
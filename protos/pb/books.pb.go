@@ -7,8 +7,10 @@
 package pb
 
 import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -21,27 +23,27 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-type UpdateBookRequest struct {
+type GetBookRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	BookId        string                 `protobuf:"bytes,1,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UpdateBookRequest) Reset() {
-	*x = UpdateBookRequest{}
+func (x *GetBookRequest) Reset() {
+	*x = GetBookRequest{}
 	mi := &file_books_proto_msgTypes[0]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UpdateBookRequest) String() string {
+func (x *GetBookRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpdateBookRequest) ProtoMessage() {}
+func (*GetBookRequest) ProtoMessage() {}
 
-func (x *UpdateBookRequest) ProtoReflect() protoreflect.Message {
+func (x *GetBookRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_books_proto_msgTypes[0]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -53,39 +55,39 @@ func (x *UpdateBookRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpdateBookRequest.ProtoReflect.Descriptor instead.
-func (*UpdateBookRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use GetBookRequest.ProtoReflect.Descriptor instead.
+func (*GetBookRequest) Descriptor() ([]byte, []int) {
 	return file_books_proto_rawDescGZIP(), []int{0}
 }
 
-func (x *UpdateBookRequest) GetBookId() string {
+func (x *GetBookRequest) GetBookId() string {
 	if x != nil {
 		return x.BookId
 	}
 	return ""
 }
 
-type GetBookRequest struct {
+type GetBooksRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	BookId        string                 `protobuf:"bytes,1,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	BookIds       []string               `protobuf:"bytes,1,rep,name=book_ids,json=bookIds,proto3" json:"book_ids,omitempty"` // Не больше 100
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetBookRequest) Reset() {
-	*x = GetBookRequest{}
+func (x *GetBooksRequest) Reset() {
+	*x = GetBooksRequest{}
 	mi := &file_books_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetBookRequest) String() string {
+func (x *GetBooksRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetBookRequest) ProtoMessage() {}
+func (*GetBooksRequest) ProtoMessage() {}
 
-func (x *GetBookRequest) ProtoReflect() protoreflect.Message {
+func (x *GetBooksRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_books_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -97,30 +99,80 @@ func (x *GetBookRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetBookRequest.ProtoReflect.Descriptor instead.
-func (*GetBookRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use GetBooksRequest.ProtoReflect.Descriptor instead.
+func (*GetBooksRequest) Descriptor() ([]byte, []int) {
 	return file_books_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *GetBookRequest) GetBookId() string {
+func (x *GetBooksRequest) GetBookIds() []string {
 	if x != nil {
-		return x.BookId
+		return x.BookIds
 	}
-	return ""
+	return nil
+}
+
+type GetBooksResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Книги в порядке запроса; несуществующие id пропускаются
+	Books         []*BookResponse `protobuf:"bytes,1,rep,name=books,proto3" json:"books,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBooksResponse) Reset() {
+	*x = GetBooksResponse{}
+	mi := &file_books_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBooksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBooksResponse) ProtoMessage() {}
+
+func (x *GetBooksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBooksResponse.ProtoReflect.Descriptor instead.
+func (*GetBooksResponse) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetBooksResponse) GetBooks() []*BookResponse {
+	if x != nil {
+		return x.Books
+	}
+	return nil
 }
 
 type CreateBookRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
-	Author        string                 `protobuf:"bytes,2,opt,name=author,proto3" json:"author,omitempty"`
-	Year          int32                  `protobuf:"varint,3,opt,name=year,proto3" json:"year,omitempty"`
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Title  string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Author string                 `protobuf:"bytes,2,opt,name=author,proto3" json:"author,omitempty"`
+	Year   int32                  `protobuf:"varint,3,opt,name=year,proto3" json:"year,omitempty"`
+	Copies int32                  `protobuf:"varint,4,opt,name=copies,proto3" json:"copies,omitempty"` // Сколько экземпляров завести со сгенерированными штрихкодами; 0 — один
+	Isbn   string                 `protobuf:"bytes,5,opt,name=isbn,proto3" json:"isbn,omitempty"`      // ISBN-10 или ISBN-13, дефисы и пробелы допускаются; необязателен
+	// Авторы, переводчики и редакторы; вместо author. Имена сопоставляются с авторами и их вариантами,
+	// неизвестные заводятся как новые авторы.
+	Contributors  []*Contributor `protobuf:"bytes,6,rep,name=contributors,proto3" json:"contributors,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *CreateBookRequest) Reset() {
 	*x = CreateBookRequest{}
-	mi := &file_books_proto_msgTypes[2]
+	mi := &file_books_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -132,7 +184,7 @@ func (x *CreateBookRequest) String() string {
 func (*CreateBookRequest) ProtoMessage() {}
 
 func (x *CreateBookRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_books_proto_msgTypes[2]
+	mi := &file_books_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -145,7 +197,7 @@ func (x *CreateBookRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateBookRequest.ProtoReflect.Descriptor instead.
 func (*CreateBookRequest) Descriptor() ([]byte, []int) {
-	return file_books_proto_rawDescGZIP(), []int{2}
+	return file_books_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *CreateBookRequest) GetTitle() string {
@@ -169,20 +221,112 @@ func (x *CreateBookRequest) GetYear() int32 {
 	return 0
 }
 
-type BookResponse struct {
+func (x *CreateBookRequest) GetCopies() int32 {
+	if x != nil {
+		return x.Copies
+	}
+	return 0
+}
+
+func (x *CreateBookRequest) GetIsbn() string {
+	if x != nil {
+		return x.Isbn
+	}
+	return ""
+}
+
+func (x *CreateBookRequest) GetContributors() []*Contributor {
+	if x != nil {
+		return x.Contributors
+	}
+	return nil
+}
+
+type Contributor struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
-	Author        string                 `protobuf:"bytes,3,opt,name=author,proto3" json:"author,omitempty"`
-	Year          int32                  `protobuf:"varint,4,opt,name=year,proto3" json:"year,omitempty"`
-	Available     bool                   `protobuf:"varint,5,opt,name=available,proto3" json:"available,omitempty"`
+	AuthorId      string                 `protobuf:"bytes,1,opt,name=author_id,json=authorId,proto3" json:"author_id,omitempty"` // Или name
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                         // В ответах — каноническое имя
+	Role          string                 `protobuf:"bytes,3,opt,name=role,proto3" json:"role,omitempty"`                         // "author" (по умолчанию), "translator" или "editor"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Contributor) Reset() {
+	*x = Contributor{}
+	mi := &file_books_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Contributor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Contributor) ProtoMessage() {}
+
+func (x *Contributor) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Contributor.ProtoReflect.Descriptor instead.
+func (*Contributor) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Contributor) GetAuthorId() string {
+	if x != nil {
+		return x.AuthorId
+	}
+	return ""
+}
+
+func (x *Contributor) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Contributor) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+type BookResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title            string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Author           string                 `protobuf:"bytes,3,opt,name=author,proto3" json:"author,omitempty"`
+	Year             int32                  `protobuf:"varint,4,opt,name=year,proto3" json:"year,omitempty"`
+	Available        bool                   `protobuf:"varint,5,opt,name=available,proto3" json:"available,omitempty"`                                    // Есть хотя бы один экземпляр на полке
+	AvailableCopies  int32                  `protobuf:"varint,6,opt,name=available_copies,json=availableCopies,proto3" json:"available_copies,omitempty"` // «n из m экземпляров»: n
+	TotalCopies      int32                  `protobuf:"varint,7,opt,name=total_copies,json=totalCopies,proto3" json:"total_copies,omitempty"`             // m — экземпляры в фонде, без списанных и утерянных
+	Isbn13           string                 `protobuf:"bytes,8,opt,name=isbn13,proto3" json:"isbn13,omitempty"`
+	Isbn10           string                 `protobuf:"bytes,9,opt,name=isbn10,proto3" json:"isbn10,omitempty"` // Пусто для ISBN с префиксом 979
+	Withdrawn        bool                   `protobuf:"varint,10,opt,name=withdrawn,proto3" json:"withdrawn,omitempty"`
+	WithdrawalReason string                 `protobuf:"bytes,11,opt,name=withdrawal_reason,json=withdrawalReason,proto3" json:"withdrawal_reason,omitempty"`
+	WithdrawnAt      string                 `protobuf:"bytes,12,opt,name=withdrawn_at,json=withdrawnAt,proto3" json:"withdrawn_at,omitempty"` // RFC 3339
+	// author — отображаемое имя: канонические имена авторов через запятую
+	Contributors  []*Contributor `protobuf:"bytes,13,rep,name=contributors,proto3" json:"contributors,omitempty"`
+	Subjects      []*Subject     `protobuf:"bytes,14,rep,name=subjects,proto3" json:"subjects,omitempty"` // Без path и book_count
+	Tags          []string       `protobuf:"bytes,15,rep,name=tags,proto3" json:"tags,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *BookResponse) Reset() {
 	*x = BookResponse{}
-	mi := &file_books_proto_msgTypes[3]
+	mi := &file_books_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -194,7 +338,7 @@ func (x *BookResponse) String() string {
 func (*BookResponse) ProtoMessage() {}
 
 func (x *BookResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_books_proto_msgTypes[3]
+	mi := &file_books_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -207,7 +351,7 @@ func (x *BookResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BookResponse.ProtoReflect.Descriptor instead.
 func (*BookResponse) Descriptor() ([]byte, []int) {
-	return file_books_proto_rawDescGZIP(), []int{3}
+	return file_books_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *BookResponse) GetId() string {
@@ -245,30 +389,3302 @@ func (x *BookResponse) GetAvailable() bool {
 	return false
 }
 
-var File_books_proto protoreflect.FileDescriptor
+func (x *BookResponse) GetAvailableCopies() int32 {
+	if x != nil {
+		return x.AvailableCopies
+	}
+	return 0
+}
 
-const file_books_proto_rawDesc = "" +
-	"\n" +
-	"\vbooks.proto\x12\alibrary\",\n" +
-	"\x11UpdateBookRequest\x12\x17\n" +
-	"\abook_id\x18\x01 \x01(\tR\x06bookId\")\n" +
-	"\x0eGetBookRequest\x12\x17\n" +
-	"\abook_id\x18\x01 \x01(\tR\x06bookId\"U\n" +
-	"\x11CreateBookRequest\x12\x14\n" +
-	"\x05title\x18\x01 \x01(\tR\x05title\x12\x16\n" +
-	"\x06author\x18\x02 \x01(\tR\x06author\x12\x12\n" +
-	"\x04year\x18\x03 \x01(\x05R\x04year\"~\n" +
-	"\fBookResponse\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
-	"\x05title\x18\x02 \x01(\tR\x05title\x12\x16\n" +
-	"\x06author\x18\x03 \x01(\tR\x06author\x12\x12\n" +
-	"\x04year\x18\x04 \x01(\x05R\x04year\x12\x1c\n" +
-	"\tavailable\x18\x05 \x01(\bR\tavailable2\xd6\x01\n" +
-	"\vBookService\x12;\n" +
-	"\aGetBook\x12\x17.library.GetBookRequest\x1a\x15.library.BookResponse\"\x00\x12A\n" +
+func (x *BookResponse) GetTotalCopies() int32 {
+	if x != nil {
+		return x.TotalCopies
+	}
+	return 0
+}
+
+func (x *BookResponse) GetIsbn13() string {
+	if x != nil {
+		return x.Isbn13
+	}
+	return ""
+}
+
+func (x *BookResponse) GetIsbn10() string {
+	if x != nil {
+		return x.Isbn10
+	}
+	return ""
+}
+
+func (x *BookResponse) GetWithdrawn() bool {
+	if x != nil {
+		return x.Withdrawn
+	}
+	return false
+}
+
+func (x *BookResponse) GetWithdrawalReason() string {
+	if x != nil {
+		return x.WithdrawalReason
+	}
+	return ""
+}
+
+func (x *BookResponse) GetWithdrawnAt() string {
+	if x != nil {
+		return x.WithdrawnAt
+	}
+	return ""
+}
+
+func (x *BookResponse) GetContributors() []*Contributor {
+	if x != nil {
+		return x.Contributors
+	}
+	return nil
+}
+
+func (x *BookResponse) GetSubjects() []*Subject {
+	if x != nil {
+		return x.Subjects
+	}
+	return nil
+}
+
+func (x *BookResponse) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type UpdateBookRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	BookId string                 `protobuf:"bytes,1,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	Title  string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Author string                 `protobuf:"bytes,3,opt,name=author,proto3" json:"author,omitempty"`
+	Year   int32                  `protobuf:"varint,4,opt,name=year,proto3" json:"year,omitempty"`
+	Isbn   string                 `protobuf:"bytes,5,opt,name=isbn,proto3" json:"isbn,omitempty"`
+	// Поля для обновления: "title", "author", "year", "isbn", "contributors". Пустая маска обновляет
+	// все непустые поля; "isbn" в маске с пустым значением удаляет ISBN. "author" заменяет авторов,
+	// сохраняя переводчиков и редакторов, а "contributors" — весь список.
+	UpdateMask    *fieldmaskpb.FieldMask `protobuf:"bytes,6,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	Contributors  []*Contributor         `protobuf:"bytes,7,rep,name=contributors,proto3" json:"contributors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateBookRequest) Reset() {
+	*x = UpdateBookRequest{}
+	mi := &file_books_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateBookRequest) ProtoMessage() {}
+
+func (x *UpdateBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateBookRequest.ProtoReflect.Descriptor instead.
+func (*UpdateBookRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateBookRequest) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *UpdateBookRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *UpdateBookRequest) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *UpdateBookRequest) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+func (x *UpdateBookRequest) GetIsbn() string {
+	if x != nil {
+		return x.Isbn
+	}
+	return ""
+}
+
+func (x *UpdateBookRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+func (x *UpdateBookRequest) GetContributors() []*Contributor {
+	if x != nil {
+		return x.Contributors
+	}
+	return nil
+}
+
+type Author struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Variants      []string               `protobuf:"bytes,3,rep,name=variants,proto3" json:"variants,omitempty"` // Другие написания, в том числе имена слитых авторов
+	BookCount     int32                  `protobuf:"varint,4,opt,name=book_count,json=bookCount,proto3" json:"book_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Author) Reset() {
+	*x = Author{}
+	mi := &file_books_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Author) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Author) ProtoMessage() {}
+
+func (x *Author) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Author.ProtoReflect.Descriptor instead.
+func (*Author) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Author) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Author) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Author) GetVariants() []string {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+func (x *Author) GetBookCount() int32 {
+	if x != nil {
+		return x.BookCount
+	}
+	return 0
+}
+
+type CreateAuthorRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Variants      []string               `protobuf:"bytes,2,rep,name=variants,proto3" json:"variants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateAuthorRequest) Reset() {
+	*x = CreateAuthorRequest{}
+	mi := &file_books_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateAuthorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateAuthorRequest) ProtoMessage() {}
+
+func (x *CreateAuthorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateAuthorRequest.ProtoReflect.Descriptor instead.
+func (*CreateAuthorRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CreateAuthorRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateAuthorRequest) GetVariants() []string {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+type GetAuthorRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AuthorId      string                 `protobuf:"bytes,1,opt,name=author_id,json=authorId,proto3" json:"author_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAuthorRequest) Reset() {
+	*x = GetAuthorRequest{}
+	mi := &file_books_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAuthorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAuthorRequest) ProtoMessage() {}
+
+func (x *GetAuthorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAuthorRequest.ProtoReflect.Descriptor instead.
+func (*GetAuthorRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetAuthorRequest) GetAuthorId() string {
+	if x != nil {
+		return x.AuthorId
+	}
+	return ""
+}
+
+type SearchAuthorsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	PageSize      int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"` // По умолчанию 20, максимум 100
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchAuthorsRequest) Reset() {
+	*x = SearchAuthorsRequest{}
+	mi := &file_books_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchAuthorsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchAuthorsRequest) ProtoMessage() {}
+
+func (x *SearchAuthorsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchAuthorsRequest.ProtoReflect.Descriptor instead.
+func (*SearchAuthorsRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SearchAuthorsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchAuthorsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+type SearchAuthorsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Authors       []*Author              `protobuf:"bytes,1,rep,name=authors,proto3" json:"authors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchAuthorsResponse) Reset() {
+	*x = SearchAuthorsResponse{}
+	mi := &file_books_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchAuthorsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchAuthorsResponse) ProtoMessage() {}
+
+func (x *SearchAuthorsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchAuthorsResponse.ProtoReflect.Descriptor instead.
+func (*SearchAuthorsResponse) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SearchAuthorsResponse) GetAuthors() []*Author {
+	if x != nil {
+		return x.Authors
+	}
+	return nil
+}
+
+type MergeAuthorsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AuthorId      string                 `protobuf:"bytes,1,opt,name=author_id,json=authorId,proto3" json:"author_id,omitempty"`    // Остаётся
+	SourceIds     []string               `protobuf:"bytes,2,rep,name=source_ids,json=sourceIds,proto3" json:"source_ids,omitempty"` // Сливаются в author_id и удаляются
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MergeAuthorsRequest) Reset() {
+	*x = MergeAuthorsRequest{}
+	mi := &file_books_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MergeAuthorsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MergeAuthorsRequest) ProtoMessage() {}
+
+func (x *MergeAuthorsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MergeAuthorsRequest.ProtoReflect.Descriptor instead.
+func (*MergeAuthorsRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *MergeAuthorsRequest) GetAuthorId() string {
+	if x != nil {
+		return x.AuthorId
+	}
+	return ""
+}
+
+func (x *MergeAuthorsRequest) GetSourceIds() []string {
+	if x != nil {
+		return x.SourceIds
+	}
+	return nil
+}
+
+// Subject — рубрика или жанр; рубрики образуют дерево.
+type Subject struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	ParentId      string                 `protobuf:"bytes,3,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`          // Пусто для рубрик верхнего уровня
+	ClassNumber   string                 `protobuf:"bytes,4,opt,name=class_number,json=classNumber,proto3" json:"class_number,omitempty"` // Индекс УДК или Дьюи, необязателен
+	Path          []string               `protobuf:"bytes,5,rep,name=path,proto3" json:"path,omitempty"`                                  // Имена рубрик от корня до этой, включительно
+	BookCount     int32                  `protobuf:"varint,6,opt,name=book_count,json=bookCount,proto3" json:"book_count,omitempty"`      // Книги в рубрике и её подрубриках, без списанных
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Subject) Reset() {
+	*x = Subject{}
+	mi := &file_books_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Subject) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Subject) ProtoMessage() {}
+
+func (x *Subject) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Subject.ProtoReflect.Descriptor instead.
+func (*Subject) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Subject) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Subject) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Subject) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+func (x *Subject) GetClassNumber() string {
+	if x != nil {
+		return x.ClassNumber
+	}
+	return ""
+}
+
+func (x *Subject) GetPath() []string {
+	if x != nil {
+		return x.Path
+	}
+	return nil
+}
+
+func (x *Subject) GetBookCount() int32 {
+	if x != nil {
+		return x.BookCount
+	}
+	return 0
+}
+
+type CreateSubjectRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	ParentId      string                 `protobuf:"bytes,2,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	ClassNumber   string                 `protobuf:"bytes,3,opt,name=class_number,json=classNumber,proto3" json:"class_number,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSubjectRequest) Reset() {
+	*x = CreateSubjectRequest{}
+	mi := &file_books_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSubjectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSubjectRequest) ProtoMessage() {}
+
+func (x *CreateSubjectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSubjectRequest.ProtoReflect.Descriptor instead.
+func (*CreateSubjectRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *CreateSubjectRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateSubjectRequest) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+func (x *CreateSubjectRequest) GetClassNumber() string {
+	if x != nil {
+		return x.ClassNumber
+	}
+	return ""
+}
+
+type ListSubjectsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ParentId      string                 `protobuf:"bytes,1,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"` // Пусто — рубрики верхнего уровня
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSubjectsRequest) Reset() {
+	*x = ListSubjectsRequest{}
+	mi := &file_books_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSubjectsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSubjectsRequest) ProtoMessage() {}
+
+func (x *ListSubjectsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSubjectsRequest.ProtoReflect.Descriptor instead.
+func (*ListSubjectsRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListSubjectsRequest) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+type ListSubjectsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Parent        *Subject               `protobuf:"bytes,1,opt,name=parent,proto3" json:"parent,omitempty"`
+	Subjects      []*Subject             `protobuf:"bytes,2,rep,name=subjects,proto3" json:"subjects,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSubjectsResponse) Reset() {
+	*x = ListSubjectsResponse{}
+	mi := &file_books_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSubjectsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSubjectsResponse) ProtoMessage() {}
+
+func (x *ListSubjectsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSubjectsResponse.ProtoReflect.Descriptor instead.
+func (*ListSubjectsResponse) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ListSubjectsResponse) GetParent() *Subject {
+	if x != nil {
+		return x.Parent
+	}
+	return nil
+}
+
+func (x *ListSubjectsResponse) GetSubjects() []*Subject {
+	if x != nil {
+		return x.Subjects
+	}
+	return nil
+}
+
+type ListBooksBySubjectRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SubjectId     string                 `protobuf:"bytes,1,opt,name=subject_id,json=subjectId,proto3" json:"subject_id,omitempty"`
+	Exact         bool                   `protobuf:"varint,2,opt,name=exact,proto3" json:"exact,omitempty"`                       // Только сама рубрика, без подрубрик
+	Tag           string                 `protobuf:"bytes,3,opt,name=tag,proto3" json:"tag,omitempty"`                            // Дополнительно сузить по тегу
+	PageSize      int32                  `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"` // По умолчанию 20, максимум 100
+	PageToken     string                 `protobuf:"bytes,5,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBooksBySubjectRequest) Reset() {
+	*x = ListBooksBySubjectRequest{}
+	mi := &file_books_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBooksBySubjectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBooksBySubjectRequest) ProtoMessage() {}
+
+func (x *ListBooksBySubjectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBooksBySubjectRequest.ProtoReflect.Descriptor instead.
+func (*ListBooksBySubjectRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ListBooksBySubjectRequest) GetSubjectId() string {
+	if x != nil {
+		return x.SubjectId
+	}
+	return ""
+}
+
+func (x *ListBooksBySubjectRequest) GetExact() bool {
+	if x != nil {
+		return x.Exact
+	}
+	return false
+}
+
+func (x *ListBooksBySubjectRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *ListBooksBySubjectRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListBooksBySubjectRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListBooksBySubjectResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subject       *Subject               `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
+	Books         []*BookResponse        `protobuf:"bytes,2,rep,name=books,proto3" json:"books,omitempty"` // По названию
+	TotalCount    int32                  `protobuf:"varint,3,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,4,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBooksBySubjectResponse) Reset() {
+	*x = ListBooksBySubjectResponse{}
+	mi := &file_books_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBooksBySubjectResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBooksBySubjectResponse) ProtoMessage() {}
+
+func (x *ListBooksBySubjectResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBooksBySubjectResponse.ProtoReflect.Descriptor instead.
+func (*ListBooksBySubjectResponse) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListBooksBySubjectResponse) GetSubject() *Subject {
+	if x != nil {
+		return x.Subject
+	}
+	return nil
+}
+
+func (x *ListBooksBySubjectResponse) GetBooks() []*BookResponse {
+	if x != nil {
+		return x.Books
+	}
+	return nil
+}
+
+func (x *ListBooksBySubjectResponse) GetTotalCount() int32 {
+	if x != nil {
+		return x.TotalCount
+	}
+	return 0
+}
+
+func (x *ListBooksBySubjectResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type SetBookSubjectsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BookId        string                 `protobuf:"bytes,1,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	SubjectIds    []string               `protobuf:"bytes,2,rep,name=subject_ids,json=subjectIds,proto3" json:"subject_ids,omitempty"` // Пустой список снимает все рубрики
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetBookSubjectsRequest) Reset() {
+	*x = SetBookSubjectsRequest{}
+	mi := &file_books_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetBookSubjectsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetBookSubjectsRequest) ProtoMessage() {}
+
+func (x *SetBookSubjectsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetBookSubjectsRequest.ProtoReflect.Descriptor instead.
+func (*SetBookSubjectsRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *SetBookSubjectsRequest) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *SetBookSubjectsRequest) GetSubjectIds() []string {
+	if x != nil {
+		return x.SubjectIds
+	}
+	return nil
+}
+
+type SetBookTagsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BookId        string                 `protobuf:"bytes,1,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	Tags          []string               `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"` // Приводятся к нижнему регистру; пустой список снимает все теги
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetBookTagsRequest) Reset() {
+	*x = SetBookTagsRequest{}
+	mi := &file_books_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetBookTagsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetBookTagsRequest) ProtoMessage() {}
+
+func (x *SetBookTagsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetBookTagsRequest.ProtoReflect.Descriptor instead.
+func (*SetBookTagsRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *SetBookTagsRequest) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *SetBookTagsRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type ListTagsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"` // По умолчанию 50, максимум 200
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTagsRequest) Reset() {
+	*x = ListTagsRequest{}
+	mi := &file_books_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTagsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTagsRequest) ProtoMessage() {}
+
+func (x *ListTagsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTagsRequest.ProtoReflect.Descriptor instead.
+func (*ListTagsRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ListTagsRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *ListTagsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListTagsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tags          []*FacetCount          `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTagsResponse) Reset() {
+	*x = ListTagsResponse{}
+	mi := &file_books_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTagsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTagsResponse) ProtoMessage() {}
+
+func (x *ListTagsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTagsResponse.ProtoReflect.Descriptor instead.
+func (*ListTagsResponse) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ListTagsResponse) GetTags() []*FacetCount {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type WithdrawBookRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BookId        string                 `protobuf:"bytes,1,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"` // Обязательна: «утеряна», «списана по ветхости» и т.п.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WithdrawBookRequest) Reset() {
+	*x = WithdrawBookRequest{}
+	mi := &file_books_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WithdrawBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithdrawBookRequest) ProtoMessage() {}
+
+func (x *WithdrawBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithdrawBookRequest.ProtoReflect.Descriptor instead.
+func (*WithdrawBookRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *WithdrawBookRequest) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *WithdrawBookRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type GetBookHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BookId        string                 `protobuf:"bytes,1,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBookHistoryRequest) Reset() {
+	*x = GetBookHistoryRequest{}
+	mi := &file_books_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBookHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBookHistoryRequest) ProtoMessage() {}
+
+func (x *GetBookHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBookHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetBookHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *GetBookHistoryRequest) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+type GetBookHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Revisions     []*BookRevision        `protobuf:"bytes,1,rep,name=revisions,proto3" json:"revisions,omitempty"` // От старых к новым
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBookHistoryResponse) Reset() {
+	*x = GetBookHistoryResponse{}
+	mi := &file_books_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBookHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBookHistoryResponse) ProtoMessage() {}
+
+func (x *GetBookHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBookHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetBookHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *GetBookHistoryResponse) GetRevisions() []*BookRevision {
+	if x != nil {
+		return x.Revisions
+	}
+	return nil
+}
+
+type BookRevision struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	BookId        string                 `protobuf:"bytes,2,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	Action        string                 `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"` // "create", "update" или "withdraw"
+	Changes       []*FieldChange         `protobuf:"bytes,4,rep,name=changes,proto3" json:"changes,omitempty"`
+	Reason        string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`
+	Actor         string                 `protobuf:"bytes,6,opt,name=actor,proto3" json:"actor,omitempty"`                          // id пользователя, "api-key:<id>" или пусто для системы
+	CreatedAt     string                 `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // RFC 3339
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BookRevision) Reset() {
+	*x = BookRevision{}
+	mi := &file_books_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookRevision) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookRevision) ProtoMessage() {}
+
+func (x *BookRevision) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookRevision.ProtoReflect.Descriptor instead.
+func (*BookRevision) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *BookRevision) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BookRevision) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *BookRevision) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *BookRevision) GetChanges() []*FieldChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+func (x *BookRevision) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *BookRevision) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *BookRevision) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+type FieldChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Field         string                 `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	OldValue      string                 `protobuf:"bytes,2,opt,name=old_value,json=oldValue,proto3" json:"old_value,omitempty"`
+	NewValue      string                 `protobuf:"bytes,3,opt,name=new_value,json=newValue,proto3" json:"new_value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FieldChange) Reset() {
+	*x = FieldChange{}
+	mi := &file_books_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FieldChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldChange) ProtoMessage() {}
+
+func (x *FieldChange) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldChange.ProtoReflect.Descriptor instead.
+func (*FieldChange) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *FieldChange) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *FieldChange) GetOldValue() string {
+	if x != nil {
+		return x.OldValue
+	}
+	return ""
+}
+
+func (x *FieldChange) GetNewValue() string {
+	if x != nil {
+		return x.NewValue
+	}
+	return ""
+}
+
+type SearchCatalogRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Query     string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`                        // Слова названия и автора; поддерживаются "фраза", OR и -исключение
+	PageSize  int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"` // По умолчанию 20, максимум 100
+	PageToken string                 `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// Фильтры по фасетам
+	Author        string `protobuf:"bytes,4,opt,name=author,proto3" json:"author,omitempty"`
+	Decade        int32  `protobuf:"varint,5,opt,name=decade,proto3" json:"decade,omitempty"` // Например, 1990 — книги 1990–1999 годов
+	AvailableOnly bool   `protobuf:"varint,6,opt,name=available_only,json=availableOnly,proto3" json:"available_only,omitempty"`
+	SubjectId     string `protobuf:"bytes,7,opt,name=subject_id,json=subjectId,proto3" json:"subject_id,omitempty"` // Рубрика вместе с подрубриками
+	Tag           string `protobuf:"bytes,8,opt,name=tag,proto3" json:"tag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchCatalogRequest) Reset() {
+	*x = SearchCatalogRequest{}
+	mi := &file_books_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchCatalogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchCatalogRequest) ProtoMessage() {}
+
+func (x *SearchCatalogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchCatalogRequest.ProtoReflect.Descriptor instead.
+func (*SearchCatalogRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *SearchCatalogRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchCatalogRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *SearchCatalogRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *SearchCatalogRequest) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *SearchCatalogRequest) GetDecade() int32 {
+	if x != nil {
+		return x.Decade
+	}
+	return 0
+}
+
+func (x *SearchCatalogRequest) GetAvailableOnly() bool {
+	if x != nil {
+		return x.AvailableOnly
+	}
+	return false
+}
+
+func (x *SearchCatalogRequest) GetSubjectId() string {
+	if x != nil {
+		return x.SubjectId
+	}
+	return ""
+}
+
+func (x *SearchCatalogRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+type SearchCatalogResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hits          []*SearchHit           `protobuf:"bytes,1,rep,name=hits,proto3" json:"hits,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	TotalMatches  int32                  `protobuf:"varint,3,opt,name=total_matches,json=totalMatches,proto3" json:"total_matches,omitempty"` // С учётом фильтров
+	Facets        *SearchFacets          `protobuf:"bytes,4,opt,name=facets,proto3" json:"facets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchCatalogResponse) Reset() {
+	*x = SearchCatalogResponse{}
+	mi := &file_books_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchCatalogResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchCatalogResponse) ProtoMessage() {}
+
+func (x *SearchCatalogResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchCatalogResponse.ProtoReflect.Descriptor instead.
+func (*SearchCatalogResponse) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *SearchCatalogResponse) GetHits() []*SearchHit {
+	if x != nil {
+		return x.Hits
+	}
+	return nil
+}
+
+func (x *SearchCatalogResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+func (x *SearchCatalogResponse) GetTotalMatches() int32 {
+	if x != nil {
+		return x.TotalMatches
+	}
+	return 0
+}
+
+func (x *SearchCatalogResponse) GetFacets() *SearchFacets {
+	if x != nil {
+		return x.Facets
+	}
+	return nil
+}
+
+type SearchHit struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Book  *BookResponse          `protobuf:"bytes,1,opt,name=book,proto3" json:"book,omitempty"`
+	Score float32                `protobuf:"fixed32,2,opt,name=score,proto3" json:"score,omitempty"`
+	// Текст с найденными словами в <mark></mark>, экранированный для HTML
+	TitleHighlight  string `protobuf:"bytes,3,opt,name=title_highlight,json=titleHighlight,proto3" json:"title_highlight,omitempty"`
+	AuthorHighlight string `protobuf:"bytes,4,opt,name=author_highlight,json=authorHighlight,proto3" json:"author_highlight,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SearchHit) Reset() {
+	*x = SearchHit{}
+	mi := &file_books_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchHit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchHit) ProtoMessage() {}
+
+func (x *SearchHit) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchHit.ProtoReflect.Descriptor instead.
+func (*SearchHit) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *SearchHit) GetBook() *BookResponse {
+	if x != nil {
+		return x.Book
+	}
+	return nil
+}
+
+func (x *SearchHit) GetScore() float32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *SearchHit) GetTitleHighlight() string {
+	if x != nil {
+		return x.TitleHighlight
+	}
+	return ""
+}
+
+func (x *SearchHit) GetAuthorHighlight() string {
+	if x != nil {
+		return x.AuthorHighlight
+	}
+	return ""
+}
+
+// Каждый фасет считается с учётом остальных фильтров, но не своего.
+type SearchFacets struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Authors       []*FacetCount          `protobuf:"bytes,1,rep,name=authors,proto3" json:"authors,omitempty"`
+	Decades       []*FacetCount          `protobuf:"bytes,2,rep,name=decades,proto3" json:"decades,omitempty"`           // "1990"
+	Availability  []*FacetCount          `protobuf:"bytes,3,rep,name=availability,proto3" json:"availability,omitempty"` // "available" и "unavailable"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchFacets) Reset() {
+	*x = SearchFacets{}
+	mi := &file_books_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchFacets) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchFacets) ProtoMessage() {}
+
+func (x *SearchFacets) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchFacets.ProtoReflect.Descriptor instead.
+func (*SearchFacets) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *SearchFacets) GetAuthors() []*FacetCount {
+	if x != nil {
+		return x.Authors
+	}
+	return nil
+}
+
+func (x *SearchFacets) GetDecades() []*FacetCount {
+	if x != nil {
+		return x.Decades
+	}
+	return nil
+}
+
+func (x *SearchFacets) GetAvailability() []*FacetCount {
+	if x != nil {
+		return x.Availability
+	}
+	return nil
+}
+
+type FacetCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FacetCount) Reset() {
+	*x = FacetCount{}
+	mi := &file_books_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FacetCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FacetCount) ProtoMessage() {}
+
+func (x *FacetCount) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FacetCount.ProtoReflect.Descriptor instead.
+func (*FacetCount) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *FacetCount) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *FacetCount) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type SuggestRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"` // Начало любого слова названия или имени автора
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`  // По умолчанию 8, максимум 20
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SuggestRequest) Reset() {
+	*x = SuggestRequest{}
+	mi := &file_books_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SuggestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SuggestRequest) ProtoMessage() {}
+
+func (x *SuggestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SuggestRequest.ProtoReflect.Descriptor instead.
+func (*SuggestRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *SuggestRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *SuggestRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type SuggestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Suggestions   []*Suggestion          `protobuf:"bytes,1,rep,name=suggestions,proto3" json:"suggestions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SuggestResponse) Reset() {
+	*x = SuggestResponse{}
+	mi := &file_books_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SuggestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SuggestResponse) ProtoMessage() {}
+
+func (x *SuggestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SuggestResponse.ProtoReflect.Descriptor instead.
+func (*SuggestResponse) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *SuggestResponse) GetSuggestions() []*Suggestion {
+	if x != nil {
+		return x.Suggestions
+	}
+	return nil
+}
+
+type Suggestion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	Kind          string                 `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`                   // "title" или "author"
+	BookId        string                 `protobuf:"bytes,3,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"` // Только для названий
+	Loans         int32                  `protobuf:"varint,4,opt,name=loans,proto3" json:"loans,omitempty"`                // Сколько раз брали книгу; для автора — все его книги
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Suggestion) Reset() {
+	*x = Suggestion{}
+	mi := &file_books_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Suggestion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Suggestion) ProtoMessage() {}
+
+func (x *Suggestion) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Suggestion.ProtoReflect.Descriptor instead.
+func (*Suggestion) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *Suggestion) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Suggestion) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *Suggestion) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *Suggestion) GetLoans() int32 {
+	if x != nil {
+		return x.Loans
+	}
+	return 0
+}
+
+type GetBookByISBNRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Isbn          string                 `protobuf:"bytes,1,opt,name=isbn,proto3" json:"isbn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBookByISBNRequest) Reset() {
+	*x = GetBookByISBNRequest{}
+	mi := &file_books_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBookByISBNRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBookByISBNRequest) ProtoMessage() {}
+
+func (x *GetBookByISBNRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBookByISBNRequest.ProtoReflect.Descriptor instead.
+func (*GetBookByISBNRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *GetBookByISBNRequest) GetIsbn() string {
+	if x != nil {
+		return x.Isbn
+	}
+	return ""
+}
+
+type LookupBooksByISBNRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Isbns         []string               `protobuf:"bytes,1,rep,name=isbns,proto3" json:"isbns,omitempty"` // Не больше 100
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupBooksByISBNRequest) Reset() {
+	*x = LookupBooksByISBNRequest{}
+	mi := &file_books_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupBooksByISBNRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupBooksByISBNRequest) ProtoMessage() {}
+
+func (x *LookupBooksByISBNRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupBooksByISBNRequest.ProtoReflect.Descriptor instead.
+func (*LookupBooksByISBNRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *LookupBooksByISBNRequest) GetIsbns() []string {
+	if x != nil {
+		return x.Isbns
+	}
+	return nil
+}
+
+type LookupBooksByISBNResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// По одному результату на каждый ISBN запроса, в том же порядке
+	Results       []*ISBNLookupResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupBooksByISBNResponse) Reset() {
+	*x = LookupBooksByISBNResponse{}
+	mi := &file_books_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupBooksByISBNResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupBooksByISBNResponse) ProtoMessage() {}
+
+func (x *LookupBooksByISBNResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupBooksByISBNResponse.ProtoReflect.Descriptor instead.
+func (*LookupBooksByISBNResponse) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *LookupBooksByISBNResponse) GetResults() []*ISBNLookupResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type ISBNLookupResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Isbn          string                 `protobuf:"bytes,1,opt,name=isbn,proto3" json:"isbn,omitempty"`     // Как в запросе
+	Isbn13        string                 `protobuf:"bytes,2,opt,name=isbn13,proto3" json:"isbn13,omitempty"` // Нормализованный; пусто, если ISBN некорректен
+	Book          *BookResponse          `protobuf:"bytes,3,opt,name=book,proto3" json:"book,omitempty"`     // Не задано, если книга не найдена
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`   // Почему ISBN некорректен
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ISBNLookupResult) Reset() {
+	*x = ISBNLookupResult{}
+	mi := &file_books_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ISBNLookupResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ISBNLookupResult) ProtoMessage() {}
+
+func (x *ISBNLookupResult) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ISBNLookupResult.ProtoReflect.Descriptor instead.
+func (*ISBNLookupResult) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *ISBNLookupResult) GetIsbn() string {
+	if x != nil {
+		return x.Isbn
+	}
+	return ""
+}
+
+func (x *ISBNLookupResult) GetIsbn13() string {
+	if x != nil {
+		return x.Isbn13
+	}
+	return ""
+}
+
+func (x *ISBNLookupResult) GetBook() *BookResponse {
+	if x != nil {
+		return x.Book
+	}
+	return nil
+}
+
+func (x *ISBNLookupResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ImportBooksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DryRun        bool                   `protobuf:"varint,1,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"` // Читается из первого сообщения: проверить и сопоставить, ничего не сохраняя
+	Rows          []*ImportRow           `protobuf:"bytes,2,rep,name=rows,proto3" json:"rows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportBooksRequest) Reset() {
+	*x = ImportBooksRequest{}
+	mi := &file_books_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportBooksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportBooksRequest) ProtoMessage() {}
+
+func (x *ImportBooksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportBooksRequest.ProtoReflect.Descriptor instead.
+func (*ImportBooksRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *ImportBooksRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *ImportBooksRequest) GetRows() []*ImportRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+type ImportRow struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Line          int32                  `protobuf:"varint,1,opt,name=line,proto3" json:"line,omitempty"` // Номер строки в файле для отчёта; 0 — порядковый номер в потоке
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Author        string                 `protobuf:"bytes,3,opt,name=author,proto3" json:"author,omitempty"`
+	Year          int32                  `protobuf:"varint,4,opt,name=year,proto3" json:"year,omitempty"`
+	Isbn          string                 `protobuf:"bytes,5,opt,name=isbn,proto3" json:"isbn,omitempty"`      // Обязателен: по нему книга создаётся или обновляется
+	Copies        int32                  `protobuf:"varint,6,opt,name=copies,proto3" json:"copies,omitempty"` // Только для новых книг; 0 — один экземпляр
+	Error         string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`    // Строку не удалось разобрать на клиенте; попадает в отчёт как отклонённая
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportRow) Reset() {
+	*x = ImportRow{}
+	mi := &file_books_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportRow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportRow) ProtoMessage() {}
+
+func (x *ImportRow) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportRow.ProtoReflect.Descriptor instead.
+func (*ImportRow) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *ImportRow) GetLine() int32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *ImportRow) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *ImportRow) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *ImportRow) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+func (x *ImportRow) GetIsbn() string {
+	if x != nil {
+		return x.Isbn
+	}
+	return ""
+}
+
+func (x *ImportRow) GetCopies() int32 {
+	if x != nil {
+		return x.Copies
+	}
+	return 0
+}
+
+func (x *ImportRow) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ImportBooksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Created       int32                  `protobuf:"varint,1,opt,name=created,proto3" json:"created,omitempty"`
+	Updated       int32                  `protobuf:"varint,2,opt,name=updated,proto3" json:"updated,omitempty"`
+	Unchanged     int32                  `protobuf:"varint,3,opt,name=unchanged,proto3" json:"unchanged,omitempty"`
+	Rejected      int32                  `protobuf:"varint,4,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	DryRun        bool                   `protobuf:"varint,5,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	Results       []*ImportRowResult     `protobuf:"bytes,6,rep,name=results,proto3" json:"results,omitempty"` // По одному на каждую строку, в порядке потока
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportBooksResponse) Reset() {
+	*x = ImportBooksResponse{}
+	mi := &file_books_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportBooksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportBooksResponse) ProtoMessage() {}
+
+func (x *ImportBooksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportBooksResponse.ProtoReflect.Descriptor instead.
+func (*ImportBooksResponse) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *ImportBooksResponse) GetCreated() int32 {
+	if x != nil {
+		return x.Created
+	}
+	return 0
+}
+
+func (x *ImportBooksResponse) GetUpdated() int32 {
+	if x != nil {
+		return x.Updated
+	}
+	return 0
+}
+
+func (x *ImportBooksResponse) GetUnchanged() int32 {
+	if x != nil {
+		return x.Unchanged
+	}
+	return 0
+}
+
+func (x *ImportBooksResponse) GetRejected() int32 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *ImportBooksResponse) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *ImportBooksResponse) GetResults() []*ImportRowResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type ImportRowResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Line          int32                  `protobuf:"varint,1,opt,name=line,proto3" json:"line,omitempty"`
+	Isbn13        string                 `protobuf:"bytes,2,opt,name=isbn13,proto3" json:"isbn13,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`               // "created", "updated", "unchanged" или "rejected"
+	BookId        string                 `protobuf:"bytes,4,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"` // Пусто для отклонённых строк и для новых книг при dry_run
+	Reason        string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`               // Почему строка отклонена
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportRowResult) Reset() {
+	*x = ImportRowResult{}
+	mi := &file_books_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportRowResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportRowResult) ProtoMessage() {}
+
+func (x *ImportRowResult) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportRowResult.ProtoReflect.Descriptor instead.
+func (*ImportRowResult) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *ImportRowResult) GetLine() int32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *ImportRowResult) GetIsbn13() string {
+	if x != nil {
+		return x.Isbn13
+	}
+	return ""
+}
+
+func (x *ImportRowResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ImportRowResult) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *ImportRowResult) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// MARC 21 records, one per book. In the import report line is the record's number in the file.
+type ImportMarcRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Format        string                 `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"` // "iso2709" или "marcxml"; пусто — определить по содержимому
+	DryRun        bool                   `protobuf:"varint,3,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportMarcRequest) Reset() {
+	*x = ImportMarcRequest{}
+	mi := &file_books_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportMarcRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportMarcRequest) ProtoMessage() {}
+
+func (x *ImportMarcRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportMarcRequest.ProtoReflect.Descriptor instead.
+func (*ImportMarcRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *ImportMarcRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ImportMarcRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *ImportMarcRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type ExportMarcRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BookIds       []string               `protobuf:"bytes,1,rep,name=book_ids,json=bookIds,proto3" json:"book_ids,omitempty"` // Не больше 100, записи идут в том же порядке
+	Format        string                 `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`                  // "iso2709" (по умолчанию) или "marcxml"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportMarcRequest) Reset() {
+	*x = ExportMarcRequest{}
+	mi := &file_books_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportMarcRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportMarcRequest) ProtoMessage() {}
+
+func (x *ExportMarcRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportMarcRequest.ProtoReflect.Descriptor instead.
+func (*ExportMarcRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *ExportMarcRequest) GetBookIds() []string {
+	if x != nil {
+		return x.BookIds
+	}
+	return nil
+}
+
+func (x *ExportMarcRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+type ExportMarcResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Format        string                 `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`
+	ContentType   string                 `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"` // "application/marc" или "application/marcxml+xml"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportMarcResponse) Reset() {
+	*x = ExportMarcResponse{}
+	mi := &file_books_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportMarcResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportMarcResponse) ProtoMessage() {}
+
+func (x *ExportMarcResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportMarcResponse.ProtoReflect.Descriptor instead.
+func (*ExportMarcResponse) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *ExportMarcResponse) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ExportMarcResponse) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *ExportMarcResponse) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+// Item is a physical copy of a book.
+type Item struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	BookId        string                 `protobuf:"bytes,2,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	Barcode       string                 `protobuf:"bytes,3,opt,name=barcode,proto3" json:"barcode,omitempty"`
+	Condition     string                 `protobuf:"bytes,4,opt,name=condition,proto3" json:"condition,omitempty"` // "new", "good", "fair", "poor" или "damaged"
+	Location      string                 `protobuf:"bytes,5,opt,name=location,proto3" json:"location,omitempty"`   // Полка или филиал
+	Status        string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`       // "available", "on_loan", "in_repair", "lost" или "withdrawn"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Item) Reset() {
+	*x = Item{}
+	mi := &file_books_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Item) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Item) ProtoMessage() {}
+
+func (x *Item) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Item.ProtoReflect.Descriptor instead.
+func (*Item) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *Item) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Item) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *Item) GetBarcode() string {
+	if x != nil {
+		return x.Barcode
+	}
+	return ""
+}
+
+func (x *Item) GetCondition() string {
+	if x != nil {
+		return x.Condition
+	}
+	return ""
+}
+
+func (x *Item) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *Item) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type AddItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BookId        string                 `protobuf:"bytes,1,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	Barcode       string                 `protobuf:"bytes,2,opt,name=barcode,proto3" json:"barcode,omitempty"`     // Пусто — сгенерировать
+	Condition     string                 `protobuf:"bytes,3,opt,name=condition,proto3" json:"condition,omitempty"` // По умолчанию "good"
+	Location      string                 `protobuf:"bytes,4,opt,name=location,proto3" json:"location,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddItemRequest) Reset() {
+	*x = AddItemRequest{}
+	mi := &file_books_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddItemRequest) ProtoMessage() {}
+
+func (x *AddItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddItemRequest.ProtoReflect.Descriptor instead.
+func (*AddItemRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *AddItemRequest) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *AddItemRequest) GetBarcode() string {
+	if x != nil {
+		return x.Barcode
+	}
+	return ""
+}
+
+func (x *AddItemRequest) GetCondition() string {
+	if x != nil {
+		return x.Condition
+	}
+	return ""
+}
+
+func (x *AddItemRequest) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+type GetItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ItemId        string                 `protobuf:"bytes,1,opt,name=item_id,json=itemId,proto3" json:"item_id,omitempty"`
+	Barcode       string                 `protobuf:"bytes,2,opt,name=barcode,proto3" json:"barcode,omitempty"` // Вместо item_id
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetItemRequest) Reset() {
+	*x = GetItemRequest{}
+	mi := &file_books_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetItemRequest) ProtoMessage() {}
+
+func (x *GetItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetItemRequest.ProtoReflect.Descriptor instead.
+func (*GetItemRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *GetItemRequest) GetItemId() string {
+	if x != nil {
+		return x.ItemId
+	}
+	return ""
+}
+
+func (x *GetItemRequest) GetBarcode() string {
+	if x != nil {
+		return x.Barcode
+	}
+	return ""
+}
+
+type ListItemsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BookId        string                 `protobuf:"bytes,1,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListItemsRequest) Reset() {
+	*x = ListItemsRequest{}
+	mi := &file_books_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListItemsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListItemsRequest) ProtoMessage() {}
+
+func (x *ListItemsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListItemsRequest.ProtoReflect.Descriptor instead.
+func (*ListItemsRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *ListItemsRequest) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+type ListItemsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*Item                `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListItemsResponse) Reset() {
+	*x = ListItemsResponse{}
+	mi := &file_books_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListItemsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListItemsResponse) ProtoMessage() {}
+
+func (x *ListItemsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListItemsResponse.ProtoReflect.Descriptor instead.
+func (*ListItemsResponse) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *ListItemsResponse) GetItems() []*Item {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// UpdateItemRequest changes the non-empty fields. Status "on_loan" is set only by CheckoutItem.
+type UpdateItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ItemId        string                 `protobuf:"bytes,1,opt,name=item_id,json=itemId,proto3" json:"item_id,omitempty"`
+	Condition     string                 `protobuf:"bytes,2,opt,name=condition,proto3" json:"condition,omitempty"`
+	Location      string                 `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateItemRequest) Reset() {
+	*x = UpdateItemRequest{}
+	mi := &file_books_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateItemRequest) ProtoMessage() {}
+
+func (x *UpdateItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateItemRequest.ProtoReflect.Descriptor instead.
+func (*UpdateItemRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *UpdateItemRequest) GetItemId() string {
+	if x != nil {
+		return x.ItemId
+	}
+	return ""
+}
+
+func (x *UpdateItemRequest) GetCondition() string {
+	if x != nil {
+		return x.Condition
+	}
+	return ""
+}
+
+func (x *UpdateItemRequest) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *UpdateItemRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type CheckoutItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BookId        string                 `protobuf:"bytes,1,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	ItemId        string                 `protobuf:"bytes,2,opt,name=item_id,json=itemId,proto3" json:"item_id,omitempty"` // Пусто — любой доступный экземпляр книги
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckoutItemRequest) Reset() {
+	*x = CheckoutItemRequest{}
+	mi := &file_books_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckoutItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckoutItemRequest) ProtoMessage() {}
+
+func (x *CheckoutItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckoutItemRequest.ProtoReflect.Descriptor instead.
+func (*CheckoutItemRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *CheckoutItemRequest) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *CheckoutItemRequest) GetItemId() string {
+	if x != nil {
+		return x.ItemId
+	}
+	return ""
+}
+
+type CheckinItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ItemId        string                 `protobuf:"bytes,1,opt,name=item_id,json=itemId,proto3" json:"item_id,omitempty"`
+	BookId        string                 `protobuf:"bytes,2,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"` // Для займов без экземпляра: вернуть любой выданный экземпляр книги
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckinItemRequest) Reset() {
+	*x = CheckinItemRequest{}
+	mi := &file_books_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckinItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckinItemRequest) ProtoMessage() {}
+
+func (x *CheckinItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_books_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckinItemRequest.ProtoReflect.Descriptor instead.
+func (*CheckinItemRequest) Descriptor() ([]byte, []int) {
+	return file_books_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *CheckinItemRequest) GetItemId() string {
+	if x != nil {
+		return x.ItemId
+	}
+	return ""
+}
+
+func (x *CheckinItemRequest) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+var File_books_proto protoreflect.FileDescriptor
+
+const file_books_proto_rawDesc = "" +
+	"\n" +
+	"\vbooks.proto\x12\alibrary\x1a\x1cgoogle/api/annotations.proto\x1a google/protobuf/field_mask.proto\")\n" +
+	"\x0eGetBookRequest\x12\x17\n" +
+	"\abook_id\x18\x01 \x01(\tR\x06bookId\",\n" +
+	"\x0fGetBooksRequest\x12\x19\n" +
+	"\bbook_ids\x18\x01 \x03(\tR\abookIds\"?\n" +
+	"\x10GetBooksResponse\x12+\n" +
+	"\x05books\x18\x01 \x03(\v2\x15.library.BookResponseR\x05books\"\xbb\x01\n" +
+	"\x11CreateBookRequest\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12\x16\n" +
+	"\x06author\x18\x02 \x01(\tR\x06author\x12\x12\n" +
+	"\x04year\x18\x03 \x01(\x05R\x04year\x12\x16\n" +
+	"\x06copies\x18\x04 \x01(\x05R\x06copies\x12\x12\n" +
+	"\x04isbn\x18\x05 \x01(\tR\x04isbn\x128\n" +
+	"\fcontributors\x18\x06 \x03(\v2\x14.library.ContributorR\fcontributors\"R\n" +
+	"\vContributor\x12\x1b\n" +
+	"\tauthor_id\x18\x01 \x01(\tR\bauthorId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04role\x18\x03 \x01(\tR\x04role\"\xe6\x03\n" +
+	"\fBookResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x16\n" +
+	"\x06author\x18\x03 \x01(\tR\x06author\x12\x12\n" +
+	"\x04year\x18\x04 \x01(\x05R\x04year\x12\x1c\n" +
+	"\tavailable\x18\x05 \x01(\bR\tavailable\x12)\n" +
+	"\x10available_copies\x18\x06 \x01(\x05R\x0favailableCopies\x12!\n" +
+	"\ftotal_copies\x18\a \x01(\x05R\vtotalCopies\x12\x16\n" +
+	"\x06isbn13\x18\b \x01(\tR\x06isbn13\x12\x16\n" +
+	"\x06isbn10\x18\t \x01(\tR\x06isbn10\x12\x1c\n" +
+	"\twithdrawn\x18\n" +
+	" \x01(\bR\twithdrawn\x12+\n" +
+	"\x11withdrawal_reason\x18\v \x01(\tR\x10withdrawalReason\x12!\n" +
+	"\fwithdrawn_at\x18\f \x01(\tR\vwithdrawnAt\x128\n" +
+	"\fcontributors\x18\r \x03(\v2\x14.library.ContributorR\fcontributors\x12,\n" +
+	"\bsubjects\x18\x0e \x03(\v2\x10.library.SubjectR\bsubjects\x12\x12\n" +
+	"\x04tags\x18\x0f \x03(\tR\x04tags\"\xf9\x01\n" +
+	"\x11UpdateBookRequest\x12\x17\n" +
+	"\abook_id\x18\x01 \x01(\tR\x06bookId\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x16\n" +
+	"\x06author\x18\x03 \x01(\tR\x06author\x12\x12\n" +
+	"\x04year\x18\x04 \x01(\x05R\x04year\x12\x12\n" +
+	"\x04isbn\x18\x05 \x01(\tR\x04isbn\x12;\n" +
+	"\vupdate_mask\x18\x06 \x01(\v2\x1a.google.protobuf.FieldMaskR\n" +
+	"updateMask\x128\n" +
+	"\fcontributors\x18\a \x03(\v2\x14.library.ContributorR\fcontributors\"g\n" +
+	"\x06Author\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
+	"\bvariants\x18\x03 \x03(\tR\bvariants\x12\x1d\n" +
+	"\n" +
+	"book_count\x18\x04 \x01(\x05R\tbookCount\"E\n" +
+	"\x13CreateAuthorRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1a\n" +
+	"\bvariants\x18\x02 \x03(\tR\bvariants\"/\n" +
+	"\x10GetAuthorRequest\x12\x1b\n" +
+	"\tauthor_id\x18\x01 \x01(\tR\bauthorId\"I\n" +
+	"\x14SearchAuthorsRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\"B\n" +
+	"\x15SearchAuthorsResponse\x12)\n" +
+	"\aauthors\x18\x01 \x03(\v2\x0f.library.AuthorR\aauthors\"Q\n" +
+	"\x13MergeAuthorsRequest\x12\x1b\n" +
+	"\tauthor_id\x18\x01 \x01(\tR\bauthorId\x12\x1d\n" +
+	"\n" +
+	"source_ids\x18\x02 \x03(\tR\tsourceIds\"\xa0\x01\n" +
+	"\aSubject\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1b\n" +
+	"\tparent_id\x18\x03 \x01(\tR\bparentId\x12!\n" +
+	"\fclass_number\x18\x04 \x01(\tR\vclassNumber\x12\x12\n" +
+	"\x04path\x18\x05 \x03(\tR\x04path\x12\x1d\n" +
+	"\n" +
+	"book_count\x18\x06 \x01(\x05R\tbookCount\"j\n" +
+	"\x14CreateSubjectRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1b\n" +
+	"\tparent_id\x18\x02 \x01(\tR\bparentId\x12!\n" +
+	"\fclass_number\x18\x03 \x01(\tR\vclassNumber\"2\n" +
+	"\x13ListSubjectsRequest\x12\x1b\n" +
+	"\tparent_id\x18\x01 \x01(\tR\bparentId\"n\n" +
+	"\x14ListSubjectsResponse\x12(\n" +
+	"\x06parent\x18\x01 \x01(\v2\x10.library.SubjectR\x06parent\x12,\n" +
+	"\bsubjects\x18\x02 \x03(\v2\x10.library.SubjectR\bsubjects\"\x9e\x01\n" +
+	"\x19ListBooksBySubjectRequest\x12\x1d\n" +
+	"\n" +
+	"subject_id\x18\x01 \x01(\tR\tsubjectId\x12\x14\n" +
+	"\x05exact\x18\x02 \x01(\bR\x05exact\x12\x10\n" +
+	"\x03tag\x18\x03 \x01(\tR\x03tag\x12\x1b\n" +
+	"\tpage_size\x18\x04 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x05 \x01(\tR\tpageToken\"\xbe\x01\n" +
+	"\x1aListBooksBySubjectResponse\x12*\n" +
+	"\asubject\x18\x01 \x01(\v2\x10.library.SubjectR\asubject\x12+\n" +
+	"\x05books\x18\x02 \x03(\v2\x15.library.BookResponseR\x05books\x12\x1f\n" +
+	"\vtotal_count\x18\x03 \x01(\x05R\n" +
+	"totalCount\x12&\n" +
+	"\x0fnext_page_token\x18\x04 \x01(\tR\rnextPageToken\"R\n" +
+	"\x16SetBookSubjectsRequest\x12\x17\n" +
+	"\abook_id\x18\x01 \x01(\tR\x06bookId\x12\x1f\n" +
+	"\vsubject_ids\x18\x02 \x03(\tR\n" +
+	"subjectIds\"A\n" +
+	"\x12SetBookTagsRequest\x12\x17\n" +
+	"\abook_id\x18\x01 \x01(\tR\x06bookId\x12\x12\n" +
+	"\x04tags\x18\x02 \x03(\tR\x04tags\"?\n" +
+	"\x0fListTagsRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\";\n" +
+	"\x10ListTagsResponse\x12'\n" +
+	"\x04tags\x18\x01 \x03(\v2\x13.library.FacetCountR\x04tags\"F\n" +
+	"\x13WithdrawBookRequest\x12\x17\n" +
+	"\abook_id\x18\x01 \x01(\tR\x06bookId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"0\n" +
+	"\x15GetBookHistoryRequest\x12\x17\n" +
+	"\abook_id\x18\x01 \x01(\tR\x06bookId\"M\n" +
+	"\x16GetBookHistoryResponse\x123\n" +
+	"\trevisions\x18\x01 \x03(\v2\x15.library.BookRevisionR\trevisions\"\xcc\x01\n" +
+	"\fBookRevision\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\abook_id\x18\x02 \x01(\tR\x06bookId\x12\x16\n" +
+	"\x06action\x18\x03 \x01(\tR\x06action\x12.\n" +
+	"\achanges\x18\x04 \x03(\v2\x14.library.FieldChangeR\achanges\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\x12\x14\n" +
+	"\x05actor\x18\x06 \x01(\tR\x05actor\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\a \x01(\tR\tcreatedAt\"]\n" +
+	"\vFieldChange\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12\x1b\n" +
+	"\told_value\x18\x02 \x01(\tR\boldValue\x12\x1b\n" +
+	"\tnew_value\x18\x03 \x01(\tR\bnewValue\"\xf0\x01\n" +
+	"\x14SearchCatalogRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\x12\x16\n" +
+	"\x06author\x18\x04 \x01(\tR\x06author\x12\x16\n" +
+	"\x06decade\x18\x05 \x01(\x05R\x06decade\x12%\n" +
+	"\x0eavailable_only\x18\x06 \x01(\bR\ravailableOnly\x12\x1d\n" +
+	"\n" +
+	"subject_id\x18\a \x01(\tR\tsubjectId\x12\x10\n" +
+	"\x03tag\x18\b \x01(\tR\x03tag\"\xbb\x01\n" +
+	"\x15SearchCatalogResponse\x12&\n" +
+	"\x04hits\x18\x01 \x03(\v2\x12.library.SearchHitR\x04hits\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\x12#\n" +
+	"\rtotal_matches\x18\x03 \x01(\x05R\ftotalMatches\x12-\n" +
+	"\x06facets\x18\x04 \x01(\v2\x15.library.SearchFacetsR\x06facets\"\xa0\x01\n" +
+	"\tSearchHit\x12)\n" +
+	"\x04book\x18\x01 \x01(\v2\x15.library.BookResponseR\x04book\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x02R\x05score\x12'\n" +
+	"\x0ftitle_highlight\x18\x03 \x01(\tR\x0etitleHighlight\x12)\n" +
+	"\x10author_highlight\x18\x04 \x01(\tR\x0fauthorHighlight\"\xa5\x01\n" +
+	"\fSearchFacets\x12-\n" +
+	"\aauthors\x18\x01 \x03(\v2\x13.library.FacetCountR\aauthors\x12-\n" +
+	"\adecades\x18\x02 \x03(\v2\x13.library.FacetCountR\adecades\x127\n" +
+	"\favailability\x18\x03 \x03(\v2\x13.library.FacetCountR\favailability\"8\n" +
+	"\n" +
+	"FacetCount\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\">\n" +
+	"\x0eSuggestRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"H\n" +
+	"\x0fSuggestResponse\x125\n" +
+	"\vsuggestions\x18\x01 \x03(\v2\x13.library.SuggestionR\vsuggestions\"c\n" +
+	"\n" +
+	"Suggestion\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x12\n" +
+	"\x04kind\x18\x02 \x01(\tR\x04kind\x12\x17\n" +
+	"\abook_id\x18\x03 \x01(\tR\x06bookId\x12\x14\n" +
+	"\x05loans\x18\x04 \x01(\x05R\x05loans\"*\n" +
+	"\x14GetBookByISBNRequest\x12\x12\n" +
+	"\x04isbn\x18\x01 \x01(\tR\x04isbn\"0\n" +
+	"\x18LookupBooksByISBNRequest\x12\x14\n" +
+	"\x05isbns\x18\x01 \x03(\tR\x05isbns\"P\n" +
+	"\x19LookupBooksByISBNResponse\x123\n" +
+	"\aresults\x18\x01 \x03(\v2\x19.library.ISBNLookupResultR\aresults\"\x7f\n" +
+	"\x10ISBNLookupResult\x12\x12\n" +
+	"\x04isbn\x18\x01 \x01(\tR\x04isbn\x12\x16\n" +
+	"\x06isbn13\x18\x02 \x01(\tR\x06isbn13\x12)\n" +
+	"\x04book\x18\x03 \x01(\v2\x15.library.BookResponseR\x04book\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"U\n" +
+	"\x12ImportBooksRequest\x12\x17\n" +
+	"\adry_run\x18\x01 \x01(\bR\x06dryRun\x12&\n" +
+	"\x04rows\x18\x02 \x03(\v2\x12.library.ImportRowR\x04rows\"\xa3\x01\n" +
+	"\tImportRow\x12\x12\n" +
+	"\x04line\x18\x01 \x01(\x05R\x04line\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x16\n" +
+	"\x06author\x18\x03 \x01(\tR\x06author\x12\x12\n" +
+	"\x04year\x18\x04 \x01(\x05R\x04year\x12\x12\n" +
+	"\x04isbn\x18\x05 \x01(\tR\x04isbn\x12\x16\n" +
+	"\x06copies\x18\x06 \x01(\x05R\x06copies\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\"\xd0\x01\n" +
+	"\x13ImportBooksResponse\x12\x18\n" +
+	"\acreated\x18\x01 \x01(\x05R\acreated\x12\x18\n" +
+	"\aupdated\x18\x02 \x01(\x05R\aupdated\x12\x1c\n" +
+	"\tunchanged\x18\x03 \x01(\x05R\tunchanged\x12\x1a\n" +
+	"\brejected\x18\x04 \x01(\x05R\brejected\x12\x17\n" +
+	"\adry_run\x18\x05 \x01(\bR\x06dryRun\x122\n" +
+	"\aresults\x18\x06 \x03(\v2\x18.library.ImportRowResultR\aresults\"\x86\x01\n" +
+	"\x0fImportRowResult\x12\x12\n" +
+	"\x04line\x18\x01 \x01(\x05R\x04line\x12\x16\n" +
+	"\x06isbn13\x18\x02 \x01(\tR\x06isbn13\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x17\n" +
+	"\abook_id\x18\x04 \x01(\tR\x06bookId\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\"X\n" +
+	"\x11ImportMarcRequest\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x16\n" +
+	"\x06format\x18\x02 \x01(\tR\x06format\x12\x17\n" +
+	"\adry_run\x18\x03 \x01(\bR\x06dryRun\"F\n" +
+	"\x11ExportMarcRequest\x12\x19\n" +
+	"\bbook_ids\x18\x01 \x03(\tR\abookIds\x12\x16\n" +
+	"\x06format\x18\x02 \x01(\tR\x06format\"c\n" +
+	"\x12ExportMarcResponse\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x16\n" +
+	"\x06format\x18\x02 \x01(\tR\x06format\x12!\n" +
+	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\"\x9b\x01\n" +
+	"\x04Item\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\abook_id\x18\x02 \x01(\tR\x06bookId\x12\x18\n" +
+	"\abarcode\x18\x03 \x01(\tR\abarcode\x12\x1c\n" +
+	"\tcondition\x18\x04 \x01(\tR\tcondition\x12\x1a\n" +
+	"\blocation\x18\x05 \x01(\tR\blocation\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\"}\n" +
+	"\x0eAddItemRequest\x12\x17\n" +
+	"\abook_id\x18\x01 \x01(\tR\x06bookId\x12\x18\n" +
+	"\abarcode\x18\x02 \x01(\tR\abarcode\x12\x1c\n" +
+	"\tcondition\x18\x03 \x01(\tR\tcondition\x12\x1a\n" +
+	"\blocation\x18\x04 \x01(\tR\blocation\"C\n" +
+	"\x0eGetItemRequest\x12\x17\n" +
+	"\aitem_id\x18\x01 \x01(\tR\x06itemId\x12\x18\n" +
+	"\abarcode\x18\x02 \x01(\tR\abarcode\"+\n" +
+	"\x10ListItemsRequest\x12\x17\n" +
+	"\abook_id\x18\x01 \x01(\tR\x06bookId\"8\n" +
+	"\x11ListItemsResponse\x12#\n" +
+	"\x05items\x18\x01 \x03(\v2\r.library.ItemR\x05items\"~\n" +
+	"\x11UpdateItemRequest\x12\x17\n" +
+	"\aitem_id\x18\x01 \x01(\tR\x06itemId\x12\x1c\n" +
+	"\tcondition\x18\x02 \x01(\tR\tcondition\x12\x1a\n" +
+	"\blocation\x18\x03 \x01(\tR\blocation\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\"G\n" +
+	"\x13CheckoutItemRequest\x12\x17\n" +
+	"\abook_id\x18\x01 \x01(\tR\x06bookId\x12\x17\n" +
+	"\aitem_id\x18\x02 \x01(\tR\x06itemId\"F\n" +
+	"\x12CheckinItemRequest\x12\x17\n" +
+	"\aitem_id\x18\x01 \x01(\tR\x06itemId\x12\x17\n" +
+	"\abook_id\x18\x02 \x01(\tR\x06bookId2\xd5\x16\n" +
+	"\vBookService\x12V\n" +
+	"\aGetBook\x12\x17.library.GetBookRequest\x1a\x15.library.BookResponse\"\x1b\x82\xd3\xe4\x93\x02\x15\x12\x13/v1/books/{book_id}\x12U\n" +
+	"\n" +
+	"CreateBook\x12\x1a.library.CreateBookRequest\x1a\x15.library.BookResponse\"\x14\x82\xd3\xe4\x93\x02\x0e:\x01*\"\t/v1/books\x12_\n" +
+	"\n" +
+	"UpdateBook\x12\x1a.library.UpdateBookRequest\x1a\x15.library.BookResponse\"\x1e\x82\xd3\xe4\x93\x02\x18:\x01*2\x13/v1/books/{book_id}\x12l\n" +
+	"\fWithdrawBook\x12\x1c.library.WithdrawBookRequest\x1a\x15.library.BookResponse\"'\x82\xd3\xe4\x93\x02!:\x01*\"\x1c/v1/books/{book_id}:withdraw\x12v\n" +
+	"\x0eGetBookHistory\x12\x1e.library.GetBookHistoryRequest\x1a\x1f.library.GetBookHistoryResponse\"#\x82\xd3\xe4\x93\x02\x1d\x12\x1b/v1/books/{book_id}/history\x12[\n" +
+	"\bGetBooks\x12\x18.library.GetBooksRequest\x1a\x19.library.GetBooksResponse\"\x1a\x82\xd3\xe4\x93\x02\x14\x12\x12/v1/books:batchGet\x12h\n" +
+	"\rSearchCatalog\x12\x1d.library.SearchCatalogRequest\x1a\x1e.library.SearchCatalogResponse\"\x18\x82\xd3\xe4\x93\x02\x12\x12\x10/v1/books:search\x12W\n" +
+	"\aSuggest\x12\x17.library.SuggestRequest\x1a\x18.library.SuggestResponse\"\x19\x82\xd3\xe4\x93\x02\x13\x12\x11/v1/books:suggest\x12d\n" +
+	"\rGetBookByISBN\x12\x1d.library.GetBookByISBNRequest\x1a\x15.library.BookResponse\"\x1d\x82\xd3\xe4\x93\x02\x17\x12\x15/v1/books/isbn/{isbn}\x12|\n" +
+	"\x11LookupBooksByISBN\x12!.library.LookupBooksByISBNRequest\x1a\".library.LookupBooksByISBNResponse\" \x82\xd3\xe4\x93\x02\x1a:\x01*\"\x15/v1/books/isbn:lookup\x12J\n" +
+	"\vImportBooks\x12\x1b.library.ImportBooksRequest\x1a\x1c.library.ImportBooksResponse(\x01\x12g\n" +
+	"\n" +
+	"ImportMarc\x12\x1a.library.ImportMarcRequest\x1a\x1c.library.ImportBooksResponse\"\x1f\x82\xd3\xe4\x93\x02\x19:\x01*\"\x14/v1/books:importMarc\x12c\n" +
+	"\n" +
+	"ExportMarc\x12\x1a.library.ExportMarcRequest\x1a\x1b.library.ExportMarcResponse\"\x1c\x82\xd3\xe4\x93\x02\x16\x12\x14/v1/books:exportMarc\x12U\n" +
+	"\fCreateAuthor\x12\x1c.library.CreateAuthorRequest\x1a\x0f.library.Author\"\x16\x82\xd3\xe4\x93\x02\x10:\x01*\"\v/v1/authors\x12X\n" +
+	"\tGetAuthor\x12\x19.library.GetAuthorRequest\x1a\x0f.library.Author\"\x1f\x82\xd3\xe4\x93\x02\x19\x12\x17/v1/authors/{author_id}\x12j\n" +
+	"\rSearchAuthors\x12\x1d.library.SearchAuthorsRequest\x1a\x1e.library.SearchAuthorsResponse\"\x1a\x82\xd3\xe4\x93\x02\x14\x12\x12/v1/authors:search\x12g\n" +
+	"\fMergeAuthors\x12\x1c.library.MergeAuthorsRequest\x1a\x0f.library.Author\"(\x82\xd3\xe4\x93\x02\":\x01*\"\x1d/v1/authors/{author_id}:merge\x12Y\n" +
+	"\rCreateSubject\x12\x1d.library.CreateSubjectRequest\x1a\x10.library.Subject\"\x17\x82\xd3\xe4\x93\x02\x11:\x01*\"\f/v1/subjects\x12a\n" +
+	"\fListSubjects\x12\x1c.library.ListSubjectsRequest\x1a\x1d.library.ListSubjectsResponse\"\x14\x82\xd3\xe4\x93\x02\x0e\x12\f/v1/subjects\x12\x86\x01\n" +
+	"\x12ListBooksBySubject\x12\".library.ListBooksBySubjectRequest\x1a#.library.ListBooksBySubjectResponse\"'\x82\xd3\xe4\x93\x02!\x12\x1f/v1/subjects/{subject_id}/books\x12r\n" +
+	"\x0fSetBookSubjects\x12\x1f.library.SetBookSubjectsRequest\x1a\x15.library.BookResponse\"'\x82\xd3\xe4\x93\x02!:\x01*\x1a\x1c/v1/books/{book_id}/subjects\x12f\n" +
+	"\vSetBookTags\x12\x1b.library.SetBookTagsRequest\x1a\x15.library.BookResponse\"#\x82\xd3\xe4\x93\x02\x1d:\x01*\x1a\x18/v1/books/{book_id}/tags\x12Q\n" +
+	"\bListTags\x12\x18.library.ListTagsRequest\x1a\x19.library.ListTagsResponse\"\x10\x82\xd3\xe4\x93\x02\n" +
+	"\x12\b/v1/tags\x12W\n" +
+	"\aAddItem\x12\x17.library.AddItemRequest\x1a\r.library.Item\"$\x82\xd3\xe4\x93\x02\x1e:\x01*\"\x19/v1/books/{book_id}/items\x12N\n" +
+	"\aGetItem\x12\x17.library.GetItemRequest\x1a\r.library.Item\"\x1b\x82\xd3\xe4\x93\x02\x15\x12\x13/v1/items/{item_id}\x12e\n" +
+	"\tListItems\x12\x19.library.ListItemsRequest\x1a\x1a.library.ListItemsResponse\"!\x82\xd3\xe4\x93\x02\x1b\x12\x19/v1/books/{book_id}/items\x12W\n" +
 	"\n" +
-	"CreateBook\x12\x1a.library.CreateBookRequest\x1a\x15.library.BookResponse\"\x00\x12G\n" +
-	"\x10UpdateBookStatus\x12\x1a.library.UpdateBookRequest\x1a\x15.library.BookResponse\"\x00B\x06Z\x04.;pbb\x06proto3"
+	"UpdateItem\x12\x1a.library.UpdateItemRequest\x1a\r.library.Item\"\x1e\x82\xd3\xe4\x93\x02\x18:\x01*2\x13/v1/items/{item_id}\x12j\n" +
+	"\fCheckoutItem\x12\x1c.library.CheckoutItemRequest\x1a\r.library.Item\"-\x82\xd3\xe4\x93\x02':\x01*\"\"/v1/books/{book_id}/items:checkout\x12a\n" +
+	"\vCheckinItem\x12\x1b.library.CheckinItemRequest\x1a\r.library.Item\"&\x82\xd3\xe4\x93\x02 :\x01*\"\x1b/v1/items/{item_id}:checkinB\x06Z\x04.;pbb\x06proto3"
 
 var (
 	file_books_proto_rawDescOnce sync.Once
@@ -282,25 +3698,155 @@ func file_books_proto_rawDescGZIP() []byte {
 	return file_books_proto_rawDescData
 }
 
-var file_books_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_books_proto_msgTypes = make([]protoimpl.MessageInfo, 55)
 var file_books_proto_goTypes = []any{
-	(*UpdateBookRequest)(nil), // 0: library.UpdateBookRequest
-	(*GetBookRequest)(nil),    // 1: library.GetBookRequest
-	(*CreateBookRequest)(nil), // 2: library.CreateBookRequest
-	(*BookResponse)(nil),      // 3: library.BookResponse
+	(*GetBookRequest)(nil),             // 0: library.GetBookRequest
+	(*GetBooksRequest)(nil),            // 1: library.GetBooksRequest
+	(*GetBooksResponse)(nil),           // 2: library.GetBooksResponse
+	(*CreateBookRequest)(nil),          // 3: library.CreateBookRequest
+	(*Contributor)(nil),                // 4: library.Contributor
+	(*BookResponse)(nil),               // 5: library.BookResponse
+	(*UpdateBookRequest)(nil),          // 6: library.UpdateBookRequest
+	(*Author)(nil),                     // 7: library.Author
+	(*CreateAuthorRequest)(nil),        // 8: library.CreateAuthorRequest
+	(*GetAuthorRequest)(nil),           // 9: library.GetAuthorRequest
+	(*SearchAuthorsRequest)(nil),       // 10: library.SearchAuthorsRequest
+	(*SearchAuthorsResponse)(nil),      // 11: library.SearchAuthorsResponse
+	(*MergeAuthorsRequest)(nil),        // 12: library.MergeAuthorsRequest
+	(*Subject)(nil),                    // 13: library.Subject
+	(*CreateSubjectRequest)(nil),       // 14: library.CreateSubjectRequest
+	(*ListSubjectsRequest)(nil),        // 15: library.ListSubjectsRequest
+	(*ListSubjectsResponse)(nil),       // 16: library.ListSubjectsResponse
+	(*ListBooksBySubjectRequest)(nil),  // 17: library.ListBooksBySubjectRequest
+	(*ListBooksBySubjectResponse)(nil), // 18: library.ListBooksBySubjectResponse
+	(*SetBookSubjectsRequest)(nil),     // 19: library.SetBookSubjectsRequest
+	(*SetBookTagsRequest)(nil),         // 20: library.SetBookTagsRequest
+	(*ListTagsRequest)(nil),            // 21: library.ListTagsRequest
+	(*ListTagsResponse)(nil),           // 22: library.ListTagsResponse
+	(*WithdrawBookRequest)(nil),        // 23: library.WithdrawBookRequest
+	(*GetBookHistoryRequest)(nil),      // 24: library.GetBookHistoryRequest
+	(*GetBookHistoryResponse)(nil),     // 25: library.GetBookHistoryResponse
+	(*BookRevision)(nil),               // 26: library.BookRevision
+	(*FieldChange)(nil),                // 27: library.FieldChange
+	(*SearchCatalogRequest)(nil),       // 28: library.SearchCatalogRequest
+	(*SearchCatalogResponse)(nil),      // 29: library.SearchCatalogResponse
+	(*SearchHit)(nil),                  // 30: library.SearchHit
+	(*SearchFacets)(nil),               // 31: library.SearchFacets
+	(*FacetCount)(nil),                 // 32: library.FacetCount
+	(*SuggestRequest)(nil),             // 33: library.SuggestRequest
+	(*SuggestResponse)(nil),            // 34: library.SuggestResponse
+	(*Suggestion)(nil),                 // 35: library.Suggestion
+	(*GetBookByISBNRequest)(nil),       // 36: library.GetBookByISBNRequest
+	(*LookupBooksByISBNRequest)(nil),   // 37: library.LookupBooksByISBNRequest
+	(*LookupBooksByISBNResponse)(nil),  // 38: library.LookupBooksByISBNResponse
+	(*ISBNLookupResult)(nil),           // 39: library.ISBNLookupResult
+	(*ImportBooksRequest)(nil),         // 40: library.ImportBooksRequest
+	(*ImportRow)(nil),                  // 41: library.ImportRow
+	(*ImportBooksResponse)(nil),        // 42: library.ImportBooksResponse
+	(*ImportRowResult)(nil),            // 43: library.ImportRowResult
+	(*ImportMarcRequest)(nil),          // 44: library.ImportMarcRequest
+	(*ExportMarcRequest)(nil),          // 45: library.ExportMarcRequest
+	(*ExportMarcResponse)(nil),         // 46: library.ExportMarcResponse
+	(*Item)(nil),                       // 47: library.Item
+	(*AddItemRequest)(nil),             // 48: library.AddItemRequest
+	(*GetItemRequest)(nil),             // 49: library.GetItemRequest
+	(*ListItemsRequest)(nil),           // 50: library.ListItemsRequest
+	(*ListItemsResponse)(nil),          // 51: library.ListItemsResponse
+	(*UpdateItemRequest)(nil),          // 52: library.UpdateItemRequest
+	(*CheckoutItemRequest)(nil),        // 53: library.CheckoutItemRequest
+	(*CheckinItemRequest)(nil),         // 54: library.CheckinItemRequest
+	(*fieldmaskpb.FieldMask)(nil),      // 55: google.protobuf.FieldMask
 }
 var file_books_proto_depIdxs = []int32{
-	1, // 0: library.BookService.GetBook:input_type -> library.GetBookRequest
-	2, // 1: library.BookService.CreateBook:input_type -> library.CreateBookRequest
-	0, // 2: library.BookService.UpdateBookStatus:input_type -> library.UpdateBookRequest
-	3, // 3: library.BookService.GetBook:output_type -> library.BookResponse
-	3, // 4: library.BookService.CreateBook:output_type -> library.BookResponse
-	3, // 5: library.BookService.UpdateBookStatus:output_type -> library.BookResponse
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	5,  // 0: library.GetBooksResponse.books:type_name -> library.BookResponse
+	4,  // 1: library.CreateBookRequest.contributors:type_name -> library.Contributor
+	4,  // 2: library.BookResponse.contributors:type_name -> library.Contributor
+	13, // 3: library.BookResponse.subjects:type_name -> library.Subject
+	55, // 4: library.UpdateBookRequest.update_mask:type_name -> google.protobuf.FieldMask
+	4,  // 5: library.UpdateBookRequest.contributors:type_name -> library.Contributor
+	7,  // 6: library.SearchAuthorsResponse.authors:type_name -> library.Author
+	13, // 7: library.ListSubjectsResponse.parent:type_name -> library.Subject
+	13, // 8: library.ListSubjectsResponse.subjects:type_name -> library.Subject
+	13, // 9: library.ListBooksBySubjectResponse.subject:type_name -> library.Subject
+	5,  // 10: library.ListBooksBySubjectResponse.books:type_name -> library.BookResponse
+	32, // 11: library.ListTagsResponse.tags:type_name -> library.FacetCount
+	26, // 12: library.GetBookHistoryResponse.revisions:type_name -> library.BookRevision
+	27, // 13: library.BookRevision.changes:type_name -> library.FieldChange
+	30, // 14: library.SearchCatalogResponse.hits:type_name -> library.SearchHit
+	31, // 15: library.SearchCatalogResponse.facets:type_name -> library.SearchFacets
+	5,  // 16: library.SearchHit.book:type_name -> library.BookResponse
+	32, // 17: library.SearchFacets.authors:type_name -> library.FacetCount
+	32, // 18: library.SearchFacets.decades:type_name -> library.FacetCount
+	32, // 19: library.SearchFacets.availability:type_name -> library.FacetCount
+	35, // 20: library.SuggestResponse.suggestions:type_name -> library.Suggestion
+	39, // 21: library.LookupBooksByISBNResponse.results:type_name -> library.ISBNLookupResult
+	5,  // 22: library.ISBNLookupResult.book:type_name -> library.BookResponse
+	41, // 23: library.ImportBooksRequest.rows:type_name -> library.ImportRow
+	43, // 24: library.ImportBooksResponse.results:type_name -> library.ImportRowResult
+	47, // 25: library.ListItemsResponse.items:type_name -> library.Item
+	0,  // 26: library.BookService.GetBook:input_type -> library.GetBookRequest
+	3,  // 27: library.BookService.CreateBook:input_type -> library.CreateBookRequest
+	6,  // 28: library.BookService.UpdateBook:input_type -> library.UpdateBookRequest
+	23, // 29: library.BookService.WithdrawBook:input_type -> library.WithdrawBookRequest
+	24, // 30: library.BookService.GetBookHistory:input_type -> library.GetBookHistoryRequest
+	1,  // 31: library.BookService.GetBooks:input_type -> library.GetBooksRequest
+	28, // 32: library.BookService.SearchCatalog:input_type -> library.SearchCatalogRequest
+	33, // 33: library.BookService.Suggest:input_type -> library.SuggestRequest
+	36, // 34: library.BookService.GetBookByISBN:input_type -> library.GetBookByISBNRequest
+	37, // 35: library.BookService.LookupBooksByISBN:input_type -> library.LookupBooksByISBNRequest
+	40, // 36: library.BookService.ImportBooks:input_type -> library.ImportBooksRequest
+	44, // 37: library.BookService.ImportMarc:input_type -> library.ImportMarcRequest
+	45, // 38: library.BookService.ExportMarc:input_type -> library.ExportMarcRequest
+	8,  // 39: library.BookService.CreateAuthor:input_type -> library.CreateAuthorRequest
+	9,  // 40: library.BookService.GetAuthor:input_type -> library.GetAuthorRequest
+	10, // 41: library.BookService.SearchAuthors:input_type -> library.SearchAuthorsRequest
+	12, // 42: library.BookService.MergeAuthors:input_type -> library.MergeAuthorsRequest
+	14, // 43: library.BookService.CreateSubject:input_type -> library.CreateSubjectRequest
+	15, // 44: library.BookService.ListSubjects:input_type -> library.ListSubjectsRequest
+	17, // 45: library.BookService.ListBooksBySubject:input_type -> library.ListBooksBySubjectRequest
+	19, // 46: library.BookService.SetBookSubjects:input_type -> library.SetBookSubjectsRequest
+	20, // 47: library.BookService.SetBookTags:input_type -> library.SetBookTagsRequest
+	21, // 48: library.BookService.ListTags:input_type -> library.ListTagsRequest
+	48, // 49: library.BookService.AddItem:input_type -> library.AddItemRequest
+	49, // 50: library.BookService.GetItem:input_type -> library.GetItemRequest
+	50, // 51: library.BookService.ListItems:input_type -> library.ListItemsRequest
+	52, // 52: library.BookService.UpdateItem:input_type -> library.UpdateItemRequest
+	53, // 53: library.BookService.CheckoutItem:input_type -> library.CheckoutItemRequest
+	54, // 54: library.BookService.CheckinItem:input_type -> library.CheckinItemRequest
+	5,  // 55: library.BookService.GetBook:output_type -> library.BookResponse
+	5,  // 56: library.BookService.CreateBook:output_type -> library.BookResponse
+	5,  // 57: library.BookService.UpdateBook:output_type -> library.BookResponse
+	5,  // 58: library.BookService.WithdrawBook:output_type -> library.BookResponse
+	25, // 59: library.BookService.GetBookHistory:output_type -> library.GetBookHistoryResponse
+	2,  // 60: library.BookService.GetBooks:output_type -> library.GetBooksResponse
+	29, // 61: library.BookService.SearchCatalog:output_type -> library.SearchCatalogResponse
+	34, // 62: library.BookService.Suggest:output_type -> library.SuggestResponse
+	5,  // 63: library.BookService.GetBookByISBN:output_type -> library.BookResponse
+	38, // 64: library.BookService.LookupBooksByISBN:output_type -> library.LookupBooksByISBNResponse
+	42, // 65: library.BookService.ImportBooks:output_type -> library.ImportBooksResponse
+	42, // 66: library.BookService.ImportMarc:output_type -> library.ImportBooksResponse
+	46, // 67: library.BookService.ExportMarc:output_type -> library.ExportMarcResponse
+	7,  // 68: library.BookService.CreateAuthor:output_type -> library.Author
+	7,  // 69: library.BookService.GetAuthor:output_type -> library.Author
+	11, // 70: library.BookService.SearchAuthors:output_type -> library.SearchAuthorsResponse
+	7,  // 71: library.BookService.MergeAuthors:output_type -> library.Author
+	13, // 72: library.BookService.CreateSubject:output_type -> library.Subject
+	16, // 73: library.BookService.ListSubjects:output_type -> library.ListSubjectsResponse
+	18, // 74: library.BookService.ListBooksBySubject:output_type -> library.ListBooksBySubjectResponse
+	5,  // 75: library.BookService.SetBookSubjects:output_type -> library.BookResponse
+	5,  // 76: library.BookService.SetBookTags:output_type -> library.BookResponse
+	22, // 77: library.BookService.ListTags:output_type -> library.ListTagsResponse
+	47, // 78: library.BookService.AddItem:output_type -> library.Item
+	47, // 79: library.BookService.GetItem:output_type -> library.Item
+	51, // 80: library.BookService.ListItems:output_type -> library.ListItemsResponse
+	47, // 81: library.BookService.UpdateItem:output_type -> library.Item
+	47, // 82: library.BookService.CheckoutItem:output_type -> library.Item
+	47, // 83: library.BookService.CheckinItem:output_type -> library.Item
+	55, // [55:84] is the sub-list for method output_type
+	26, // [26:55] is the sub-list for method input_type
+	26, // [26:26] is the sub-list for extension type_name
+	26, // [26:26] is the sub-list for extension extendee
+	0,  // [0:26] is the sub-list for field type_name
 }
 
 func init() { file_books_proto_init() }
@@ -314,7 +3860,7 @@ func file_books_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_books_proto_rawDesc), len(file_books_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   55,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,9 +19,35 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	BookService_GetBook_FullMethodName          = "/library.BookService/GetBook"
-	BookService_CreateBook_FullMethodName       = "/library.BookService/CreateBook"
-	BookService_UpdateBookStatus_FullMethodName = "/library.BookService/UpdateBookStatus"
+	BookService_GetBook_FullMethodName            = "/library.BookService/GetBook"
+	BookService_CreateBook_FullMethodName         = "/library.BookService/CreateBook"
+	BookService_UpdateBook_FullMethodName         = "/library.BookService/UpdateBook"
+	BookService_WithdrawBook_FullMethodName       = "/library.BookService/WithdrawBook"
+	BookService_GetBookHistory_FullMethodName     = "/library.BookService/GetBookHistory"
+	BookService_GetBooks_FullMethodName           = "/library.BookService/GetBooks"
+	BookService_SearchCatalog_FullMethodName      = "/library.BookService/SearchCatalog"
+	BookService_Suggest_FullMethodName            = "/library.BookService/Suggest"
+	BookService_GetBookByISBN_FullMethodName      = "/library.BookService/GetBookByISBN"
+	BookService_LookupBooksByISBN_FullMethodName  = "/library.BookService/LookupBooksByISBN"
+	BookService_ImportBooks_FullMethodName        = "/library.BookService/ImportBooks"
+	BookService_ImportMarc_FullMethodName         = "/library.BookService/ImportMarc"
+	BookService_ExportMarc_FullMethodName         = "/library.BookService/ExportMarc"
+	BookService_CreateAuthor_FullMethodName       = "/library.BookService/CreateAuthor"
+	BookService_GetAuthor_FullMethodName          = "/library.BookService/GetAuthor"
+	BookService_SearchAuthors_FullMethodName      = "/library.BookService/SearchAuthors"
+	BookService_MergeAuthors_FullMethodName       = "/library.BookService/MergeAuthors"
+	BookService_CreateSubject_FullMethodName      = "/library.BookService/CreateSubject"
+	BookService_ListSubjects_FullMethodName       = "/library.BookService/ListSubjects"
+	BookService_ListBooksBySubject_FullMethodName = "/library.BookService/ListBooksBySubject"
+	BookService_SetBookSubjects_FullMethodName    = "/library.BookService/SetBookSubjects"
+	BookService_SetBookTags_FullMethodName        = "/library.BookService/SetBookTags"
+	BookService_ListTags_FullMethodName           = "/library.BookService/ListTags"
+	BookService_AddItem_FullMethodName            = "/library.BookService/AddItem"
+	BookService_GetItem_FullMethodName            = "/library.BookService/GetItem"
+	BookService_ListItems_FullMethodName          = "/library.BookService/ListItems"
+	BookService_UpdateItem_FullMethodName         = "/library.BookService/UpdateItem"
+	BookService_CheckoutItem_FullMethodName       = "/library.BookService/CheckoutItem"
+	BookService_CheckinItem_FullMethodName        = "/library.BookService/CheckinItem"
 )
 
 // BookServiceClient is the client API for BookService service.
@@ -30,7 +56,50 @@ const (
 type BookServiceClient interface {
 	GetBook(ctx context.Context, in *GetBookRequest, opts ...grpc.CallOption) (*BookResponse, error)
 	CreateBook(ctx context.Context, in *CreateBookRequest, opts ...grpc.CallOption) (*BookResponse, error)
-	UpdateBookStatus(ctx context.Context, in *UpdateBookRequest, opts ...grpc.CallOption) (*BookResponse, error)
+	UpdateBook(ctx context.Context, in *UpdateBookRequest, opts ...grpc.CallOption) (*BookResponse, error)
+	// WithdrawBook removes a lost or discarded book from the catalog together with its copies.
+	// The record is kept for loans and history.
+	WithdrawBook(ctx context.Context, in *WithdrawBookRequest, opts ...grpc.CallOption) (*BookResponse, error)
+	GetBookHistory(ctx context.Context, in *GetBookHistoryRequest, opts ...grpc.CallOption) (*GetBookHistoryResponse, error)
+	GetBooks(ctx context.Context, in *GetBooksRequest, opts ...grpc.CallOption) (*GetBooksResponse, error)
+	// SearchCatalog finds books by title and author, tolerating typos, ranked by relevance.
+	SearchCatalog(ctx context.Context, in *SearchCatalogRequest, opts ...grpc.CallOption) (*SearchCatalogResponse, error)
+	// Suggest completes a search box prefix with titles and authors, the most borrowed first.
+	Suggest(ctx context.Context, in *SuggestRequest, opts ...grpc.CallOption) (*SuggestResponse, error)
+	// GetBookByISBN finds a book by its ISBN-10 or ISBN-13, e.g. a scanned barcode.
+	GetBookByISBN(ctx context.Context, in *GetBookByISBNRequest, opts ...grpc.CallOption) (*BookResponse, error)
+	// LookupBooksByISBN resolves a stack of scanned ISBNs at once, reporting each one.
+	LookupBooksByISBN(ctx context.Context, in *LookupBooksByISBNRequest, opts ...grpc.CallOption) (*LookupBooksByISBNResponse, error)
+	// ImportBooks loads a catalog file streamed in chunks of rows, creating or updating books by ISBN.
+	// gRPC only: the REST gateway does not proxy client streams.
+	ImportBooks(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ImportBooksRequest, ImportBooksResponse], error)
+	ImportMarc(ctx context.Context, in *ImportMarcRequest, opts ...grpc.CallOption) (*ImportBooksResponse, error)
+	ExportMarc(ctx context.Context, in *ExportMarcRequest, opts ...grpc.CallOption) (*ExportMarcResponse, error)
+	CreateAuthor(ctx context.Context, in *CreateAuthorRequest, opts ...grpc.CallOption) (*Author, error)
+	GetAuthor(ctx context.Context, in *GetAuthorRequest, opts ...grpc.CallOption) (*Author, error)
+	// SearchAuthors matches canonical and variant names, tolerating typos.
+	SearchAuthors(ctx context.Context, in *SearchAuthorsRequest, opts ...grpc.CallOption) (*SearchAuthorsResponse, error)
+	// MergeAuthors folds duplicate authors into one: their books are repointed and
+	// their names become variants of the remaining author.
+	MergeAuthors(ctx context.Context, in *MergeAuthorsRequest, opts ...grpc.CallOption) (*Author, error)
+	CreateSubject(ctx context.Context, in *CreateSubjectRequest, opts ...grpc.CallOption) (*Subject, error)
+	// ListSubjects browses the subject tree one level at a time, with book counts.
+	ListSubjects(ctx context.Context, in *ListSubjectsRequest, opts ...grpc.CallOption) (*ListSubjectsResponse, error)
+	ListBooksBySubject(ctx context.Context, in *ListBooksBySubjectRequest, opts ...grpc.CallOption) (*ListBooksBySubjectResponse, error)
+	// SetBookSubjects replaces the subjects of a book.
+	SetBookSubjects(ctx context.Context, in *SetBookSubjectsRequest, opts ...grpc.CallOption) (*BookResponse, error)
+	// SetBookTags replaces the tags of a book.
+	SetBookTags(ctx context.Context, in *SetBookTagsRequest, opts ...grpc.CallOption) (*BookResponse, error)
+	// ListTags returns the most used tags with book counts.
+	ListTags(ctx context.Context, in *ListTagsRequest, opts ...grpc.CallOption) (*ListTagsResponse, error)
+	AddItem(ctx context.Context, in *AddItemRequest, opts ...grpc.CallOption) (*Item, error)
+	GetItem(ctx context.Context, in *GetItemRequest, opts ...grpc.CallOption) (*Item, error)
+	ListItems(ctx context.Context, in *ListItemsRequest, opts ...grpc.CallOption) (*ListItemsResponse, error)
+	UpdateItem(ctx context.Context, in *UpdateItemRequest, opts ...grpc.CallOption) (*Item, error)
+	// CheckoutItem marks a copy as on loan: the given one, or any available copy of the book.
+	CheckoutItem(ctx context.Context, in *CheckoutItemRequest, opts ...grpc.CallOption) (*Item, error)
+	// CheckinItem puts a copy on loan back on the shelf.
+	CheckinItem(ctx context.Context, in *CheckinItemRequest, opts ...grpc.CallOption) (*Item, error)
 }
 
 type bookServiceClient struct {
@@ -61,10 +130,273 @@ func (c *bookServiceClient) CreateBook(ctx context.Context, in *CreateBookReques
 	return out, nil
 }
 
-func (c *bookServiceClient) UpdateBookStatus(ctx context.Context, in *UpdateBookRequest, opts ...grpc.CallOption) (*BookResponse, error) {
+func (c *bookServiceClient) UpdateBook(ctx context.Context, in *UpdateBookRequest, opts ...grpc.CallOption) (*BookResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(BookResponse)
-	err := c.cc.Invoke(ctx, BookService_UpdateBookStatus_FullMethodName, in, out, cOpts...)
+	err := c.cc.Invoke(ctx, BookService_UpdateBook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) WithdrawBook(ctx context.Context, in *WithdrawBookRequest, opts ...grpc.CallOption) (*BookResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BookResponse)
+	err := c.cc.Invoke(ctx, BookService_WithdrawBook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) GetBookHistory(ctx context.Context, in *GetBookHistoryRequest, opts ...grpc.CallOption) (*GetBookHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetBookHistoryResponse)
+	err := c.cc.Invoke(ctx, BookService_GetBookHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) GetBooks(ctx context.Context, in *GetBooksRequest, opts ...grpc.CallOption) (*GetBooksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetBooksResponse)
+	err := c.cc.Invoke(ctx, BookService_GetBooks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) SearchCatalog(ctx context.Context, in *SearchCatalogRequest, opts ...grpc.CallOption) (*SearchCatalogResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchCatalogResponse)
+	err := c.cc.Invoke(ctx, BookService_SearchCatalog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) Suggest(ctx context.Context, in *SuggestRequest, opts ...grpc.CallOption) (*SuggestResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SuggestResponse)
+	err := c.cc.Invoke(ctx, BookService_Suggest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) GetBookByISBN(ctx context.Context, in *GetBookByISBNRequest, opts ...grpc.CallOption) (*BookResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BookResponse)
+	err := c.cc.Invoke(ctx, BookService_GetBookByISBN_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) LookupBooksByISBN(ctx context.Context, in *LookupBooksByISBNRequest, opts ...grpc.CallOption) (*LookupBooksByISBNResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LookupBooksByISBNResponse)
+	err := c.cc.Invoke(ctx, BookService_LookupBooksByISBN_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) ImportBooks(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ImportBooksRequest, ImportBooksResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BookService_ServiceDesc.Streams[0], BookService_ImportBooks_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ImportBooksRequest, ImportBooksResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BookService_ImportBooksClient = grpc.ClientStreamingClient[ImportBooksRequest, ImportBooksResponse]
+
+func (c *bookServiceClient) ImportMarc(ctx context.Context, in *ImportMarcRequest, opts ...grpc.CallOption) (*ImportBooksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportBooksResponse)
+	err := c.cc.Invoke(ctx, BookService_ImportMarc_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) ExportMarc(ctx context.Context, in *ExportMarcRequest, opts ...grpc.CallOption) (*ExportMarcResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExportMarcResponse)
+	err := c.cc.Invoke(ctx, BookService_ExportMarc_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) CreateAuthor(ctx context.Context, in *CreateAuthorRequest, opts ...grpc.CallOption) (*Author, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Author)
+	err := c.cc.Invoke(ctx, BookService_CreateAuthor_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) GetAuthor(ctx context.Context, in *GetAuthorRequest, opts ...grpc.CallOption) (*Author, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Author)
+	err := c.cc.Invoke(ctx, BookService_GetAuthor_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) SearchAuthors(ctx context.Context, in *SearchAuthorsRequest, opts ...grpc.CallOption) (*SearchAuthorsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchAuthorsResponse)
+	err := c.cc.Invoke(ctx, BookService_SearchAuthors_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) MergeAuthors(ctx context.Context, in *MergeAuthorsRequest, opts ...grpc.CallOption) (*Author, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Author)
+	err := c.cc.Invoke(ctx, BookService_MergeAuthors_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) CreateSubject(ctx context.Context, in *CreateSubjectRequest, opts ...grpc.CallOption) (*Subject, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Subject)
+	err := c.cc.Invoke(ctx, BookService_CreateSubject_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) ListSubjects(ctx context.Context, in *ListSubjectsRequest, opts ...grpc.CallOption) (*ListSubjectsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSubjectsResponse)
+	err := c.cc.Invoke(ctx, BookService_ListSubjects_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) ListBooksBySubject(ctx context.Context, in *ListBooksBySubjectRequest, opts ...grpc.CallOption) (*ListBooksBySubjectResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListBooksBySubjectResponse)
+	err := c.cc.Invoke(ctx, BookService_ListBooksBySubject_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) SetBookSubjects(ctx context.Context, in *SetBookSubjectsRequest, opts ...grpc.CallOption) (*BookResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BookResponse)
+	err := c.cc.Invoke(ctx, BookService_SetBookSubjects_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) SetBookTags(ctx context.Context, in *SetBookTagsRequest, opts ...grpc.CallOption) (*BookResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BookResponse)
+	err := c.cc.Invoke(ctx, BookService_SetBookTags_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) ListTags(ctx context.Context, in *ListTagsRequest, opts ...grpc.CallOption) (*ListTagsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTagsResponse)
+	err := c.cc.Invoke(ctx, BookService_ListTags_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) AddItem(ctx context.Context, in *AddItemRequest, opts ...grpc.CallOption) (*Item, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Item)
+	err := c.cc.Invoke(ctx, BookService_AddItem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) GetItem(ctx context.Context, in *GetItemRequest, opts ...grpc.CallOption) (*Item, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Item)
+	err := c.cc.Invoke(ctx, BookService_GetItem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) ListItems(ctx context.Context, in *ListItemsRequest, opts ...grpc.CallOption) (*ListItemsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListItemsResponse)
+	err := c.cc.Invoke(ctx, BookService_ListItems_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) UpdateItem(ctx context.Context, in *UpdateItemRequest, opts ...grpc.CallOption) (*Item, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Item)
+	err := c.cc.Invoke(ctx, BookService_UpdateItem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) CheckoutItem(ctx context.Context, in *CheckoutItemRequest, opts ...grpc.CallOption) (*Item, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Item)
+	err := c.cc.Invoke(ctx, BookService_CheckoutItem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookServiceClient) CheckinItem(ctx context.Context, in *CheckinItemRequest, opts ...grpc.CallOption) (*Item, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Item)
+	err := c.cc.Invoke(ctx, BookService_CheckinItem_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +409,50 @@ func (c *bookServiceClient) UpdateBookStatus(ctx context.Context, in *UpdateBook
 type BookServiceServer interface {
 	GetBook(context.Context, *GetBookRequest) (*BookResponse, error)
 	CreateBook(context.Context, *CreateBookRequest) (*BookResponse, error)
-	UpdateBookStatus(context.Context, *UpdateBookRequest) (*BookResponse, error)
+	UpdateBook(context.Context, *UpdateBookRequest) (*BookResponse, error)
+	// WithdrawBook removes a lost or discarded book from the catalog together with its copies.
+	// The record is kept for loans and history.
+	WithdrawBook(context.Context, *WithdrawBookRequest) (*BookResponse, error)
+	GetBookHistory(context.Context, *GetBookHistoryRequest) (*GetBookHistoryResponse, error)
+	GetBooks(context.Context, *GetBooksRequest) (*GetBooksResponse, error)
+	// SearchCatalog finds books by title and author, tolerating typos, ranked by relevance.
+	SearchCatalog(context.Context, *SearchCatalogRequest) (*SearchCatalogResponse, error)
+	// Suggest completes a search box prefix with titles and authors, the most borrowed first.
+	Suggest(context.Context, *SuggestRequest) (*SuggestResponse, error)
+	// GetBookByISBN finds a book by its ISBN-10 or ISBN-13, e.g. a scanned barcode.
+	GetBookByISBN(context.Context, *GetBookByISBNRequest) (*BookResponse, error)
+	// LookupBooksByISBN resolves a stack of scanned ISBNs at once, reporting each one.
+	LookupBooksByISBN(context.Context, *LookupBooksByISBNRequest) (*LookupBooksByISBNResponse, error)
+	// ImportBooks loads a catalog file streamed in chunks of rows, creating or updating books by ISBN.
+	// gRPC only: the REST gateway does not proxy client streams.
+	ImportBooks(grpc.ClientStreamingServer[ImportBooksRequest, ImportBooksResponse]) error
+	ImportMarc(context.Context, *ImportMarcRequest) (*ImportBooksResponse, error)
+	ExportMarc(context.Context, *ExportMarcRequest) (*ExportMarcResponse, error)
+	CreateAuthor(context.Context, *CreateAuthorRequest) (*Author, error)
+	GetAuthor(context.Context, *GetAuthorRequest) (*Author, error)
+	// SearchAuthors matches canonical and variant names, tolerating typos.
+	SearchAuthors(context.Context, *SearchAuthorsRequest) (*SearchAuthorsResponse, error)
+	// MergeAuthors folds duplicate authors into one: their books are repointed and
+	// their names become variants of the remaining author.
+	MergeAuthors(context.Context, *MergeAuthorsRequest) (*Author, error)
+	CreateSubject(context.Context, *CreateSubjectRequest) (*Subject, error)
+	// ListSubjects browses the subject tree one level at a time, with book counts.
+	ListSubjects(context.Context, *ListSubjectsRequest) (*ListSubjectsResponse, error)
+	ListBooksBySubject(context.Context, *ListBooksBySubjectRequest) (*ListBooksBySubjectResponse, error)
+	// SetBookSubjects replaces the subjects of a book.
+	SetBookSubjects(context.Context, *SetBookSubjectsRequest) (*BookResponse, error)
+	// SetBookTags replaces the tags of a book.
+	SetBookTags(context.Context, *SetBookTagsRequest) (*BookResponse, error)
+	// ListTags returns the most used tags with book counts.
+	ListTags(context.Context, *ListTagsRequest) (*ListTagsResponse, error)
+	AddItem(context.Context, *AddItemRequest) (*Item, error)
+	GetItem(context.Context, *GetItemRequest) (*Item, error)
+	ListItems(context.Context, *ListItemsRequest) (*ListItemsResponse, error)
+	UpdateItem(context.Context, *UpdateItemRequest) (*Item, error)
+	// CheckoutItem marks a copy as on loan: the given one, or any available copy of the book.
+	CheckoutItem(context.Context, *CheckoutItemRequest) (*Item, error)
+	// CheckinItem puts a copy on loan back on the shelf.
+	CheckinItem(context.Context, *CheckinItemRequest) (*Item, error)
 	mustEmbedUnimplementedBookServiceServer()
 }
 
@@ -94,8 +469,86 @@ func (UnimplementedBookServiceServer) GetBook(context.Context, *GetBookRequest)
 func (UnimplementedBookServiceServer) CreateBook(context.Context, *CreateBookRequest) (*BookResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateBook not implemented")
 }
-func (UnimplementedBookServiceServer) UpdateBookStatus(context.Context, *UpdateBookRequest) (*BookResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateBookStatus not implemented")
+func (UnimplementedBookServiceServer) UpdateBook(context.Context, *UpdateBookRequest) (*BookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateBook not implemented")
+}
+func (UnimplementedBookServiceServer) WithdrawBook(context.Context, *WithdrawBookRequest) (*BookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WithdrawBook not implemented")
+}
+func (UnimplementedBookServiceServer) GetBookHistory(context.Context, *GetBookHistoryRequest) (*GetBookHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBookHistory not implemented")
+}
+func (UnimplementedBookServiceServer) GetBooks(context.Context, *GetBooksRequest) (*GetBooksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBooks not implemented")
+}
+func (UnimplementedBookServiceServer) SearchCatalog(context.Context, *SearchCatalogRequest) (*SearchCatalogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchCatalog not implemented")
+}
+func (UnimplementedBookServiceServer) Suggest(context.Context, *SuggestRequest) (*SuggestResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Suggest not implemented")
+}
+func (UnimplementedBookServiceServer) GetBookByISBN(context.Context, *GetBookByISBNRequest) (*BookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBookByISBN not implemented")
+}
+func (UnimplementedBookServiceServer) LookupBooksByISBN(context.Context, *LookupBooksByISBNRequest) (*LookupBooksByISBNResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LookupBooksByISBN not implemented")
+}
+func (UnimplementedBookServiceServer) ImportBooks(grpc.ClientStreamingServer[ImportBooksRequest, ImportBooksResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ImportBooks not implemented")
+}
+func (UnimplementedBookServiceServer) ImportMarc(context.Context, *ImportMarcRequest) (*ImportBooksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportMarc not implemented")
+}
+func (UnimplementedBookServiceServer) ExportMarc(context.Context, *ExportMarcRequest) (*ExportMarcResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportMarc not implemented")
+}
+func (UnimplementedBookServiceServer) CreateAuthor(context.Context, *CreateAuthorRequest) (*Author, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAuthor not implemented")
+}
+func (UnimplementedBookServiceServer) GetAuthor(context.Context, *GetAuthorRequest) (*Author, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAuthor not implemented")
+}
+func (UnimplementedBookServiceServer) SearchAuthors(context.Context, *SearchAuthorsRequest) (*SearchAuthorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchAuthors not implemented")
+}
+func (UnimplementedBookServiceServer) MergeAuthors(context.Context, *MergeAuthorsRequest) (*Author, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MergeAuthors not implemented")
+}
+func (UnimplementedBookServiceServer) CreateSubject(context.Context, *CreateSubjectRequest) (*Subject, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSubject not implemented")
+}
+func (UnimplementedBookServiceServer) ListSubjects(context.Context, *ListSubjectsRequest) (*ListSubjectsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSubjects not implemented")
+}
+func (UnimplementedBookServiceServer) ListBooksBySubject(context.Context, *ListBooksBySubjectRequest) (*ListBooksBySubjectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBooksBySubject not implemented")
+}
+func (UnimplementedBookServiceServer) SetBookSubjects(context.Context, *SetBookSubjectsRequest) (*BookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBookSubjects not implemented")
+}
+func (UnimplementedBookServiceServer) SetBookTags(context.Context, *SetBookTagsRequest) (*BookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBookTags not implemented")
+}
+func (UnimplementedBookServiceServer) ListTags(context.Context, *ListTagsRequest) (*ListTagsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTags not implemented")
+}
+func (UnimplementedBookServiceServer) AddItem(context.Context, *AddItemRequest) (*Item, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddItem not implemented")
+}
+func (UnimplementedBookServiceServer) GetItem(context.Context, *GetItemRequest) (*Item, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetItem not implemented")
+}
+func (UnimplementedBookServiceServer) ListItems(context.Context, *ListItemsRequest) (*ListItemsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListItems not implemented")
+}
+func (UnimplementedBookServiceServer) UpdateItem(context.Context, *UpdateItemRequest) (*Item, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateItem not implemented")
+}
+func (UnimplementedBookServiceServer) CheckoutItem(context.Context, *CheckoutItemRequest) (*Item, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckoutItem not implemented")
+}
+func (UnimplementedBookServiceServer) CheckinItem(context.Context, *CheckinItemRequest) (*Item, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckinItem not implemented")
 }
 func (UnimplementedBookServiceServer) mustEmbedUnimplementedBookServiceServer() {}
 func (UnimplementedBookServiceServer) testEmbeddedByValue()                     {}
@@ -154,44 +607,607 @@ func _BookService_CreateBook_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
-func _BookService_UpdateBookStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+func _BookService_UpdateBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UpdateBookRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(BookServiceServer).UpdateBookStatus(ctx, in)
+		return srv.(BookServiceServer).UpdateBook(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: BookService_UpdateBookStatus_FullMethodName,
+		FullMethod: BookService_UpdateBook_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(BookServiceServer).UpdateBookStatus(ctx, req.(*UpdateBookRequest))
+		return srv.(BookServiceServer).UpdateBook(ctx, req.(*UpdateBookRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-// BookService_ServiceDesc is the grpc.ServiceDesc for BookService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var BookService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "library.BookService",
-	HandlerType: (*BookServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "GetBook",
-			Handler:    _BookService_GetBook_Handler,
-		},
-		{
-			MethodName: "CreateBook",
-			Handler:    _BookService_CreateBook_Handler,
-		},
+func _BookService_WithdrawBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WithdrawBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).WithdrawBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_WithdrawBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).WithdrawBook(ctx, req.(*WithdrawBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_GetBookHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBookHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).GetBookHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_GetBookHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).GetBookHistory(ctx, req.(*GetBookHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_GetBooks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBooksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).GetBooks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_GetBooks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).GetBooks(ctx, req.(*GetBooksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_SearchCatalog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchCatalogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).SearchCatalog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_SearchCatalog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).SearchCatalog(ctx, req.(*SearchCatalogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_Suggest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SuggestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).Suggest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_Suggest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).Suggest(ctx, req.(*SuggestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_GetBookByISBN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBookByISBNRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).GetBookByISBN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_GetBookByISBN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).GetBookByISBN(ctx, req.(*GetBookByISBNRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_LookupBooksByISBN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupBooksByISBNRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).LookupBooksByISBN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_LookupBooksByISBN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).LookupBooksByISBN(ctx, req.(*LookupBooksByISBNRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_ImportBooks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BookServiceServer).ImportBooks(&grpc.GenericServerStream[ImportBooksRequest, ImportBooksResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BookService_ImportBooksServer = grpc.ClientStreamingServer[ImportBooksRequest, ImportBooksResponse]
+
+func _BookService_ImportMarc_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportMarcRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).ImportMarc(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_ImportMarc_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).ImportMarc(ctx, req.(*ImportMarcRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_ExportMarc_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportMarcRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).ExportMarc(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_ExportMarc_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).ExportMarc(ctx, req.(*ExportMarcRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_CreateAuthor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAuthorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).CreateAuthor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_CreateAuthor_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).CreateAuthor(ctx, req.(*CreateAuthorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_GetAuthor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAuthorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).GetAuthor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_GetAuthor_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).GetAuthor(ctx, req.(*GetAuthorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_SearchAuthors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchAuthorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).SearchAuthors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_SearchAuthors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).SearchAuthors(ctx, req.(*SearchAuthorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_MergeAuthors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MergeAuthorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).MergeAuthors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_MergeAuthors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).MergeAuthors(ctx, req.(*MergeAuthorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_CreateSubject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSubjectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).CreateSubject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_CreateSubject_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).CreateSubject(ctx, req.(*CreateSubjectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_ListSubjects_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSubjectsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).ListSubjects(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_ListSubjects_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).ListSubjects(ctx, req.(*ListSubjectsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_ListBooksBySubject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBooksBySubjectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).ListBooksBySubject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_ListBooksBySubject_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).ListBooksBySubject(ctx, req.(*ListBooksBySubjectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_SetBookSubjects_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetBookSubjectsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).SetBookSubjects(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_SetBookSubjects_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).SetBookSubjects(ctx, req.(*SetBookSubjectsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_SetBookTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetBookTagsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).SetBookTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_SetBookTags_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).SetBookTags(ctx, req.(*SetBookTagsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_ListTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTagsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).ListTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_ListTags_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).ListTags(ctx, req.(*ListTagsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_AddItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).AddItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_AddItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).AddItem(ctx, req.(*AddItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_GetItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).GetItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_GetItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).GetItem(ctx, req.(*GetItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_ListItems_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListItemsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).ListItems(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_ListItems_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).ListItems(ctx, req.(*ListItemsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_UpdateItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).UpdateItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_UpdateItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).UpdateItem(ctx, req.(*UpdateItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_CheckoutItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckoutItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).CheckoutItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_CheckoutItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).CheckoutItem(ctx, req.(*CheckoutItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookService_CheckinItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckinItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookServiceServer).CheckinItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookService_CheckinItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookServiceServer).CheckinItem(ctx, req.(*CheckinItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BookService_ServiceDesc is the grpc.ServiceDesc for BookService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BookService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "library.BookService",
+	HandlerType: (*BookServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBook",
+			Handler:    _BookService_GetBook_Handler,
+		},
+		{
+			MethodName: "CreateBook",
+			Handler:    _BookService_CreateBook_Handler,
+		},
+		{
+			MethodName: "UpdateBook",
+			Handler:    _BookService_UpdateBook_Handler,
+		},
+		{
+			MethodName: "WithdrawBook",
+			Handler:    _BookService_WithdrawBook_Handler,
+		},
+		{
+			MethodName: "GetBookHistory",
+			Handler:    _BookService_GetBookHistory_Handler,
+		},
+		{
+			MethodName: "GetBooks",
+			Handler:    _BookService_GetBooks_Handler,
+		},
+		{
+			MethodName: "SearchCatalog",
+			Handler:    _BookService_SearchCatalog_Handler,
+		},
+		{
+			MethodName: "Suggest",
+			Handler:    _BookService_Suggest_Handler,
+		},
+		{
+			MethodName: "GetBookByISBN",
+			Handler:    _BookService_GetBookByISBN_Handler,
+		},
+		{
+			MethodName: "LookupBooksByISBN",
+			Handler:    _BookService_LookupBooksByISBN_Handler,
+		},
+		{
+			MethodName: "ImportMarc",
+			Handler:    _BookService_ImportMarc_Handler,
+		},
+		{
+			MethodName: "ExportMarc",
+			Handler:    _BookService_ExportMarc_Handler,
+		},
+		{
+			MethodName: "CreateAuthor",
+			Handler:    _BookService_CreateAuthor_Handler,
+		},
+		{
+			MethodName: "GetAuthor",
+			Handler:    _BookService_GetAuthor_Handler,
+		},
+		{
+			MethodName: "SearchAuthors",
+			Handler:    _BookService_SearchAuthors_Handler,
+		},
+		{
+			MethodName: "MergeAuthors",
+			Handler:    _BookService_MergeAuthors_Handler,
+		},
+		{
+			MethodName: "CreateSubject",
+			Handler:    _BookService_CreateSubject_Handler,
+		},
+		{
+			MethodName: "ListSubjects",
+			Handler:    _BookService_ListSubjects_Handler,
+		},
+		{
+			MethodName: "ListBooksBySubject",
+			Handler:    _BookService_ListBooksBySubject_Handler,
+		},
+		{
+			MethodName: "SetBookSubjects",
+			Handler:    _BookService_SetBookSubjects_Handler,
+		},
+		{
+			MethodName: "SetBookTags",
+			Handler:    _BookService_SetBookTags_Handler,
+		},
+		{
+			MethodName: "ListTags",
+			Handler:    _BookService_ListTags_Handler,
+		},
+		{
+			MethodName: "AddItem",
+			Handler:    _BookService_AddItem_Handler,
+		},
+		{
+			MethodName: "GetItem",
+			Handler:    _BookService_GetItem_Handler,
+		},
+		{
+			MethodName: "ListItems",
+			Handler:    _BookService_ListItems_Handler,
+		},
+		{
+			MethodName: "UpdateItem",
+			Handler:    _BookService_UpdateItem_Handler,
+		},
+		{
+			MethodName: "CheckoutItem",
+			Handler:    _BookService_CheckoutItem_Handler,
+		},
+		{
+			MethodName: "CheckinItem",
+			Handler:    _BookService_CheckinItem_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
 		{
-			MethodName: "UpdateBookStatus",
-			Handler:    _BookService_UpdateBookStatus_Handler,
+			StreamName:    "ImportBooks",
+			Handler:       _BookService_ImportBooks_Handler,
+			ClientStreams: true,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "books.proto",
 }
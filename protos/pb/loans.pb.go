@@ -7,6 +7,7 @@
 package pb
 
 import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -21,10 +22,12 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// BorrowRequest names a book, a copy or both. With only book_id any available copy is lent.
 type BorrowRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	BookId        string                 `protobuf:"bytes,2,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	ItemId        string                 `protobuf:"bytes,3,opt,name=item_id,json=itemId,proto3" json:"item_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -73,6 +76,13 @@ func (x *BorrowRequest) GetBookId() string {
 	return ""
 }
 
+func (x *BorrowRequest) GetItemId() string {
+	if x != nil {
+		return x.ItemId
+	}
+	return ""
+}
+
 type ReturnRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	LoanId        string                 `protobuf:"bytes,1,opt,name=loan_id,json=loanId,proto3" json:"loan_id,omitempty"`
@@ -117,6 +127,118 @@ func (x *ReturnRequest) GetLoanId() string {
 	return ""
 }
 
+type UserLoanSummaryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserLoanSummaryRequest) Reset() {
+	*x = UserLoanSummaryRequest{}
+	mi := &file_loans_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserLoanSummaryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserLoanSummaryRequest) ProtoMessage() {}
+
+func (x *UserLoanSummaryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserLoanSummaryRequest.ProtoReflect.Descriptor instead.
+func (*UserLoanSummaryRequest) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *UserLoanSummaryRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type UserLoanSummaryResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	UserId           string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ActiveLoans      int32                  `protobuf:"varint,2,opt,name=active_loans,json=activeLoans,proto3" json:"active_loans,omitempty"`
+	OverdueLoans     int32                  `protobuf:"varint,3,opt,name=overdue_loans,json=overdueLoans,proto3" json:"overdue_loans,omitempty"`
+	UnpaidFinesCents int64                  `protobuf:"varint,4,opt,name=unpaid_fines_cents,json=unpaidFinesCents,proto3" json:"unpaid_fines_cents,omitempty"` // Штрафы пока не начисляются: всегда 0
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *UserLoanSummaryResponse) Reset() {
+	*x = UserLoanSummaryResponse{}
+	mi := &file_loans_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserLoanSummaryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserLoanSummaryResponse) ProtoMessage() {}
+
+func (x *UserLoanSummaryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserLoanSummaryResponse.ProtoReflect.Descriptor instead.
+func (*UserLoanSummaryResponse) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UserLoanSummaryResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *UserLoanSummaryResponse) GetActiveLoans() int32 {
+	if x != nil {
+		return x.ActiveLoans
+	}
+	return 0
+}
+
+func (x *UserLoanSummaryResponse) GetOverdueLoans() int32 {
+	if x != nil {
+		return x.OverdueLoans
+	}
+	return 0
+}
+
+func (x *UserLoanSummaryResponse) GetUnpaidFinesCents() int64 {
+	if x != nil {
+		return x.UnpaidFinesCents
+	}
+	return 0
+}
+
 type LoanResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -125,13 +247,14 @@ type LoanResponse struct {
 	BorrowedDate  string                 `protobuf:"bytes,4,opt,name=borrowed_date,json=borrowedDate,proto3" json:"borrowed_date,omitempty"` // Формат: RFC3339 "2006-01-02T15:04:05Z07:00"
 	DueDate       string                 `protobuf:"bytes,5,opt,name=due_date,json=dueDate,proto3" json:"due_date,omitempty"`
 	ReturnedDate  string                 `protobuf:"bytes,6,opt,name=returned_date,json=returnedDate,proto3" json:"returned_date,omitempty"`
+	Item          *Item                  `protobuf:"bytes,7,opt,name=item,proto3" json:"item,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *LoanResponse) Reset() {
 	*x = LoanResponse{}
-	mi := &file_loans_proto_msgTypes[2]
+	mi := &file_loans_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -143,7 +266,7 @@ func (x *LoanResponse) String() string {
 func (*LoanResponse) ProtoMessage() {}
 
 func (x *LoanResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_loans_proto_msgTypes[2]
+	mi := &file_loans_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -156,7 +279,7 @@ func (x *LoanResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use LoanResponse.ProtoReflect.Descriptor instead.
 func (*LoanResponse) Descriptor() ([]byte, []int) {
-	return file_loans_proto_rawDescGZIP(), []int{2}
+	return file_loans_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *LoanResponse) GetId() string {
@@ -201,28 +324,239 @@ func (x *LoanResponse) GetReturnedDate() string {
 	return ""
 }
 
+func (x *LoanResponse) GetItem() *Item {
+	if x != nil {
+		return x.Item
+	}
+	return nil
+}
+
+type ListUserLoansRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUserLoansRequest) Reset() {
+	*x = ListUserLoansRequest{}
+	mi := &file_loans_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUserLoansRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserLoansRequest) ProtoMessage() {}
+
+func (x *ListUserLoansRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserLoansRequest.ProtoReflect.Descriptor instead.
+func (*ListUserLoansRequest) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListUserLoansRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type ListUserLoansResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Loans         []*Loan                `protobuf:"bytes,1,rep,name=loans,proto3" json:"loans,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUserLoansResponse) Reset() {
+	*x = ListUserLoansResponse{}
+	mi := &file_loans_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUserLoansResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserLoansResponse) ProtoMessage() {}
+
+func (x *ListUserLoansResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserLoansResponse.ProtoReflect.Descriptor instead.
+func (*ListUserLoansResponse) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListUserLoansResponse) GetLoans() []*Loan {
+	if x != nil {
+		return x.Loans
+	}
+	return nil
+}
+
+// Loan is a loan record without the user and book details, see LoanResponse.
+type Loan struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	BookId        string                 `protobuf:"bytes,3,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	BorrowedDate  string                 `protobuf:"bytes,4,opt,name=borrowed_date,json=borrowedDate,proto3" json:"borrowed_date,omitempty"` // RFC3339
+	DueDate       string                 `protobuf:"bytes,5,opt,name=due_date,json=dueDate,proto3" json:"due_date,omitempty"`                // "2006-01-02"
+	Overdue       bool                   `protobuf:"varint,6,opt,name=overdue,proto3" json:"overdue,omitempty"`
+	ItemId        string                 `protobuf:"bytes,7,opt,name=item_id,json=itemId,proto3" json:"item_id,omitempty"` // Пусто у займов, оформленных до учёта экземпляров
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Loan) Reset() {
+	*x = Loan{}
+	mi := &file_loans_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Loan) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Loan) ProtoMessage() {}
+
+func (x *Loan) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Loan.ProtoReflect.Descriptor instead.
+func (*Loan) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Loan) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Loan) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Loan) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *Loan) GetBorrowedDate() string {
+	if x != nil {
+		return x.BorrowedDate
+	}
+	return ""
+}
+
+func (x *Loan) GetDueDate() string {
+	if x != nil {
+		return x.DueDate
+	}
+	return ""
+}
+
+func (x *Loan) GetOverdue() bool {
+	if x != nil {
+		return x.Overdue
+	}
+	return false
+}
+
+func (x *Loan) GetItemId() string {
+	if x != nil {
+		return x.ItemId
+	}
+	return ""
+}
+
 var File_loans_proto protoreflect.FileDescriptor
 
 const file_loans_proto_rawDesc = "" +
 	"\n" +
-	"\vloans.proto\x12\alibrary\x1a\vusers.proto\x1a\vbooks.proto\"A\n" +
+	"\vloans.proto\x12\alibrary\x1a\x1cgoogle/api/annotations.proto\x1a\vusers.proto\x1a\vbooks.proto\"Z\n" +
 	"\rBorrowRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x17\n" +
-	"\abook_id\x18\x02 \x01(\tR\x06bookId\"(\n" +
+	"\abook_id\x18\x02 \x01(\tR\x06bookId\x12\x17\n" +
+	"\aitem_id\x18\x03 \x01(\tR\x06itemId\"(\n" +
 	"\rReturnRequest\x12\x17\n" +
-	"\aloan_id\x18\x01 \x01(\tR\x06loanId\"\xd9\x01\n" +
+	"\aloan_id\x18\x01 \x01(\tR\x06loanId\"1\n" +
+	"\x16UserLoanSummaryRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"\xa8\x01\n" +
+	"\x17UserLoanSummaryResponse\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12!\n" +
+	"\factive_loans\x18\x02 \x01(\x05R\vactiveLoans\x12#\n" +
+	"\roverdue_loans\x18\x03 \x01(\x05R\foverdueLoans\x12,\n" +
+	"\x12unpaid_fines_cents\x18\x04 \x01(\x03R\x10unpaidFinesCents\"\xfc\x01\n" +
 	"\fLoanResponse\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12)\n" +
 	"\x04user\x18\x02 \x01(\v2\x15.library.UserResponseR\x04user\x12)\n" +
 	"\x04book\x18\x03 \x01(\v2\x15.library.BookResponseR\x04book\x12#\n" +
 	"\rborrowed_date\x18\x04 \x01(\tR\fborrowedDate\x12\x19\n" +
 	"\bdue_date\x18\x05 \x01(\tR\adueDate\x12#\n" +
-	"\rreturned_date\x18\x06 \x01(\tR\freturnedDate2\x8b\x01\n" +
-	"\vLoanService\x12=\n" +
+	"\rreturned_date\x18\x06 \x01(\tR\freturnedDate\x12!\n" +
+	"\x04item\x18\a \x01(\v2\r.library.ItemR\x04item\"/\n" +
+	"\x14ListUserLoansRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"<\n" +
+	"\x15ListUserLoansResponse\x12#\n" +
+	"\x05loans\x18\x01 \x03(\v2\r.library.LoanR\x05loans\"\xbb\x01\n" +
+	"\x04Loan\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x17\n" +
+	"\abook_id\x18\x03 \x01(\tR\x06bookId\x12#\n" +
+	"\rborrowed_date\x18\x04 \x01(\tR\fborrowedDate\x12\x19\n" +
+	"\bdue_date\x18\x05 \x01(\tR\adueDate\x12\x18\n" +
+	"\aoverdue\x18\x06 \x01(\bR\aoverdue\x12\x17\n" +
+	"\aitem_id\x18\a \x01(\tR\x06itemId2\xbb\x03\n" +
+	"\vLoanService\x12Q\n" +
 	"\n" +
-	"BorrowBook\x12\x16.library.BorrowRequest\x1a\x15.library.LoanResponse\"\x00\x12=\n" +
+	"BorrowBook\x12\x16.library.BorrowRequest\x1a\x15.library.LoanResponse\"\x14\x82\xd3\xe4\x93\x02\x0e:\x01*\"\t/v1/loans\x12b\n" +
 	"\n" +
-	"ReturnBook\x12\x16.library.ReturnRequest\x1a\x15.library.LoanResponse\"\x00B\x06Z\x04.;pbb\x06proto3"
+	"ReturnBook\x12\x16.library.ReturnRequest\x1a\x15.library.LoanResponse\"%\x82\xd3\xe4\x93\x02\x1f:\x01*\"\x1a/v1/loans/{loan_id}:return\x12\x81\x01\n" +
+	"\x12GetUserLoanSummary\x12\x1f.library.UserLoanSummaryRequest\x1a .library.UserLoanSummaryResponse\"(\x82\xd3\xe4\x93\x02\"\x12 /v1/users/{user_id}/loan-summary\x12q\n" +
+	"\rListUserLoans\x12\x1d.library.ListUserLoansRequest\x1a\x1e.library.ListUserLoansResponse\"!\x82\xd3\xe4\x93\x02\x1b\x12\x19/v1/users/{user_id}/loansB\x06Z\x04.;pbb\x06proto3"
 
 var (
 	file_loans_proto_rawDescOnce sync.Once
@@ -236,26 +570,38 @@ func file_loans_proto_rawDescGZIP() []byte {
 	return file_loans_proto_rawDescData
 }
 
-var file_loans_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_loans_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_loans_proto_goTypes = []any{
-	(*BorrowRequest)(nil), // 0: library.BorrowRequest
-	(*ReturnRequest)(nil), // 1: library.ReturnRequest
-	(*LoanResponse)(nil),  // 2: library.LoanResponse
-	(*UserResponse)(nil),  // 3: library.UserResponse
-	(*BookResponse)(nil),  // 4: library.BookResponse
+	(*BorrowRequest)(nil),           // 0: library.BorrowRequest
+	(*ReturnRequest)(nil),           // 1: library.ReturnRequest
+	(*UserLoanSummaryRequest)(nil),  // 2: library.UserLoanSummaryRequest
+	(*UserLoanSummaryResponse)(nil), // 3: library.UserLoanSummaryResponse
+	(*LoanResponse)(nil),            // 4: library.LoanResponse
+	(*ListUserLoansRequest)(nil),    // 5: library.ListUserLoansRequest
+	(*ListUserLoansResponse)(nil),   // 6: library.ListUserLoansResponse
+	(*Loan)(nil),                    // 7: library.Loan
+	(*UserResponse)(nil),            // 8: library.UserResponse
+	(*BookResponse)(nil),            // 9: library.BookResponse
+	(*Item)(nil),                    // 10: library.Item
 }
 var file_loans_proto_depIdxs = []int32{
-	3, // 0: library.LoanResponse.user:type_name -> library.UserResponse
-	4, // 1: library.LoanResponse.book:type_name -> library.BookResponse
-	0, // 2: library.LoanService.BorrowBook:input_type -> library.BorrowRequest
-	1, // 3: library.LoanService.ReturnBook:input_type -> library.ReturnRequest
-	2, // 4: library.LoanService.BorrowBook:output_type -> library.LoanResponse
-	2, // 5: library.LoanService.ReturnBook:output_type -> library.LoanResponse
-	4, // [4:6] is the sub-list for method output_type
-	2, // [2:4] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	8,  // 0: library.LoanResponse.user:type_name -> library.UserResponse
+	9,  // 1: library.LoanResponse.book:type_name -> library.BookResponse
+	10, // 2: library.LoanResponse.item:type_name -> library.Item
+	7,  // 3: library.ListUserLoansResponse.loans:type_name -> library.Loan
+	0,  // 4: library.LoanService.BorrowBook:input_type -> library.BorrowRequest
+	1,  // 5: library.LoanService.ReturnBook:input_type -> library.ReturnRequest
+	2,  // 6: library.LoanService.GetUserLoanSummary:input_type -> library.UserLoanSummaryRequest
+	5,  // 7: library.LoanService.ListUserLoans:input_type -> library.ListUserLoansRequest
+	4,  // 8: library.LoanService.BorrowBook:output_type -> library.LoanResponse
+	4,  // 9: library.LoanService.ReturnBook:output_type -> library.LoanResponse
+	3,  // 10: library.LoanService.GetUserLoanSummary:output_type -> library.UserLoanSummaryResponse
+	6,  // 11: library.LoanService.ListUserLoans:output_type -> library.ListUserLoansResponse
+	8,  // [8:12] is the sub-list for method output_type
+	4,  // [4:8] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_loans_proto_init() }
@@ -271,7 +617,7 @@ func file_loans_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_loans_proto_rawDesc), len(file_loans_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
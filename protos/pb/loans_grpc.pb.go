@@ -19,8 +19,10 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	LoanService_BorrowBook_FullMethodName = "/library.LoanService/BorrowBook"
-	LoanService_ReturnBook_FullMethodName = "/library.LoanService/ReturnBook"
+	LoanService_BorrowBook_FullMethodName         = "/library.LoanService/BorrowBook"
+	LoanService_ReturnBook_FullMethodName         = "/library.LoanService/ReturnBook"
+	LoanService_GetUserLoanSummary_FullMethodName = "/library.LoanService/GetUserLoanSummary"
+	LoanService_ListUserLoans_FullMethodName      = "/library.LoanService/ListUserLoans"
 )
 
 // LoanServiceClient is the client API for LoanService service.
@@ -29,6 +31,8 @@ const (
 type LoanServiceClient interface {
 	BorrowBook(ctx context.Context, in *BorrowRequest, opts ...grpc.CallOption) (*LoanResponse, error)
 	ReturnBook(ctx context.Context, in *ReturnRequest, opts ...grpc.CallOption) (*LoanResponse, error)
+	GetUserLoanSummary(ctx context.Context, in *UserLoanSummaryRequest, opts ...grpc.CallOption) (*UserLoanSummaryResponse, error)
+	ListUserLoans(ctx context.Context, in *ListUserLoansRequest, opts ...grpc.CallOption) (*ListUserLoansResponse, error)
 }
 
 type loanServiceClient struct {
@@ -59,12 +63,34 @@ func (c *loanServiceClient) ReturnBook(ctx context.Context, in *ReturnRequest, o
 	return out, nil
 }
 
+func (c *loanServiceClient) GetUserLoanSummary(ctx context.Context, in *UserLoanSummaryRequest, opts ...grpc.CallOption) (*UserLoanSummaryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserLoanSummaryResponse)
+	err := c.cc.Invoke(ctx, LoanService_GetUserLoanSummary_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *loanServiceClient) ListUserLoans(ctx context.Context, in *ListUserLoansRequest, opts ...grpc.CallOption) (*ListUserLoansResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUserLoansResponse)
+	err := c.cc.Invoke(ctx, LoanService_ListUserLoans_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // LoanServiceServer is the server API for LoanService service.
 // All implementations must embed UnimplementedLoanServiceServer
 // for forward compatibility.
 type LoanServiceServer interface {
 	BorrowBook(context.Context, *BorrowRequest) (*LoanResponse, error)
 	ReturnBook(context.Context, *ReturnRequest) (*LoanResponse, error)
+	GetUserLoanSummary(context.Context, *UserLoanSummaryRequest) (*UserLoanSummaryResponse, error)
+	ListUserLoans(context.Context, *ListUserLoansRequest) (*ListUserLoansResponse, error)
 	mustEmbedUnimplementedLoanServiceServer()
 }
 
@@ -81,6 +107,12 @@ func (UnimplementedLoanServiceServer) BorrowBook(context.Context, *BorrowRequest
 func (UnimplementedLoanServiceServer) ReturnBook(context.Context, *ReturnRequest) (*LoanResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ReturnBook not implemented")
 }
+func (UnimplementedLoanServiceServer) GetUserLoanSummary(context.Context, *UserLoanSummaryRequest) (*UserLoanSummaryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserLoanSummary not implemented")
+}
+func (UnimplementedLoanServiceServer) ListUserLoans(context.Context, *ListUserLoansRequest) (*ListUserLoansResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserLoans not implemented")
+}
 func (UnimplementedLoanServiceServer) mustEmbedUnimplementedLoanServiceServer() {}
 func (UnimplementedLoanServiceServer) testEmbeddedByValue()                     {}
 
@@ -138,6 +170,42 @@ func _LoanService_ReturnBook_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _LoanService_GetUserLoanSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserLoanSummaryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoanServiceServer).GetUserLoanSummary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LoanService_GetUserLoanSummary_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoanServiceServer).GetUserLoanSummary(ctx, req.(*UserLoanSummaryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LoanService_ListUserLoans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUserLoansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoanServiceServer).ListUserLoans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LoanService_ListUserLoans_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoanServiceServer).ListUserLoans(ctx, req.(*ListUserLoansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // LoanService_ServiceDesc is the grpc.ServiceDesc for LoanService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,6 +221,14 @@ var LoanService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ReturnBook",
 			Handler:    _LoanService_ReturnBook_Handler,
 		},
+		{
+			MethodName: "GetUserLoanSummary",
+			Handler:    _LoanService_GetUserLoanSummary_Handler,
+		},
+		{
+			MethodName: "ListUserLoans",
+			Handler:    _LoanService_ListUserLoans_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "loans.proto",
@@ -7,6 +7,7 @@
 package pb
 
 import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -113,13 +114,13 @@ var File_notifications_proto protoreflect.FileDescriptor
 
 const file_notifications_proto_rawDesc = "" +
 	"\n" +
-	"\x13notifications.proto\x12\alibrary\"B\n" +
+	"\x13notifications.proto\x12\alibrary\x1a\x1cgoogle/api/annotations.proto\"B\n" +
 	"\x13NotificationRequest\x12+\n" +
 	"\x11notification_type\x18\x01 \x01(\tR\x10notificationType\"0\n" +
 	"\x14NotificationResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess2h\n" +
-	"\x13NotificationService\x12Q\n" +
-	"\x10SendNotification\x12\x1c.library.NotificationRequest\x1a\x1d.library.NotificationResponse\"\x00B\x06Z\x04.;pbb\x06proto3"
+	"\asuccess\x18\x01 \x01(\bR\asuccess2\x84\x01\n" +
+	"\x13NotificationService\x12m\n" +
+	"\x10SendNotification\x12\x1c.library.NotificationRequest\x1a\x1d.library.NotificationResponse\"\x1c\x82\xd3\xe4\x93\x02\x16:\x01*\"\x11/v1/notificationsB\x06Z\x04.;pbb\x06proto3"
 
 var (
 	file_notifications_proto_rawDescOnce sync.Once
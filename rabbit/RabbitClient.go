@@ -6,8 +6,12 @@ import (
 	"errors"
 	"time"
 
+	"github.com/ViktorOHJ/library-system/observability"
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type RabbitMQClient struct {
@@ -16,13 +20,14 @@ type RabbitMQClient struct {
 }
 
 type TaskMessage struct {
-	Type       string `json:"type"`
-	UserName   string `json:"user_name"`
-	BookTitle  string `json:"book_title"`
-	BookAuthor string `json:"book_author"`
-	DueDate    string `json:"due_date"`
-	LoanID     string `json:"loan_id"`
-	Email      string `json:"email"`
+	Type        string `json:"type"`
+	UserName    string `json:"user_name"`
+	BookTitle   string `json:"book_title"`
+	BookAuthor  string `json:"book_author"`
+	DueDate     string `json:"due_date"`
+	LoanID      string `json:"loan_id"`
+	Email       string `json:"email"`
+	CalendarURL string `json:"calendar_url,omitempty"`
 }
 
 func NewRabbitMQClient(logger *logrus.Logger, url string) (*RabbitMQClient, error) {
@@ -50,46 +55,52 @@ func (r *RabbitMQClient) PublishTask(parentCtx context.Context, logger *logrus.L
 	ctx, cancel := context.WithTimeout(parentCtx, 5*time.Second)
 	defer cancel()
 
+	var queue string
 	switch {
 	case message.Type == "Borrow":
 		logger.Infof("message boorow: %v", message)
-		err = r.ch.PublishWithContext(
-			ctx,
-			"library",
-			"borrow_queue",
-			false, // mandatory
-			false, // immediate
-			amqp091.Publishing{
-				ContentType: "application/json",
-				Body:        body,
-				Timestamp:   time.Now(),
-			},
-		)
+		queue = "borrow_queue"
 	case message.Type == "Return":
 		logger.Infof("message return: %v", message)
-		err = r.ch.PublishWithContext(
-			ctx,
-			"library",
-			"return_queue",
-			false, // mandatory
-			false, // immediate
-			amqp091.Publishing{
-				ContentType: "application/json",
-				Body:        body,
-				Timestamp:   time.Now(),
-			},
-		)
+		queue = "return_queue"
 	default:
 		return errors.New("invalid queue")
 	}
 
+	ctx, span := tracer.Start(ctx, queue+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		messagingAttributes(queue, "send"))
+	defer span.End()
+
+	headers := amqp091.Table{}
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(headers))
+
+	err = r.ch.PublishWithContext(
+		ctx,
+		"library",
+		queue,
+		false, // mandatory
+		false, // immediate
+		amqp091.Publishing{
+			Headers:     headers,
+			ContentType: "application/json",
+			Body:        body,
+			Timestamp:   time.Now(),
+		},
+	)
+	observability.MessagePublished(queue, err)
+
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
 		logger.Errorf("Publish Error: %v", err)
 		return err
 	}
 	return nil
 }
 
+// ConsumeFromQueue delivers messages with the publisher's trace context in their headers;
+// handlers pick it up with StartConsumerSpan.
 func (r *RabbitMQClient) ConsumeFromQueue(queueName string) (<-chan amqp091.Delivery, error) {
 	return r.ch.Consume(
 		queueName, // queue
@@ -0,0 +1,19 @@
+package rabbit
+
+import (
+	"context"
+	"errors"
+)
+
+// Check is a readiness check of the client's own connection and channel, the ones messages
+// actually go through. The client does not reconnect, so once either is closed the check
+// keeps failing until the service is restarted.
+func (r *RabbitMQClient) Check(ctx context.Context) error {
+	if r.conn.IsClosed() {
+		return errors.New("rabbitmq connection is closed")
+	}
+	if r.ch.IsClosed() {
+		return errors.New("rabbitmq channel is closed")
+	}
+	return nil
+}
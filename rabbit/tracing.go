@@ -0,0 +1,51 @@
+package rabbit
+
+import (
+	"context"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/ViktorOHJ/library-system/rabbit")
+
+// headerCarrier lets the OpenTelemetry propagator read and write AMQP message headers.
+type headerCarrier amqp091.Table
+
+func (c headerCarrier) Get(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func messagingAttributes(queue, operation string) trace.SpanStartOption {
+	return trace.WithAttributes(
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.destination.name", queue),
+		attribute.String("messaging.operation.type", operation),
+	)
+}
+
+// StartConsumerSpan continues the trace of the publisher that sent d. If the message
+// carries no trace context, the span becomes a child of the span in ctx.
+func StartConsumerSpan(ctx context.Context, queue string, d amqp091.Delivery) (context.Context, trace.Span) {
+	if d.Headers != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier(d.Headers))
+	}
+	return tracer.Start(ctx, queue+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		messagingAttributes(queue, "process"))
+}
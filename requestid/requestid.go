@@ -0,0 +1,95 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Header is both the HTTP header and the gRPC metadata key carrying the request id.
+const Header = "x-request-id"
+
+const maxLength = 128
+
+type requestIDKey struct{}
+
+func New() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Valid rejects ids that are too long or contain anything but printable ASCII,
+// so that client supplied ids are safe to log and echo back.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// UnaryServerInterceptor takes the request id from incoming metadata or generates one,
+// returns it in the response header and logs every call with it.
+func UnaryServerInterceptor(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var id string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(Header); len(values) > 0 && Valid(values[0]) {
+				id = values[0]
+			}
+		}
+		if id == "" {
+			id = New()
+		}
+		ctx = WithID(ctx, id)
+		grpc.SetHeader(ctx, metadata.Pairs(Header, id))
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		fields := logrus.Fields{
+			"method":     info.FullMethod,
+			"request_id": id,
+			"code":       status.Code(err).String(),
+			"duration":   time.Since(start).String(),
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			fields["trace_id"] = sc.TraceID().String()
+		}
+		logger.WithFields(fields).Info("RPC finished")
+		return resp, err
+	}
+}
+
+// UnaryClientInterceptor passes the request id on to downstream services.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if id := FromContext(ctx); id != "" {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			if len(md.Get(Header)) == 0 {
+				ctx = metadata.AppendToOutgoingContext(ctx, Header, id)
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,59 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("req-42"))
+	assert.True(t, Valid(New()))
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("with space"))
+	assert.False(t, Valid("line\nbreak"))
+	assert.False(t, Valid(strings.Repeat("a", maxLength+1)))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	interceptor := UnaryServerInterceptor(logger)
+	var got string
+	handler := func(ctx context.Context, req any) (any, error) {
+		got = FromContext(ctx)
+		return nil, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/library.BookService/GetBook"}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(Header, "req-42"))
+	_, err := interceptor(ctx, nil, info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "req-42", got)
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+	assert.True(t, Valid(got))
+	assert.NotEqual(t, "req-42", got)
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	var sent metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	interceptor := UnaryClientInterceptor()
+
+	require.NoError(t, interceptor(WithID(context.Background(), "req-42"), "/m", nil, nil, nil, invoker))
+	assert.Equal(t, []string{"req-42"}, sent.Get(Header))
+
+	require.NoError(t, interceptor(context.Background(), "/m", nil, nil, nil, invoker))
+	assert.Empty(t, sent.Get(Header))
+}
@@ -0,0 +1,170 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	devCAValidity   = 10 * 365 * 24 * time.Hour
+	devCertValidity = 365 * 24 * time.Hour
+)
+
+// Files names the certificate, key and CA bundle of a service.
+type Files struct {
+	Cert, Key, CA string
+}
+
+// DevCertificates makes sure dir holds a local CA and a certificate for service signed
+// by it, generating whatever is missing. Services started with the same dir trust each
+// other. The certificate is valid for localhost and the service name, and can be used
+// both by the server and as a client certificate.
+func DevCertificates(dir, service string) (Files, error) {
+	caDir := filepath.Join(dir, "ca")
+	files := Files{
+		Cert: filepath.Join(dir, service+".pem"),
+		Key:  filepath.Join(dir, service+"-key.pem"),
+		CA:   filepath.Join(caDir, "ca.pem"),
+	}
+	ca, caKey, err := devCA(caDir)
+	if err != nil {
+		return Files{}, err
+	}
+	if _, err := os.Stat(files.Cert); err == nil {
+		return files, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Files{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber(),
+		Subject:      pkix.Name{CommonName: service, Organization: []string{"library-system dev"}},
+		DNSNames:     []string{service, "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(devCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return Files{}, err
+	}
+	// The key is written first so that a present certificate always has its key.
+	if err := writeKey(files.Key, key); err != nil {
+		return Files{}, err
+	}
+	if err := writePEM(files.Cert, "CERTIFICATE", der, 0o644); err != nil {
+		return Files{}, err
+	}
+	return files, nil
+}
+
+// devCA reads the CA from dir or creates it. Several services may start at once, so the
+// CA is written to a temporary directory and renamed into place; the loser of the race
+// reads the winner's CA.
+func devCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	if ca, key, err := readCA(dir); err == nil {
+		return ca, key, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return nil, nil, err
+	}
+	tmp, err := os.MkdirTemp(parent, "ca-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber(),
+		Subject:               pkix.Name{CommonName: "library-system dev CA", Organization: []string{"library-system dev"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(devCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writePEM(filepath.Join(tmp, "ca.pem"), "CERTIFICATE", der, 0o644); err != nil {
+		return nil, nil, err
+	}
+	if err := writeKey(filepath.Join(tmp, "ca-key.pem"), key); err != nil {
+		return nil, nil, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		if _, statErr := os.Stat(dir); statErr != nil {
+			return nil, nil, err
+		}
+	}
+	return readCA(dir)
+}
+
+func readCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(dir, "ca-key.pem"))
+	if err != nil {
+		return nil, nil, err
+	}
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, fmt.Errorf("malformed development CA in %s", dir)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func writeKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return writePEM(path, "EC PRIVATE KEY", der, 0o600)
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), perm)
+}
+
+func serialNumber() *big.Int {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
@@ -0,0 +1,264 @@
+// Package tlsconfig builds the gRPC transport credentials of a service from config.TLS.
+// Certificates and the CA bundle are re-read when their files change, so rotated
+// certificates are picked up without a restart.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/config"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const reloadInterval = 30 * time.Second
+
+// Reloader holds the current certificate and CA pool of a service. A nil *Reloader
+// means TLS is disabled and yields plaintext credentials.
+type Reloader struct {
+	certFile, keyFile, caFile string
+	clientAuth                bool
+	allowedClients            []string
+	logger                    *logrus.Logger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time
+}
+
+// Load reads the files named in cfg, generating them first in dev mode. It returns
+// nil when TLS is disabled.
+func Load(cfg config.TLS, service string, logger *logrus.Logger) (*Reloader, error) {
+	if !cfg.Enabled() {
+		logger.Warn("TLS disabled, gRPC traffic is not encrypted")
+		return nil, nil
+	}
+	if cfg.Dev {
+		files, err := DevCertificates(cfg.DevDir, service)
+		if err != nil {
+			return nil, fmt.Errorf("generate development certificates: %w", err)
+		}
+		cfg.CertFile, cfg.KeyFile, cfg.CAFile = files.Cert, files.Key, files.CA
+		logger.Warnf("TLS dev mode: using certificates from %s, do not use in production", cfg.DevDir)
+	}
+
+	r := &Reloader{
+		certFile:       cfg.CertFile,
+		keyFile:        cfg.KeyFile,
+		caFile:         cfg.CAFile,
+		clientAuth:     cfg.ClientAuth,
+		allowedClients: cfg.AllowedClients,
+		logger:         logger,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	logger.WithField("mutual", r.clientAuth).Info("TLS enabled")
+	return r, nil
+}
+
+// Reload re-reads the files if any of them changed since the last load. A failed reload
+// keeps the previous certificates.
+func (r *Reloader) Reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	unchanged := !modTime.After(r.modTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("load certificate: %w", err)
+		}
+		cert = &c
+	}
+	caPEM, err := os.ReadFile(r.caFile)
+	if err != nil {
+		return fmt.Errorf("read CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("no certificates found in CA bundle %s", r.caFile)
+	}
+
+	r.mu.Lock()
+	r.cert, r.pool, r.modTime = cert, pool, modTime
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *Reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile, r.caFile} {
+		if name == "" {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// Start polls the files for changes until ctx is done.
+func (r *Reloader) Start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(reloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Reload(); err != nil {
+					r.logger.Errorf("Failed to reload TLS certificates, keeping the previous ones: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.pool
+}
+
+// ServerConfig returns a config that picks up reloaded certificates on every handshake.
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := r.current()
+			if cert == nil {
+				return nil, errors.New("no server certificate configured")
+			}
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+			}
+			if r.clientAuth {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				cfg.ClientCAs = pool
+				cfg.VerifyConnection = r.verifyClient
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// ClientConfig returns a config that verifies servers against the current CA pool and,
+// with mutual TLS, presents the current certificate.
+func (r *Reloader) ClientConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Verification is done in VerifyConnection against the pool of the moment,
+		// which the static RootCAs field cannot follow.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			_, pool := r.current()
+			return verifyChain(cs, pool, cs.ServerName, x509.ExtKeyUsageServerAuth)
+		},
+	}
+	if r.clientAuth {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			if cert == nil {
+				return nil, errors.New("no client certificate configured")
+			}
+			return cert, nil
+		}
+	}
+	return cfg
+}
+
+// verifyClient checks the identity of a client whose chain the handshake already verified.
+func (r *Reloader) verifyClient(cs tls.ConnectionState) error {
+	if len(r.allowedClients) == 0 {
+		return nil
+	}
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no client certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+	if id := Identity(leaf); slices.Contains(r.allowedClients, id) {
+		return nil
+	}
+	// The service's own identity is always accepted; the -healthcheck probe uses it.
+	if cert, _ := r.current(); cert != nil && cert.Leaf != nil && Identity(cert.Leaf) == Identity(leaf) {
+		return nil
+	}
+	for _, name := range leaf.DNSNames {
+		if slices.Contains(r.allowedClients, name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("client certificate %q is not allowed", Identity(leaf))
+}
+
+// Identity names the service a certificate belongs to: its common name, or its first
+// DNS name when the common name is empty.
+func Identity(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" || len(cert.DNSNames) == 0 {
+		return cert.Subject.CommonName
+	}
+	return cert.DNSNames[0]
+}
+
+func verifyChain(cs tls.ConnectionState, pool *x509.CertPool, serverName string, usage x509.ExtKeyUsage) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no peer certificate")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// ServerOption returns the credentials option for grpc.NewServer.
+func (r *Reloader) ServerOption() grpc.ServerOption {
+	if r == nil {
+		return grpc.EmptyServerOption{}
+	}
+	return grpc.Creds(credentials.NewTLS(r.ServerConfig()))
+}
+
+// DialOption returns the credentials option for connections to other services.
+func (r *Reloader) DialOption() grpc.DialOption {
+	if r == nil {
+		return grpc.WithTransportCredentials(insecure.NewCredentials())
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(r.ClientConfig()))
+}
@@ -0,0 +1,130 @@
+package tlsconfig
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func load(t *testing.T, cfg config.TLS, service string) *Reloader {
+	r, err := Load(cfg, service, logrus.New())
+	require.NoError(t, err)
+	return r
+}
+
+func TestLoad_Disabled(t *testing.T) {
+	r := load(t, config.TLS{DevDir: t.TempDir()}, "books")
+	assert.Nil(t, r)
+	assert.NotNil(t, r.DialOption(), "nil reloader dials in plaintext")
+}
+
+func TestDevCertificates(t *testing.T) {
+	dir := t.TempDir()
+	books, err := DevCertificates(dir, "books")
+	require.NoError(t, err)
+	loans, err := DevCertificates(dir, "loans")
+	require.NoError(t, err)
+
+	assert.Equal(t, books.CA, loans.CA, "services share the CA")
+	for _, f := range []string{books.Cert, books.Key, books.CA, loans.Cert, filepath.Join(dir, "ca", "ca-key.pem")} {
+		assert.FileExists(t, f)
+	}
+	info, err := os.Stat(books.Key)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	again, err := DevCertificates(dir, "books")
+	require.NoError(t, err)
+	assert.Equal(t, books, again)
+}
+
+// serve starts a health server with the credentials of r.
+func serve(t *testing.T, r *Reloader) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(r.ServerOption())
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	return "localhost:" + port
+}
+
+func check(addr string, r *Reloader) error {
+	conn, err := grpc.NewClient(addr, r.DialOption())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	server := load(t, config.TLS{Dev: true, DevDir: dir, ClientAuth: true, AllowedClients: []string{"loans"}}, "books")
+	addr := serve(t, server)
+
+	tests := []struct {
+		name    string
+		client  *Reloader
+		wantErr bool
+	}{
+		{name: "allowed client", client: load(t, config.TLS{Dev: true, DevDir: dir, ClientAuth: true}, "loans")},
+		{name: "own identity", client: load(t, config.TLS{Dev: true, DevDir: dir, ClientAuth: true}, "books")},
+		{name: "client not in the allow list", client: load(t, config.TLS{Dev: true, DevDir: dir, ClientAuth: true}, "users"), wantErr: true},
+		{name: "no client certificate", client: load(t, config.TLS{Dev: true, DevDir: dir}, "loans"), wantErr: true},
+		{name: "other CA", client: load(t, config.TLS{Dev: true, DevDir: t.TempDir(), ClientAuth: true}, "loans"), wantErr: true},
+		{name: "plaintext", client: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := check(addr, tt.client)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	r := load(t, config.TLS{Dev: true, DevDir: dir}, "books")
+	before, _ := r.current()
+
+	// Rotate: issue a new certificate and make sure its files look newer.
+	require.NoError(t, os.Remove(filepath.Join(dir, "books.pem")))
+	files, err := DevCertificates(dir, "books")
+	require.NoError(t, err)
+	later := time.Now().Add(time.Minute)
+	for _, f := range []string{files.Cert, files.Key} {
+		require.NoError(t, os.Chtimes(f, later, later))
+	}
+
+	require.NoError(t, r.Reload())
+	after, _ := r.current()
+	assert.NotEqual(t, before.Leaf.SerialNumber, after.Leaf.SerialNumber)
+
+	require.NoError(t, os.WriteFile(files.Cert, []byte("garbage"), 0o644))
+	later = later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(files.Cert, later, later))
+	assert.Error(t, r.Reload())
+	kept, _ := r.current()
+	assert.Equal(t, after, kept, "a failed reload keeps the previous certificate")
+}
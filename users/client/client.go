@@ -4,21 +4,34 @@ import (
 	"context"
 	"time"
 
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/grpcclient"
 	pb "github.com/ViktorOHJ/library-system/protos/pb"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
+// service sets the deadlines and retries of every call made by this client.
+var service = grpcclient.Service{
+	Name:       pb.UserService_ServiceDesc.ServiceName,
+	Idempotent: []string{"GetUser", "ListUsers", "SearchUsers", "ListApiKeys", "VerifyApiKey"},
+	Timeout:    5 * time.Second,
+	Timeouts: map[string]time.Duration{
+		"GetUser":      2 * time.Second,
+		"VerifyApiKey": 2 * time.Second,
+	},
+}
+
 type UserClient struct {
 	client  pb.UserServiceClient
 	timeout time.Duration
-	conn    *grpc.ClientConn
+	conn    *grpcclient.Conn
 	logger  *logrus.Logger
 }
 
-func NewUserClient(addr string, timeout time.Duration, logger *logrus.Logger) (*UserClient, error) {
-	conn, err := grpc.Dial("localhost:"+addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewUserClient(addr string, timeout time.Duration, logger *logrus.Logger, opts ...grpc.DialOption) (*UserClient, error) {
+	conn, err := grpcclient.Dial(addr, service, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -53,6 +66,161 @@ func (c *UserClient) Get(ctx context.Context, id string) (*pb.UserResponse, erro
 
 }
 
+func (c *UserClient) Update(ctx context.Context, id string, name, email string, paths ...string) (*pb.UserResponse, error) {
+	c.logger.Infof("UpdateUser called with UserId: %s", id)
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.UpdateUser(ctx, &pb.UpdateUserRequest{
+		UserId:     id,
+		Name:       name,
+		Email:      email,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: paths},
+	})
+}
+
+// ChangePassword sets a new password. Users changing their own password pass the current
+// one; an admin resetting another user's password leaves it empty.
+func (c *UserClient) ChangePassword(ctx context.Context, id, currentPassword, newPassword string) (*pb.UserResponse, error) {
+	c.logger.Infof("UpdateUser called with UserId: %s", id)
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.UpdateUser(ctx, &pb.UpdateUserRequest{
+		UserId:          id,
+		Password:        newPassword,
+		CurrentPassword: currentPassword,
+		UpdateMask:      &fieldmaskpb.FieldMask{Paths: []string{"password"}},
+	})
+}
+
+func (c *UserClient) Deactivate(ctx context.Context, id string) (*pb.UserResponse, error) {
+	c.logger.Infof("DeactivateUser called with UserId: %s", id)
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.DeactivateUser(ctx, &pb.DeactivateUserRequest{
+		UserId: id,
+	})
+}
+
+func (c *UserClient) Delete(ctx context.Context, id string) error {
+	c.logger.Infof("DeleteUser called with UserId: %s", id)
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.client.DeleteUser(ctx, &pb.DeleteUserRequest{
+		UserId: id,
+	})
+	return err
+}
+
+func (c *UserClient) List(ctx context.Context, filter pb.UserStatusFilter, pageSize int32, pageToken string) (*pb.ListUsersResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.ListUsers(ctx, &pb.ListUsersRequest{
+		PageSize:  pageSize,
+		PageToken: pageToken,
+		Status:    filter,
+	})
+}
+
+func (c *UserClient) Search(ctx context.Context, query string, filter pb.UserStatusFilter, pageSize int32, pageToken string) (*pb.ListUsersResponse, error) {
+	c.logger.Infof("SearchUsers called with query: %s", query)
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.SearchUsers(ctx, &pb.SearchUsersRequest{
+		Query:     query,
+		PageSize:  pageSize,
+		PageToken: pageToken,
+		Status:    filter,
+	})
+}
+
+func (c *UserClient) Login(ctx context.Context, email, password string) (*pb.TokenResponse, error) {
+	c.logger.Infof("Login called with email: %s", email)
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.Login(ctx, &pb.LoginRequest{
+		Email:    email,
+		Password: password,
+	})
+}
+
+func (c *UserClient) Refresh(ctx context.Context, refreshToken string) (*pb.TokenResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.RefreshToken(ctx, &pb.RefreshTokenRequest{
+		RefreshToken: refreshToken,
+	})
+}
+
+func (c *UserClient) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt time.Time) (*pb.CreateApiKeyResponse, error) {
+	c.logger.Infof("CreateApiKey called with name: %s", name)
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req := &pb.CreateApiKeyRequest{
+		Name:   name,
+		Scopes: scopes,
+	}
+	if !expiresAt.IsZero() {
+		req.ExpiresAt = expiresAt.Format(time.RFC3339)
+	}
+	return c.client.CreateApiKey(ctx, req)
+}
+
+func (c *UserClient) ListAPIKeys(ctx context.Context, includeRevoked bool) (*pb.ListApiKeysResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.ListApiKeys(ctx, &pb.ListApiKeysRequest{
+		IncludeRevoked: includeRevoked,
+	})
+}
+
+func (c *UserClient) RevokeAPIKey(ctx context.Context, id string) (*pb.ApiKey, error) {
+	c.logger.Infof("RevokeApiKey called with id: %s", id)
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.RevokeApiKey(ctx, &pb.RevokeApiKeyRequest{
+		Id: id,
+	})
+}
+
+// AuthenticateAPIKey implements auth.KeyVerifier, so other services can accept API keys.
+func (c *UserClient) AuthenticateAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	apiKey, err := c.client.VerifyApiKey(ctx, &pb.VerifyApiKeyRequest{
+		Key: key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return auth.KeyPrincipal(apiKey, key), nil
+}
+
+// Ping fails while the circuit breaker is open and otherwise asks the service's health
+// endpoint whether it is serving.
+func (c *UserClient) Ping(ctx context.Context) error {
+	return c.conn.Ping(ctx)
+}
+
 func (c *UserClient) Close() error {
 	return c.conn.Close()
 }
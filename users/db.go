@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/ViktorOHJ/library-system/observability"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -15,7 +16,14 @@ func InitDB(parentContext context.Context, dbUrl string, logger *logrus.Logger)
 	ctx, cancel := context.WithTimeout(parentContext, 10*time.Second)
 	defer cancel()
 
-	pool, err := pgxpool.New(ctx, dbUrl)
+	config, err := pgxpool.ParseConfig(dbUrl)
+	if err != nil {
+		logger.Errorf("Error to parse db url: %v", err)
+		return nil, err
+	}
+	config.ConnConfig.Tracer = observability.PgxTracer()
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		logger.Errorf("Error to create pool: %v", err)
 		return nil, err
@@ -6,10 +6,17 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/config"
+	"github.com/ViktorOHJ/library-system/healthcheck"
+	"github.com/ViktorOHJ/library-system/loans/clients"
+	"github.com/ViktorOHJ/library-system/observability"
 	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/ViktorOHJ/library-system/requestid"
+	"github.com/ViktorOHJ/library-system/tlsconfig"
 	userserver "github.com/ViktorOHJ/library-system/users/server"
-	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 )
@@ -22,21 +29,31 @@ func main() {
 		ForceColors:     true,
 	})
 
-	err := godotenv.Load(".env")
+	cfg, err := config.LoadUsers(os.Args[1:])
 	if err != nil {
-		logger.Fatal("Error loading .env file")
+		logger.Fatal(err)
 	}
-	dbUrl := os.Getenv("USR_DBURL")
-	if dbUrl == "" {
-		logger.Fatal("USR_DBURL not set in .env file")
+	tlsCreds, err := tlsconfig.Load(cfg.TLS, "users", logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure TLS: %v", err)
+	}
+	if cfg.Server.Healthcheck {
+		os.Exit(healthcheck.RunProbe("localhost:"+cfg.Server.Port, tlsCreds.DialOption()))
 	}
+	tlsCreds.Start(context.Background())
 
-	path := os.Getenv("USERS_MIGRATIONS_PATH")
-	if path == "" {
-		path = "file://users/migrations"
+	shutdownTracing, err := observability.InitTracing(context.Background(), observability.TracingConfig{
+		ServiceName: "users",
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+	}, logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure tracing: %v", err)
 	}
+	defer shutdownTracing()
 
-	runMigrations(path, dbUrl, logger)
+	dbUrl := cfg.Database.URL
+	runMigrations(cfg.Database.MigrationsPath, dbUrl, logger)
 	logger.Info("Running migrations...")
 
 	ctx := context.Background()
@@ -46,17 +63,36 @@ func main() {
 	}
 	logger.Info("Database connection established")
 	defer db.Close()
+	if err := observability.RegisterPool("users", db); err != nil {
+		logger.Fatalf("Failed to register pool metrics: %v", err)
+	}
 
-	userServer := userserver.NewUserServer(db, logger)
-	server := grpc.NewServer()
-	pb.RegisterUserServiceServer(server, userServer)
+	loansClient, err := clients.NewLoansClient(cfg.Services.Loans, 10*time.Second, logger, tlsCreds.DialOption())
+	if err != nil {
+		logger.Fatalf("Failed to create loans client: %v", err)
+	}
+	defer loansClient.Close()
 
-	PORT := os.Getenv("USERS_PORT")
-	if PORT == "" {
-		PORT = "50051"
-		logger.Infof("USERS_PORT not set, using default port %s", PORT)
+	tokens, err := auth.NewTokenManager(cfg.Auth.JWTSecret, cfg.Auth.AccessTTL, cfg.Auth.RefreshTTL)
+	if err != nil {
+		logger.Fatalf("Failed to configure authentication: %v", err)
 	}
 
+	userServer := userserver.NewUserServerWithDeps(db, logger, loansClient, tokens)
+	server := grpc.NewServer(
+		tlsCreds.ServerOption(),
+		grpc.ChainUnaryInterceptor(observability.UnaryServerInterceptor(), requestid.UnaryServerInterceptor(logger)),
+		observability.GRPCServerHandler(),
+		auth.ServerInterceptors(tokens, auth.CacheKeys(userServer, time.Minute), auth.Permissions),
+	)
+	pb.RegisterUserServiceServer(server, userServer)
+
+	checker := healthcheck.New(logger, pb.UserService_ServiceDesc.ServiceName)
+	checker.Add("database", db.Ping)
+	checker.Register(server)
+	checker.Start(ctx)
+
+	PORT := cfg.Server.Port
 	lis, err := net.Listen("tcp", ":"+PORT)
 	if err != nil {
 		logger.Fatalf("Failed to listen: %v", err)
@@ -64,6 +100,8 @@ func main() {
 
 	logger.Infof("Listening on port %s", PORT)
 
+	metricsServer := observability.Serve(cfg.Server.MetricsPort, logger)
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	go func() {
@@ -74,6 +112,8 @@ func main() {
 
 	<-stop
 	logger.Info("Received shutdown signal, stopping server gracefully...")
+	checker.Shutdown()
 	server.GracefulStop()
+	observability.Shutdown(metricsServer)
 	logger.Info("Server stopped gracefully")
 }
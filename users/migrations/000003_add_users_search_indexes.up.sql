@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS users_lower_name_idx ON users (lower(name) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS users_lower_email_idx ON users (lower(email) text_pattern_ops);
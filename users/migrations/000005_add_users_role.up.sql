@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'patron'
+    CONSTRAINT users_role_check CHECK (role IN ('patron', 'librarian', 'admin'));
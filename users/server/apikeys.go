@@ -0,0 +1,226 @@
+package userserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/dberr"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultAPIKeyTTL = 365 * 24 * time.Hour
+	maxAPIKeyTTL     = 2 * 365 * 24 * time.Hour
+
+	apiKeyColumns = "id, name, prefix, scopes, created_by, created_at, expires_at, revoked_at"
+)
+
+func (s *UserServer) CreateApiKey(parentCtx context.Context, req *pb.CreateApiKeyRequest) (*pb.CreateApiKeyResponse, error) {
+	s.logger.Info("CreateApiKey called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, status.Error(codes.InvalidArgument, "name must be 1 to 100 characters")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown scope %q", scope)
+		}
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(defaultAPIKeyTTL)
+	if req.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "expires_at must be an RFC 3339 timestamp")
+		}
+		if !t.After(now) || t.Sub(now) > maxAPIKeyTTL {
+			return nil, status.Error(codes.InvalidArgument, "expires_at must be in the future and within two years")
+		}
+		expiresAt = t
+	}
+
+	var createdBy *string
+	if p, ok := auth.FromContext(parentCtx); ok && p.UserID != "" {
+		createdBy = &p.UserID
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		s.logger.Errorf("Failed to generate api key: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
+	defer cancel()
+
+	apiKey, err := scanAPIKey(s.db.QueryRow(ctx,
+		`INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING `+apiKeyColumns,
+		name, prefix, hash, req.Scopes, createdBy, expiresAt))
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "api key")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"api_key_id": apiKey.Id,
+		"name":       apiKey.Name,
+		"scopes":     apiKey.Scopes,
+	}).Info("API key created")
+	return &pb.CreateApiKeyResponse{ApiKey: apiKey, Key: key}, nil
+}
+
+func (s *UserServer) ListApiKeys(parentCtx context.Context, req *pb.ListApiKeysRequest) (*pb.ListApiKeysResponse, error) {
+	s.logger.Info("ListApiKeys called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE $1 OR revoked_at IS NULL ORDER BY id",
+		req.IncludeRevoked)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "api key")
+	}
+	defer rows.Close()
+
+	res := &pb.ListApiKeysResponse{}
+	for rows.Next() {
+		apiKey, err := scanAPIKey(rows)
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "api key")
+		}
+		res.ApiKeys = append(res.ApiKeys, apiKey)
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "api key")
+	}
+	return res, nil
+}
+
+func (s *UserServer) RevokeApiKey(parentCtx context.Context, req *pb.RevokeApiKeyRequest) (*pb.ApiKey, error) {
+	s.logger.Info("RevokeApiKey called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := strconv.Atoi(req.Id)
+	if err != nil || id <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid api key id")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
+	defer cancel()
+
+	// Revoking twice keeps the original revocation time.
+	apiKey, err := scanAPIKey(s.db.QueryRow(ctx,
+		"UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1 RETURNING "+apiKeyColumns, id))
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("Database error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "api key")
+	}
+
+	s.logger.WithField("api_key_id", apiKey.Id).Info("API key revoked")
+	return apiKey, nil
+}
+
+// VerifyApiKey lets other services check keys presented to them. It is public:
+// knowing a valid key is all that is needed to use it anyway.
+func (s *UserServer) VerifyApiKey(parentCtx context.Context, req *pb.VerifyApiKeyRequest) (*pb.ApiKey, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	return s.lookupAPIKey(parentCtx, req.Key)
+}
+
+// AuthenticateAPIKey implements auth.KeyVerifier for the users service's own interceptor.
+func (s *UserServer) AuthenticateAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	apiKey, err := s.lookupAPIKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return auth.KeyPrincipal(apiKey, key), nil
+}
+
+func (s *UserServer) lookupAPIKey(parentCtx context.Context, key string) (*pb.ApiKey, error) {
+	invalid := status.Error(codes.Unauthenticated, "invalid, expired or revoked api key")
+
+	prefix, ok := auth.ParseAPIKeyPrefix(key)
+	if !ok {
+		return nil, invalid
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
+	defer cancel()
+
+	var hash string
+	var revokedAt *time.Time
+	var expiresAt time.Time
+	row := s.db.QueryRow(ctx, "SELECT key_hash, expires_at, revoked_at, "+apiKeyColumns+" FROM api_keys WHERE prefix = $1", prefix)
+	apiKey, err := scanAPIKey(row, &hash, &expiresAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, invalid
+		}
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "api key")
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(auth.HashAPIKey(key))) != 1 {
+		return nil, invalid
+	}
+	if revokedAt != nil || !time.Now().Before(expiresAt) {
+		return nil, invalid
+	}
+	return apiKey, nil
+}
+
+// scanAPIKey scans apiKeyColumns; extra destinations are scanned from columns selected before them.
+func scanAPIKey(row pgx.Row, extra ...any) (*pb.ApiKey, error) {
+	var (
+		id        int
+		createdBy *int
+		createdAt time.Time
+		expiresAt time.Time
+		revokedAt *time.Time
+		apiKey    = &pb.ApiKey{}
+	)
+	dest := append(extra, &id, &apiKey.Name, &apiKey.Prefix, &apiKey.Scopes, &createdBy, &createdAt, &expiresAt, &revokedAt)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	apiKey.Id = strconv.Itoa(id)
+	if createdBy != nil {
+		apiKey.CreatedBy = strconv.Itoa(*createdBy)
+	}
+	apiKey.CreatedAt = createdAt.Format(time.RFC3339)
+	apiKey.ExpiresAt = expiresAt.Format(time.RFC3339)
+	if revokedAt != nil {
+		apiKey.RevokedAt = revokedAt.Format(time.RFC3339)
+	}
+	return apiKey, nil
+}
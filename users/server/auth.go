@@ -0,0 +1,196 @@
+package userserver
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/dberr"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores anything longer
+)
+
+// dummyHash is compared against when the email is unknown, so both paths take the same time.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("library-system-dummy"), bcrypt.DefaultCost)
+
+func (s *UserServer) Login(parentCtx context.Context, req *pb.LoginRequest) (*pb.TokenResponse, error) {
+	s.logger.Info("Login called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	if req.Email == "" || req.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "email and password are required")
+	}
+	if s.tokens == nil {
+		s.logger.Error("Login called without token manager")
+		return nil, status.Error(codes.Unavailable, "authentication is not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
+	defer cancel()
+
+	var (
+		id           int
+		email        string
+		active       bool
+		role         string
+		version      int
+		passwordHash *string
+	)
+	err := s.db.QueryRow(ctx, "SELECT id, email, active, role, token_version, password_hash FROM users WHERE lower(email) = lower($1)",
+		req.Email).Scan(&id, &email, &active, &role, &version, &passwordHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "user")
+	}
+
+	hash := dummyHash
+	if passwordHash != nil {
+		hash = []byte(*passwordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) != nil || passwordHash == nil {
+		s.logger.WithField("email", req.Email).Warn("Failed login attempt")
+		return nil, status.Error(codes.Unauthenticated, "invalid email or password")
+	}
+	if !active {
+		return nil, status.Error(codes.PermissionDenied, "user is deactivated")
+	}
+
+	return s.issueTokens(strconv.Itoa(id), email, auth.Role(role), version)
+}
+
+func (s *UserServer) RefreshToken(parentCtx context.Context, req *pb.RefreshTokenRequest) (*pb.TokenResponse, error) {
+	s.logger.Info("RefreshToken called")
+
+	if req == nil || req.RefreshToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+	if s.tokens == nil {
+		s.logger.Error("RefreshToken called without token manager")
+		return nil, status.Error(codes.Unavailable, "authentication is not configured")
+	}
+
+	claims, err := s.tokens.Verify(req.RefreshToken, auth.RefreshToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired refresh token")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
+	defer cancel()
+
+	var (
+		email   string
+		active  bool
+		role    string
+		version int
+	)
+	err = s.db.QueryRow(ctx, "SELECT email, active, role, token_version FROM users WHERE id = $1",
+		claims.Subject).Scan(&email, &active, &role, &version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired refresh token")
+		}
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "user")
+	}
+	// The password was changed after this token was issued.
+	if claims.Version != version {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired refresh token")
+	}
+	if !active {
+		return nil, status.Error(codes.PermissionDenied, "user is deactivated")
+	}
+
+	return s.issueTokens(claims.Subject, email, auth.Role(role), version)
+}
+
+func (s *UserServer) issueTokens(userID, email string, role auth.Role, version int) (*pb.TokenResponse, error) {
+	pair, err := s.tokens.IssuePair(userID, email, role, version)
+	if err != nil {
+		s.logger.Errorf("Failed to issue tokens: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	s.logger.WithField("user_id", userID).Info("Tokens issued")
+	return &pb.TokenResponse{
+		AccessToken:  pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(pair.ExpiresIn.Seconds()),
+	}, nil
+}
+
+// authorizeCredentialChange decides who may change a user's email or password: the user
+// themselves, who must also give the current password (self is true), or an admin resetting
+// another account. Librarians and API keys cannot change credentials.
+func authorizeCredentialChange(ctx context.Context, req *pb.UpdateUserRequest) (self bool, err error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return false, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	switch {
+	case p.IsAPIKey():
+		return false, status.Error(codes.PermissionDenied, "API keys cannot change email or password")
+	case p.UserID == req.UserId:
+		if req.CurrentPassword == "" {
+			return false, status.Error(codes.InvalidArgument, "current_password is required to change email or password")
+		}
+		return true, nil
+	case p.Role == auth.RoleAdmin:
+		return false, nil
+	default:
+		return false, status.Error(codes.PermissionDenied, "only an admin can change another user's email or password")
+	}
+}
+
+// checkCurrentPassword compares against dummyHash when the user has no password yet, so
+// that case takes as long as a wrong password.
+func (s *UserServer) checkCurrentPassword(ctx context.Context, id int, password string) error {
+	var passwordHash *string
+	err := s.db.QueryRow(ctx, "SELECT password_hash FROM users WHERE id = $1", id).Scan(&passwordHash)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("Database error: %v", err)
+		}
+		return dberr.ToStatus(err, "user")
+	}
+	hash := dummyHash
+	if passwordHash != nil {
+		hash = []byte(*passwordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || passwordHash == nil {
+		s.logger.WithField("user_id", id).Warn("Wrong current password on credential change")
+		return status.Error(codes.PermissionDenied, "current password is incorrect")
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	if err := validatePassword(password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", status.Error(codes.Internal, "internal server error")
+	}
+	return string(hash), nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return status.Errorf(codes.InvalidArgument, "password must be at least %d characters", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return status.Errorf(codes.InvalidArgument, "password must be at most %d bytes", maxPasswordLength)
+	}
+	return nil
+}
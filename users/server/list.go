@@ -0,0 +1,133 @@
+package userserver
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ViktorOHJ/library-system/dberr"
+	pb "github.com/ViktorOHJ/library-system/protos/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func (s *UserServer) ListUsers(parentCtx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	s.logger.Info("ListUsers called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	return s.listUsers(parentCtx, "", req.Status, req.PageSize, req.PageToken)
+}
+
+func (s *UserServer) SearchUsers(parentCtx context.Context, req *pb.SearchUsersRequest) (*pb.ListUsersResponse, error) {
+	s.logger.Info("SearchUsers called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return nil, status.Error(codes.InvalidArgument, "query cannot be empty")
+	}
+	if len(query) > 100 {
+		return nil, status.Error(codes.InvalidArgument, "query too long")
+	}
+	return s.listUsers(parentCtx, query, req.Status, req.PageSize, req.PageToken)
+}
+
+func (s *UserServer) listUsers(parentCtx context.Context, query string, filter pb.UserStatusFilter, pageSize int32, pageToken string) (*pb.ListUsersResponse, error) {
+	if pageSize < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size cannot be negative")
+	}
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	afterID, err := decodePageToken(pageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	conds := []string{"id > $1"}
+	args := []any{afterID}
+	switch filter {
+	case pb.UserStatusFilter_USER_STATUS_ANY:
+	case pb.UserStatusFilter_USER_STATUS_ACTIVE:
+		conds = append(conds, "active")
+	case pb.UserStatusFilter_USER_STATUS_INACTIVE:
+		conds = append(conds, "NOT active")
+	default:
+		return nil, status.Error(codes.InvalidArgument, "invalid status filter")
+	}
+	if query != "" {
+		args = append(args, escapeLike(strings.ToLower(query))+"%")
+		conds = append(conds, fmt.Sprintf("(lower(name) LIKE $%[1]d OR lower(email) LIKE $%[1]d)", len(args)))
+	}
+	args = append(args, pageSize+1)
+	sql := fmt.Sprintf("SELECT %s FROM users WHERE %s ORDER BY id LIMIT $%d",
+		userColumns, strings.Join(conds, " AND "), len(args))
+
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "user")
+	}
+	defer rows.Close()
+
+	res := &pb.ListUsersResponse{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "user")
+		}
+		res.Users = append(res.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "user")
+	}
+
+	if len(res.Users) > int(pageSize) {
+		res.Users = res.Users[:pageSize]
+		res.NextPageToken = encodePageToken(res.Users[pageSize-1].Id)
+	}
+	return res, nil
+}
+
+// Page tokens are opaque to clients; they carry the id of the last returned user.
+func encodePageToken(lastID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("after:" + lastID))
+}
+
+func decodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "invalid page token")
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(string(raw), "after:"))
+	if err != nil || id <= 0 || !strings.HasPrefix(string(raw), "after:") {
+		return 0, status.Error(codes.InvalidArgument, "invalid page token")
+	}
+	return id, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -7,8 +7,10 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/ViktorOHJ/library-system/auth"
+	"github.com/ViktorOHJ/library-system/dberr"
 	pb "github.com/ViktorOHJ/library-system/protos/pb"
-	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
@@ -17,12 +19,20 @@ import (
 
 const (
 	timeout = 10 * time.Second
+
+	userColumns = "id, name, email, active, role"
 )
 
+type LoanService interface {
+	UserLoanSummary(ctx context.Context, userID string) (*pb.UserLoanSummaryResponse, error)
+}
+
 type UserServer struct {
 	pb.UnimplementedUserServiceServer
-	db     *pgxpool.Pool
-	logger *logrus.Logger
+	db          *pgxpool.Pool
+	logger      *logrus.Logger
+	loanService LoanService
+	tokens      *auth.TokenManager
 }
 
 func NewUserServer(db *pgxpool.Pool, logger *logrus.Logger) *UserServer {
@@ -30,6 +40,15 @@ func NewUserServer(db *pgxpool.Pool, logger *logrus.Logger) *UserServer {
 		logger: logger}
 }
 
+func NewUserServerWithDeps(db *pgxpool.Pool, logger *logrus.Logger, loanService LoanService, tokens *auth.TokenManager) *UserServer {
+	return &UserServer{
+		db:          db,
+		logger:      logger,
+		loanService: loanService,
+		tokens:      tokens,
+	}
+}
+
 func (s *UserServer) CreateUser(parentCtx context.Context, req *pb.CreateUserRequest) (res *pb.UserResponse, err error) {
 	s.logger.Info("CreateUser called")
 
@@ -38,15 +57,23 @@ func (s *UserServer) CreateUser(parentCtx context.Context, req *pb.CreateUserReq
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	var passwordHash *string
+	if req.Password != "" {
+		hash, err := hashPassword(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		passwordHash = &hash
+	}
+
 	ctx, cancel := context.WithTimeout(parentCtx, timeout)
 	defer cancel()
 
-	user := &pb.UserResponse{}
-	err = s.db.QueryRow(ctx, "INSERT INTO users (name, email) VALUES ($1, $2) RETURNING id, name, email",
-		req.Name, req.Email).Scan(&user.Id, &user.Name, &user.Email)
+	user, err := scanUser(s.db.QueryRow(ctx, "INSERT INTO users (name, email, password_hash) VALUES ($1, $2, $3) RETURNING "+userColumns,
+		req.Name, req.Email, passwordHash))
 	if err != nil {
 		s.logger.Errorf("Database error: %v", err)
-		return nil, status.Errorf(codes.Internal, "internal server error")
+		return nil, dberr.ToStatus(err, "user")
 	}
 	return user, nil
 }
@@ -73,19 +100,245 @@ func (s *UserServer) GetUser(parentCtx context.Context, req *pb.GetUserRequest)
 		return nil, status.Error(codes.InvalidArgument, "Invalid UserId format")
 	}
 
-	row := s.db.QueryRow(ctx, "SELECT id, name, email FROM users WHERE id=$1", id)
+	if err := auth.RequireSelfOrStaff(parentCtx, req.UserId); err != nil {
+		return nil, err
+	}
 
-	err = row.Scan(&res.Id, &res.Name, &res.Email)
+	res, err = scanUser(s.db.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id=$1", id))
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, status.Error(codes.NotFound, "user not found")
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("Database error: %v", err)
 		}
-		s.logger.Errorf("Database error: %v", err)
-		return nil, status.Error(codes.Internal, "internal server error")
+		return nil, dberr.ToStatus(err, "user")
 	}
 	return res, nil
 }
 
+func (s *UserServer) UpdateUser(parentCtx context.Context, req *pb.UpdateUserRequest) (*pb.UserResponse, error) {
+	s.logger.Info("UpdateUser called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := parseUserID(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		if req.Name != "" {
+			paths = append(paths, "name")
+		}
+		if req.Email != "" {
+			paths = append(paths, "email")
+		}
+		if req.Password != "" {
+			paths = append(paths, "password")
+		}
+	}
+	if len(paths) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "nothing to update")
+	}
+
+	var name, email, passwordHash *string
+	for _, path := range paths {
+		switch path {
+		case "name":
+			if req.Name == "" {
+				return nil, status.Error(codes.InvalidArgument, "name cannot be empty")
+			}
+			name = &req.Name
+		case "email":
+			if req.Email == "" || !isValidEmail(req.Email) {
+				return nil, status.Error(codes.InvalidArgument, "invalid email")
+			}
+			email = &req.Email
+		case "password":
+			hash, err := hashPassword(req.Password)
+			if err != nil {
+				return nil, err
+			}
+			passwordHash = &hash
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unknown update_mask path %q", path)
+		}
+	}
+
+	credentials := email != nil || passwordHash != nil
+	self := false
+	if credentials {
+		self, err = authorizeCredentialChange(parentCtx, req)
+	} else {
+		err = auth.RequireSelfOrStaff(parentCtx, req.UserId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
+	defer cancel()
+
+	if self {
+		if err := s.checkCurrentPassword(ctx, id, req.CurrentPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	if email != nil {
+		var taken bool
+		err = s.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE lower(email) = lower($1) AND id <> $2)",
+			*email, id).Scan(&taken)
+		if err != nil {
+			s.logger.Errorf("Database error: %v", err)
+			return nil, dberr.ToStatus(err, "user")
+		}
+		if taken {
+			return nil, status.Error(codes.AlreadyExists, "email already in use")
+		}
+	}
+
+	// A new password bumps token_version, which revokes every refresh token issued before.
+	user, err := scanUser(s.db.QueryRow(ctx, `UPDATE users SET name = COALESCE($2, name), email = COALESCE($3, email),
+	password_hash = COALESCE($4, password_hash),
+	token_version = token_version + CASE WHEN $4::text IS NULL THEN 0 ELSE 1 END
+	WHERE id = $1 RETURNING `+userColumns,
+		id, name, email, passwordHash))
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("Database error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "user")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id": user.Id,
+		"fields":  paths,
+	}).Info("User updated")
+	return user, nil
+}
+
+func (s *UserServer) DeactivateUser(parentCtx context.Context, req *pb.DeactivateUserRequest) (*pb.UserResponse, error) {
+	s.logger.Info("DeactivateUser called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := parseUserID(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
+	defer cancel()
+
+	user, err := scanUser(s.db.QueryRow(ctx, "UPDATE users SET active = FALSE WHERE id = $1 RETURNING "+userColumns, id))
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("Database error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "user")
+	}
+
+	s.logger.WithField("user_id", user.Id).Info("User deactivated")
+	return user, nil
+}
+
+func (s *UserServer) DeleteUser(parentCtx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
+	s.logger.Info("DeleteUser called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := parseUserID(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if s.loanService == nil {
+		s.logger.Error("DeleteUser called without loan service")
+		return nil, status.Error(codes.Unavailable, "cannot verify user loans")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
+	defer cancel()
+
+	summary, err := s.loanService.UserLoanSummary(ctx, req.UserId)
+	if err != nil {
+		s.logger.Errorf("Failed to get loan summary for user %s: %v", req.UserId, err)
+		return nil, status.Error(codes.Unavailable, "cannot verify user loans")
+	}
+	if summary.ActiveLoans > 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "user has %d active loans", summary.ActiveLoans)
+	}
+
+	tag, err := s.db.Exec(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		s.logger.Errorf("Database error: %v", err)
+		return nil, dberr.ToStatus(err, "user")
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	s.logger.WithField("user_id", id).Info("User deleted")
+	return &pb.DeleteUserResponse{Success: true}, nil
+}
+
+func (s *UserServer) SetUserRole(parentCtx context.Context, req *pb.SetUserRoleRequest) (*pb.UserResponse, error) {
+	s.logger.Info("SetUserRole called")
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+	id, err := parseUserID(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	role, ok := auth.ParseRole(req.Role)
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "role must be patron, librarian or admin")
+	}
+	if p, ok := auth.FromContext(parentCtx); ok && p.UserID == req.UserId && role != auth.RoleAdmin {
+		return nil, status.Error(codes.FailedPrecondition, "admins cannot revoke their own admin role")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
+	defer cancel()
+
+	user, err := scanUser(s.db.QueryRow(ctx, "UPDATE users SET role = $2 WHERE id = $1 RETURNING "+userColumns, id, string(role)))
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Errorf("Database error: %v", err)
+		}
+		return nil, dberr.ToStatus(err, "user")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id": user.Id,
+		"role":    user.Role,
+	}).Info("User role changed")
+	return user, nil
+}
+
+func scanUser(row pgx.Row) (*pb.UserResponse, error) {
+	user := &pb.UserResponse{}
+	if err := row.Scan(&user.Id, &user.Name, &user.Email, &user.Active, &user.Role); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func parseUserID(userID string) (int, error) {
+	if userID == "" {
+		return 0, status.Error(codes.InvalidArgument, "UserId cannot be empty")
+	}
+	id, err := strconv.Atoi(userID)
+	if err != nil || id <= 0 {
+		return 0, status.Error(codes.InvalidArgument, "Invalid UserId format")
+	}
+	return id, nil
+}
+
 func validateCreateUserRequest(name, email string) error {
 	if name == "" {
 		return status.Error(codes.InvalidArgument, "name cannot be empty")
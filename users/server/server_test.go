@@ -2,15 +2,18 @@ package userserver
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 
+	"github.com/ViktorOHJ/library-system/auth"
 	"github.com/ViktorOHJ/library-system/protos/pb"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func setupTestDB(t *testing.T, logger *logrus.Logger) *pgxpool.Pool {
@@ -27,7 +30,25 @@ func setupTestDB(t *testing.T, logger *logrus.Logger) *pgxpool.Pool {
 	CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     name VARCHAR(100) NOT NULL,
-    email VARCHAR(100) UNIQUE NOT NULL
+    email VARCHAR(100) UNIQUE NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    password_hash TEXT,
+    role VARCHAR(20) NOT NULL DEFAULT 'patron',
+    token_version INTEGER NOT NULL DEFAULT 0
+)`)
+	require.NoError(t, err)
+
+	_, err = pool.Exec(context.Background(), `
+	CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix CHAR(8) UNIQUE NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
 )`)
 	require.NoError(t, err)
 
@@ -112,7 +133,8 @@ func TestUserServer_GetUser_Success(t *testing.T) {
 	req := &pb.GetUserRequest{
 		UserId: "1",
 	}
-	resp, err := server.GetUser(context.Background(), req)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "1", Role: auth.RolePatron})
+	resp, err := server.GetUser(ctx, req)
 
 	require.NoError(t, err)
 	require.NotEmpty(t, resp.Id)
@@ -176,3 +198,375 @@ func TestUserServer_GetUser_InvalidArgument(t *testing.T) {
 		})
 	}
 }
+
+type mockLoanService struct {
+	summary *pb.UserLoanSummaryResponse
+	err     error
+}
+
+func (m *mockLoanService) UserLoanSummary(ctx context.Context, userID string) (*pb.UserLoanSummaryResponse, error) {
+	return m.summary, m.err
+}
+
+func TestUserServer_UpdateUser_InvalidArgument(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewUserServer(nil, logger)
+
+	tests := []struct {
+		name    string
+		req     *pb.UpdateUserRequest
+		errCode string
+	}{
+		{
+			name:    "Nil Request",
+			req:     nil,
+			errCode: "InvalidArgument",
+		},
+		{
+			name:    "Invalid UserId",
+			req:     &pb.UpdateUserRequest{UserId: "abc", Name: "New Name"},
+			errCode: "InvalidArgument",
+		},
+		{
+			name:    "Nothing To Update",
+			req:     &pb.UpdateUserRequest{UserId: "1"},
+			errCode: "InvalidArgument",
+		},
+		{
+			name: "Empty Name In Mask",
+			req: &pb.UpdateUserRequest{
+				UserId:     "1",
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+			},
+			errCode: "InvalidArgument",
+		},
+		{
+			name:    "Invalid Email",
+			req:     &pb.UpdateUserRequest{UserId: "1", Email: "invalid-email"},
+			errCode: "InvalidArgument",
+		},
+		{
+			name: "Unknown Path",
+			req: &pb.UpdateUserRequest{
+				UserId:     "1",
+				Name:       "New Name",
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"id"}},
+			},
+			errCode: "InvalidArgument",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.UpdateUser(context.Background(), tt.req)
+			require.Nil(t, resp)
+			require.Error(t, err)
+			st, ok := status.FromError(err)
+			require.True(t, ok)
+			require.Equal(t, tt.errCode, st.Code().String())
+		})
+	}
+}
+
+func TestUserServer_DeleteUser_Blocked(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	tests := []struct {
+		name        string
+		loanService LoanService
+		errCode     string
+	}{
+		{
+			name:        "Active Loans",
+			loanService: &mockLoanService{summary: &pb.UserLoanSummaryResponse{ActiveLoans: 2}},
+			errCode:     "FailedPrecondition",
+		},
+		{
+			name:        "Loan Service Down",
+			loanService: &mockLoanService{err: errors.New("connection refused")},
+			errCode:     "Unavailable",
+		},
+		{
+			name:        "No Loan Service",
+			loanService: nil,
+			errCode:     "Unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewUserServerWithDeps(nil, logger, tt.loanService, nil)
+			resp, err := server.DeleteUser(context.Background(), &pb.DeleteUserRequest{UserId: "1"})
+			require.Nil(t, resp)
+			require.Error(t, err)
+			st, ok := status.FromError(err)
+			require.True(t, ok)
+			require.Equal(t, tt.errCode, st.Code().String())
+		})
+	}
+}
+
+func TestUserServer_SearchUsers_InvalidArgument(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewUserServer(nil, logger)
+
+	tests := []struct {
+		name    string
+		req     *pb.SearchUsersRequest
+		errCode string
+	}{
+		{
+			name:    "Nil Request",
+			req:     nil,
+			errCode: "InvalidArgument",
+		},
+		{
+			name:    "Empty Query",
+			req:     &pb.SearchUsersRequest{Query: "   "},
+			errCode: "InvalidArgument",
+		},
+		{
+			name:    "Negative Page Size",
+			req:     &pb.SearchUsersRequest{Query: "iv", PageSize: -1},
+			errCode: "InvalidArgument",
+		},
+		{
+			name:    "Malformed Page Token",
+			req:     &pb.SearchUsersRequest{Query: "iv", PageToken: "!!!"},
+			errCode: "InvalidArgument",
+		},
+		{
+			name:    "Foreign Page Token",
+			req:     &pb.SearchUsersRequest{Query: "iv", PageToken: "MTIz"},
+			errCode: "InvalidArgument",
+		},
+		{
+			name:    "Unknown Status Filter",
+			req:     &pb.SearchUsersRequest{Query: "iv", Status: pb.UserStatusFilter(42)},
+			errCode: "InvalidArgument",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.SearchUsers(context.Background(), tt.req)
+			require.Nil(t, resp)
+			require.Error(t, err)
+			st, ok := status.FromError(err)
+			require.True(t, ok)
+			require.Equal(t, tt.errCode, st.Code().String())
+		})
+	}
+}
+
+func TestPageToken_RoundTrip(t *testing.T) {
+	id, err := decodePageToken(encodePageToken("42"))
+	require.NoError(t, err)
+	require.Equal(t, 42, id)
+
+	id, err = decodePageToken("")
+	require.NoError(t, err)
+	require.Equal(t, 0, id)
+}
+
+func TestEscapeLike(t *testing.T) {
+	require.Equal(t, `a\%b\_c\\`, escapeLike(`a%b_c\`))
+	require.Equal(t, "ivan", escapeLike("ivan"))
+}
+
+func TestUserServer_Login_InvalidArgument(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewUserServer(nil, logger)
+
+	_, err := server.Login(context.Background(), &pb.LoginRequest{Email: "test@gmail.com"})
+	require.Equal(t, "InvalidArgument", status.Code(err).String())
+
+	_, err = server.RefreshToken(context.Background(), &pb.RefreshTokenRequest{})
+	require.Equal(t, "InvalidArgument", status.Code(err).String())
+}
+
+func TestValidatePassword(t *testing.T) {
+	require.Error(t, validatePassword("short"))
+	require.Error(t, validatePassword(string(make([]byte, 73))))
+	require.NoError(t, validatePassword("correct horse"))
+}
+
+func TestUserServer_GetUser_OtherUserDenied(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewUserServer(nil, logger)
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "1", Role: auth.RolePatron})
+	resp, err := server.GetUser(ctx, &pb.GetUserRequest{UserId: "2"})
+	require.Nil(t, resp)
+	require.Equal(t, "PermissionDenied", status.Code(err).String())
+
+	resp, err = server.GetUser(context.Background(), &pb.GetUserRequest{UserId: "2"})
+	require.Nil(t, resp)
+	require.Equal(t, "Unauthenticated", status.Code(err).String())
+}
+
+func TestUserServer_SetUserRole_Invalid(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewUserServer(nil, logger)
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "1", Role: auth.RoleAdmin})
+
+	tests := []struct {
+		name    string
+		req     *pb.SetUserRoleRequest
+		errCode string
+	}{
+		{
+			name:    "Unknown Role",
+			req:     &pb.SetUserRoleRequest{UserId: "2", Role: "superuser"},
+			errCode: "InvalidArgument",
+		},
+		{
+			name:    "Public Role",
+			req:     &pb.SetUserRoleRequest{UserId: "2", Role: "public"},
+			errCode: "InvalidArgument",
+		},
+		{
+			name:    "Self Demotion",
+			req:     &pb.SetUserRoleRequest{UserId: "1", Role: "patron"},
+			errCode: "FailedPrecondition",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.SetUserRole(admin, tt.req)
+			require.Nil(t, resp)
+			require.Equal(t, tt.errCode, status.Code(err).String())
+		})
+	}
+}
+
+func TestUserServer_UpdateUser_CredentialsDenied(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewUserServer(nil, logger)
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		req       *pb.UpdateUserRequest
+		errCode   string
+	}{
+		{
+			name:      "Librarian Changes Admin Email",
+			principal: &auth.Principal{UserID: "2", Role: auth.RoleLibrarian},
+			req:       &pb.UpdateUserRequest{UserId: "1", Email: "attacker@example.com"},
+			errCode:   "PermissionDenied",
+		},
+		{
+			name:      "Librarian Changes Admin Password",
+			principal: &auth.Principal{UserID: "2", Role: auth.RoleLibrarian},
+			req:       &pb.UpdateUserRequest{UserId: "1", Password: "new password"},
+			errCode:   "PermissionDenied",
+		},
+		{
+			name:      "API Key Changes Password",
+			principal: &auth.Principal{APIKeyID: "5", Role: auth.RoleLibrarian, Scopes: []string{"users:write"}},
+			req:       &pb.UpdateUserRequest{UserId: "1", Password: "new password"},
+			errCode:   "PermissionDenied",
+		},
+		{
+			name:      "Self Without Current Password",
+			principal: &auth.Principal{UserID: "1", Role: auth.RolePatron},
+			req:       &pb.UpdateUserRequest{UserId: "1", Email: "new@example.com"},
+			errCode:   "InvalidArgument",
+		},
+		{
+			name:    "Anonymous",
+			req:     &pb.UpdateUserRequest{UserId: "1", Password: "new password"},
+			errCode: "Unauthenticated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.WithPrincipal(ctx, tt.principal)
+			}
+			resp, err := server.UpdateUser(ctx, tt.req)
+			require.Nil(t, resp)
+			require.Equal(t, tt.errCode, status.Code(err).String())
+		})
+	}
+}
+
+func TestUserServer_UpdateUser_PasswordRevokesRefreshTokens(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	db := setupTestDB(t, logger)
+	defer db.Close()
+	tokens, err := auth.NewTokenManager("test-secret-that-is-long-enough-32b", 0, 0)
+	require.NoError(t, err)
+	server := NewUserServerWithDeps(db, logger, nil, tokens)
+
+	user, err := server.CreateUser(context.Background(), &pb.CreateUserRequest{
+		Name: "Token User", Email: "token-user@example.com", Password: "old password"})
+	require.NoError(t, err)
+	login, err := server.Login(context.Background(), &pb.LoginRequest{Email: user.Email, Password: "old password"})
+	require.NoError(t, err)
+
+	self := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: user.Id, Role: auth.RolePatron})
+	_, err = server.UpdateUser(self, &pb.UpdateUserRequest{UserId: user.Id, Password: "new password", CurrentPassword: "wrong password"})
+	require.Equal(t, "PermissionDenied", status.Code(err).String())
+	_, err = server.UpdateUser(self, &pb.UpdateUserRequest{UserId: user.Id, Password: "new password", CurrentPassword: "old password"})
+	require.NoError(t, err)
+
+	_, err = server.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	require.Equal(t, "Unauthenticated", status.Code(err).String())
+
+	login, err = server.Login(context.Background(), &pb.LoginRequest{Email: user.Email, Password: "new password"})
+	require.NoError(t, err)
+	_, err = server.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	require.NoError(t, err)
+
+	// An admin may reset the password without knowing the old one.
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: "0", Role: auth.RoleAdmin})
+	_, err = server.UpdateUser(admin, &pb.UpdateUserRequest{UserId: user.Id, Password: "reset password"})
+	require.NoError(t, err)
+	_, err = server.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	require.Equal(t, "Unauthenticated", status.Code(err).String())
+}
+
+func TestUserServer_CreateApiKey_Invalid(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewUserServer(nil, logger)
+
+	tests := []struct {
+		name string
+		req  *pb.CreateApiKeyRequest
+	}{
+		{name: "Nil Request", req: nil},
+		{name: "Empty Name", req: &pb.CreateApiKeyRequest{Name: " ", Scopes: []string{"books:read"}}},
+		{name: "No Scopes", req: &pb.CreateApiKeyRequest{Name: "kiosk"}},
+		{name: "Unknown Scope", req: &pb.CreateApiKeyRequest{Name: "kiosk", Scopes: []string{"books:delete"}}},
+		{name: "Bad Expiry", req: &pb.CreateApiKeyRequest{Name: "kiosk", Scopes: []string{"books:read"}, ExpiresAt: "tomorrow"}},
+		{name: "Past Expiry", req: &pb.CreateApiKeyRequest{Name: "kiosk", Scopes: []string{"books:read"}, ExpiresAt: "2020-01-01T00:00:00Z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.CreateApiKey(context.Background(), tt.req)
+			require.Nil(t, resp)
+			require.Equal(t, "InvalidArgument", status.Code(err).String())
+		})
+	}
+}
+
+func TestUserServer_VerifyApiKey_Malformed(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	server := NewUserServer(nil, logger)
+
+	for _, key := range []string{"", "not-a-key", "lsk_short_x"} {
+		resp, err := server.VerifyApiKey(context.Background(), &pb.VerifyApiKeyRequest{Key: key})
+		require.Nil(t, resp)
+		require.Equal(t, "Unauthenticated", status.Code(err).String())
+	}
+}